@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/Utsav-pixel/go-sensor-engine/internal/publisher"
+)
+
+const (
+	// benchmarkReadings is the fixed load sent through every publisher
+	benchmarkReadings = 20000
+	// benchmarkBatchSize is the number of readings per publish call
+	benchmarkBatchSize = 100
+)
+
+// benchmarkTarget builds one publisher of the benchmark matrix. dir is a
+// scratch directory and endpoint a local HTTP server that accepts anything.
+type benchmarkTarget struct {
+	name string
+	open func(dir, endpoint string) (engine.Publisher[float64], error)
+}
+
+var benchmarkTargets = []benchmarkTarget{
+	{"discard", func(dir, endpoint string) (engine.Publisher[float64], error) {
+		return &discardPublisher[float64]{}, nil
+	}},
+	{"file/ndjson", func(dir, endpoint string) (engine.Publisher[float64], error) {
+		return publisher.NewFilePublisher[float64](filepath.Join(dir, "bench.ndjson"))
+	}},
+	{"file/cbor", func(dir, endpoint string) (engine.Publisher[float64], error) {
+		return publisher.NewFilePublisher[float64](filepath.Join(dir, "bench.cbor"), publisher.WithFileFormat(publisher.FileFormatCBOR))
+	}},
+	{"http/json", func(dir, endpoint string) (engine.Publisher[float64], error) {
+		return publisher.NewGenericHTTPPublisher[float64](endpoint, publisher.WithTransport(publisher.HighThroughputTransport())), nil
+	}},
+	{"http/cbor", func(dir, endpoint string) (engine.Publisher[float64], error) {
+		return publisher.NewGenericHTTPPublisher[float64](endpoint, publisher.WithTransport(publisher.HighThroughputTransport()), publisher.WithCBOR()), nil
+	}},
+}
+
+// runBenchmarkPublishers sends the same synthetic load through every
+// publisher of the matrix and prints throughput, publish latency
+// percentiles and error rates in a table. The local publishers need no
+// external infrastructure.
+func runBenchmarkPublishers() {
+	dir, err := os.MkdirTemp("", "sensor-engine-bench")
+	if err != nil {
+		log.Fatalf("Failed to create scratch directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	log.Printf("📏 Publishing %d readings in batches of %d through %d publishers...", benchmarkReadings, benchmarkBatchSize, len(benchmarkTargets))
+	fmt.Printf("%-12s %12s %10s %10s %10s %8s %8s\n", "PUBLISHER", "READINGS/S", "P50", "P95", "P99", "ERRORS", "ERR%")
+	for _, target := range benchmarkTargets {
+		pub, err := target.open(dir, server.URL)
+		if err != nil {
+			fmt.Printf("%-12s failed to open: %v\n", target.name, err)
+			continue
+		}
+		result, err := benchmarkPublisher(pub)
+		if err != nil {
+			fmt.Printf("%-12s failed: %v\n", target.name, err)
+			continue
+		}
+		fmt.Printf("%-12s %12.0f %10v %10v %10v %8d %7.2f%%\n", target.name, result.throughput,
+			result.percentile(0.50), result.percentile(0.95), result.percentile(0.99),
+			result.errors, 100*float64(result.errors)/float64(max(result.calls, 1)))
+	}
+}
+
+// benchmarkResult is the outcome of one publisher's run
+type benchmarkResult struct {
+	throughput float64         // Readings published per second of wall time
+	latencies  []time.Duration // Duration of every publish call, sorted
+	calls      uint64
+	errors     uint64
+}
+
+// percentile returns the latency below which a fraction p of the publish
+// calls finished
+func (r benchmarkResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(r.latencies)))) - 1
+	return r.latencies[max(index, 0)]
+}
+
+// benchmarkPublisher runs the fixed load through pub on virtual time, so
+// readings are produced as fast as pub accepts them, and closes pub
+func benchmarkPublisher(pub engine.Publisher[float64]) (benchmarkResult, error) {
+	config := engine.Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      benchmarkBatchSize,
+		MaxWorkers:     1,
+		Clock:          engine.NewManualClock(time.Now()),
+		RandSource:     rand.NewPCG(1, 2),
+	}
+	timed := &timingPublisher{next: pub}
+	sensorFunc := engine.NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+		return input * 100.0
+	})
+	e := engine.NewEngine(config, engine.NewRandomSeederWithSource(0, 1, rand.NewPCG(3, 4)), sensorFunc, timed)
+
+	start := time.Now()
+	if err := e.RunFor(context.Background(), benchmarkReadings*config.ProductionRate); err != nil {
+		pub.Close()
+		return benchmarkResult{}, err
+	}
+	elapsed := time.Since(start)
+
+	stats := e.Stats()
+	slices.Sort(timed.latencies)
+	return benchmarkResult{
+		throughput: float64(stats.Published) / elapsed.Seconds(),
+		latencies:  timed.latencies,
+		calls:      stats.Batches + stats.Singles + stats.PublishErrors,
+		errors:     stats.PublishErrors,
+	}, nil
+}
+
+// timingPublisher records the duration of every publish call to next
+type timingPublisher struct {
+	next      engine.Publisher[float64]
+	mutex     sync.Mutex
+	latencies []time.Duration
+}
+
+func (t *timingPublisher) Publish(ctx context.Context, data engine.SensorData[float64]) error {
+	return t.time(func() error { return t.next.Publish(ctx, data) })
+}
+
+func (t *timingPublisher) PublishBatch(ctx context.Context, data []engine.SensorData[float64]) error {
+	return t.time(func() error { return t.next.PublishBatch(ctx, data) })
+}
+
+func (t *timingPublisher) time(publish func() error) error {
+	start := time.Now()
+	err := publish()
+	t.mutex.Lock()
+	t.latencies = append(t.latencies, time.Since(start))
+	t.mutex.Unlock()
+	return err
+}
+
+func (t *timingPublisher) Close() error {
+	return t.next.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/examples"
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// runCheckCompat compares the readings of two example types or config
+// files, given as "old,new", and reports whether the new readings are
+// compatible with the old ones
+func runCheckCompat(identifiers string) bool {
+	oldID, newID, ok := strings.Cut(identifiers, ",")
+	if !ok {
+		log.Fatalf("-check-compat takes two example types or config files separated by a comma, got %q", identifiers)
+	}
+
+	samples := make([]any, 2)
+	for i, id := range []string{oldID, newID} {
+		sample, err := compatSample(id)
+		if err != nil {
+			log.Fatalf("Failed to generate a reading for %s: %v", id, err)
+		}
+		samples[i] = sample
+	}
+
+	problems := engine.CheckJSONCompat(samples[0], samples[1])
+	for _, problem := range problems {
+		fmt.Printf("BREAKING  %s\n", problem)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("✅ %s is compatible with %s\n", newID, oldID)
+	}
+	return len(problems) == 0
+}
+
+// compatSample generates one reading of an example type, or of the first
+// engine of a config file, as published. Readings of a config with an
+// output envelope are wrapped in it.
+func compatSample(id string) (any, error) {
+	if sample, err := examples.SampleReading(id); err == nil {
+		return sample, nil
+	}
+
+	configFile, err := engine.LoadConfigFromFile(id)
+	if err != nil {
+		return nil, fmt.Errorf("not an example type, and %w", err)
+	}
+	group, err := engine.NewEngineGroup(configFile, func(*engine.ConfigFile) (engine.SensorFunction[float64], engine.Publisher[float64], error) {
+		sensorFunc := engine.NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+			return input * 100.0
+		})
+		return sensorFunc, &discardPublisher[float64]{}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e := group.Engines[0]
+	if envelope, err := e.GenerateEnvelope(); err == nil {
+		return envelope, nil
+	}
+	return e.Generate(), nil
+}
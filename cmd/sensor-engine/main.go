@@ -2,23 +2,54 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Utsav-pixel/go-sensor-engine/examples"
 	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/Utsav-pixel/go-sensor-engine/internal/publisher"
 )
 
+// stringList collects the values of a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ", ") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
+	var overrides stringList
+	flag.Var(&overrides, "set", "Override a config field, as in engine.batch_size=50 (repeatable)")
+
 	var (
 		sensorType = flag.String("type", "", "Sensor example type: temperature, iot, industrial, weather, financial, config")
-		config     = flag.String("config", "", "JSON configuration file path")
+		config     = flag.String("config", "", "JSON configuration file path, or - for stdin")
 		duration   = flag.Duration("duration", 10*time.Second, "How long to run the sensor engine")
+		tune       = flag.Bool("tune", false, "Recommend batch settings for the -config production rate")
+		selftest   = flag.Bool("selftest", false, "Check that the seeders, engine and publisher shutdown work")
+		compat     = flag.String("check-compat", "", "Report breaking changes between the readings of two built-in example types or config files (float64 data): old,new")
+		benchmark  = flag.Bool("benchmark-publishers", false, "Compare throughput, latency and errors of the local publishers")
+		replay     = flag.String("replay", "", "NDJSON capture of readings to publish again at their captured pace")
+		speed      = flag.Float64("speed", 1.0, "Playback speed for -replay: 2 replays twice as fast")
+		target     replayTarget
 		help       = flag.Bool("help", false, "Show help information")
 	)
+	flag.StringVar(&target.publisher, "publisher", "console", "Publisher for -replay: console, http or kafka")
+	flag.StringVar(&target.endpoint, "endpoint", "http://localhost:8080/sensors", "HTTP endpoint URL for -publisher=http")
+	flag.StringVar(&target.brokers, "brokers", "localhost:9092", "Comma-separated Kafka broker addresses for -publisher=kafka")
+	flag.StringVar(&target.topic, "topic", "sensors", "Kafka topic for -publisher=kafka")
 	flag.Parse()
 
 	if *help {
@@ -26,14 +57,47 @@ func main() {
 		return
 	}
 
+	if *selftest {
+		if !runSelfTest() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *benchmark {
+		runBenchmarkPublishers()
+		return
+	}
+
+	if *replay != "" {
+		runReplay(*replay, *speed, target)
+		return
+	}
+
+	if *compat != "" {
+		if !runCheckCompat(*compat) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *sensorType == "" && *config == "" {
 		fmt.Println("Error: Please specify either -type or -config")
 		showHelp()
 		os.Exit(1)
 	}
 
+	if *tune {
+		if *config == "" {
+			fmt.Println("Error: -tune requires -config")
+			os.Exit(1)
+		}
+		runTune(*config, overrides)
+		return
+	}
+
 	if *config != "" {
-		runFromConfig(*config, *duration)
+		runFromConfig(*config, overrides, *duration)
 		return
 	}
 
@@ -60,37 +124,231 @@ func main() {
 	}
 }
 
-func runFromConfig(configPath string, duration time.Duration) {
+func runFromConfig(configPath string, overrides []string, duration time.Duration) {
 	log.Printf("🚀 Starting sensor engine from config: %s", configPath)
 
-	// Create a simple function for demonstration
+	configFile := loadConfig(configPath, overrides)
+
+	// Create engines from config, each with a simple function for demonstration
+	group, err := engine.NewEngineGroup(configFile, func(*engine.ConfigFile) (engine.SensorFunction[float64], engine.Publisher[float64], error) {
+		sensorFunc := engine.NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+			return input * 100.0
+		})
+		return sensorFunc, examples.NewConsolePublisher[float64](), nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create engine from config: %v", err)
+	}
+	if len(group.Engines) > 1 {
+		log.Printf("🚀 Running %d engines: %s", len(group.Engines), strings.Join(group.Names, ", "))
+	}
+
+	// One signal handler stops every engine
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	if configPath != stdinConfigPath {
+		go reloadOnHangup(ctx, group, configFile, configPath, overrides)
+	}
+
+	group.Start(ctx, func(name string, err error) {
+		if err != nil {
+			log.Printf("Engine %s error: %v", name, err)
+		}
+	})
+
+	stats := group.Stats()
+	log.Printf("📊 Generated %d, published %d, publish errors %d", stats.Generated, stats.Published, stats.PublishErrors)
+	log.Printf("📊 Quality: OK %d, NOISY %d, PARTIAL %d, CORRUPT %d", stats.OKCount, stats.NoisyCount, stats.PartialCount, stats.CorruptCount)
+	log.Println("✅ Sensor engine completed successfully")
+}
+
+// stdinConfigPath is the -config value that reads the config from stdin
+const stdinConfigPath = "-"
+
+// loadConfig loads a config file, or stdin for "-", and applies the -set
+// overrides to it
+func loadConfig(configPath string, overrides []string) *engine.ConfigFile {
+	load := engine.LoadConfigFromFile
+	if configPath == stdinConfigPath {
+		load = func(string) (*engine.ConfigFile, error) { return engine.LoadConfig(os.Stdin) }
+	}
+	configFile, err := load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := configFile.ApplyOverrides(overrides); err != nil {
+		log.Fatalf("Failed to apply -set: %v", err)
+	}
+	return configFile
+}
+
+// reloadOnHangup rereads the config file, with the -set overrides, on every
+// SIGHUP until ctx is done and applies the hot-reloadable settings to the
+// running engines. Changes that need a restart are only logged, and a file
+// that fails to load or validate leaves the engines as they are.
+func reloadOnHangup(ctx context.Context, group *engine.EngineGroup[float64], current *engine.ConfigFile, configPath string, overrides []string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		log.Printf("🔄 Reloading config: %s", configPath)
+		updated, err := engine.LoadConfigFromFile(configPath)
+		if err == nil {
+			err = updated.ApplyOverrides(overrides)
+		}
+		if err == nil {
+			var changes map[string]engine.ConfigReload
+			changes, err = group.Reload(current, updated)
+			logReload(group.Names, changes)
+		}
+		if err != nil {
+			log.Printf("⚠️  Reload failed, keeping the current settings: %v", err)
+			continue
+		}
+		current = updated
+	}
+}
+
+// logReload logs what a reload changed, engine by engine in group order
+// followed by engines added to the file
+func logReload(names []string, changes map[string]engine.ConfigReload) {
+	if len(changes) == 0 {
+		log.Println("🔄 Config unchanged")
+		return
+	}
+	ordered := slices.Clone(names)
+	for name := range changes {
+		if !slices.Contains(ordered, name) {
+			ordered = append(ordered, name)
+		}
+	}
+	for _, name := range ordered {
+		reload, ok := changes[name]
+		if !ok {
+			continue
+		}
+		for _, change := range reload.Applied {
+			log.Printf("🔄 %s: applied %s", name, change)
+		}
+		for _, change := range reload.Restart {
+			log.Printf("⚠️  %s: %s requires a restart", name, change)
+		}
+	}
+}
+
+// httpTimeouts returns the publisher options for the connect_timeout and
+// request_timeout output params, duration strings such as "2s"
+func httpTimeouts(params map[string]interface{}) []publisher.HTTPOption {
+	var opts []publisher.HTTPOption
+	for name, option := range map[string]func(time.Duration) publisher.HTTPOption{
+		"connect_timeout": publisher.WithConnectTimeout,
+		"request_timeout": publisher.WithRequestTimeout,
+	} {
+		value, ok := params[name]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(fmt.Sprint(value))
+		if err != nil {
+			log.Fatalf("Invalid output param %s: %v", name, err)
+		}
+		opts = append(opts, option(d))
+	}
+	return opts
+}
+
+func runTune(configPath string, overrides []string) {
+	configFile := loadConfig(configPath, overrides)
+	engineConfig, err := configFile.ToEngineConfig()
+	if err != nil {
+		log.Fatalf("Failed to convert engine config: %v", err)
+	}
+	seeder, err := configFile.CreateSeeder()
+	if err != nil {
+		log.Fatalf("Failed to create seeder: %v", err)
+	}
+
 	sensorFunc := engine.NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
 		return input * 100.0
 	})
 
-	// Create engine from config
-	testEngine, err := engine.CreateEngineFromConfig(configPath, sensorFunc, examples.NewConsolePublisher[float64]())
+	var pub engine.Publisher[float64] = &discardPublisher[float64]{}
+	if endpoint := configFile.Output.Params["endpoint"]; configFile.Output.Type == "http" && endpoint != nil {
+		pub = publisher.NewGenericHTTPPublisher[float64](fmt.Sprint(endpoint), httpTimeouts(configFile.Output.Params)...)
+		defer pub.Close()
+		log.Printf("🔧 Tuning against HTTP endpoint %v", endpoint)
+	} else {
+		log.Println("🔧 Tuning against an in-memory publisher (set output type http with an endpoint to tune against a real backend)")
+	}
+
+	log.Printf("🔧 Searching for settings that sustain one reading every %v...", engineConfig.ProductionRate)
+	result, err := engine.RecommendConfig(context.Background(), seeder, sensorFunc, pub, engineConfig.ProductionRate, engine.DefaultTuneOptions())
 	if err != nil {
-		log.Fatalf("Failed to create engine from config: %v", err)
+		log.Fatalf("Tuning failed: %v", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), duration)
-	defer cancel()
+	for _, trial := range result.Trials {
+		fmt.Printf("batch_size=%-4d max_workers=%-2d batch_timeout=%-6v throughput=%8.1f/s latency=%-12v achieved=%t\n",
+			trial.Config.BatchSize, trial.Config.MaxWorkers, trial.Config.BatchTimeout,
+			trial.Throughput, trial.Latency, trial.Achieved)
+	}
+	if !result.Achieved {
+		log.Println("⚠️  No combination sustained the target rate; recommending the highest throughput")
+	}
 
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
+	recommended, err := json.MarshalIndent(map[string]engine.EngineConfig{
+		"engine": {
+			ProductionRate: result.Config.ProductionRate.String(),
+			BatchSize:      result.Config.BatchSize,
+			BatchTimeout:   result.Config.BatchTimeout.String(),
+			MaxWorkers:     result.Config.MaxWorkers,
+		},
+	}, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode recommendation: %v", err)
 	}
+	fmt.Printf("\nRecommended configuration:\n%s\n", recommended)
+}
 
-	log.Println("✅ Sensor engine completed successfully")
+// discardPublisher drops every reading, counting them, and records whether
+// it was closed
+type discardPublisher[T any] struct {
+	readings atomic.Int64
+	closed   atomic.Bool
+}
+
+func (p *discardPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	p.readings.Add(1)
+	return nil
+}
+
+func (p *discardPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	p.readings.Add(int64(len(data)))
+	return nil
+}
+
+func (p *discardPublisher[T]) Close() error {
+	p.closed.Store(true)
+	return nil
 }
 
 func showHelp() {
-	fmt.Println(`
+	fmt.Print(`
 🎯 Generic Sensor Engine - Real-World Examples
 
 USAGE:
   sensor-engine -type=<example_type> [options]
   sensor-engine -config=<config_file> [options]
+  sensor-engine -replay=<capture.ndjson> [-speed=<factor>] [-publisher=<type>]
 
 EXAMPLE TYPES:
   temperature    🌡️  Temperature sensor with time-based seeder showing daily cycles
@@ -101,9 +359,28 @@ EXAMPLE TYPES:
 
 OPTIONS:
   -type <type>        Example type to run (see list above)
-  -config <file>      JSON configuration file to use
-  -publisher <type>    Publisher type (console, http, kafka, grpc)
-  -duration <time>     How long to run (default: 10s)
+  -config <file>      JSON configuration file to use, or - to read it from
+                      stdin; a file is reloaded on SIGHUP
+  -publisher <type>   Publisher for -replay (console, http, kafka)
+  -endpoint <url>     HTTP endpoint for -publisher=http
+  -brokers <list>     Comma-separated Kafka brokers for -publisher=kafka
+  -topic <name>       Kafka topic for -publisher=kafka (default: sensors)
+  -duration <time>    How long to run (default: 10s)
+  -set <path>=<value>
+                      Override a -config field by its dotted JSON path
+                      (repeatable)
+  -tune               Recommend batch_size, max_workers and batch_timeout for -config
+  -selftest           Check the seeders, engine and publisher shutdown without a backend
+  -check-compat <old>,<new>
+                      Report fields removed or retyped between the readings of
+                      two built-in example types or config files; configs only
+                      produce float64 data, so use engine.CheckJSONCompat in
+                      tests for your own types
+  -benchmark-publishers
+                      Run a fixed load through the discard, file and local HTTP
+                      publishers and compare throughput, latency and error rates
+  -replay <file>      Publish an NDJSON capture again, keeping the gaps between
+                      its timestamps; -speed <factor> scales the pace
   -help               Show this help message
 
 SEEDER + FUNCTION INTEGRATION EXAMPLES:
@@ -129,7 +406,7 @@ SEEDER + FUNCTION INTEGRATION EXAMPLES:
    - Shows: Statistical distributions in natural phenomena
 
 5. Financial Metrics (Custom Seeder):
-   - Seeder: Custom MarketSeeder with cycles and trends
+   - Seeder: NewMarketSeeder(DefaultMarketConfig()) with cycles and trends
    - Function: Market sentiment to financial data transformation
    - Shows: Complex custom seeder + function combinations
 
@@ -138,6 +415,8 @@ JSON CONFIGURATION:
   - configs/temperature-sensor.json
   - configs/medical-sensor.json  
   - configs/industrial-sensor.json
+  - configs/financial-metrics.json
+  A file with an "engines" array runs every engine it defines side by side.
 
 EXAMPLES:
   # Run temperature sensor for 30 seconds
@@ -149,6 +428,21 @@ EXAMPLES:
   # Run from JSON configuration
   sensor-engine -config=configs/temperature-sensor.json -duration=2m
 
+  # Run a configuration with a larger batch and a taller wave
+  sensor-engine -config=configs/temperature-sensor.json -set engine.batch_size=50 -set seeder.params.amplitude=2.0
+
+  # Recommend batch settings for a configuration
+  sensor-engine -config=configs/temperature-sensor.json -tune
+
+  # Check that a config change does not break consumers of its readings
+  sensor-engine -check-compat=configs/sensor-v1.json,configs/sensor-v2.json
+
+  # Replay a capture to Kafka at twice the captured pace
+  sensor-engine -replay=capture.ndjson -speed=2.0 -publisher=kafka -brokers=localhost:9092
+
+  # Check that the installation works
+  sensor-engine -selftest
+
   # Run financial metrics example
   sensor-engine -type=financial -duration=45s
 `)
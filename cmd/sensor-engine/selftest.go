@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// selfTestGenerations is how many values each seeder must produce
+const selfTestGenerations = 100
+
+// runSelfTest exercises the built-in seeders, a short engine cycle and
+// publisher shutdown without any backend, printing one line per check. It
+// reports whether every check passed.
+func runSelfTest() bool {
+	passed := true
+	report := func(name string, err error) {
+		if err != nil {
+			passed = false
+			fmt.Printf("FAIL  %-22s %v\n", name, err)
+			return
+		}
+		fmt.Printf("PASS  %s\n", name)
+	}
+
+	seeders := []struct {
+		name   string
+		seeder func() (engine.Seeder, error)
+	}{
+		{"seeder/time", fixedSeeder(engine.NewTimeSeeder(1.0, 0.1, 0.0))},
+		{"seeder/random", fixedSeeder(engine.NewRandomSeeder(0.0, 1.0))},
+		{"seeder/linear", fixedSeeder(engine.NewLinearSeeder(1.0, 0.0))},
+		{"seeder/normal", fixedSeeder(engine.NewNormalSeeder(0.0, 1.0))},
+		{"seeder/custom", fixedSeeder(engine.NewCustomSeeder(func() float64 { return 42 }))},
+		{"seeder/reduce", fixedSeeder(engine.NewReduceSeeder(engine.ReduceMean, engine.NewRandomSeeder(0, 1), engine.NewNormalSeeder(0, 1)))},
+		{"seeder/csv", func() (engine.Seeder, error) {
+			return engine.NewCSVSeederFromReader(strings.NewReader("value\n1.5\n2.5\n3.5\n"), engine.CSVOptions{Header: true})
+		}},
+	}
+	for _, s := range seeders {
+		seeder, err := s.seeder()
+		if err == nil {
+			err = checkSeeder(seeder)
+		}
+		report(s.name, err)
+	}
+
+	flushErr, closeErr := checkEngineCycle()
+	report("engine/flush", flushErr)
+	report("publisher/close", closeErr)
+
+	return passed
+}
+
+// fixedSeeder wraps an already built seeder for the self-test table
+func fixedSeeder(s engine.Seeder) func() (engine.Seeder, error) {
+	return func() (engine.Seeder, error) { return s, nil }
+}
+
+// checkSeeder verifies that a seeder produces finite values
+func checkSeeder(s engine.Seeder) error {
+	for i := range selfTestGenerations {
+		if v := s.Generate(); math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("generation %d produced %v", i, v)
+		}
+	}
+	return nil
+}
+
+// checkEngineCycle runs the engine briefly against a counting publisher and
+// verifies that readings were batched and published and that the publisher
+// was closed
+func checkEngineCycle() (flushErr, closeErr error) {
+	config := engine.Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      10,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     2,
+	}
+	pub := &discardPublisher[float64]{}
+	sensorFunc := engine.NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+		return input * 100.0
+	})
+	e := engine.NewEngine(config, engine.NewRandomSeeder(0, 1), sensorFunc, pub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := e.Start(ctx); err != nil {
+		return err, err
+	}
+
+	stats := e.Stats()
+	switch {
+	case stats.Generated == 0:
+		flushErr = fmt.Errorf("no readings were generated")
+	case stats.Batches == 0 || pub.readings.Load() == 0:
+		flushErr = fmt.Errorf("%d readings were generated but no batch was published", stats.Generated)
+	case stats.PublishErrors > 0:
+		flushErr = fmt.Errorf("%d publish errors", stats.PublishErrors)
+	}
+	if !pub.closed.Load() {
+		closeErr = fmt.Errorf("publisher was not closed when the engine stopped")
+	}
+	return flushErr, closeErr
+}
@@ -36,16 +36,30 @@ func (p *ConsolePublisher[T]) Close() error {
 	return nil
 }
 
+// TemperatureReading is the output of the temperature sensor example
+type TemperatureReading struct {
+	Celsius    float64 `json:"celsius"`
+	Fahrenheit float64 `json:"fahrenheit"`
+	Humidity   float64 `json:"humidity_percent"`
+	Location   string  `json:"location"`
+}
+
 // Example 1: Temperature Sensor with Time-based Seeder
 // Shows how environmental factors change over time
 func TemperatureSensorExample() {
-	type TemperatureReading struct {
-		Celsius    float64 `json:"celsius"`
-		Fahrenheit float64 `json:"fahrenheit"`
-		Humidity   float64 `json:"humidity_percent"`
-		Location   string  `json:"location"`
+	testEngine := temperatureEngine(NewConsolePublisher[TemperatureReading]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Println("🌡️  Starting Temperature Sensor Example...")
+	if err := testEngine.Start(ctx); err != nil {
+		log.Printf("Engine error: %v", err)
 	}
+}
 
+// temperatureEngine builds the engine of the temperature sensor example
+func temperatureEngine(publisher engine.Publisher[TemperatureReading]) *engine.Engine[TemperatureReading] {
 	// Time-based seeder generates values that change over time
 	// This simulates daily temperature cycles
 	seeder := engine.NewTimeSeeder(
@@ -89,39 +103,49 @@ func TemperatureSensorExample() {
 		}
 	})
 
-	publisher := NewConsolePublisher[TemperatureReading]()
-
 	config := engine.DefaultConfig()
 	config.ProductionRate = 1 * time.Second
 	config.BatchSize = 3
 
-	testEngine := engine.NewEngine(config, seeder, sensorFunc, publisher)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	return engine.NewEngine(config, seeder, sensorFunc, publisher)
+}
 
-	log.Println("🌡️  Starting Temperature Sensor Example...")
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
-	}
+// IoTReading is the output of the IoT device example
+type IoTReading struct {
+	DeviceID    string  `json:"device_id"`
+	Battery     float64 `json:"battery_percent"`
+	Signal      int     `json:"signal_strength_dbm"`
+	Temperature float64 `json:"temperature_celsius"`
+	Status      string  `json:"status"`
+	LastSeen    int64   `json:"last_seen_unix"`
 }
 
 // Example 2: IoT Device with Random Seeder
 // Shows how random events can trigger sensor readings
 func IoTDeviceExample() {
-	type IoTReading struct {
-		DeviceID    string  `json:"device_id"`
-		Battery     float64 `json:"battery_percent"`
-		Signal      int     `json:"signal_strength_dbm"`
-		Temperature float64 `json:"temperature_celsius"`
-		Status      string  `json:"status"`
-		LastSeen    int64   `json:"last_seen_unix"`
+	testEngine := iotEngine(NewConsolePublisher[IoTReading]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+
+	log.Println("📱 Starting IoT Device Example...")
+	if err := testEngine.Start(ctx); err != nil {
+		log.Printf("Engine error: %v", err)
 	}
+}
 
+// iotEngine builds the engine of the IoT device example
+func iotEngine(publisher engine.Publisher[IoTReading]) *engine.Engine[IoTReading] {
 	// Random seeder simulates random device states
 	// Each call generates a random value between 0 and 1
 	seeder := engine.NewRandomSeeder(0.0, 1.0)
 
+	// Device status follows battery level, which falls as activity rises
+	statusFunc := engine.NewCategoricalFunction(
+		engine.Category[string]{Value: "excellent", Weight: 2}, // Battery above 80%
+		engine.Category[string]{Value: "good", Weight: 1},      // Battery 70-80%
+	)
+
 	// User-defined function that uses random input to simulate IoT device behavior
 	sensorFunc := engine.NewFunction(func(input float64, timestamp time.Time) IoTReading {
 		// Input from random seeder represents device stress/activity level
@@ -137,17 +161,7 @@ func IoTDeviceExample() {
 		temperature := 25.0 + (input * 15.0) // 25-40°C
 
 		// Device status based on battery level
-		var status string
-		switch {
-		case battery > 80:
-			status = "excellent"
-		case battery > 50:
-			status = "good"
-		case battery > 20:
-			status = "low"
-		default:
-			status = "critical"
-		}
+		status := statusFunc.Pick(input)
 
 		return IoTReading{
 			DeviceID:    fmt.Sprintf("iot-%04d", int(input*9999)),
@@ -159,40 +173,52 @@ func IoTDeviceExample() {
 		}
 	})
 
-	publisher := NewConsolePublisher[IoTReading]()
-
 	config := engine.DefaultConfig()
 	config.ProductionRate = 500 * time.Millisecond
 	config.BatchSize = 5
 
-	testEngine := engine.NewEngine(config, seeder, sensorFunc, publisher)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
-	defer cancel()
+	return engine.NewEngine(config, seeder, sensorFunc, publisher)
+}
 
-	log.Println("📱 Starting IoT Device Example...")
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
-	}
+// MachineMetrics is the output of the industrial sensor example
+type MachineMetrics struct {
+	MachineID   string  `json:"machine_id"`
+	Vibration   float64 `json:"vibration_mm_s"`
+	Pressure    float64 `json:"pressure_bar"`
+	RPM         int     `json:"rpm"`
+	Temperature float64 `json:"temperature_celsius"`
+	Efficiency  float64 `json:"efficiency_percent"`
+	Status      string  `json:"status"`
 }
 
 // Example 3: Industrial Sensor with Linear Seeder
 // Shows how progressive changes can be simulated
 func IndustrialSensorExample() {
-	type MachineMetrics struct {
-		MachineID   string  `json:"machine_id"`
-		Vibration   float64 `json:"vibration_mm_s"`
-		Pressure    float64 `json:"pressure_bar"`
-		RPM         int     `json:"rpm"`
-		Temperature float64 `json:"temperature_celsius"`
-		Efficiency  float64 `json:"efficiency_percent"`
-		Status      string  `json:"status"`
+	testEngine := industrialEngine(NewConsolePublisher[MachineMetrics]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+
+	log.Println("🏭 Starting Industrial Sensor Example...")
+	if err := testEngine.Start(ctx); err != nil {
+		log.Printf("Engine error: %v", err)
 	}
+}
 
+// industrialEngine builds the engine of the industrial sensor example
+func industrialEngine(publisher engine.Publisher[MachineMetrics]) *engine.Engine[MachineMetrics] {
 	// Linear seeder simulates gradual machine wear over time
 	// Starts at 0.1 and increases by 0.01 each generation
 	seeder := engine.NewLinearSeeder(0.01, 0.1)
 
+	// Status bands by wear level; wear beyond 1.0 stays critical
+	statusFunc := engine.NewCategoricalFunction(
+		engine.Category[string]{Value: "normal", Weight: 0.3},
+		engine.Category[string]{Value: "monitor", Weight: 0.3},
+		engine.Category[string]{Value: "warning", Weight: 0.2},
+		engine.Category[string]{Value: "critical_maintenance", Weight: 0.2},
+	)
+
 	// User-defined function that simulates machine degradation
 	sensorFunc := engine.NewFunction(func(input float64, timestamp time.Time) MachineMetrics {
 		// Input from linear seeder represents machine wear factor (0.1 to 1.0+)
@@ -219,17 +245,7 @@ func IndustrialSensorExample() {
 		}
 
 		// Status based on wear level
-		var status string
-		switch {
-		case input > 0.8:
-			status = "critical_maintenance"
-		case input > 0.6:
-			status = "warning"
-		case input > 0.3:
-			status = "monitor"
-		default:
-			status = "normal"
-		}
+		status := statusFunc.Pick(input)
 
 		return MachineMetrics{
 			MachineID:   fmt.Sprintf("CNC-%03d", int(input*999)),
@@ -242,37 +258,41 @@ func IndustrialSensorExample() {
 		}
 	})
 
-	publisher := NewConsolePublisher[MachineMetrics]()
-
 	config := engine.DefaultConfig()
 	config.ProductionRate = 2 * time.Second
 	config.BatchSize = 2
 
-	testEngine := engine.NewEngine(config, seeder, sensorFunc, publisher)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
-	defer cancel()
+	return engine.NewEngine(config, seeder, sensorFunc, publisher)
+}
 
-	log.Println("🏭 Starting Industrial Sensor Example...")
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
-	}
+// WeatherData is the output of the weather station example
+type WeatherData struct {
+	StationID     string  `json:"station_id"`
+	Temperature   float64 `json:"temperature_celsius"`
+	Humidity      float64 `json:"humidity_percent"`
+	Pressure      float64 `json:"pressure_hpa"`
+	WindSpeed     float64 `json:"wind_speed_kmh"`
+	WindDirection int     `json:"wind_direction_degrees"`
+	Conditions    string  `json:"conditions"`
+	Timestamp     int64   `json:"timestamp_unix"`
 }
 
 // Example 4: Weather Station with Normal Distribution Seeder
 // Shows how realistic statistical patterns can be simulated
 func WeatherStationExample() {
-	type WeatherData struct {
-		StationID     string  `json:"station_id"`
-		Temperature   float64 `json:"temperature_celsius"`
-		Humidity      float64 `json:"humidity_percent"`
-		Pressure      float64 `json:"pressure_hpa"`
-		WindSpeed     float64 `json:"wind_speed_kmh"`
-		WindDirection int     `json:"wind_direction_degrees"`
-		Conditions    string  `json:"conditions"`
-		Timestamp     int64   `json:"timestamp_unix"`
+	testEngine := weatherEngine(NewConsolePublisher[WeatherData]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	log.Println("🌤️  Starting Weather Station Example...")
+	if err := testEngine.Start(ctx); err != nil {
+		log.Printf("Engine error: %v", err)
 	}
+}
 
+// weatherEngine builds the engine of the weather station example
+func weatherEngine(publisher engine.Publisher[WeatherData]) *engine.Engine[WeatherData] {
 	// Normal seeder generates values following normal distribution
 	// Mean=0.5, StdDev=0.2 - simulates natural weather variations
 	seeder := engine.NewNormalSeeder(0.5, 0.2)
@@ -332,68 +352,50 @@ func WeatherStationExample() {
 		}
 	})
 
-	publisher := NewConsolePublisher[WeatherData]()
-
 	config := engine.DefaultConfig()
 	config.ProductionRate = 3 * time.Second
 	config.BatchSize = 1
 
-	testEngine := engine.NewEngine(config, seeder, sensorFunc, publisher)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	log.Println("🌤️  Starting Weather Station Example...")
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
-	}
+	return engine.NewEngine(config, seeder, sensorFunc, publisher)
 }
 
-// MarketSeeder simulates market behavior for financial metrics
-type MarketSeeder struct {
-	cycle float64
-}
-
-// Generate implements the Seeder interface
-func (m *MarketSeeder) Generate() float64 {
-	m.cycle += 0.1
-	baseValue := 0.5
-
-	// Add market cycles
-	cycle := math.Sin(m.cycle*0.1) * 0.3
-
-	// Add random market noise
-	noise := (rand.Float64() - 0.5) * 0.2
-
-	// Add trend component
-	trend := math.Sin(m.cycle*0.01) * 0.2
-
-	result := baseValue + cycle + noise + trend
-
-	// Keep within bounds
-	if result < 0 {
-		result = 0
-	} else if result > 1 {
-		result = 1
-	}
-
-	return result
+// FinancialMetrics is the output of the financial metrics example
+type FinancialMetrics struct {
+	Symbol     string  `json:"symbol"`
+	Price      float64 `json:"price_usd"`
+	Volume     int64   `json:"volume_24h"`
+	Change     float64 `json:"change_percent_24h"`
+	Volatility float64 `json:"volatility_index"`
+	Trend      string  `json:"trend"`
+	Timestamp  int64   `json:"timestamp_unix"`
 }
 
 // Example 5: Custom Seeder with Complex Function
 // Shows how to create completely custom seeder + function combinations
 func CustomSeederExample() {
-	type FinancialMetrics struct {
-		Symbol     string  `json:"symbol"`
-		Price      float64 `json:"price_usd"`
-		Volume     int64   `json:"volume_24h"`
-		Change     float64 `json:"change_percent_24h"`
-		Volatility float64 `json:"volatility_index"`
-		Trend      string  `json:"trend"`
-		Timestamp  int64   `json:"timestamp_unix"`
+	testEngine := financialEngine(NewConsolePublisher[FinancialMetrics]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Println("💰 Starting Custom Seeder Example...")
+	if err := testEngine.Start(ctx); err != nil {
+		log.Printf("Engine error: %v", err)
 	}
+}
 
-	seeder := &MarketSeeder{cycle: 0}
+// financialEngine builds the engine of the financial metrics example
+func financialEngine(publisher engine.Publisher[FinancialMetrics]) *engine.Engine[FinancialMetrics] {
+	seeder := engine.NewMarketSeeder(engine.DefaultMarketConfig())
+
+	// Trend bands by market sentiment, from bear to bull
+	trendFunc := engine.NewCategoricalFunction(
+		engine.Category[string]{Value: "strong_bear", Weight: 0.3},
+		engine.Category[string]{Value: "bear", Weight: 0.1},
+		engine.Category[string]{Value: "sideways", Weight: 0.2},
+		engine.Category[string]{Value: "bull", Weight: 0.1},
+		engine.Category[string]{Value: "strong_bull", Weight: 0.3},
+	)
 
 	// User-defined function that simulates financial metrics
 	sensorFunc := engine.NewFunction(func(input float64, timestamp time.Time) FinancialMetrics {
@@ -417,19 +419,7 @@ func CustomSeederExample() {
 		volatility := math.Abs(input-0.5)*2.0 + rand.Float64()*0.5
 
 		// Trend determination
-		var trend string
-		switch {
-		case input > 0.7:
-			trend = "strong_bull"
-		case input > 0.6:
-			trend = "bull"
-		case input > 0.4:
-			trend = "sideways"
-		case input > 0.3:
-			trend = "bear"
-		default:
-			trend = "strong_bear"
-		}
+		trend := trendFunc.Pick(input)
 
 		return FinancialMetrics{
 			Symbol:     "CRYPTO-USD",
@@ -442,19 +432,28 @@ func CustomSeederExample() {
 		}
 	})
 
-	publisher := NewConsolePublisher[FinancialMetrics]()
-
 	config := engine.DefaultConfig()
 	config.ProductionRate = 1 * time.Second
 	config.BatchSize = 2
 
-	testEngine := engine.NewEngine(config, seeder, sensorFunc, publisher)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	return engine.NewEngine(config, seeder, sensorFunc, publisher)
+}
 
-	log.Println("💰 Starting Custom Seeder Example...")
-	if err := testEngine.Start(ctx); err != nil {
-		log.Printf("Engine error: %v", err)
+// SampleReading generates one reading of the named example, as given to
+// the CLI's -type flag, without publishing it
+func SampleReading(exampleType string) (any, error) {
+	switch exampleType {
+	case "temperature":
+		return temperatureEngine(NewConsolePublisher[TemperatureReading]()).Generate(), nil
+	case "iot":
+		return iotEngine(NewConsolePublisher[IoTReading]()).Generate(), nil
+	case "industrial":
+		return industrialEngine(NewConsolePublisher[MachineMetrics]()).Generate(), nil
+	case "weather":
+		return weatherEngine(NewConsolePublisher[WeatherData]()).Generate(), nil
+	case "financial":
+		return financialEngine(NewConsolePublisher[FinancialMetrics]()).Generate(), nil
+	default:
+		return nil, fmt.Errorf("unknown example type %q", exampleType)
 	}
 }
@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdaptiveRateConfig controls automatic tuning of the production rate.
+// At least one of TargetLatency or TargetBacklog should be set.
+type AdaptiveRateConfig struct {
+	TargetLatency  time.Duration // Desired mean publish latency (0 to ignore)
+	TargetBacklog  int           // Desired number of queued batches (0 to ignore)
+	MinRate        time.Duration // Fastest allowed production interval (default 1ms)
+	MaxRate        time.Duration // Slowest allowed production interval (default 10x ProductionRate)
+	AdjustInterval time.Duration // How often the control loop runs (default 500ms)
+}
+
+// ProductionRate returns the current interval between generated readings
+func (e *Engine[T]) ProductionRate() time.Duration {
+	return time.Duration(e.rate.Load())
+}
+
+// SetProductionRate changes the interval between generated readings while
+// the engine is running. Non-positive values are ignored.
+func (e *Engine[T]) SetProductionRate(rate time.Duration) {
+	if rate <= 0 {
+		return
+	}
+	if time.Duration(e.rate.Swap(int64(rate))) == rate {
+		return
+	}
+	select {
+	case e.rateChanged <- struct{}{}:
+	default:
+	}
+}
+
+// adaptRate periodically measures publish latency and backlog depth and
+// adjusts the production rate to keep them under the configured targets
+func (e *Engine[T]) adaptRate(ctx context.Context, batchChan chan []SensorData[T], wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	cfg := *e.config.AdaptiveRate
+	if cfg.MinRate <= 0 {
+		cfg.MinRate = time.Millisecond
+	}
+	if cfg.MaxRate <= 0 {
+		cfg.MaxRate = 10 * e.config.ProductionRate
+	}
+	if cfg.AdjustInterval <= 0 {
+		cfg.AdjustInterval = 500 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(cfg.AdjustInterval)
+	defer ticker.Stop()
+
+	lastCalls := e.stats.publishCalls.Load()
+	lastLatency := e.stats.publishLatency.Load()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			calls := e.stats.publishCalls.Load()
+			latency := e.stats.publishLatency.Load()
+
+			// ratio is the worst measured/target value across enabled targets;
+			// a negative ratio means there was nothing to measure
+			ratio := -1.0
+			if cfg.TargetLatency > 0 && calls > lastCalls {
+				avg := float64(latency-lastLatency) / float64(calls-lastCalls)
+				ratio = max(ratio, avg/float64(cfg.TargetLatency))
+			}
+			if cfg.TargetBacklog > 0 {
+				ratio = max(ratio, float64(len(batchChan))/float64(cfg.TargetBacklog))
+			}
+			lastCalls, lastLatency = calls, latency
+
+			current := float64(e.ProductionRate())
+			next := current
+			switch {
+			case ratio > 1:
+				// Over target: slow down proportionally, at most doubling per step
+				next = current * min(ratio, 2)
+			case ratio >= 0 && ratio < 0.8:
+				// Comfortably under target: speed up gently
+				next = current * 0.9
+			}
+
+			rate := time.Duration(next)
+			rate = max(rate, cfg.MinRate)
+			rate = min(rate, cfg.MaxRate)
+			e.SetProductionRate(rate)
+		}
+	}
+}
+
+// RatePoint sets the production interval at an offset from when the engine
+// starts
+type RatePoint struct {
+	At   time.Duration // Offset from engine start
+	Rate time.Duration // Production interval at that offset
+}
+
+// RateEnvelope is a piecewise-linear production rate schedule, such as a
+// ramp up, hold and ramp down for soak tests. Between two points the
+// frequency of readings (not the interval) changes linearly, so a ramp
+// increases the load at a steady pace. Before the first point the first rate
+// applies and after the last point the last rate holds.
+type RateEnvelope []RatePoint
+
+// Validate checks that the points are in order and every rate is positive
+func (r RateEnvelope) Validate() error {
+	for i, p := range r {
+		if p.Rate <= 0 {
+			return fmt.Errorf("rate envelope point %d has non-positive rate %v", i, p.Rate)
+		}
+		if i > 0 && p.At < r[i-1].At {
+			return fmt.Errorf("rate envelope point %d at %v is before the previous point at %v", i, p.At, r[i-1].At)
+		}
+	}
+	return nil
+}
+
+// RateAt returns the production interval elapsed after engine start
+func (r RateEnvelope) RateAt(elapsed time.Duration) time.Duration {
+	if len(r) == 0 {
+		return 0
+	}
+	if elapsed <= r[0].At {
+		return r[0].Rate
+	}
+	for i := 1; i < len(r); i++ {
+		if elapsed >= r[i].At {
+			continue
+		}
+		from, to := r[i-1], r[i]
+		fraction := float64(elapsed-from.At) / float64(to.At-from.At)
+		fromFreq := 1 / float64(from.Rate)
+		toFreq := 1 / float64(to.Rate)
+		return time.Duration(1 / (fromFreq + (toFreq-fromFreq)*fraction))
+	}
+	return r[len(r)-1].Rate
+}
+
+// followEnvelope moves the production rate along the configured envelope and
+// resets the ticker when it changes
+func (e *Engine[T]) followEnvelope(ticker *time.Ticker, elapsed time.Duration) {
+	rate := e.config.RateEnvelope.RateAt(elapsed)
+	if rate <= 0 || rate == e.ProductionRate() {
+		return
+	}
+	e.rate.Store(int64(rate))
+	ticker.Reset(rate)
+}
+
+// AdaptiveBatchConfig controls automatic tuning of the batch size from
+// measured publish latency: batches grow while publishes finish well within
+// TargetLatency and shrink when they take longer
+type AdaptiveBatchConfig struct {
+	TargetLatency time.Duration // Desired mean PublishBatch latency
+	MinSize       int           // Smallest batch size (default 1)
+	MaxSize       int           // Largest batch size (default 10x BatchSize)
+	Window        int           // Batches averaged per adjustment (default 5)
+}
+
+// withDefaults fills in the defaults of unset fields
+func (c AdaptiveBatchConfig) withDefaults(batchSize int) AdaptiveBatchConfig {
+	if c.MinSize <= 0 {
+		c.MinSize = 1
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = max(10*batchSize, c.MinSize)
+	}
+	if c.Window <= 0 {
+		c.Window = 5
+	}
+	return c
+}
+
+// validate checks the settings that have no default
+func (c AdaptiveBatchConfig) validate() error {
+	if c.TargetLatency <= 0 {
+		return fmt.Errorf("adaptive batch target latency must be positive, got %v", c.TargetLatency)
+	}
+	if c.MaxSize > 0 && c.MaxSize < c.MinSize {
+		return fmt.Errorf("adaptive batch max size %d is below the min size %d", c.MaxSize, c.MinSize)
+	}
+	return nil
+}
+
+// adaptiveBatch is the state of the batch size controller
+type adaptiveBatch struct {
+	mutex   sync.Mutex
+	samples int
+	total   time.Duration
+}
+
+// BatchSize returns the number of readings per batch the engine currently
+// aims for: Config.BatchSize, the tuned size with AdaptiveBatch or the size
+// set with SetBatchSize
+func (e *Engine[T]) BatchSize() int {
+	if size := e.batchSize.Load(); size > 0 {
+		return int(size)
+	}
+	return e.config.BatchSize
+}
+
+// SetBatchSize changes the number of readings per batch while the engine is
+// running; batches being assembled are sent once they reach the new size.
+// With AdaptiveBatch the controller carries on tuning from size. An engine
+// started with a BatchSize of 1 and no AdaptiveBatch publishes every
+// reading on its own until it is restarted, whatever the size set here.
+// Values below 1 are ignored.
+func (e *Engine[T]) SetBatchSize(size int) {
+	if size < 1 {
+		return
+	}
+	e.batchSize.Store(int64(size))
+}
+
+// BatchTimeout returns the current time after which a partial batch is
+// sent: Config.BatchTimeout, or the value set with SetBatchTimeout
+func (e *Engine[T]) BatchTimeout() time.Duration {
+	if timeout := e.batchTimeout.Load(); timeout > 0 {
+		return time.Duration(timeout)
+	}
+	return e.config.BatchTimeout
+}
+
+// SetBatchTimeout changes the batch timeout while the engine is running,
+// from the next batch on. An engine started without a batch timeout only
+// sends full batches until it is restarted. Non-positive values are
+// ignored.
+func (e *Engine[T]) SetBatchTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	e.batchTimeout.Store(int64(timeout))
+}
+
+// startAdaptiveBatch sets the initial batch size, the configured one within
+// the adaptive bounds. A size tuned in an earlier run is kept.
+func (e *Engine[T]) startAdaptiveBatch() {
+	if e.config.AdaptiveBatch == nil || e.batchSize.Load() > 0 {
+		return
+	}
+	cfg := e.config.AdaptiveBatch.withDefaults(e.config.BatchSize)
+	e.batchSize.Store(int64(min(max(e.config.BatchSize, cfg.MinSize), cfg.MaxSize)))
+}
+
+// observeBatchLatency records the duration of a batch publish and, once a
+// window of batches is complete, adjusts the batch size towards the target
+// latency: proportionally down when over it, at most halving, and up by a
+// quarter when under 80% of it
+func (e *Engine[T]) observeBatchLatency(latency time.Duration) {
+	if e.config.AdaptiveBatch == nil {
+		return
+	}
+	cfg := e.config.AdaptiveBatch.withDefaults(e.config.BatchSize)
+
+	a := &e.adaptiveBatch
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.samples++
+	a.total += latency
+	if a.samples < cfg.Window {
+		return
+	}
+	avg := float64(a.total) / float64(a.samples)
+	a.samples, a.total = 0, 0
+
+	size := e.BatchSize()
+	next := size
+	target := float64(cfg.TargetLatency)
+	switch {
+	case avg > target:
+		next = min(max(int(float64(size)*target/avg), size/2), size-1)
+	case avg < 0.8*target:
+		next = max(size*5/4, size+1)
+	}
+	e.batchSize.Store(int64(min(max(next, cfg.MinSize), cfg.MaxSize)))
+}
+
+// recordBatchPublish records a batch publish in the stats and feeds its
+// latency to the batch size controller
+func (e *Engine[T]) recordBatchPublish(items int, latency time.Duration, err error) {
+	e.stats.recordPublish(items, latency, err)
+	e.observeBatchLatency(latency)
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffStrategy decides how long to wait before retrying a failed
+// operation. attempt counts the failures so far, starting at 0 for the
+// first one; NextDelay returns false when no further retry should be made.
+type BackoffStrategy interface {
+	NextDelay(attempt int) (time.Duration, bool)
+}
+
+// ConstantBackoff waits Delay before each of up to MaxRetries retries
+type ConstantBackoff struct {
+	Delay      time.Duration
+	MaxRetries int
+}
+
+// NextDelay returns Delay until MaxRetries retries have been made
+func (c ConstantBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	if attempt >= c.MaxRetries {
+		return 0, false
+	}
+	return c.Delay, true
+}
+
+// ExponentialBackoff waits Base*2^n before retry n, capped at Max when Max
+// is positive, for up to MaxRetries retries
+type ExponentialBackoff struct {
+	Base       time.Duration
+	Max        time.Duration
+	MaxRetries int
+}
+
+// NextDelay returns the doubled delay until MaxRetries retries have been
+// made
+func (x ExponentialBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	if attempt >= x.MaxRetries {
+		return 0, false
+	}
+	return exponentialDelay(x.Base, x.Max, attempt), true
+}
+
+// FullJitterBackoff waits a random duration between 0 and the
+// ExponentialBackoff delay before each of up to MaxRetries retries, which
+// spreads out retries from many clients failing at the same time
+type FullJitterBackoff struct {
+	Base       time.Duration
+	Max        time.Duration
+	MaxRetries int
+}
+
+// NextDelay returns a jittered delay until MaxRetries retries have been
+// made
+func (f FullJitterBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	if attempt >= f.MaxRetries {
+		return 0, false
+	}
+	ceiling := exponentialDelay(f.Base, f.Max, attempt)
+	switch ceiling {
+	case 0:
+		return 0, true
+	case math.MaxInt64:
+		return rand.N(ceiling), true
+	}
+	return rand.N(ceiling + 1), true
+}
+
+// exponentialDelay returns min(limit, base*2^attempt), treating a limit of
+// 0 as no cap. A zero base yields the limit.
+func exponentialDelay(base, limit time.Duration, attempt int) time.Duration {
+	delay := base << attempt
+	if attempt >= 63 || delay>>attempt != base || delay <= 0 {
+		// Overflowed, or nothing to double
+		switch {
+		case limit > 0:
+			return limit
+		case base > 0:
+			return math.MaxInt64
+		default:
+			return 0
+		}
+	}
+	if limit > 0 && delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// Retry calls op until it succeeds, it fails with an error retryable
+// rejects, the strategy stops or ctx is done. A nil strategy makes a single
+// attempt and a nil retryable retries every error. Retries also stop when
+// the next delay would pass the context deadline. The last error is
+// returned.
+func Retry(ctx context.Context, strategy BackoffStrategy, retryable func(error) bool, op func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || strategy == nil || (retryable != nil && !retryable(err)) {
+			return err
+		}
+
+		delay, ok := strategy.NextDelay(attempt)
+		if !ok {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("giving up after %d attempts, context deadline too close: %w", attempt+1, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
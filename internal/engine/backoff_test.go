@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// delays returns the delays a strategy hands out until it stops, up to
+// limit attempts
+func delays(strategy BackoffStrategy, limit int) []time.Duration {
+	var result []time.Duration
+	for attempt := 0; attempt < limit; attempt++ {
+		delay, ok := strategy.NextDelay(attempt)
+		if !ok {
+			break
+		}
+		result = append(result, delay)
+	}
+	return result
+}
+
+func TestConstantBackoff(t *testing.T) {
+	got := delays(ConstantBackoff{Delay: 5 * time.Millisecond, MaxRetries: 3}, 10)
+	want := []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if got := delays(ConstantBackoff{Delay: time.Millisecond}, 10); len(got) != 0 {
+		t.Errorf("Expected no retries without MaxRetries, got %v", got)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	got := delays(ExponentialBackoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond, MaxRetries: 5}, 10)
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i := range want {
+		want[i] *= time.Millisecond
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestExponentialBackoff_Overflow(t *testing.T) {
+	capped := ExponentialBackoff{Base: time.Second, Max: time.Hour, MaxRetries: 100}
+	if delay, _ := capped.NextDelay(80); delay != time.Hour {
+		t.Errorf("Expected an overflowing delay to be capped at Max, got %v", delay)
+	}
+
+	uncapped := ExponentialBackoff{Base: time.Second, MaxRetries: 100}
+	if delay, _ := uncapped.NextDelay(80); delay != math.MaxInt64 {
+		t.Errorf("Expected an overflowing uncapped delay to saturate, got %v", delay)
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	strategy := FullJitterBackoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond, MaxRetries: 5}
+
+	for attempt, ceiling := range []time.Duration{10, 20, 40, 50, 50} {
+		ceiling *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d, ok := strategy.NextDelay(attempt); !ok || d < 0 || d > ceiling {
+				t.Fatalf("Attempt %d: delay %v (ok %v) outside [0, %v]", attempt, d, ok, ceiling)
+			}
+		}
+	}
+	if _, ok := strategy.NextDelay(5); ok {
+		t.Error("Expected no retry after MaxRetries")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+
+	calls := 0
+	err := Retry(context.Background(), ConstantBackoff{MaxRetries: 5}, nil, func() error {
+		if calls++; calls < 3 {
+			return errFlaky
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third call, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), ConstantBackoff{MaxRetries: 2}, nil, func() error {
+		calls++
+		return errFlaky
+	})
+	if !errors.Is(err, errFlaky) || calls != 3 {
+		t.Errorf("Expected the last error after 3 calls, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), ConstantBackoff{MaxRetries: 5}, func(error) bool { return false }, func() error {
+		calls++
+		return errFlaky
+	})
+	if !errors.Is(err, errFlaky) || calls != 1 {
+		t.Errorf("Expected a non-retryable error to stop retries, got %d calls", calls)
+	}
+
+	calls = 0
+	if err := Retry(context.Background(), nil, nil, func() error { calls++; return errFlaky }); err == nil || calls != 1 {
+		t.Errorf("Expected a single attempt without a strategy, got %d calls", calls)
+	}
+}
+
+func TestRetry_RespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Retry(ctx, ConstantBackoff{Delay: time.Second, MaxRetries: 10}, nil, func() error {
+		return errors.New("down")
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Retries should be bounded by the context deadline, took %v", elapsed)
+	}
+}
+
+func TestEngine_WithPublishRetry(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(harnessEpoch),
+	}
+	failed := 0
+	publisher := &flakyPublisher[float64]{}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher,
+		WithPublishRetry[float64](ConstantBackoff{MaxRetries: 2}),
+		WithOnPublishError(func([]SensorData[float64], error) { failed++ }),
+	)
+
+	if err := engine.RunFor(context.Background(), 5*config.ProductionRate); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if publisher.calls != 3 {
+		t.Errorf("Expected the batch to succeed on the third attempt, got %d attempts", publisher.calls)
+	}
+	if failed != 0 {
+		t.Errorf("Expected no failed batches after a successful retry, got %d", failed)
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// BatchEnvelope wraps a published batch with tracing information so a batch
+// can be correlated end-to-end. WindowStart and WindowEnd bound the
+// timestamps of the batch, so window processors can align tumbling windows
+// and check completeness without scanning the items.
+type BatchEnvelope[T any] struct {
+	BatchID     string          `json:"batch_id"`
+	Count       int             `json:"count"`
+	CreatedAt   time.Time       `json:"created_at"`
+	WindowStart time.Time       `json:"window_start"`
+	WindowEnd   time.Time       `json:"window_end"`
+	Items       []SensorData[T] `json:"items"`
+}
+
+// NewBatchEnvelope wraps items in an envelope, reusing the batch ID and
+// window carried by ctx when present, and generating a new ID and computing
+// the window of items otherwise
+func NewBatchEnvelope[T any](ctx context.Context, items []SensorData[T]) BatchEnvelope[T] {
+	batchID, ok := BatchIDFromContext(ctx)
+	if !ok {
+		batchID = NewBatchID()
+	}
+	window, ok := BatchWindowFromContext(ctx)
+	if !ok {
+		window = BatchWindowOf(items)
+	}
+	return BatchEnvelope[T]{
+		BatchID:     batchID,
+		Count:       len(items),
+		CreatedAt:   time.Now(),
+		WindowStart: window.Start,
+		WindowEnd:   window.End,
+		Items:       items,
+	}
+}
+
+// BatchWindow is the time span of a batch, from its earliest to its latest
+// reading timestamp
+type BatchWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// BatchWindowOf returns the window of items, the zero window if there are
+// none. Heartbeat and offline markers only count when the batch holds
+// nothing else, so they do not widen the window of its readings.
+func BatchWindowOf[T any](items []SensorData[T]) BatchWindow {
+	var window BatchWindow
+	found := false
+	for _, markers := range []bool{false, true} {
+		for _, item := range items {
+			if isStatusMarker(item.Quality) != markers {
+				continue
+			}
+			switch {
+			case !found:
+				window = BatchWindow{Start: item.Timestamp, End: item.Timestamp}
+				found = true
+			case item.Timestamp.Before(window.Start):
+				window.Start = item.Timestamp
+			case item.Timestamp.After(window.End):
+				window.End = item.Timestamp
+			}
+		}
+		if found {
+			break
+		}
+	}
+	return window
+}
+
+// NewBatchID returns a random (version 4) UUID string
+func NewBatchID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return formatUUID(b)
+}
+
+// newBatchID returns a batch ID drawn from the engine's random source, so a
+// seeded source yields reproducible IDs
+func (e *Engine[T]) newBatchID() string {
+	if e.rng == nil {
+		return NewBatchID()
+	}
+
+	var b [16]byte
+	e.rngMu.Lock()
+	binary.LittleEndian.PutUint64(b[:8], e.rng.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], e.rng.Uint64())
+	e.rngMu.Unlock()
+	return formatUUID(b)
+}
+
+// formatUUID formats 16 random bytes as a version 4 UUID
+func formatUUID(b [16]byte) string {
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+type batchIDKey struct{}
+
+// WithBatchID returns a copy of ctx carrying the given batch ID
+func WithBatchID(ctx context.Context, batchID string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, batchID)
+}
+
+// BatchIDFromContext returns the batch ID carried by ctx, if any
+func BatchIDFromContext(ctx context.Context) (string, bool) {
+	batchID, ok := ctx.Value(batchIDKey{}).(string)
+	return batchID, ok
+}
+
+type batchWindowKey struct{}
+
+// WithBatchWindow returns a copy of ctx carrying the given batch window
+func WithBatchWindow(ctx context.Context, window BatchWindow) context.Context {
+	return context.WithValue(ctx, batchWindowKey{}, window)
+}
+
+// BatchWindowFromContext returns the batch window carried by ctx, if any.
+// The engine sets it for every batch it publishes.
+func BatchWindowFromContext(ctx context.Context) (BatchWindow, bool) {
+	window, ok := ctx.Value(batchWindowKey{}).(BatchWindow)
+	return window, ok
+}
+
+// batchContext returns the context a prepared batch is published with,
+// carrying its window and, with TraceBatches, a new batch ID, which is also returned
+func (e *Engine[T]) batchContext(ctx context.Context, batch []SensorData[T]) (context.Context, string) {
+	ctx = WithBatchWindow(ctx, BatchWindowOf(batch))
+	if !e.config.TraceBatches {
+		return ctx, ""
+	}
+	batchID := e.newBatchID()
+	return WithBatchID(ctx, batchID), batchID
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// changeTrigger publishes a reading only when a key derived from it differs
+// from the key of the last published reading. It is owned by the generator.
+type changeTrigger[T any] struct {
+	key       func(T) any
+	heartbeat time.Duration
+
+	started  bool
+	last     any       // Key of the last published reading
+	lastTime time.Time // Timestamp of the last published reading
+}
+
+// WithEmitOnChangeOf publishes a reading only when key, applied to its Data,
+// returns something other than it did for the last published reading, for
+// event-driven simulations such as a status moving from "normal" to
+// "warning". Unlike deduplication it compares a derived attribute, so
+// readings whose values differ but whose key does not are suppressed. The
+// first reading is always published.
+//
+// heartbeat, when positive, also publishes an unchanged reading once that
+// long has passed, by reading timestamp, since the last published one, so
+// consumers can tell a steady state from a dead sensor. Suppressed readings
+// are counted in Stats.Unchanged. It panics if key is nil; key must return
+// comparable values, such as strings, numbers or structs of them.
+func WithEmitOnChangeOf[T any](key func(T) any, heartbeat time.Duration) Option[T] {
+	if key == nil {
+		panic("engine: EmitOnChangeOf needs a key function")
+	}
+	return func(e *Engine[T]) {
+		e.changeTrigger = &changeTrigger[T]{key: key, heartbeat: heartbeat}
+	}
+}
+
+// emitOnChange applies the change trigger, if any, to a generated reading.
+// It reports false when the reading must not be published.
+func (e *Engine[T]) emitOnChange(data SensorData[T]) bool {
+	trigger := e.changeTrigger
+	if trigger == nil {
+		return true
+	}
+
+	key := trigger.key(data.Data)
+	if key != nil && !reflect.TypeOf(key).Comparable() {
+		panic(fmt.Sprintf("engine: EmitOnChangeOf key of type %T is not comparable", key))
+	}
+	unchanged := trigger.started && key == trigger.last
+	if unchanged && (trigger.heartbeat <= 0 || data.Timestamp.Sub(trigger.lastTime) < trigger.heartbeat) {
+		e.stats.unchanged.Add(1)
+		return false
+	}
+	trigger.started = true
+	trigger.last = key
+	trigger.lastTime = data.Timestamp
+	return true
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// statusReading carries a value and a status derived from it
+type statusReading struct {
+	Value  float64 `json:"value"`
+	Status string  `json:"status"`
+}
+
+// statusFunction reports "warning" for inputs above 5
+var statusFunction = NewFunction(func(input float64, _ time.Time) statusReading {
+	status := "normal"
+	if input > 5 {
+		status = "warning"
+	}
+	return statusReading{Value: input, Status: status}
+})
+
+// statusHarness prepares a run whose status flips every three readings,
+// with values that change even while the status does not
+func statusHarness(t *testing.T) (*harness[statusReading], Seeder) {
+	h := newHarness[statusReading](t, Config{
+		ProductionRate: time.Second,
+		BatchSize:      4,
+		MaxWorkers:     1,
+	})
+	return h, NewTestSeeder([]float64{1, 2, 3, 7, 8, 9})
+}
+
+func statusKey(data statusReading) any { return data.Status }
+
+func TestEmitOnChangeOf_Transitions(t *testing.T) {
+	h, seeder := statusHarness(t)
+	published := h.run(seeder, statusFunction, 12, WithEmitOnChangeOf(statusKey, 0))
+
+	var got []string
+	for _, d := range published.data {
+		got = append(got, d.Data.Status)
+	}
+	if want := []string{"normal", "warning", "normal", "warning"}; !slices.Equal(got, want) {
+		t.Fatalf("Expected only transitions %v, got %v", want, got)
+	}
+	if published.data[1].Data.Value != 7 {
+		t.Errorf("Expected the first reading of each status, got %+v", published.data[1])
+	}
+	if published.data[1].ID != "sensor-1" {
+		t.Errorf("Expected suppressed readings to take no IDs, got %s", published.data[1].ID)
+	}
+}
+
+func TestEmitOnChangeOf_Heartbeat(t *testing.T) {
+	h, seeder := statusHarness(t)
+	engine := NewEngineWithOptions(h.config, seeder, statusFunction, h.Publisher, WithEmitOnChangeOf(statusKey, 2*time.Second))
+	if err := engine.RunFor(context.Background(), 12*time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	// Each status is published when it starts and again two readings later
+	var got []float64
+	for _, d := range h.Publisher.data {
+		got = append(got, d.Data.Value)
+	}
+	if want := []float64{1, 3, 7, 9, 1, 3, 7, 9}; !slices.Equal(got, want) {
+		t.Errorf("Expected readings %v, got %v", want, got)
+	}
+	if stats := engine.Stats(); stats.Generated != 12 || stats.Unchanged != 4 {
+		t.Errorf("Expected 12 readings with 4 unchanged, got %+v", stats)
+	}
+}
+
+func TestEmitOnChangeOf_NilKeyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a nil key function")
+		}
+	}()
+	WithEmitOnChangeOf[float64](nil, 0)
+}
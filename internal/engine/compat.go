@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// CheckJSONCompat marshals sample values of two versions of a type and
+// reports the changes in new that would break consumers of old: removed
+// fields and fields whose JSON type changed. Added fields are compatible.
+// Pass SensorData[T] samples to check the reading's own fields along with
+// Data. Null values match any type, so samples should have every field set.
+// A field missing from new's sample is not reported as removed while new's
+// type still declares it, as with an omitempty field left at its zero value.
+// The result is sorted and empty when the versions are compatible.
+func CheckJSONCompat(old, new any) []string {
+	oldShape, err := jsonShape(old)
+	if err != nil {
+		return []string{fmt.Sprintf("old sample: %v", err)}
+	}
+	newShape, err := jsonShape(new)
+	if err != nil {
+		return []string{fmt.Sprintf("new sample: %v", err)}
+	}
+
+	declared := make(map[string]bool)
+	if new != nil {
+		declaredFields(reflect.TypeOf(new), "", declared, make(map[reflect.Type]bool))
+	}
+
+	var problems []string
+	compareJSON("", oldShape, newShape, declared, &problems)
+	slices.Sort(problems)
+	return problems
+}
+
+// jsonShape marshals v and decodes it into generic JSON values
+func jsonShape(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var shape any
+	err = json.Unmarshal(data, &shape)
+	return shape, err
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// declaredFields adds the paths of the JSON object fields that values of t
+// can have, as compareJSON names them, to fields. Types that marshal
+// themselves and map keys are not followed; visiting stops recursive types.
+func declaredFields(t reflect.Type, path string, fields map[string]bool, visiting map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if visiting[t] || t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		declaredFields(t.Elem(), path+"[]", fields, visiting)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" {
+				// Fields of embedded structs are promoted
+				declaredFields(field.Type, path, fields, visiting)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			fields[name] = true
+			declaredFields(field.Type, name, fields, visiting)
+		}
+	}
+}
+
+// compareJSON appends the breaking changes between two decoded values at
+// path to problems. Fields in declared are missing from new only because
+// they were omitted.
+func compareJSON(path string, old, new any, declared map[string]bool, problems *[]string) {
+	if old == nil || new == nil {
+		return
+	}
+	oldKind, newKind := jsonKind(old), jsonKind(new)
+	if oldKind != newKind {
+		*problems = append(*problems, fmt.Sprintf("%s: type changed from %s to %s", displayPath(path), oldKind, newKind))
+		return
+	}
+
+	switch old := old.(type) {
+	case map[string]any:
+		newFields := new.(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(old)) {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			value, ok := newFields[key]
+			if !ok {
+				if !declared[field] {
+					*problems = append(*problems, fmt.Sprintf("%s: field removed", field))
+				}
+				continue
+			}
+			compareJSON(field, old[key], value, declared, problems)
+		}
+	case []any:
+		// Elements are compared by their first sample
+		if newItems := new.([]any); len(old) > 0 && len(newItems) > 0 {
+			compareJSON(path+"[]", old[0], newItems[0], declared, problems)
+		}
+	}
+}
+
+// jsonKind names the JSON type of a decoded value
+func jsonKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// displayPath names the top-level value when path is empty
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckJSONCompat(t *testing.T) {
+	type location struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	}
+	type readingV1 struct {
+		Celsius  float64    `json:"celsius"`
+		Location location   `json:"location"`
+		Tags     []string   `json:"tags"`
+		Device   string     `json:"device"`
+		History  []location `json:"history"`
+	}
+	type readingV2 struct {
+		Celsius  string                `json:"celsius"`  // Changed type
+		Location struct{ Lat float64 } `json:"location"` // Lost lon, and lat is renamed
+		Tags     []int                 `json:"tags"`     // Changed element type
+		History  []location            `json:"history"`
+		Kelvin   float64               `json:"kelvin"` // Added, which is compatible
+	}
+
+	now := time.Now()
+	old := SensorData[readingV1]{ID: "a", Timestamp: now, Quality: QualityOK, Data: readingV1{
+		Location: location{1, 2}, Tags: []string{"x"}, Device: "d", History: []location{{1, 2}},
+	}}
+	new := SensorData[readingV2]{ID: "a", Timestamp: now, Quality: QualityOK, Data: readingV2{
+		Celsius: "20", Tags: []int{1}, History: []location{{3, 4}},
+	}}
+
+	got := CheckJSONCompat(old, new)
+	want := []string{
+		"data.celsius: type changed from number to string",
+		"data.device: field removed",
+		"data.location.lat: field removed",
+		"data.location.lon: field removed",
+		"data.tags[]: type changed from string to number",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCheckJSONCompat_Compatible(t *testing.T) {
+	old := SensorData[float64]{ID: "a", Data: 1, Quality: QualityOK}
+	added := map[string]any{"id": "b", "timestamp": "2024-01-01T00:00:00Z", "data": 2.5, "quality": "NOISY", "unit": "C"}
+	if problems := CheckJSONCompat(old, added); len(problems) != 0 {
+		t.Errorf("Expected no problems for added fields, got %q", problems)
+	}
+
+	// Null matches anything and raw samples are compared as decoded
+	if problems := CheckJSONCompat(json.RawMessage(`{"data":null}`), json.RawMessage(`{"data":{"x":1}}`)); len(problems) != 0 {
+		t.Errorf("Expected null to match any type, got %q", problems)
+	}
+	// An omitempty field left empty in the new sample is still declared
+	type tagged struct {
+		Unit  string   `json:"unit,omitempty"`
+		Range *[2]int  `json:"range,omitempty"`
+		Notes []string `json:"notes,omitempty"`
+	}
+	full := SensorData[tagged]{ID: "a", Data: tagged{Unit: "C", Range: &[2]int{0, 1}, Notes: []string{"x"}}}
+	if problems := CheckJSONCompat(full, SensorData[tagged]{ID: "a"}); len(problems) != 0 {
+		t.Errorf("Expected omitted fields not to count as removed, got %q", problems)
+	}
+
+	if problems := CheckJSONCompat(1, "1"); !slices.Equal(problems, []string{"(root): type changed from number to string"}) {
+		t.Errorf("Unexpected problems for a changed root type: %q", problems)
+	}
+}
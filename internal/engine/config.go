@@ -3,38 +3,62 @@ package engine
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"strconv"
 	"time"
 )
 
-// ConfigFile represents the JSON configuration file structure
+// ConfigFile represents the JSON configuration file structure. A file
+// either defines one engine through Engine, Seeder and Output, or several
+// through Engines, each a complete definition of its own.
 type ConfigFile struct {
-	Engine EngineConfig `json:"engine"`
-	Seeder SeederConfig `json:"seeder"`
-	Output OutputConfig `json:"output"`
+	Name    string       `json:"name,omitempty"` // Identifies the engine in logs and stats
+	Engine  EngineConfig `json:"engine"`
+	Seeder  SeederConfig `json:"seeder"`
+	Output  OutputConfig `json:"output"`
+	Engines []ConfigFile `json:"engines,omitempty"`
 }
 
 // EngineConfig holds engine configuration
 type EngineConfig struct {
-	ProductionRate string `json:"production_rate"` // Duration string like "100ms", "1s"
-	BatchSize      int    `json:"batch_size"`
-	BatchTimeout   string `json:"batch_timeout"` // Duration string
-	MaxWorkers     int    `json:"max_workers"`
+	ProductionRate    string `json:"production_rate"` // Duration string like "100ms", "1s"
+	BatchSize         int    `json:"batch_size"`
+	BatchTimeout      string `json:"batch_timeout"` // Duration string
+	MaxWorkers        int    `json:"max_workers"`
+	PublishMode       string `json:"publish_mode,omitempty"` // "batch" (default) or "single"
+	MinBatchSize      int    `json:"min_batch_size,omitempty"`
+	MaxBatchWait      string `json:"max_batch_wait,omitempty"` // Duration string
+	BatchQueueSize    int    `json:"batch_queue_size,omitempty"`
+	DebugTee          bool   `json:"debug_tee,omitempty"`          // Also print every batch to stderr
+	MaxDuration       string `json:"max_duration,omitempty"`       // Duration string; stop on its own after this long
+	ShutdownTimeout   string `json:"shutdown_timeout,omitempty"`   // Duration string; give up on workers that do not drain in time
+	WarmupGenerations int    `json:"warmup_generations,omitempty"` // Seeder values discarded before the first reading
+	IncludeInput      bool   `json:"include_input,omitempty"`      // Record the seeder value in each reading
+
+	IncludeQualityScore bool   `json:"include_quality_score,omitempty"` // Record the quality of each reading as a number
+	StallTimeout        string `json:"stall_timeout,omitempty"`         // Duration string; warn when nothing is generated for this long
+	StopOnStall         bool   `json:"stop_on_stall,omitempty"`         // Stop the engine when the stall watchdog fires
+
+	MaxConsecutivePublishErrors int `json:"max_consecutive_publish_errors,omitempty"` // Stop after this many failed publishes in a row
 }
 
 // SeederConfig holds seeder configuration
 type SeederConfig struct {
-	Type     string                 `json:"type"`     // "time", "random", "linear", "normal", "custom"
+	Type     string                 `json:"type"`     // "time", "random", "linear", "normal", "csv", "market", "histogram", "mixture", "custom"
 	Params   map[string]interface{} `json:"params"`   // Type-specific parameters
 	Function *FunctionConfig        `json:"function"` // Optional inline function definition
 }
 
 // OutputConfig holds output configuration
 type OutputConfig struct {
-	Type     string                 `json:"type"`     // "http", "kafka", "grpc", "console"
-	Params   map[string]interface{} `json:"params"`   // Publisher-specific parameters
-	Metadata map[string]string      `json:"metadata"` // Optional metadata to include in output
+	Type     string                 `json:"type"`               // "http", "kafka", "grpc", "console"
+	Params   map[string]interface{} `json:"params"`             // Publisher-specific parameters
+	Metadata map[string]string      `json:"metadata"`           // Optional metadata to include in output; see ResolveMetadata for placeholders
+	Envelope bool                   `json:"envelope,omitempty"` // Publish envelopes carrying the resolved metadata
+	Unit     string                 `json:"unit,omitempty"`     // Unit of the values, such as "°C"; requires envelope
+	Units    map[string]string      `json:"units,omitempty"`    // Units of the fields of struct values by JSON name; requires envelope
 }
 
 // FunctionConfig represents a simple function configuration
@@ -45,7 +69,18 @@ type FunctionConfig struct {
 
 // LoadConfigFromFile loads configuration from a JSON file
 func LoadConfigFromFile(filename string) (*ConfigFile, error) {
-	data, err := os.ReadFile(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+	return LoadConfig(file)
+}
+
+// LoadConfig loads configuration from JSON read from r, such as standard
+// input or a config rendered by a template
+func LoadConfig(r io.Reader) (*ConfigFile, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -70,11 +105,80 @@ func (c *ConfigFile) ToEngineConfig() (Config, error) {
 		return Config{}, fmt.Errorf("invalid batch_timeout: %w", err)
 	}
 
+	publishMode := PublishMode(c.Engine.PublishMode)
+	switch publishMode {
+	case "":
+		publishMode = PublishModeBatch
+	case PublishModeBatch, PublishModeSingle:
+	default:
+		return Config{}, fmt.Errorf("invalid publish_mode: %s", c.Engine.PublishMode)
+	}
+
+	var maxBatchWait time.Duration
+	if c.Engine.MaxBatchWait != "" {
+		maxBatchWait, err = time.ParseDuration(c.Engine.MaxBatchWait)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid max_batch_wait: %w", err)
+		}
+	}
+
+	var maxDuration time.Duration
+	if c.Engine.MaxDuration != "" {
+		maxDuration, err = time.ParseDuration(c.Engine.MaxDuration)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid max_duration: %w", err)
+		}
+	}
+
+	var shutdownTimeout time.Duration
+	if c.Engine.ShutdownTimeout != "" {
+		shutdownTimeout, err = time.ParseDuration(c.Engine.ShutdownTimeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid shutdown_timeout: %w", err)
+		}
+	}
+
+	var stallTimeout time.Duration
+	if c.Engine.StallTimeout != "" {
+		stallTimeout, err = time.ParseDuration(c.Engine.StallTimeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid stall_timeout: %w", err)
+		}
+	}
+
+	// Metadata is only published in envelopes, so it is left unresolved
+	// without one
+	var envelope *EnvelopeConfig
+	if c.Output.Envelope {
+		metadata, err := ResolveMetadata(c.Output.Metadata)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid output metadata: %w", err)
+		}
+		envelope = &EnvelopeConfig{Metadata: metadata, Unit: c.Output.Unit, Units: c.Output.Units}
+	} else if c.Output.Unit != "" || len(c.Output.Units) > 0 {
+		return Config{}, fmt.Errorf("output unit and units are published in envelopes and require envelope")
+	}
+
 	return Config{
-		ProductionRate: productionRate,
-		BatchSize:      c.Engine.BatchSize,
-		BatchTimeout:   batchTimeout,
-		MaxWorkers:     c.Engine.MaxWorkers,
+		ProductionRate:    productionRate,
+		BatchSize:         c.Engine.BatchSize,
+		BatchTimeout:      batchTimeout,
+		MaxWorkers:        c.Engine.MaxWorkers,
+		PublishMode:       publishMode,
+		MinBatchSize:      c.Engine.MinBatchSize,
+		MaxBatchWait:      maxBatchWait,
+		BatchQueueSize:    c.Engine.BatchQueueSize,
+		DebugTee:          c.Engine.DebugTee,
+		MaxDuration:       maxDuration,
+		ShutdownTimeout:   shutdownTimeout,
+		WarmupGenerations: c.Engine.WarmupGenerations,
+		IncludeInput:      c.Engine.IncludeInput,
+		Envelope:          envelope,
+
+		IncludeQualityScore:         c.Engine.IncludeQualityScore,
+		StallTimeout:                stallTimeout,
+		StopOnStall:                 c.Engine.StopOnStall,
+		MaxConsecutivePublishErrors: c.Engine.MaxConsecutivePublishErrors,
 	}, nil
 }
 
@@ -89,6 +193,14 @@ func (c *ConfigFile) CreateSeeder() (Seeder, error) {
 		return c.createLinearSeeder()
 	case "normal":
 		return c.createNormalSeeder()
+	case "csv":
+		return c.createCSVSeeder()
+	case "market":
+		return c.createMarketSeeder()
+	case "histogram":
+		return c.createHistogramSeeder()
+	case "mixture":
+		return c.createMixtureSeeder()
 	case "custom":
 		return c.createCustomSeeder()
 	default:
@@ -100,8 +212,9 @@ func (c *ConfigFile) createTimeSeeder() (Seeder, error) {
 	amplitude := getFloatParam(c.Seeder.Params, "amplitude", 1.0)
 	frequency := getFloatParam(c.Seeder.Params, "frequency", 0.1)
 	offset := getFloatParam(c.Seeder.Params, "offset", 0.0)
+	phase := getFloatParam(c.Seeder.Params, "phase", 0.0)
 
-	return NewTimeSeeder(amplitude, frequency, offset), nil
+	return NewTimeSeederWithPhase(amplitude, frequency, offset, phase), nil
 }
 
 func (c *ConfigFile) createRandomSeeder() (Seeder, error) {
@@ -121,10 +234,100 @@ func (c *ConfigFile) createLinearSeeder() (Seeder, error) {
 func (c *ConfigFile) createNormalSeeder() (Seeder, error) {
 	mean := getFloatParam(c.Seeder.Params, "mean", 0.0)
 	stdDev := getFloatParam(c.Seeder.Params, "std_dev", 1.0)
+	if !(stdDev >= 0) {
+		return nil, fmt.Errorf("normal seeder std_dev must be non-negative, got %v", stdDev)
+	}
 
 	return NewNormalSeeder(mean, stdDev), nil
 }
 
+func (c *ConfigFile) createCSVSeeder() (Seeder, error) {
+	path := getStringParam(c.Seeder.Params, "path", "")
+	if path == "" {
+		return nil, fmt.Errorf("csv seeder requires a path")
+	}
+
+	options := CSVOptions{
+		Header:      getBoolParam(c.Seeder.Params, "header", false),
+		SkipInvalid: getBoolParam(c.Seeder.Params, "skip_invalid", false),
+		OnEnd:       CSVEndBehavior(getStringParam(c.Seeder.Params, "on_end", string(CSVLoop))),
+	}
+	// column is a header name, or a zero-based index when numeric
+	switch column := c.Seeder.Params["column"].(type) {
+	case string:
+		options.Column = column
+	case float64:
+		options.ColumnIndex = int(column)
+	}
+
+	return NewCSVSeeder(path, options)
+}
+
+func (c *ConfigFile) createMarketSeeder() (Seeder, error) {
+	defaults := DefaultMarketConfig()
+	config := MarketConfig{
+		Base:           getFloatParam(c.Seeder.Params, "base", defaults.Base),
+		CycleAmplitude: getFloatParam(c.Seeder.Params, "cycle_amplitude", defaults.CycleAmplitude),
+		CyclePeriod:    getFloatParam(c.Seeder.Params, "cycle_period", defaults.CyclePeriod),
+		TrendAmplitude: getFloatParam(c.Seeder.Params, "trend_amplitude", defaults.TrendAmplitude),
+		TrendPeriod:    getFloatParam(c.Seeder.Params, "trend_period", defaults.TrendPeriod),
+		Noise:          getFloatParam(c.Seeder.Params, "noise", defaults.Noise),
+		Min:            getFloatParam(c.Seeder.Params, "min", defaults.Min),
+		Max:            getFloatParam(c.Seeder.Params, "max", defaults.Max),
+	}
+	if !(config.CyclePeriod > 0) || !(config.TrendPeriod > 0) {
+		return nil, fmt.Errorf("market seeder periods must be positive, got cycle_period %v and trend_period %v", config.CyclePeriod, config.TrendPeriod)
+	}
+
+	return NewMarketSeeder(config), nil
+}
+
+func (c *ConfigFile) createHistogramSeeder() (Seeder, error) {
+	edges, err := getFloatSliceParam(c.Seeder.Params, "edges")
+	if err != nil {
+		return nil, err
+	}
+	weights, err := getFloatSliceParam(c.Seeder.Params, "weights")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateHistogram(edges, weights); err != nil {
+		return nil, fmt.Errorf("invalid histogram seeder: %w", err)
+	}
+
+	return NewHistogramSeeder(edges, weights), nil
+}
+
+// createMixtureSeeder builds a mixture from the components param, a list of
+// objects each holding a weight and the type and params of a seeder, which
+// may itself be a mixture
+func (c *ConfigFile) createMixtureSeeder() (Seeder, error) {
+	specs, ok := c.Seeder.Params["components"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mixture seeder requires a list of components")
+	}
+
+	components := make([]MixtureSeederComponent, len(specs))
+	for i, spec := range specs {
+		fields, ok := spec.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("mixture seeder component %d must be an object", i)
+		}
+		params, _ := fields["params"].(map[string]interface{})
+		component := ConfigFile{Seeder: SeederConfig{Type: getStringParam(fields, "type", ""), Params: params}}
+		seeder, err := component.CreateSeeder()
+		if err != nil {
+			return nil, fmt.Errorf("mixture seeder component %d: %w", i, err)
+		}
+		components[i] = MixtureSeederComponent{Seeder: seeder, Weight: getFloatParam(fields, "weight", 1.0)}
+	}
+	if err := validateMixtureSeeder(components); err != nil {
+		return nil, fmt.Errorf("invalid mixture seeder: %w", err)
+	}
+
+	return NewMixtureSeeder(components...), nil
+}
+
 func (c *ConfigFile) createCustomSeeder() (Seeder, error) {
 	// For custom seeders, we'd need to load Go code or use a scripting language
 	// For now, return a simple sine wave as example
@@ -152,6 +355,30 @@ func getFloatParam(params map[string]interface{}, key string, defaultValue float
 	return defaultValue
 }
 
+// getFloatSliceParam returns a list of numbers, nil if the key is missing
+func getFloatSliceParam(params map[string]interface{}, key string) ([]float64, error) {
+	val, ok := params[key]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("param %s must be a list of numbers", key)
+	}
+	values := make([]float64, len(list))
+	for i, item := range list {
+		switch v := item.(type) {
+		case float64:
+			values[i] = v
+		case int:
+			values[i] = float64(v)
+		default:
+			return nil, fmt.Errorf("param %s must be a list of numbers, got %v", key, item)
+		}
+	}
+	return values, nil
+}
+
 func getIntParam(params map[string]interface{}, key string, defaultValue int) int {
 	if val, ok := params[key]; ok {
 		switch v := val.(type) {
@@ -177,6 +404,20 @@ func getStringParam(params map[string]interface{}, key string, defaultValue stri
 	return defaultValue
 }
 
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if val, ok := params[key]; ok {
+		switch v := val.(type) {
+		case bool:
+			return v
+		case string:
+			if parsed, err := strconv.ParseBool(v); err == nil {
+				return parsed
+			}
+		}
+	}
+	return defaultValue
+}
+
 // Parse functions for string parameters
 func parseFloat(s string) (float64, error) {
 	var f float64
@@ -197,6 +438,10 @@ func CreateEngineFromConfig[T any](filename string, function SensorFunction[T],
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := checkSerializable[T](configFile.Output.Type); err != nil {
+		return nil, err
+	}
+
 	engineConfig, err := configFile.ToEngineConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert engine config: %w", err)
@@ -210,6 +455,20 @@ func CreateEngineFromConfig[T any](filename string, function SensorFunction[T],
 	return NewEngine(engineConfig, seeder, function, publisher), nil
 }
 
+// checkSerializable reports an error if readings of type T cannot be
+// serialized for the output type. Every output encodes readings as JSON, so
+// this marshals a zero reading: it catches types the encoder rejects, such as
+// channel or function fields, before the first publish instead of at it.
+// Values that only fail when set, such as a non-nil interface holding a
+// channel, still surface when published.
+func checkSerializable[T any](outputType string) error {
+	var zero SensorData[T]
+	if _, err := json.Marshal(zero); err != nil {
+		return fmt.Errorf("output %q cannot serialize %T readings: %w", outputType, zero.Data, err)
+	}
+	return nil
+}
+
 // DefaultConfigFile returns a default configuration structure
 func DefaultConfigFile() *ConfigFile {
 	return &ConfigFile{
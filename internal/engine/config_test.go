@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,6 +81,24 @@ func TestConfigFile_LoadConfigFromFile(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_Reader(t *testing.T) {
+	config, err := LoadConfig(strings.NewReader(`{
+		"engine": {"production_rate": "250ms", "batch_size": 5, "batch_timeout": "1s", "max_workers": 1},
+		"seeder": {"type": "random", "params": {"min": 0, "max": 1}},
+		"output": {"type": "console"}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Engine.ProductionRate != "250ms" || config.Seeder.Type != "random" || config.Output.Type != "console" {
+		t.Errorf("Expected the config read from the reader, got %+v", config)
+	}
+
+	if _, err := LoadConfig(strings.NewReader(`{"engine":`)); err == nil {
+		t.Error("Expected an error for truncated JSON")
+	}
+}
+
 func TestConfigFile_ToEngineConfig(t *testing.T) {
 	config := &ConfigFile{
 		Engine: EngineConfig{
@@ -112,6 +131,120 @@ func TestConfigFile_ToEngineConfig(t *testing.T) {
 	if engineConfig.MaxWorkers != 3 {
 		t.Errorf("Expected max workers 3, got %d", engineConfig.MaxWorkers)
 	}
+
+	if engineConfig.PublishMode != PublishModeBatch {
+		t.Errorf("Expected default publish mode %q, got %q", PublishModeBatch, engineConfig.PublishMode)
+	}
+}
+
+func TestConfigFile_ToEngineConfig_PublishMode(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engine.PublishMode = "single"
+
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.PublishMode != PublishModeSingle {
+		t.Errorf("Expected publish mode %q, got %q", PublishModeSingle, engineConfig.PublishMode)
+	}
+
+	config.Engine.PublishMode = "sometimes"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for invalid publish_mode")
+	}
+}
+
+func TestConfigFile_ToEngineConfig_MinBatchSize(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engine.MinBatchSize = 20
+	config.Engine.MaxBatchWait = "2s"
+
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.MinBatchSize != 20 || engineConfig.MaxBatchWait != 2*time.Second {
+		t.Errorf("Expected min batch size 20 and max batch wait 2s, got %d and %v", engineConfig.MinBatchSize, engineConfig.MaxBatchWait)
+	}
+
+	config.Engine.MaxBatchWait = "soon"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for invalid max_batch_wait")
+	}
+}
+
+func TestConfigFile_ToEngineConfig_MaxDuration(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engine.MaxDuration = "90s"
+
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.MaxDuration != 90*time.Second {
+		t.Errorf("Expected max duration 90s, got %v", engineConfig.MaxDuration)
+	}
+
+	config.Engine.MaxDuration = "forever"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for invalid max_duration")
+	}
+}
+
+func TestConfigFile_ToEngineConfig_ShutdownTimeout(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engine.ShutdownTimeout = "5s"
+
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.ShutdownTimeout != 5*time.Second {
+		t.Errorf("Expected shutdown timeout 5s, got %v", engineConfig.ShutdownTimeout)
+	}
+
+	config.Engine.ShutdownTimeout = "soon"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for invalid shutdown_timeout")
+	}
+}
+
+func TestConfigFile_ToEngineConfig_StallTimeout(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engine.StallTimeout = "10s"
+	config.Engine.StopOnStall = true
+
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.StallTimeout != 10*time.Second || !engineConfig.StopOnStall {
+		t.Errorf("Expected a 10s stall timeout that stops the engine, got %v and %v", engineConfig.StallTimeout, engineConfig.StopOnStall)
+	}
+
+	config.Engine.StallTimeout = "never"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for invalid stall_timeout")
+	}
+}
+
+func TestConfigFile_ToEngineConfig_Units(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Output.Unit = "bar"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for a unit without envelope")
+	}
+
+	config.Output.Envelope = true
+	config.Output.Units = map[string]string{"temperature": "°C"}
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.Envelope.Unit != "bar" || engineConfig.Envelope.Units["temperature"] != "°C" {
+		t.Errorf("Expected the units in the envelope config, got %+v", engineConfig.Envelope)
+	}
 }
 
 func TestConfigFile_CreateSeeder(t *testing.T) {
@@ -158,6 +291,67 @@ func TestConfigFile_CreateSeeder(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:       "MarketSeeder",
+			seederType: "market",
+			params: map[string]interface{}{
+				"cycle_period": 100.0,
+				"noise":        0.1,
+			},
+			expectError: false,
+		},
+		{
+			name:        "MarketSeederZeroPeriod",
+			seederType:  "market",
+			params:      map[string]interface{}{"trend_period": 0.0},
+			expectError: true,
+		},
+		{
+			name:       "HistogramSeeder",
+			seederType: "histogram",
+			params: map[string]interface{}{
+				"edges":   []interface{}{0.0, 10.0, 20.0},
+				"weights": []interface{}{3.0, 1.0},
+			},
+			expectError: false,
+		},
+		{
+			name:        "HistogramSeederMismatchedBins",
+			seederType:  "histogram",
+			params:      map[string]interface{}{"edges": []interface{}{0.0, 10.0}, "weights": []interface{}{3.0, 1.0}},
+			expectError: true,
+		},
+		{
+			name:        "HistogramSeederNotAList",
+			seederType:  "histogram",
+			params:      map[string]interface{}{"edges": "0,10", "weights": []interface{}{1.0}},
+			expectError: true,
+		},
+		{
+			name:       "MixtureSeeder",
+			seederType: "mixture",
+			params: map[string]interface{}{
+				"components": []interface{}{
+					map[string]interface{}{"weight": 0.7, "type": "normal", "params": map[string]interface{}{"mean": 10.0, "std_dev": 1.0}},
+					map[string]interface{}{"weight": 0.3, "type": "normal", "params": map[string]interface{}{"mean": 30.0, "std_dev": 2.0}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:        "MixtureSeederNoComponents",
+			seederType:  "mixture",
+			params:      map[string]interface{}{"components": []interface{}{}},
+			expectError: true,
+		},
+		{
+			name:       "MixtureSeederInvalidComponent",
+			seederType: "mixture",
+			params: map[string]interface{}{
+				"components": []interface{}{map[string]interface{}{"type": "normal", "params": map[string]interface{}{"std_dev": -1.0}}},
+			},
+			expectError: true,
+		},
 		{
 			name:       "CustomSeeder",
 			seederType: "custom",
@@ -318,6 +512,30 @@ func TestCreateEngineFromConfig(t *testing.T) {
 	}
 }
 
+func TestCreateEngineFromConfig_UnserializableType(t *testing.T) {
+	type withChannel struct {
+		Value   float64
+		Updates chan float64
+	}
+
+	path := writeConfig(t, `{
+		"engine": {"production_rate": "50ms", "batch_size": 10, "batch_timeout": "100ms", "max_workers": 1},
+		"seeder": {"type": "random", "params": {"min": 0, "max": 1}},
+		"output": {"type": "kafka"}
+	}`)
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) withChannel {
+		return withChannel{Value: input}
+	})
+
+	_, err := CreateEngineFromConfig(path, function, &mockTestPublisher[withChannel]{})
+	if err == nil {
+		t.Fatal("Expected an error for a reading type with a channel field")
+	}
+	if !strings.Contains(err.Error(), `output "kafka"`) || !strings.Contains(err.Error(), "withChannel") {
+		t.Errorf("Expected the error to name the output and type, got %v", err)
+	}
+}
+
 // Helper functions and mocks
 func isFinite(f float64) bool {
 	return !(f != f || f > 1.797693134862315708145274237317043567981e+308 || f < -1.797693134862315708145274237317043567981e+308)
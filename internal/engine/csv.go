@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSVEndBehavior selects what a CSVSeeder does after its last value
+type CSVEndBehavior string
+
+const (
+	CSVLoop CSVEndBehavior = "loop" // Start again from the first value
+	CSVHold CSVEndBehavior = "hold" // Keep returning the last value
+)
+
+// CSVOptions selects the column a CSVSeeder replays and how it reads it
+type CSVOptions struct {
+	Header      bool           // The first row holds column names
+	Column      string         // Name of the column to replay; requires Header
+	ColumnIndex int            // Zero-based index of the column, used when Column is empty
+	SkipInvalid bool           // Skip empty or non-numeric cells instead of failing
+	OnEnd       CSVEndBehavior // Behavior after the last value (default CSVLoop)
+	Comma       rune           // Field delimiter (default ',')
+}
+
+// CSVSeeder replays a column of recorded values, returning the next one on
+// every call. The column is read into memory when the seeder is created.
+type CSVSeeder struct {
+	values []float64
+	onEnd  CSVEndBehavior
+	next   int
+	mutex  sync.Mutex
+}
+
+// NewCSVSeeder creates a seeder replaying a column of the CSV file at path
+func NewCSVSeeder(path string, options CSVOptions) (*CSVSeeder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	return NewCSVSeederFromReader(file, options)
+}
+
+// NewCSVSeederFromReader creates a seeder replaying a column of the CSV data
+// read from r
+func NewCSVSeederFromReader(r io.Reader, options CSVOptions) (*CSVSeeder, error) {
+	switch options.OnEnd {
+	case "":
+		options.OnEnd = CSVLoop
+	case CSVLoop, CSVHold:
+	default:
+		return nil, fmt.Errorf("unknown CSV end behavior: %s", options.OnEnd)
+	}
+	if options.Column != "" && !options.Header {
+		return nil, fmt.Errorf("CSV column %q selected by name without a header row", options.Column)
+	}
+	if options.ColumnIndex < 0 {
+		return nil, fmt.Errorf("invalid CSV column index: %d", options.ColumnIndex)
+	}
+
+	reader := csv.NewReader(r)
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+	reader.FieldsPerRecord = -1 // Rows may be ragged; only the selected cell matters
+	reader.TrimLeadingSpace = true
+
+	column := options.ColumnIndex
+	row := 0
+	if options.Header {
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		row++
+		if options.Column != "" {
+			column = -1
+			for i, name := range header {
+				if strings.TrimSpace(name) == options.Column {
+					column = i
+					break
+				}
+			}
+			if column < 0 {
+				return nil, fmt.Errorf("CSV column %q not found in header", options.Column)
+			}
+		}
+	}
+
+	var values []float64
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		row++
+
+		if column >= len(record) {
+			if options.SkipInvalid {
+				continue
+			}
+			return nil, fmt.Errorf("CSV row %d has no column %d", row, column)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[column]), 64)
+		if err != nil {
+			if options.SkipInvalid {
+				continue
+			}
+			return nil, fmt.Errorf("CSV row %d: invalid value %q", row, record[column])
+		}
+		values = append(values, value)
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("CSV column has no numeric values")
+	}
+	return &CSVSeeder{values: values, onEnd: options.OnEnd}, nil
+}
+
+// Generate returns the next value of the column
+func (c *CSVSeeder) Generate() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.next == len(c.values) {
+		if c.onEnd == CSVHold {
+			return c.values[len(c.values)-1]
+		}
+		c.next = 0
+	}
+	value := c.values[c.next]
+	c.next++
+	return value
+}
+
+// Len returns the number of values in the column
+func (c *CSVSeeder) Len() int {
+	return len(c.values)
+}
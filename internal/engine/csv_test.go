@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCSV = `time,temperature,status
+0,21.5,ok
+1,21.7,ok
+2,n/a,fault
+3,22.1,ok
+`
+
+func generateN(s Seeder, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = s.Generate()
+	}
+	return values
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCSVSeeder_ColumnByNameLoops(t *testing.T) {
+	seeder, err := NewCSVSeederFromReader(strings.NewReader(testCSV), CSVOptions{
+		Header:      true,
+		Column:      "temperature",
+		SkipInvalid: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create seeder: %v", err)
+	}
+	if seeder.Len() != 3 {
+		t.Errorf("Expected 3 values after skipping the invalid cell, got %d", seeder.Len())
+	}
+
+	want := []float64{21.5, 21.7, 22.1, 21.5, 21.7}
+	if got := generateN(seeder, 5); !equalFloats(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCSVSeeder_ColumnByIndexHolds(t *testing.T) {
+	seeder, err := NewCSVSeederFromReader(strings.NewReader("1;10\n2;20\n3;30\n"), CSVOptions{
+		ColumnIndex: 1,
+		Comma:       ';',
+		OnEnd:       CSVHold,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create seeder: %v", err)
+	}
+
+	want := []float64{10, 20, 30, 30, 30}
+	if got := generateN(seeder, 5); !equalFloats(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCSVSeeder_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		options CSVOptions
+		message string
+	}{
+		{"InvalidCell", testCSV, CSVOptions{Header: true, Column: "temperature"}, `row 4: invalid value "n/a"`},
+		{"UnknownColumn", testCSV, CSVOptions{Header: true, Column: "humidity"}, "not found in header"},
+		{"NameWithoutHeader", testCSV, CSVOptions{Column: "temperature"}, "without a header row"},
+		{"MissingColumn", "1,2\n3\n", CSVOptions{ColumnIndex: 1}, "row 2 has no column 1"},
+		{"NoValues", "temperature\n", CSVOptions{Header: true}, "no numeric values"},
+		{"UnknownEndBehavior", testCSV, CSVOptions{OnEnd: "rewind"}, "unknown CSV end behavior"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCSVSeederFromReader(strings.NewReader(tt.data), tt.options)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestConfigFile_CreateCSVSeeder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	if err := os.WriteFile(path, []byte(testCSV), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ConfigFile{
+		Seeder: SeederConfig{
+			Type: "csv",
+			Params: map[string]interface{}{
+				"path":         path,
+				"header":       true,
+				"column":       1.0,
+				"skip_invalid": true,
+				"on_end":       "hold",
+			},
+		},
+	}
+	seeder, err := config.CreateSeeder()
+	if err != nil {
+		t.Fatalf("Failed to create seeder: %v", err)
+	}
+
+	want := []float64{21.5, 21.7, 22.1, 22.1}
+	if got := generateN(seeder, 4); !equalFloats(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func BenchmarkCSVSeeder(b *testing.B) {
+	seeder, err := NewCSVSeederFromReader(strings.NewReader(testCSV), CSVOptions{Header: true, Column: "temperature", SkipInvalid: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		seeder.Generate()
+	}
+}
+
+func BenchmarkNewCSVSeederFromReader(b *testing.B) {
+	var data strings.Builder
+	data.WriteString("time,temperature\n")
+	for i := range 1000 {
+		fmt.Fprintf(&data, "%d,%d.5\n", i, 20+i%10)
+	}
+	csvData := data.String()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := NewCSVSeederFromReader(strings.NewReader(csvData), CSVOptions{Header: true, Column: "temperature"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
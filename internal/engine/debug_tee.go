@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// teeBatch writes batch to the debug output as one JSON line per reading,
+// headed by the batch size. The batch is written in a single call so
+// batches from concurrent workers do not interleave.
+func (e *Engine[T]) teeBatch(batch []SensorData[T]) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[tee] batch of %d readings\n", len(batch))
+	for _, data := range batch {
+		line, err := json.Marshal(data)
+		if err != nil {
+			fmt.Fprintf(&buf, "[tee] %+v\n", data)
+			continue
+		}
+		buf.WriteString("[tee] ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	e.debugMu.Lock()
+	defer e.debugMu.Unlock()
+	e.debugOut.Write(buf.Bytes())
+}
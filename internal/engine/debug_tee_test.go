@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngine_DebugTee(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      2,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+		DebugTee:       true,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(2.0), publisher)
+	var out bytes.Buffer
+	engine.debugOut = &out
+
+	if err := engine.RunFor(context.Background(), 40*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	if got := publisher.GetTotalDataPoints(); got != 4 {
+		t.Errorf("Expected the publisher to still receive 4 readings, got %d", got)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var readings int
+	for _, line := range lines {
+		if strings.Contains(line, `"data":2`) {
+			readings++
+		}
+	}
+	if readings != 4 {
+		t.Errorf("Expected 4 teed readings, got %d in:\n%s", readings, out.String())
+	}
+	if !strings.HasPrefix(lines[0], "[tee] batch of ") {
+		t.Errorf("Expected a batch header, got %q", lines[0])
+	}
+}
+
+func TestEngine_DebugTeeOff(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      2,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(2.0), NewMockPublisher[float64]())
+	var out bytes.Buffer
+	engine.debugOut = &out
+
+	if err := engine.RunFor(context.Background(), 40*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no tee output, got %q", out.String())
+	}
+}
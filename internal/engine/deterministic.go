@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// randFloat64 returns a uniform value in [0, 1) from the engine's random source
+func (e *Engine[T]) randFloat64() float64 {
+	if e.rng == nil {
+		return rand.Float64()
+	}
+	e.rngMu.Lock()
+	defer e.rngMu.Unlock()
+	return e.rng.Float64()
+}
+
+// randIntN returns a uniform value in [0, n) from the engine's random source
+func (e *Engine[T]) randIntN(n int) int {
+	if e.rng == nil {
+		return rand.IntN(n)
+	}
+	e.rngMu.Lock()
+	defer e.rngMu.Unlock()
+	return e.rng.IntN(n)
+}
+
+// randNormFloat64 returns a standard normal value from the engine's random
+// source
+func (e *Engine[T]) randNormFloat64() float64 {
+	if e.rng == nil {
+		return rand.NormFloat64()
+	}
+	e.rngMu.Lock()
+	defer e.rngMu.Unlock()
+	return e.rng.NormFloat64()
+}
+
+// RunFor runs the pipeline over d of virtual time instead of waiting on real
+// timers, then closes the publisher, also when the run fails or ctx is
+// cancelled. Readings are generated every ProductionRate starting from the
+// clock's current time, batches are cut by BatchSize and by BatchTimeout
+// (with MinBatchSize and MaxBatchWait) measured in virtual time, and batches
+// are published in order from a single goroutine. Time-based seeders are evaluated at each reading's virtual
+// timestamp, and a ManualClock is moved along with it.
+//
+// With a ManualClock, a seeded RandSource and seeders built from the same
+// clock and seeded sources, the published output is identical on every run.
+func (e *Engine[T]) RunFor(ctx context.Context, d time.Duration) error {
+	rate := e.ProductionRate()
+	if rate <= 0 {
+		return fmt.Errorf("production rate must be positive, got %v", rate)
+	}
+
+	start := e.clock.Now()
+	runErr := e.runVirtual(ctx, start.Add(rate), start.Add(d), rate)
+	return errors.Join(runErr, e.closePublishers())
+}
+
+// Backfill generates one reading per step across [start, end] and publishes
+// them as fast as the publisher allows, for seeding a store with historical
+// data. Timestamps and time-based seeders follow the virtual time, batching
+// works as in RunFor, and a ManualClock is moved along with it. Unlike
+// Start and RunFor, Backfill leaves the publisher open so it can be followed
+// by Start.
+func (e *Engine[T]) Backfill(ctx context.Context, start, end time.Time, step time.Duration) error {
+	if step <= 0 {
+		return fmt.Errorf("backfill step must be positive, got %v", step)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("backfill end %v is before start %v", end, start)
+	}
+	return e.runVirtual(ctx, start, end, step)
+}
+
+// runVirtual generates readings at first, first+step, ... up to and
+// including last, batching and publishing them synchronously
+func (e *Engine[T]) runVirtual(ctx context.Context, first, last time.Time, step time.Duration) error {
+	if err := e.validateConfig(); err != nil {
+		return err
+	}
+
+	e.warmUp()
+
+	parent := ctx
+	ctx, abort := e.startRun(ctx)
+	defer abort(nil)
+
+	manual, _ := e.clock.(*ManualClock)
+	clocked, _ := e.seeder.(ClockedSeeder)
+
+	// BatchTimeout counts from one step before the first reading, as if the
+	// batch timer had been started then
+	lastFlush := first.Add(-step)
+	batch := make([]SensorData[T], 0, e.config.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 || abortError(ctx) != nil {
+			return
+		}
+		e.publishVirtual(ctx, batch)
+		batch = make([]SensorData[T], 0, e.config.BatchSize)
+	}
+
+	// timedOut reports whether BatchTimeout lets the batch be sent at now,
+	// holding batches below MinBatchSize back until MaxBatchWait
+	timedOut := func(now time.Time) bool {
+		if e.config.BatchTimeout <= 0 {
+			return false
+		}
+		waited := now.Sub(lastFlush)
+		if waited < e.BatchTimeout() {
+			return false
+		}
+		return len(batch) == 0 || len(batch) >= e.config.MinBatchSize || waited >= e.config.MaxBatchWait
+	}
+
+	add := func(data SensorData[T], now time.Time) {
+		batch = append(batch, data)
+		if len(batch) >= e.BatchSize() || (e.config.MinBatchSize > 0 && timedOut(now)) {
+			flush()
+			lastFlush = now
+		}
+	}
+
+	// Readings held back for shuffling when ReorderWindow is set
+	var window []SensorData[T]
+
+	outage := newOfflineState(e.config.Offline, e.randFloat64)
+
+	for counter := 0; ; counter++ {
+		timestamp := first.Add(time.Duration(counter) * step)
+		if timestamp.After(last) || ctx.Err() != nil {
+			break
+		}
+		if manual != nil {
+			manual.Set(timestamp)
+		}
+
+		if timedOut(timestamp) {
+			flush()
+			lastFlush = timestamp
+		}
+
+		offline, marker := e.checkOffline(outage, timestamp.Sub(first), timestamp)
+		if marker != nil {
+			add(*marker, timestamp)
+		}
+		if offline {
+			continue
+		}
+
+		var input float64
+		if clocked != nil {
+			input = clocked.GenerateAt(timestamp)
+		} else {
+			input = e.seeder.Generate()
+		}
+
+		data, keep := e.newReading(reading[T]{
+			input:     input,
+			data:      e.withDrift(e.withNoise(e.function.Generate(input, timestamp)), timestamp),
+			timestamp: timestamp,
+		})
+		e.stats.generated.Add(1)
+		if !keep {
+			continue
+		}
+		data.ID = e.idGenerator(e.takeID())
+		e.stats.recordQuality(data.Quality)
+
+		if e.config.ReorderWindow > 1 {
+			window = append(window, data)
+			if len(window) < e.config.ReorderWindow {
+				continue
+			}
+			e.shuffleWindow(window)
+			for _, d := range window {
+				add(d, timestamp)
+			}
+			window = window[:0]
+		} else {
+			add(data, timestamp)
+		}
+	}
+
+	e.shuffleWindow(window)
+	for _, d := range window {
+		add(d, last)
+	}
+	flush()
+
+	if err := abortError(ctx); err != nil {
+		return err
+	}
+	return parent.Err()
+}
+
+// publishVirtual publishes one batch for RunFor, reporting failures the
+// way the publish workers do
+func (e *Engine[T]) publishVirtual(ctx context.Context, batch []SensorData[T]) {
+	batch, batchID, err := e.publishBatch(ctx, batch, e.config.PublishMode == PublishModeSingle)
+	e.recordPublishOutcome(err)
+	if err != nil {
+		e.reportPublishError(batchID, batch, err)
+	}
+}
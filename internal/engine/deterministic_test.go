@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// bufferPublisher writes every batch, with its batch ID, as JSON lines
+type bufferPublisher[T any] struct {
+	buf bytes.Buffer
+}
+
+func (b *bufferPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	return b.PublishBatch(ctx, []SensorData[T]{data})
+}
+
+func (b *bufferPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	batchID, _ := BatchIDFromContext(ctx)
+	fmt.Fprintf(&b.buf, "batch %s\n", batchID)
+	for _, d := range data {
+		line, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		b.buf.Write(append(line, '\n'))
+	}
+	return nil
+}
+
+func (b *bufferPublisher[T]) Close() error {
+	return nil
+}
+
+// runDeterministic runs a seeded engine over one virtual second
+func runDeterministic(t *testing.T) []byte {
+	t.Helper()
+
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	seeder := NewReduceSeeder(ReduceSum,
+		NewTimeSeederWithClock(10, 0.5, 20, clock),
+		NewNormalSeederWithSource(0, 0.5, rand.NewPCG(1, 2)),
+	)
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+		return input * 2
+	})
+
+	config := Config{
+		ProductionRate: 50 * time.Millisecond,
+		BatchSize:      4,
+		BatchTimeout:   150 * time.Millisecond,
+		MaxWorkers:     1,
+		TraceBatches:   true,
+		Clock:          clock,
+		RandSource:     rand.NewPCG(3, 4),
+		QualityProfile: QualityProfile{
+			{Quality: QualityOK, Weight: 0.5},
+			{Quality: QualityNoisy, Weight: 0.3},
+			{Quality: QualityCorrupt, Weight: 0.2},
+		},
+	}
+
+	publisher := &bufferPublisher[float64]{}
+	engine := NewEngine(config, seeder, function, publisher)
+	if err := engine.RunFor(context.Background(), time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	if got := engine.Stats().Generated; got != 20 {
+		t.Errorf("Expected 20 readings in one virtual second, got %d", got)
+	}
+	return publisher.buf.Bytes()
+}
+
+func TestEngine_RunForDeterministicGolden(t *testing.T) {
+	first := runDeterministic(t)
+	second := runDeterministic(t)
+	if !bytes.Equal(first, second) {
+		t.Fatal("Two runs with the same seed and clock produced different output")
+	}
+
+	golden := filepath.Join("testdata", "deterministic.golden")
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, first, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(first, want) {
+		t.Errorf("Output differs from %s (run with -update if the change is intended)\ngot:\n%s", golden, first)
+	}
+}
+
+func TestEngine_RunForBatchTimeoutInVirtualTime(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      100,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	start := time.Now()
+	if err := engine.RunFor(context.Background(), time.Hour); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("RunFor should not wait on real time, took %v", elapsed)
+	}
+
+	if publisher.GetTotalDataPoints() != 360000 {
+		t.Errorf("Expected 360000 readings, got %d", publisher.GetTotalDataPoints())
+	}
+	// The first batch is cut at 50ms before that tick's reading is added
+	if len(publisher.batches[0]) != 4 {
+		t.Errorf("Expected 4 readings in the first batch, got %d", len(publisher.batches[0]))
+	}
+	for i, batch := range publisher.batches[1 : len(publisher.batches)-1] {
+		if len(batch) != 5 {
+			t.Fatalf("Batch %d: expected 5 readings per 50ms of virtual time, got %d", i, len(batch))
+		}
+	}
+	if !publisher.IsClosed() {
+		t.Error("Expected publisher to be closed")
+	}
+}
+
+func TestEngine_RunForClosesPublisherWhenCancelled(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      10,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	publisher := NewMockPublisher[float64]()
+	deadLetter := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+	engine.SetDeadLetterPublisher(deadLetter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := engine.RunFor(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !publisher.IsClosed() {
+		t.Error("Expected publisher to be closed after a cancelled run")
+	}
+	if !deadLetter.IsClosed() {
+		t.Error("Expected dead-letter publisher to be closed after a cancelled run")
+	}
+}
+
+func TestEngine_RunForMinBatchSizeDistribution(t *testing.T) {
+	sizes := func(minBatch int, maxWait time.Duration) map[int]int {
+		config := Config{
+			ProductionRate: 10 * time.Millisecond,
+			BatchSize:      100,
+			BatchTimeout:   20 * time.Millisecond,
+			MinBatchSize:   minBatch,
+			MaxBatchWait:   maxWait,
+			MaxWorkers:     1,
+			Clock:          NewManualClock(time.Unix(0, 0)),
+		}
+		publisher := NewMockPublisher[float64]()
+		engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+		if err := engine.RunFor(context.Background(), 10*time.Second); err != nil {
+			t.Fatalf("RunFor failed: %v", err)
+		}
+
+		// Count batch sizes, leaving out the first batch, which is cut one
+		// reading early, and the final flush
+		counts := make(map[int]int)
+		for _, batch := range publisher.batches[1 : len(publisher.batches)-1] {
+			counts[len(batch)]++
+		}
+		return counts
+	}
+
+	if got := sizes(0, 0); len(got) != 1 || got[2] == 0 {
+		t.Errorf("Expected only batches of 2 without MinBatchSize, got %v", got)
+	}
+	if got := sizes(5, time.Second); len(got) != 1 || got[5] == 0 {
+		t.Errorf("Expected only batches of 5 with MinBatchSize 5, got %v", got)
+	}
+	// MaxBatchWait is reached before MinBatchSize, capping the batches at 4
+	if got := sizes(8, 40*time.Millisecond); len(got) != 1 || got[4] == 0 {
+		t.Errorf("Expected only batches of 4 capped by MaxBatchWait, got %v", got)
+	}
+}
+
+func TestEngine_Backfill(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+
+	seeder := NewLinearSeeder(1, 0)
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+		return input
+	})
+	config := Config{
+		ProductionRate: time.Hour,
+		BatchSize:      24,
+		BatchTimeout:   time.Hour,
+		MaxWorkers:     1,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, seeder, function, publisher)
+
+	if err := engine.Backfill(context.Background(), start, end, time.Minute); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+
+	var readings []SensorData[float64]
+	for _, batch := range publisher.batches {
+		readings = append(readings, batch...)
+	}
+	if want := 7*24*60 + 1; len(readings) != want {
+		t.Fatalf("Expected %d readings across the inclusive range, got %d", want, len(readings))
+	}
+	if !readings[0].Timestamp.Equal(start) || !readings[len(readings)-1].Timestamp.Equal(end) {
+		t.Errorf("Expected readings from %v to %v, got %v to %v",
+			start, end, readings[0].Timestamp, readings[len(readings)-1].Timestamp)
+	}
+
+	// The linear seeder must follow virtual time, not the wall clock
+	slope := readings[60].Data - readings[0].Data
+	if math.Abs(slope-3600) > 1e-6 {
+		t.Errorf("Expected seeder to advance 3600 per virtual hour, got %v", slope)
+	}
+	if publisher.IsClosed() {
+		t.Error("Backfill should leave the publisher open")
+	}
+}
+
+func TestEngine_BackfillInvalidRange(t *testing.T) {
+	engine := NewEngine(DefaultConfig(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+	now := time.Now()
+
+	if err := engine.Backfill(context.Background(), now, now.Add(-time.Hour), time.Minute); err == nil {
+		t.Error("Expected error when end is before start")
+	}
+	if err := engine.Backfill(context.Background(), now, now.Add(time.Hour), 0); err == nil {
+		t.Error("Expected error for zero step")
+	}
+}
+
+// randomWalk returns a seeder taking unit steps up or down from 0
+func randomWalk(seed uint64) Seeder {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	position := 0.0
+	return NewCustomSeeder(func() float64 {
+		if rng.IntN(2) == 0 {
+			position--
+		} else {
+			position++
+		}
+		return position
+	})
+}
+
+func TestEngine_WarmupGenerations(t *testing.T) {
+	const warmup = 50
+
+	// The walk's value after warmup+1 steps is the first one published
+	reference := randomWalk(7)
+	for range warmup {
+		reference.Generate()
+	}
+	want := reference.Generate()
+
+	config := Config{
+		ProductionRate:    10 * time.Millisecond,
+		BatchSize:         5,
+		MaxWorkers:        1,
+		Clock:             NewManualClock(time.Unix(0, 0)),
+		WarmupGenerations: warmup,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, randomWalk(7), NewTestSensorFunction(1.0), publisher)
+
+	for range 2 {
+		if err := engine.RunFor(context.Background(), 50*time.Millisecond); err != nil {
+			t.Fatalf("RunFor failed: %v", err)
+		}
+	}
+
+	if len(publisher.batches) == 0 {
+		t.Fatal("Expected published batches")
+	}
+	if got := publisher.batches[0][0].Data; got != want {
+		t.Errorf("Expected the first reading to follow %d warmup steps (%v), got %v", warmup, want, got)
+	}
+
+	// The warmup runs only once, so the second run continues the walk
+	// without skipping steps
+	var readings []float64
+	for _, batch := range publisher.batches {
+		for _, data := range batch {
+			readings = append(readings, data.Data)
+		}
+	}
+	for i := 1; i < len(readings); i++ {
+		if math.Abs(readings[i]-readings[i-1]) != 1 {
+			t.Fatalf("Expected unit steps between readings, got %v", readings)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// drift is a calibration offset growing linearly with the time since the
+// first reading or the last recalibration
+type drift struct {
+	rate        float64       // Units per second
+	recalibrate time.Duration // Reset interval, 0 for never
+
+	mutex sync.Mutex
+	start time.Time // Timestamp of the first reading, zero before it
+}
+
+// offset returns the drift at a reading's timestamp
+func (d *drift) offset(timestamp time.Time) float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.start.IsZero() {
+		d.start = timestamp
+	}
+	elapsed := timestamp.Sub(d.start)
+	if d.recalibrate > 0 {
+		elapsed %= d.recalibrate
+	}
+	return d.rate * elapsed.Seconds()
+}
+
+// WithDrift simulates a sensor drifting out of calibration: every reading
+// gets an offset of driftRate units per second of reading time since the
+// engine's first reading, added after the sensor function and any noise.
+// With recalibrateEvery above 0 the offset returns to zero every
+// recalibrateEvery, as if the sensor were recalibrated on a schedule. The
+// start time is part of the engine's MarshalState, so the drift continues
+// across restored runs. For integer types the offset is truncated towards
+// zero. It panics if recalibrateEvery is negative.
+func WithDrift[N Number](driftRate float64, recalibrateEvery time.Duration) Option[N] {
+	if recalibrateEvery < 0 {
+		panic(fmt.Sprintf("engine: drift recalibration interval must not be negative, got %v", recalibrateEvery))
+	}
+	return func(e *Engine[N]) {
+		e.drift = &drift{rate: driftRate, recalibrate: recalibrateEvery}
+		e.addOffset = func(v N, offset float64) N {
+			return v + N(offset)
+		}
+	}
+}
+
+// withDrift returns data with the drift at timestamp added, if drift is
+// enabled
+func (e *Engine[T]) withDrift(data T, timestamp time.Time) T {
+	if e.drift == nil {
+		return data
+	}
+	return e.addOffset(data, e.drift.offset(timestamp))
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWithDrift_GrowsLinearly(t *testing.T) {
+	config := Config{
+		ProductionRate: 100 * time.Millisecond,
+		BatchSize:      100,
+		MaxWorkers:     1,
+	}
+	h := newHarness[float64](t, config)
+	// Noisy readings around 20 drifting by 0.5 units per second
+	published := h.run(NewTestSeeder([]float64{20}), NewTestSensorFunction(1), 6000,
+		WithAR1Noise[float64](0.5, 1), WithDrift[float64](0.5, 0))
+
+	// The least-squares slope of the readings over time is the drift rate
+	var sumT, sumV, sumTT, sumTV float64
+	for _, d := range published.data {
+		elapsed := d.Timestamp.Sub(published.data[0].Timestamp).Seconds()
+		sumT += elapsed
+		sumV += d.Data
+		sumTT += elapsed * elapsed
+		sumTV += elapsed * d.Data
+	}
+	n := float64(len(published.data))
+	slope := (n*sumTV - sumT*sumV) / (n*sumTT - sumT*sumT)
+	intercept := (sumV - slope*sumT) / n
+	if math.Abs(slope-0.5) > 0.005 {
+		t.Errorf("Expected the output to rise by 0.5 per second, got %.4f", slope)
+	}
+	if math.Abs(intercept-20) > 0.2 {
+		t.Errorf("Expected the drift to start at the first reading, got an intercept of %.3f", intercept)
+	}
+}
+
+func TestWithDrift_Recalibration(t *testing.T) {
+	h := newHarness[float64](t, Config{
+		ProductionRate: time.Second,
+		BatchSize:      10,
+		MaxWorkers:     1,
+	})
+	published := h.run(NewTestSeeder([]float64{0}), NewTestSensorFunction(1), 8,
+		WithDrift[float64](2, 3*time.Second))
+
+	// The first reading starts the drift; it resets every 3 seconds
+	want := []float64{0, 2, 4, 0, 2, 4, 0, 2}
+	for i, d := range published.data {
+		if d.Data != want[i] {
+			t.Errorf("Reading %d: expected %v, got %v", i, want[i], d.Data)
+		}
+	}
+}
+
+func TestWithDrift_NegativeRecalibrationPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a negative recalibration interval")
+		}
+	}()
+	WithDrift[float64](1, -time.Second)
+}
@@ -2,17 +2,40 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand/v2"
+	"math"
 	"sync"
 	"time"
 )
 
 // Start starts the sensor engine and returns an error if any
 func (e *Engine[T]) Start(ctx context.Context) error {
+	if err := e.validateConfig(); err != nil {
+		return err
+	}
+
+	e.warmUp()
+
+	runCtx, abort := e.startRun(ctx)
+	defer abort(nil)
+	ctx = runCtx
+
+	// Stop on our own after MaxDuration, whatever deadline ctx has
+	if e.config.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.MaxDuration)
+		defer cancel()
+	}
+
 	// Create channels for data flow
 	dataChan := make(chan SensorData[T], 100)
-	batchChan := make(chan []SensorData[T], 10)
+	batchChan := make(chan []SensorData[T], e.batchQueueSize())
+	e.batchChan.Store(&batchChan)
+	defer e.batchChan.Store(nil)
+
+	// Stop the stats server, if any, once the engine has shut down
+	defer e.stopStatsServer()
 
 	// Wait groups for graceful shutdown
 	var dataWG, batchWG, publishWG sync.WaitGroup
@@ -21,89 +44,665 @@ func (e *Engine[T]) Start(ctx context.Context) error {
 	dataWG.Add(1)
 	go e.generateData(ctx, dataChan, &dataWG)
 
-	// Start batch processor
-	batchWG.Add(1)
-	go e.processBatches(ctx, dataChan, batchChan, &batchWG)
+	// Start adaptive rate controller
+	if e.config.AdaptiveRate != nil {
+		dataWG.Add(1)
+		go e.adaptRate(ctx, batchChan, &dataWG)
+	}
 
-	// Start publisher workers
-	for i := 0; i < e.config.MaxWorkers; i++ {
-		publishWG.Add(1)
-		go e.publishWorker(ctx, batchChan, &publishWG)
+	// Start stall watchdog
+	if e.config.StallTimeout > 0 {
+		dataWG.Add(1)
+		go e.watchStalls(ctx, &dataWG)
+	}
+
+	// Start batch processor and publisher workers, or with a batch size of
+	// 1 let the workers take readings straight from the generator
+	if e.singleFastPath() {
+		for i := 0; i < e.config.MaxWorkers; i++ {
+			publishWG.Add(1)
+			go e.publishReadings(ctx, dataChan, &publishWG)
+		}
+	} else {
+		batchWG.Add(1)
+		go e.processBatches(ctx, dataChan, batchChan, &batchWG)
+
+		for i := 0; i < e.config.MaxWorkers; i++ {
+			publishWG.Add(1)
+			go e.publishWorker(ctx, batchChan, &publishWG)
+		}
 	}
 
 	// Wait for context cancellation
 	<-ctx.Done()
 
+	// Every phase of the shutdown shares one ShutdownTimeout deadline
+	drain := newShutdownTimer(e.config.ShutdownTimeout)
+	defer drain.stop()
+
 	// Wait for data generator to finish first
-	dataWG.Wait()
+	if !drain.wait(&dataWG) {
+		return e.abandonShutdown(runCtx, "data generator")
+	}
 
 	// Then close data channel to signal batch processor to stop
 	close(dataChan)
 
 	// Wait for batch processor to finish
-	batchWG.Wait()
+	if !drain.wait(&batchWG) {
+		return e.abandonShutdown(runCtx, "batch processor")
+	}
 
 	// Close batch channel to signal publisher workers to stop
 	close(batchChan)
 
 	// Wait for publisher workers to finish
-	publishWG.Wait()
+	if !drain.wait(&publishWG) {
+		return e.abandonShutdown(runCtx, "publisher workers")
+	}
 
 	// Close publisher
+	closeErr := e.closePublishers()
+	if err := abortError(runCtx); err != nil {
+		return errors.Join(err, closeErr)
+	}
+	return closeErr
+}
+
+// ErrTooManyPublishErrors is returned, wrapped, by Start, RunFor and
+// Backfill when they stop after MaxConsecutivePublishErrors failures
+var ErrTooManyPublishErrors = errors.New("too many consecutive publish errors")
+
+// startRun derives the context of a run, which a streak of publish errors
+// can cancel, and resets the streak
+func (e *Engine[T]) startRun(ctx context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, abort := context.WithCancelCause(ctx)
+	e.abort = abort
+	e.publishFailures.Store(0)
+	e.startAdaptiveBatch()
+	return ctx, abort
+}
+
+// abortError returns the error that stopped the run early, if any
+func abortError(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrTooManyPublishErrors) || errors.Is(cause, ErrRangeViolation) || errors.Is(cause, ErrStalled) {
+		return cause
+	}
+	return nil
+}
+
+// ErrShutdownTimeout is returned, wrapped, by Start when its workers do not
+// finish within Config.ShutdownTimeout after the context is done
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+// abandonShutdown gives up on a shutdown phase that overran
+// ShutdownTimeout. The stuck workers are left running and the publishers are
+// not closed, since closing them could block on the same stuck call.
+func (e *Engine[T]) abandonShutdown(ctx context.Context, phase string) error {
+	e.logf("Warning: %s did not stop within the shutdown timeout of %v; abandoning it", phase, e.config.ShutdownTimeout)
+	return errors.Join(abortError(ctx), fmt.Errorf("%w: waiting for %s", ErrShutdownTimeout, phase))
+}
+
+// shutdownTimer bounds the waits of a shutdown by a single deadline. Without
+// a timeout it waits indefinitely.
+type shutdownTimer struct {
+	timer   *time.Timer
+	expired <-chan time.Time
+}
+
+func newShutdownTimer(timeout time.Duration) *shutdownTimer {
+	if timeout <= 0 {
+		return &shutdownTimer{}
+	}
+	timer := time.NewTimer(timeout)
+	return &shutdownTimer{timer: timer, expired: timer.C}
+}
+
+// wait waits for wg and reports whether it finished before the deadline
+func (s *shutdownTimer) wait(wg *sync.WaitGroup) bool {
+	if s.expired == nil {
+		wg.Wait()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-s.expired:
+		return false
+	}
+}
+
+func (s *shutdownTimer) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// recordPublishOutcome tracks consecutive publish failures and stops the
+// run once MaxConsecutivePublishErrors is reached
+func (e *Engine[T]) recordPublishOutcome(err error) {
+	limit := e.config.MaxConsecutivePublishErrors
+	if limit <= 0 {
+		return
+	}
+	if err == nil {
+		e.publishFailures.Store(0)
+		return
+	}
+	if e.publishFailures.Add(1) == int64(limit) {
+		e.abort(fmt.Errorf("%w: stopping after %d failures in a row, last: %w", ErrTooManyPublishErrors, limit, err))
+	}
+}
+
+// closePublishers closes the dead-letter publisher, if any, and the publisher
+func (e *Engine[T]) closePublishers() error {
+	if e.deadLetter != nil {
+		if err := e.deadLetter.Close(); err != nil {
+			e.logf("Error closing dead-letter publisher: %v", err)
+		}
+	}
 	if err := e.publisher.Close(); err != nil {
 		return fmt.Errorf("error closing publisher: %w", err)
 	}
-
 	return nil
 }
 
 // generateData continuously generates sensor data
-func (e *Engine[T]) generateData(ctx context.Context, dataChan chan<- SensorData[T], wg *sync.WaitGroup) {
+func (e *Engine[T]) generateData(ctx context.Context, dataChan chan SensorData[T], wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	ticker := time.NewTicker(e.config.ProductionRate)
+	start := time.Now()
+	if len(e.config.RateEnvelope) > 0 {
+		e.rate.Store(int64(e.config.RateEnvelope.RateAt(0)))
+	}
+	ticker := time.NewTicker(e.ProductionRate())
 	defer ticker.Stop()
 
-	counter := 0
+	// The heartbeat timer is reset whenever a reading is emitted, so it only
+	// fires after HeartbeatInterval of silence
+	var heartbeat <-chan time.Time
+	var heartbeatTimer *time.Timer
+	if e.config.HeartbeatInterval > 0 {
+		heartbeatTimer = time.NewTimer(e.config.HeartbeatInterval)
+		defer heartbeatTimer.Stop()
+		heartbeat = heartbeatTimer.C
+	}
+
+	heartbeats := 0
+	outage := newOfflineState(e.config.Offline, e.randFloat64)
+
+	// Outstanding seeder call when SeederTimeout is set
+	var pending chan reading[T]
+
+	// Readings held back for shuffling when ReorderWindow is set. Every
+	// return is a shutdown, which flushes what is left of the window.
+	var window []SensorData[T]
+	defer func() {
+		e.flushWindow(dataChan, window)
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-e.rateChanged:
+			ticker.Reset(e.ProductionRate())
 		case <-ticker.C:
-			input := e.seeder.Generate()
-			timestamp := time.Now()
-			data := e.function.Generate(input, timestamp)
+			if len(e.config.RateEnvelope) > 0 {
+				e.followEnvelope(ticker, time.Since(start))
+			}
 
+			offline, marker := e.checkOffline(outage, time.Since(start), e.clock.Now())
+			if marker != nil {
+				if !e.emit(ctx, dataChan, *marker) && ctx.Err() != nil {
+					return
+				}
+				if heartbeatTimer != nil {
+					heartbeatTimer.Reset(e.config.HeartbeatInterval)
+				}
+			}
+			if offline {
+				continue
+			}
+
+			r, ok := e.generateReading(ctx, &pending)
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+
+			sensorData, keep := e.newReading(r)
+			if !keep {
+				e.stats.generated.Add(1)
+				continue
+			}
+			sensorData.ID = e.idGenerator(e.takeID())
+
+			if e.config.ReorderWindow > 1 {
+				// Readings are counted as their window is emitted
+				if !e.emitReordered(ctx, dataChan, &window, sensorData) {
+					return
+				}
+			} else {
+				if !e.emit(ctx, dataChan, sensorData) {
+					if ctx.Err() != nil {
+						return
+					}
+					continue
+				}
+				e.recordEmitted(sensorData)
+			}
+			if heartbeatTimer != nil {
+				heartbeatTimer.Reset(e.config.HeartbeatInterval)
+			}
+		case <-heartbeat:
+			if outage != nil && outage.offline {
+				// An offline sensor sends nothing, heartbeats included
+				heartbeatTimer.Reset(e.config.HeartbeatInterval)
+				continue
+			}
 			sensorData := SensorData[T]{
-				ID:        fmt.Sprintf("sensor-%d", counter),
-				Timestamp: timestamp,
-				Data:      data,
-				Quality:   determineQuality(),
+				ID:        fmt.Sprintf("heartbeat-%d", heartbeats),
+				Timestamp: e.clock.Now(),
+				Quality:   QualityHeartbeat,
 			}
 
-			select {
-			case dataChan <- sensorData:
-				counter++
-			case <-ctx.Done():
+			if e.emit(ctx, dataChan, sensorData) {
+				heartbeats++
+				e.stats.heartbeats.Add(1)
+			} else if ctx.Err() != nil {
 				return
 			}
+			heartbeatTimer.Reset(e.config.HeartbeatInterval)
+		}
+	}
+}
+
+// slowCycleWarnThreshold is the number of consecutive slow generation cycles
+// after which a warning is logged
+const slowCycleWarnThreshold = 10
+
+// reading is the output of one seeder and function call
+type reading[T any] struct {
+	input     float64
+	data      T
+	timestamp time.Time
+	elapsed   time.Duration
+}
+
+// generateReading calls the seeder and sensor function once, bounded by
+// SeederTimeout when set. Cycles that take longer than the production
+// interval are counted in Stats.SlowCycles. It reports false when the call
+// timed out or ctx was cancelled; a timed out call is left in *pending for a
+// later tick to collect.
+func (e *Engine[T]) generateReading(ctx context.Context, pending *chan reading[T]) (reading[T], bool) {
+	var r reading[T]
+	if e.config.SeederTimeout <= 0 {
+		r = e.callSeeder()
+	} else {
+		if *pending == nil {
+			*pending = make(chan reading[T], 1)
+			go func(result chan<- reading[T]) {
+				result <- e.callSeeder()
+			}(*pending)
+		}
+
+		timer := time.NewTimer(e.config.SeederTimeout)
+		defer timer.Stop()
+
+		select {
+		case r = <-*pending:
+			*pending = nil
+		case <-timer.C:
+			e.recordSlowCycle()
+			return r, false
+		case <-ctx.Done():
+			return r, false
+		}
+	}
+
+	if r.elapsed > e.ProductionRate() {
+		e.recordSlowCycle()
+	} else {
+		e.slowStreak = 0
+	}
+	return r, true
+}
+
+// callSeeder runs the seeder and sensor function and times them
+func (e *Engine[T]) callSeeder() reading[T] {
+	start := time.Now()
+	input := e.seeder.Generate()
+	timestamp := e.clock.Now()
+	data := e.withDrift(e.withNoise(e.function.Generate(input, timestamp)), timestamp)
+	return reading[T]{input: input, data: data, timestamp: timestamp, elapsed: time.Since(start)}
+}
+
+// newReading turns a seeder and function result into a reading with a
+// quality and applies the range guard and change trigger. It reports false
+// when the reading must not be published; the caller assigns the ID of kept
+// readings.
+func (e *Engine[T]) newReading(r reading[T]) (SensorData[T], bool) {
+	reading := e.toSensorData(r)
+	keep := e.guardRange(&reading) && e.emitOnChange(reading)
+	e.scoreQuality(&reading)
+	return reading, keep
+}
+
+// toSensorData wraps a seeder and function result in a reading with a
+// quality, without an ID
+func (e *Engine[T]) toSensorData(r reading[T]) SensorData[T] {
+	reading := SensorData[T]{
+		Timestamp: r.timestamp,
+		Data:      r.data,
+		Quality:   e.determineQuality(),
+	}
+	if e.config.IncludeInput {
+		input := r.input
+		reading.Input = &input
+	}
+	return reading
+}
+
+// Generate produces a single reading on demand, for request/response use
+// such as HTTP handlers and tests: the seeder and sensor function are
+// called, noise is added and a quality is drawn, and the reading gets the
+// next ID in the same sequence Start uses. It bypasses batching and
+// publishing entirely, along with QualityFilter, EmitOnChangeOf,
+// enrichment, redaction and SeederTimeout.
+// A RangeGuard flags out-of-range readings CORRUPT whatever its action,
+// since there is no run to drop them from or stop.
+func (e *Engine[T]) Generate() SensorData[T] {
+	e.warmUp()
+
+	reading := e.toSensorData(e.callSeeder())
+	reading.ID = e.idGenerator(e.takeID())
+	if e.outOfRange(reading) {
+		reading.Quality = QualityCorrupt
+	}
+	e.scoreQuality(&reading)
+	e.stats.generated.Add(1)
+	e.stats.recordQuality(reading.Quality)
+	return reading
+}
+
+// GenerateEnvelope produces a single reading like Generate and wraps it in
+// an envelope with the next sequence number. It requires Config.Envelope.
+func (e *Engine[T]) GenerateEnvelope() (Envelope[T], error) {
+	if e.config.Envelope == nil {
+		return Envelope[T]{}, errors.New("GenerateEnvelope requires Config.Envelope")
+	}
+	return e.wrap([]SensorData[T]{e.Generate()})[0], nil
+}
+
+// takeID hands out the next reading sequence number. Every path producing
+// readings takes its number here, so IDs stay unique while Generate is
+// called during a run and across restarts of the same engine. It panics
+// rather than wrap around to numbers already used.
+func (e *Engine[T]) takeID() uint64 {
+	seq := e.nextID.Add(1) - 1
+	if seq == math.MaxUint64 {
+		panic("engine: reading sequence numbers exhausted")
+	}
+	return seq
+}
+
+// recordSlowCycle counts a slow generation cycle and warns once per streak of
+// consecutive slow cycles, since the ticker silently drops the missed ticks
+func (e *Engine[T]) recordSlowCycle() {
+	e.stats.slowCycles.Add(1)
+	e.slowStreak++
+	if e.slowStreak == slowCycleWarnThreshold {
+		e.logf("Warning: %d consecutive generation cycles exceeded the production interval of %v; the effective rate is below the configured rate",
+			e.slowStreak, e.ProductionRate())
+	}
+}
+
+// emitReordered adds a reading to the reorder window and, once the window is
+// full, shuffles it and queues every reading in it. It reports false when ctx
+// is cancelled, leaving the readings not yet queued in the window.
+func (e *Engine[T]) emitReordered(ctx context.Context, dataChan chan SensorData[T], window *[]SensorData[T], data SensorData[T]) bool {
+	*window = append(*window, data)
+	if len(*window) < e.config.ReorderWindow {
+		return true
+	}
+
+	e.shuffleWindow(*window)
+	for i, d := range *window {
+		if e.emit(ctx, dataChan, d) {
+			e.recordEmitted(d)
+		} else if ctx.Err() != nil {
+			*window = append((*window)[:0], (*window)[i:]...)
+			return false
+		}
+	}
+	*window = (*window)[:0]
+	return true
+}
+
+// flushWindow queues the readings of a partial reorder window on shutdown,
+// shuffled like a full one. The batch processor is stopping by then, so
+// readings are only queued while the channel has room; the rest are
+// discarded and not counted as generated.
+func (e *Engine[T]) flushWindow(dataChan chan SensorData[T], window []SensorData[T]) {
+	e.shuffleWindow(window)
+	for _, d := range window {
+		select {
+		case dataChan <- d:
+			e.buffered.Add(1)
+			e.recordEmitted(d)
+		default:
+			return
+		}
+	}
+}
+
+// recordEmitted counts a reading queued for batching as generated
+func (e *Engine[T]) recordEmitted(data SensorData[T]) {
+	e.stats.generated.Add(1)
+	e.stats.recordQuality(data.Quality)
+}
+
+// shuffleWindow swaps each reading with a random later reading in the window
+// with probability ReorderProbability, so no reading moves further than the
+// window size
+func (e *Engine[T]) shuffleWindow(window []SensorData[T]) {
+	for i := range window {
+		if e.randFloat64() < e.config.ReorderProbability {
+			j := i + e.randIntN(len(window)-i)
+			window[i], window[j] = window[j], window[i]
+		}
+	}
+}
+
+// emit queues a reading for batching and reports whether it was queued. A
+// reading is not queued when it is dropped by MaxBufferedReadings or when ctx
+// is cancelled.
+func (e *Engine[T]) emit(ctx context.Context, dataChan chan SensorData[T], data SensorData[T]) bool {
+	if e.config.MaxBufferedReadings > 0 && !e.makeRoom(dataChan) {
+		e.stats.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case dataChan <- data:
+		e.buffered.Add(1)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// makeRoom evicts the oldest buffered readings until the total number of
+// in-flight readings is below MaxBufferedReadings. Queued batches are evicted
+// before queued readings, since they hold the oldest data. It reports false
+// when nothing could be evicted because every buffered reading is held by the
+// batch currently being assembled.
+func (e *Engine[T]) makeRoom(dataChan chan SensorData[T]) bool {
+	limit := int64(e.config.MaxBufferedReadings)
+	for e.buffered.Load() >= limit {
+		var evicted int
+		select {
+		case batch := <-*e.batchChan.Load():
+			evicted = len(batch)
+		default:
+			select {
+			case <-dataChan:
+				evicted = 1
+			default:
+				return false
+			}
+		}
+		e.buffered.Add(-int64(evicted))
+		e.stats.dropped.Add(uint64(evicted))
+	}
+	return true
+}
+
+// validateConfig checks the options that cannot be validated when the
+// engine is created
+func (e *Engine[T]) validateConfig() error {
+	if e.config.ProductionRate <= 0 && len(e.config.RateEnvelope) == 0 {
+		return fmt.Errorf("production rate must be positive, got %v", e.config.ProductionRate)
+	}
+	if e.config.BatchSize < 1 {
+		return fmt.Errorf("batch size must be at least 1, got %d", e.config.BatchSize)
+	}
+	if e.config.MaxWorkers < 1 {
+		return fmt.Errorf("max workers must be at least 1, got %d", e.config.MaxWorkers)
+	}
+	if e.config.BatchTimeout < 0 {
+		return fmt.Errorf("batch timeout must not be negative, got %v", e.config.BatchTimeout)
+	}
+	if e.config.QualityProfile != nil {
+		if err := e.config.QualityProfile.Validate(); err != nil {
+			return fmt.Errorf("invalid quality profile: %w", err)
+		}
+	}
+	if err := e.config.RateEnvelope.Validate(); err != nil {
+		return fmt.Errorf("invalid rate envelope: %w", err)
+	}
+	if e.config.Offline != nil {
+		if err := e.config.Offline.Validate(); err != nil {
+			return fmt.Errorf("invalid offline schedule: %w", err)
+		}
+	}
+	if e.config.MinBatchSize > 0 && e.config.MaxBatchWait < e.config.BatchTimeout {
+		return fmt.Errorf("max batch wait %v is shorter than the batch timeout %v", e.config.MaxBatchWait, e.config.BatchTimeout)
+	}
+	if e.config.MaxConsecutivePublishErrors < 0 {
+		return fmt.Errorf("max consecutive publish errors must not be negative, got %d", e.config.MaxConsecutivePublishErrors)
+	}
+	if e.config.WarmupGenerations < 0 {
+		return fmt.Errorf("warmup generations must not be negative, got %d", e.config.WarmupGenerations)
+	}
+	if e.config.MaxDuration < 0 {
+		return fmt.Errorf("max duration must not be negative, got %v", e.config.MaxDuration)
+	}
+	if e.config.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout must not be negative, got %v", e.config.ShutdownTimeout)
+	}
+	if e.config.StallTimeout < 0 {
+		return fmt.Errorf("stall timeout must not be negative, got %v", e.config.StallTimeout)
+	}
+	if e.config.AdaptiveBatch != nil {
+		if err := e.config.AdaptiveBatch.validate(); err != nil {
+			return err
 		}
 	}
+	if e.config.BatchQueueSize < 0 {
+		return fmt.Errorf("batch queue size must not be negative, got %d", e.config.BatchQueueSize)
+	}
+	if _, err := e.envelopePublisher(); err != nil {
+		return err
+	}
+	if e.config.Envelope != nil {
+		if err := validateUnits[T](e.config.Envelope.Units); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmUp discards the first WarmupGenerations seeder values, once per engine
+func (e *Engine[T]) warmUp() {
+	e.warmup.Do(func() {
+		for range e.config.WarmupGenerations {
+			e.seeder.Generate()
+		}
+	})
 }
 
-// processBatches collects data into batches and sends them to batch channel
+// defaultBatchQueueSize is the batch queue capacity used when
+// Config.BatchQueueSize is 0
+const defaultBatchQueueSize = 10
+
+// batchQueueSize returns the capacity of the queue between the batch
+// processor and the publisher workers
+func (e *Engine[T]) batchQueueSize() int {
+	if e.config.BatchQueueSize > 0 {
+		return e.config.BatchQueueSize
+	}
+	return defaultBatchQueueSize
+}
+
+// processBatches collects data into batches and sends them to batch channel.
+// Ownership of a batch passes to the receiver when it is sent: the slice is
+// never read or written here afterwards and the next batch always starts in a
+// fresh allocation, so publishers may retain, modify or append to the batches
+// they receive.
 func (e *Engine[T]) processBatches(ctx context.Context, dataChan <-chan SensorData[T], batchChan chan<- []SensorData[T], wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	batch := make([]SensorData[T], 0, e.config.BatchSize)
-	batchTicker := time.NewTicker(e.config.BatchTimeout)
-	defer batchTicker.Stop()
+
+	// The timer is reset after every flush so BatchTimeout measures the time
+	// since the last batch was sent rather than a fixed cadence. Without a
+	// BatchTimeout there is no timer and timeout never fires, even if one is
+	// set with SetBatchTimeout later.
+	var batchTimer *time.Timer
+	var timeout <-chan time.Time
+	if e.config.BatchTimeout > 0 {
+		batchTimer = time.NewTimer(e.BatchTimeout())
+		defer batchTimer.Stop()
+		timeout = batchTimer.C
+	}
+	resetTimer := func(d time.Duration) {
+		if batchTimer != nil {
+			batchTimer.Reset(d)
+		}
+	}
+	lastFlush := time.Now()
+
+	// overdue is set when BatchTimeout has fired on a batch smaller than
+	// MinBatchSize, which is then sent as soon as it is big enough
+	overdue := false
+
+	send := func() bool {
+		select {
+		case batchChan <- batch:
+			batch = make([]SensorData[T], 0, e.config.BatchSize)
+			resetTimer(e.BatchTimeout())
+			lastFlush = time.Now()
+			overdue = false
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Send remaining batch before exiting
+			// Send remaining batch before exiting; it is not touched again
 			if len(batch) > 0 {
 				select {
 				case batchChan <- batch:
@@ -114,7 +713,8 @@ func (e *Engine[T]) processBatches(ctx context.Context, dataChan <-chan SensorDa
 
 		case data, ok := <-dataChan:
 			if !ok {
-				// Data channel closed, send remaining batch and exit
+				// Data channel closed, send remaining batch and exit; it is
+				// not touched again
 				if len(batch) > 0 {
 					select {
 					case batchChan <- batch:
@@ -126,26 +726,32 @@ func (e *Engine[T]) processBatches(ctx context.Context, dataChan <-chan SensorDa
 
 			batch = append(batch, data)
 
-			// Send batch if it reaches the size limit
-			if len(batch) >= e.config.BatchSize {
-				select {
-				case batchChan <- batch:
-					batch = make([]SensorData[T], 0, e.config.BatchSize)
-				case <-ctx.Done():
+			// Send batch if it reaches the size limit, or the minimum size
+			// after the timeout
+			if len(batch) >= e.BatchSize() || (overdue && len(batch) >= e.config.MinBatchSize) {
+				if !send() {
 					return
 				}
 			}
 
-		case <-batchTicker.C:
-			// Send batch if it has data and timeout is reached
-			if len(batch) > 0 {
-				select {
-				case batchChan <- batch:
-					batch = make([]SensorData[T], 0, e.config.BatchSize)
-				case <-ctx.Done():
-					return
+		case <-timeout:
+			if len(batch) == 0 {
+				resetTimer(e.BatchTimeout())
+				lastFlush = time.Now()
+				overdue = false
+				continue
+			}
+			// Wait up to MaxBatchWait for a batch below the minimum size
+			if len(batch) < e.config.MinBatchSize {
+				if wait := e.config.MaxBatchWait - time.Since(lastFlush); wait > 0 {
+					overdue = true
+					resetTimer(wait)
+					continue
 				}
 			}
+			if !send() {
+				return
+			}
 		}
 	}
 }
@@ -162,28 +768,133 @@ func (e *Engine[T]) publishWorker(ctx context.Context, batchChan <-chan []Sensor
 			if !ok {
 				return
 			}
+			e.buffered.Add(-int64(len(batch)))
+			e.publishAndReport(ctx, batch, e.config.PublishMode == PublishModeSingle)
+		}
+	}
+}
 
-			if err := e.publisher.PublishBatch(ctx, batch); err != nil {
-				// Log error but continue processing
-				fmt.Printf("Error publishing batch: %v\n", err)
+// singleFastPath reports whether Start bypasses batching: with a fixed
+// BatchSize of 1 there is no batch processor, and the publish workers take
+// readings straight from the generator and hand each to Publish rather than
+// to PublishBatch as a one-reading batch
+func (e *Engine[T]) singleFastPath() bool {
+	return e.config.BatchSize == 1 && e.config.AdaptiveBatch == nil
+}
+
+// publishReadings publishes readings one at a time as the generator emits
+// them, for the BatchSize 1 fast path
+func (e *Engine[T]) publishReadings(ctx context.Context, dataChan <-chan SensorData[T], wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-dataChan:
+			if !ok {
+				return
 			}
+			e.buffered.Add(-1)
+			e.publishAndReport(ctx, []SensorData[T]{data}, true)
 		}
 	}
 }
 
-// determineQuality randomly determines the quality of sensor data
-func determineQuality() Quality {
-	r := rand.Float64()
+// publishAndReport publishes batch, one reading per call if single is set,
+// and records and reports the outcome. Errors are only reported; the worker
+// carries on.
+func (e *Engine[T]) publishAndReport(ctx context.Context, batch []SensorData[T], single bool) {
+	batch, batchID, err := e.publishBatch(ctx, batch, single)
+	e.recordPublishOutcome(err)
 	switch {
-	case r < 0.01:
-		return QualityCorrupt
-	case r < 0.03:
-		return QualityPartial
-	case r < 0.08:
-		return QualityNoisy
-	default:
-		return QualityOK
+	case err != nil:
+		e.reportPublishError(batchID, batch, err)
+	case batchID != "":
+		e.logf("Published batch %s with %d items", batchID, len(batch))
+	}
+}
+
+// publishBatch enriches, redacts and filters a batch, hands it to the
+// publisher in one call or, if single is set, one call per reading, and
+// records the outcome in the engine stats. The publish context carries the
+// window of the readings left after filtering, which are the ones it
+// returns and a failure concerns, along with the batch ID if any.
+func (e *Engine[T]) publishBatch(ctx context.Context, batch []SensorData[T], single bool) ([]SensorData[T], string, error) {
+	batch = e.prepareBatch(ctx, batch)
+	if len(batch) == 0 {
+		return batch, "", nil
+	}
+	ctx, batchID := e.batchContext(ctx, batch)
+	return batch, batchID, e.sendBatch(ctx, batch, single)
+}
+
+// prepareBatch enriches, redacts and filters a batch in place and returns
+// the readings to publish
+func (e *Engine[T]) prepareBatch(ctx context.Context, batch []SensorData[T]) []SensorData[T] {
+	if e.enrich != nil {
+		// The batch is owned by this worker, so it is enriched in place
+		for i := range batch {
+			if !isStatusMarker(batch[i].Quality) {
+				batch[i] = e.enrich(batch[i])
+			}
+		}
+	}
+	if e.redact != nil {
+		// The batch is owned by this worker, so it is redacted in place
+		for i := range batch {
+			batch[i] = e.redact(batch[i])
+		}
 	}
+	if e.config.QualityFilter != nil {
+		batch = e.filterQuality(ctx, batch)
+	}
+	return batch
+}
+
+// sendBatch hands a prepared batch to the publisher in one call or, if
+// single is set, one call per reading
+func (e *Engine[T]) sendBatch(ctx context.Context, batch []SensorData[T], single bool) error {
+	if e.config.DebugTee {
+		e.teeBatch(batch)
+	}
+	if e.config.Envelope != nil {
+		publisher, err := e.envelopePublisher()
+		if err != nil {
+			return err
+		}
+		return e.publishEnvelopes(ctx, publisher, batch, single)
+	}
+
+	if !single {
+		return e.retryPublish(ctx, func() error {
+			start := time.Now()
+			err := e.publisher.PublishBatch(ctx, batch)
+			e.recordBatchPublish(len(batch), time.Since(start), err)
+			return err
+		})
+	}
+
+	failed := 0
+	var firstErr error
+	for _, data := range batch {
+		err := e.retryPublish(ctx, func() error {
+			start := time.Now()
+			err := e.publisher.Publish(ctx, data)
+			e.stats.recordSingle(time.Since(start), err)
+			return err
+		})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d single publishes failed: %w", failed, len(batch), firstErr)
+	}
+	return nil
 }
 
 // DefaultConfig returns a default engine configuration
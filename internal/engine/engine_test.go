@@ -2,12 +2,23 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
-// MockPublisher for testing
+// MockPublisher for testing, safe for concurrent use
 type MockPublisher[T any] struct {
+	mutex         sync.Mutex
 	published     []SensorData[T]
 	batches       [][]SensorData[T]
 	publishCalled int
@@ -23,31 +34,43 @@ func NewMockPublisher[T any]() *MockPublisher[T] {
 }
 
 func (m *MockPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.published = append(m.published, data)
 	m.publishCalled++
 	return nil
 }
 
 func (m *MockPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.batches = append(m.batches, data)
 	m.batchCalled++
 	return nil
 }
 
 func (m *MockPublisher[T]) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.closed = true
 	return nil
 }
 
 func (m *MockPublisher[T]) GetPublishedCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	return m.publishCalled
 }
 
 func (m *MockPublisher[T]) GetBatchCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	return m.batchCalled
 }
 
 func (m *MockPublisher[T]) GetTotalDataPoints() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	total := len(m.published)
 	for _, batch := range m.batches {
 		total += len(batch)
@@ -56,6 +79,8 @@ func (m *MockPublisher[T]) GetTotalDataPoints() int {
 }
 
 func (m *MockPublisher[T]) IsClosed() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	return m.closed
 }
 
@@ -171,6 +196,133 @@ func TestEngine_BatchProcessing(t *testing.T) {
 		publisher.GetBatchCount(), publisher.GetTotalDataPoints())
 }
 
+func TestEngine_BatchProcessingVirtual(t *testing.T) {
+	// Batches fill up in 15ms, before the 20ms timeout
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      3,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+	h := newHarness[float64](t, config)
+	publisher := h.run(NewTestSeeder([]float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0}), NewTestSensorFunction(1.5), 10)
+
+	if want := []int{3, 3, 3, 1}; !slices.Equal(publisher.batchSizes, want) {
+		t.Errorf("Expected batch sizes %v, got %v", want, publisher.batchSizes)
+	}
+	for i, data := range publisher.data {
+		if want := float64(i%8+1) * 1.5; data.Data != want {
+			t.Errorf("Reading %d: expected %v, got %v", i, want, data.Data)
+		}
+		if want := harnessEpoch.Add(time.Duration(i+1) * config.ProductionRate); !data.Timestamp.Equal(want) {
+			t.Errorf("Reading %d: expected timestamp %v, got %v", i, want, data.Timestamp)
+		}
+	}
+}
+
+func TestEngine_IncludeInput(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      4,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxWorkers:     1,
+		IncludeInput:   true,
+	}
+	var inputs []float64
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+		inputs = append(inputs, input)
+		return input * input
+	})
+
+	h := newHarness[float64](t, config)
+	publisher := h.run(NewRandomSeeder(-5, 5), function, 8)
+
+	if len(publisher.data) != len(inputs) {
+		t.Fatalf("Expected one reading per function call, got %d readings for %d calls", len(publisher.data), len(inputs))
+	}
+	for i, data := range publisher.data {
+		if data.Input == nil || *data.Input != inputs[i] {
+			t.Errorf("Reading %d: expected input %v, got %v", i, inputs[i], data.Input)
+		}
+	}
+
+	config.IncludeInput = false
+	h = newHarness[float64](t, config)
+	for _, data := range h.run(NewTestSeeder([]float64{1}), function, 2).data {
+		if data.Input != nil {
+			t.Errorf("Expected no input without IncludeInput, got %v", *data.Input)
+		}
+	}
+}
+
+// TestEngine_ProcessBatches drives Start's batch processor through its
+// channels: batches are cut at BatchSize, a batch below MinBatchSize waits
+// for MaxBatchWait or for the minimum, and closing the data channel flushes
+// the rest
+func TestEngine_ProcessBatches(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      3,
+		BatchTimeout:   20 * time.Millisecond,
+		MinBatchSize:   2,
+		MaxBatchWait:   200 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	dataChan := make(chan SensorData[float64], 10)
+	batchChan := make(chan []SensorData[float64], 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go engine.processBatches(context.Background(), dataChan, batchChan, &wg)
+
+	send := func(n int) {
+		for range n {
+			dataChan <- SensorData[float64]{Quality: QualityOK}
+		}
+	}
+	receive := func() []SensorData[float64] {
+		t.Helper()
+		select {
+		case batch := <-batchChan:
+			return batch
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a batch")
+			return nil
+		}
+	}
+
+	send(3)
+	if batch := receive(); len(batch) != 3 {
+		t.Fatalf("Expected a full batch of 3, got %d", len(batch))
+	}
+
+	// One reading is held past BatchTimeout until MaxBatchWait
+	start := time.Now()
+	send(1)
+	if batch := receive(); len(batch) != 1 {
+		t.Fatalf("Expected the held reading alone, got %d", len(batch))
+	}
+	if elapsed := time.Since(start); elapsed < 2*config.BatchTimeout {
+		t.Errorf("Expected a batch below MinBatchSize to wait for MaxBatchWait, sent after %v", elapsed)
+	}
+
+	// An overdue batch goes out once it reaches MinBatchSize
+	send(1)
+	time.Sleep(2 * config.BatchTimeout)
+	send(1)
+	if batch := receive(); len(batch) != 2 {
+		t.Fatalf("Expected the overdue batch at MinBatchSize, got %d", len(batch))
+	}
+
+	send(1)
+	close(dataChan)
+	wg.Wait()
+	if batch := receive(); len(batch) != 1 {
+		t.Errorf("Expected the rest to be flushed when the data channel closes, got %d", len(batch))
+	}
+}
+
 func TestEngine_QualityGeneration(t *testing.T) {
 	config := DefaultConfig()
 	config.ProductionRate = 5 * time.Millisecond
@@ -292,33 +444,1506 @@ func TestLowLatencyConfig(t *testing.T) {
 	}
 }
 
-// Benchmark tests
-func BenchmarkEngine_DataGeneration(b *testing.B) {
+func TestEngine_BatchTimeoutMeasuresTimeSinceLastFlush(t *testing.T) {
 	config := Config{
-		ProductionRate: 1 * time.Millisecond,
+		ProductionRate: time.Millisecond,
+		BatchSize:      2,
+		BatchTimeout:   100 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dataChan := make(chan SensorData[float64])
+	batchChan := make(chan []SensorData[float64], 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go engine.processBatches(ctx, dataChan, batchChan, &wg)
+
+	// Fill a batch shortly before a fixed-cadence tick would have fired
+	time.Sleep(80 * time.Millisecond)
+	dataChan <- SensorData[float64]{ID: "a"}
+	dataChan <- SensorData[float64]{ID: "b"}
+	<-batchChan
+	flushed := time.Now()
+
+	// A lone reading should wait a full BatchTimeout after the size flush
+	dataChan <- SensorData[float64]{ID: "c"}
+	batch := <-batchChan
+	elapsed := time.Since(flushed)
+
+	if len(batch) != 1 || batch[0].ID != "c" {
+		t.Fatalf("Expected timeout flush of reading c, got %+v", batch)
+	}
+	if elapsed < 90*time.Millisecond {
+		t.Errorf("Timeout flush came %v after the previous flush, expected ~%v", elapsed, config.BatchTimeout)
+	}
+
+	close(dataChan)
+	wg.Wait()
+}
+
+func TestEngine_MinBatchSizeExtendsTimeout(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
 		BatchSize:      100,
-		BatchTimeout:   10 * time.Millisecond,
-		MaxWorkers:     4,
+		BatchTimeout:   30 * time.Millisecond,
+		MinBatchSize:   3,
+		MaxBatchWait:   150 * time.Millisecond,
+		MaxWorkers:     1,
 	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
 
-	seeder := NewTestSeeder([]float64{1.0})
-	function := NewTestSensorFunction(1.0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dataChan := make(chan SensorData[float64])
+	batchChan := make(chan []SensorData[float64], 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go engine.processBatches(ctx, dataChan, batchChan, &wg)
+	start := time.Now()
+
+	// Two readings are below the minimum, so the timeout must not flush them
+	dataChan <- SensorData[float64]{ID: "a"}
+	dataChan <- SensorData[float64]{ID: "b"}
+	select {
+	case batch := <-batchChan:
+		t.Fatalf("Expected the timeout to wait for MinBatchSize, got a batch of %d", len(batch))
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	// The third reading completes the batch, which is sent straight away
+	dataChan <- SensorData[float64]{ID: "c"}
+	batch := <-batchChan
+	if len(batch) != 3 {
+		t.Fatalf("Expected a batch of 3 once MinBatchSize was reached, got %d", len(batch))
+	}
+	if elapsed := time.Since(start); elapsed >= config.MaxBatchWait {
+		t.Errorf("Batch was sent after %v, expected it before MaxBatchWait", elapsed)
+	}
+
+	// A lone reading is sent once MaxBatchWait has passed
+	flushed := time.Now()
+	dataChan <- SensorData[float64]{ID: "d"}
+	batch = <-batchChan
+	elapsed := time.Since(flushed)
+	if len(batch) != 1 {
+		t.Fatalf("Expected a batch of 1 after MaxBatchWait, got %d", len(batch))
+	}
+	if elapsed < 140*time.Millisecond {
+		t.Errorf("Undersized batch was sent after %v, expected ~%v", elapsed, config.MaxBatchWait)
+	}
+
+	close(dataChan)
+	wg.Wait()
+}
+
+// stuckPublisher blocks every publish, ignoring its context, until release
+// is closed
+type stuckPublisher[T any] struct {
+	MockPublisher[T]
+	release chan struct{}
+}
+
+func (s *stuckPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	<-s.release
+	return nil
+}
+
+func TestEngine_ShutdownTimeout(t *testing.T) {
+	config := Config{
+		ProductionRate:  time.Millisecond,
+		BatchSize:       5,
+		BatchTimeout:    50 * time.Millisecond,
+		MaxWorkers:      1,
+		MaxDuration:     50 * time.Millisecond,
+		ShutdownTimeout: 100 * time.Millisecond,
+	}
+	publisher := &stuckPublisher[float64]{release: make(chan struct{})}
+	defer close(publisher.release)
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- engine.Start(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrShutdownTimeout) {
+			t.Errorf("Expected ErrShutdownTimeout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start hung on a stuck publisher despite ShutdownTimeout")
+	}
+	if elapsed := time.Since(start); elapsed < config.MaxDuration+config.ShutdownTimeout {
+		t.Errorf("Start returned after %v, before the shutdown timeout", elapsed)
+	}
+	if publisher.closed {
+		t.Error("Expected the publisher in use by a stuck worker to be left open")
+	}
+}
+
+func TestEngine_MaxDurationStopsStart(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     1,
+		MaxDuration:    100 * time.Millisecond,
+	}
 	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
 
-	engine := NewEngine(config, seeder, function, publisher)
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- engine.Start(context.Background()) }()
 
-	b.ResetTimer()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Engine start failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after MaxDuration")
+	}
+	if elapsed := time.Since(start); elapsed < config.MaxDuration {
+		t.Errorf("Start returned after %v, before MaxDuration", elapsed)
+	}
+	if !publisher.closed {
+		t.Error("Expected the publisher to be closed")
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func TestEngine_MaxDurationDefersToEarlierContextDeadline(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     1,
+		MaxDuration:    time.Minute,
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
 
-	err := engine.Start(ctx)
-	if err != nil {
-		b.Fatalf("Engine start failed: %v", err)
+	start := time.Now()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
 	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Start to stop at the context deadline, took %v", elapsed)
+	}
+}
 
-	b.StopTimer()
-	dataPoints := publisher.GetTotalDataPoints()
-	b.Logf("Generated %d data points in 1 second", dataPoints)
-	b.ReportMetric(float64(dataPoints), "data_points/sec")
+func TestEngine_MaxConsecutivePublishErrorsStopsStart(t *testing.T) {
+	config := Config{
+		ProductionRate:              time.Millisecond,
+		BatchSize:                   2,
+		BatchTimeout:                time.Second,
+		MaxWorkers:                  2,
+		MaxConsecutivePublishErrors: 3,
+	}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), &failingMockPublisher[float64]{},
+		WithLogger[float64](log.New(io.Discard, "", 0)))
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Start(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTooManyPublishErrors) {
+			t.Fatalf("Expected ErrTooManyPublishErrors, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "batch publish failed") {
+			t.Errorf("Expected the last publish error in %q", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not stop after repeated publish errors")
+	}
+	if stats := engine.Stats(); stats.PublishErrors < 3 {
+		t.Errorf("Expected at least 3 publish errors, got %d", stats.PublishErrors)
+	}
+}
+
+// flakyPublisher fails every batch except every third one
+type flakyPublisher[T any] struct {
+	MockPublisher[T]
+	calls int
+}
+
+func (f *flakyPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	f.calls++
+	if f.calls%3 != 0 {
+		return errors.New("transient failure")
+	}
+	return nil
+}
+
+func TestEngine_MaxConsecutivePublishErrorsResetsOnSuccess(t *testing.T) {
+	config := Config{
+		ProductionRate:              10 * time.Millisecond,
+		BatchSize:                   1,
+		MaxWorkers:                  1,
+		Clock:                       NewManualClock(time.Unix(0, 0)),
+		MaxConsecutivePublishErrors: 3,
+	}
+	publisher := &flakyPublisher[float64]{}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher,
+		WithLogger[float64](log.New(io.Discard, "", 0)))
+
+	if err := engine.RunFor(context.Background(), 200*time.Millisecond); err != nil {
+		t.Fatalf("Expected two failures in a row to be tolerated, got %v", err)
+	}
+	if publisher.calls != 20 {
+		t.Errorf("Expected all 20 readings to be attempted, got %d calls", publisher.calls)
+	}
+}
+
+func TestEngine_MaxConsecutivePublishErrorsStopsRunFor(t *testing.T) {
+	config := Config{
+		ProductionRate:              10 * time.Millisecond,
+		BatchSize:                   1,
+		MaxWorkers:                  1,
+		Clock:                       NewManualClock(time.Unix(0, 0)),
+		MaxConsecutivePublishErrors: 2,
+	}
+	publisher := &flakyPublisher[float64]{calls: 3}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher,
+		WithLogger[float64](log.New(io.Discard, "", 0)))
+
+	err := engine.RunFor(context.Background(), time.Second)
+	if !errors.Is(err, ErrTooManyPublishErrors) {
+		t.Fatalf("Expected ErrTooManyPublishErrors, got %v", err)
+	}
+	if publisher.calls != 5 {
+		t.Errorf("Expected RunFor to stop after the second failure, got %d calls", publisher.calls-3)
+	}
+}
+
+func TestEngine_MinBatchSizeRequiresMaxBatchWait(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      10,
+		BatchTimeout:   100 * time.Millisecond,
+		MinBatchSize:   5,
+		MaxWorkers:     1,
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Expected an error for MaxBatchWait shorter than BatchTimeout")
+	}
+}
+
+func TestEngine_PublishModeSingle(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      3,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxWorkers:     1,
+		PublishMode:    PublishModeSingle,
+	}
+
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0, 2.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if publisher.GetBatchCount() != 0 {
+		t.Errorf("Expected no PublishBatch calls in single mode, got %d", publisher.GetBatchCount())
+	}
+	if publisher.GetPublishedCount() == 0 {
+		t.Fatal("Expected Publish to be called for each reading")
+	}
+
+	stats := engine.Stats()
+	if stats.Singles != uint64(publisher.GetPublishedCount()) {
+		t.Errorf("Expected %d singles in stats, got %d", publisher.GetPublishedCount(), stats.Singles)
+	}
+	if stats.Batches != 0 {
+		t.Errorf("Expected 0 batches in stats, got %d", stats.Batches)
+	}
+	if stats.Published != stats.Singles {
+		t.Errorf("Expected published (%d) to equal singles (%d)", stats.Published, stats.Singles)
+	}
+}
+
+func TestEngine_MaxBufferedReadings(t *testing.T) {
+	config := Config{
+		ProductionRate:      time.Millisecond,
+		BatchSize:           2,
+		BatchTimeout:        10 * time.Millisecond,
+		MaxWorkers:          1,
+		MaxBufferedReadings: 10,
+	}
+
+	publisher := &slowMockPublisher[float64]{delay: 30 * time.Millisecond}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// Sample the in-flight count while the engine runs
+	var peak int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			peak = max(peak, engine.buffered.Load())
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+	<-done
+
+	stats := engine.Stats()
+	if stats.Dropped == 0 {
+		t.Error("Expected readings to be dropped under a slow publisher")
+	}
+	if peak > int64(config.MaxBufferedReadings) {
+		t.Errorf("In-flight readings peaked at %d, cap is %d", peak, config.MaxBufferedReadings)
+	}
+	t.Logf("Generated %d, published %d, dropped %d", stats.Generated, stats.Published, stats.Dropped)
+}
+
+func TestEngine_HeartbeatWhileQuiet(t *testing.T) {
+	config := Config{
+		ProductionRate:    time.Hour, // Generator is effectively paused
+		BatchSize:         1,
+		BatchTimeout:      10 * time.Millisecond,
+		MaxWorkers:        1,
+		HeartbeatInterval: 10 * time.Millisecond,
+	}
+
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if publisher.GetTotalDataPoints() < 3 {
+		t.Fatalf("Expected heartbeats to keep flowing, got %d readings", publisher.GetTotalDataPoints())
+	}
+	for _, batch := range publisher.batches {
+		for _, data := range batch {
+			if data.Quality != QualityHeartbeat {
+				t.Errorf("Expected only heartbeat readings, got %s", data.Quality)
+			}
+		}
+	}
+
+	stats := engine.Stats()
+	if stats.Generated != 0 {
+		t.Errorf("Expected no generated readings, got %d", stats.Generated)
+	}
+	if stats.Heartbeats == 0 {
+		t.Error("Expected heartbeats in stats")
+	}
+}
+
+func TestEngine_NoHeartbeatWhileBusy(t *testing.T) {
+	config := Config{
+		ProductionRate:    2 * time.Millisecond,
+		BatchSize:         5,
+		BatchTimeout:      10 * time.Millisecond,
+		MaxWorkers:        1,
+		HeartbeatInterval: 30 * time.Millisecond,
+	}
+
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if hb := engine.Stats().Heartbeats; hb != 0 {
+		t.Errorf("Expected no heartbeats while data flows, got %d", hb)
+	}
+}
+
+func TestEngine_SlowSeederCountsSlowCycles(t *testing.T) {
+	config := Config{
+		ProductionRate: 2 * time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+
+	seeder := NewCustomSeeder(func() float64 {
+		time.Sleep(5 * time.Millisecond)
+		return 1.0
+	})
+	engine := NewEngine(config, seeder, NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	stats := engine.Stats()
+	if stats.Generated == 0 {
+		t.Fatal("Expected slow seeder to still generate readings")
+	}
+	if stats.SlowCycles == 0 {
+		t.Error("Expected slow cycles to be counted")
+	}
+}
+
+func TestEngine_SeederTimeoutSkipsBlockedTicks(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     1,
+		SeederTimeout:  5 * time.Millisecond,
+	}
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	var calls atomic.Int32
+	seeder := NewCustomSeeder(func() float64 {
+		calls.Add(1)
+		<-unblock
+		return 1.0
+	})
+	engine := NewEngine(config, seeder, NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Engine did not stop promptly with a blocked seeder: %v", elapsed)
+	}
+
+	stats := engine.Stats()
+	if stats.Generated != 0 {
+		t.Errorf("Expected no readings from a blocked seeder, got %d", stats.Generated)
+	}
+	if stats.SlowCycles < 2 {
+		t.Errorf("Expected timed out ticks to be counted, got %d", stats.SlowCycles)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single outstanding seeder call, got %d", calls.Load())
+	}
+}
+
+func TestEngine_AdaptiveRate_SlowsDownForSlowPublisher(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      1,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     1,
+		AdaptiveRate: &AdaptiveRateConfig{
+			TargetLatency:  2 * time.Millisecond,
+			MaxRate:        50 * time.Millisecond,
+			AdjustInterval: 20 * time.Millisecond,
+		},
+	}
+
+	publisher := &slowMockPublisher[float64]{delay: 10 * time.Millisecond}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	stats := engine.Stats()
+	if stats.CurrentRate <= config.ProductionRate {
+		t.Errorf("Expected rate to slow down from %v, got %v", config.ProductionRate, stats.CurrentRate)
+	}
+	if stats.CurrentRate > config.AdaptiveRate.MaxRate {
+		t.Errorf("Rate %v exceeds MaxRate %v", stats.CurrentRate, config.AdaptiveRate.MaxRate)
+	}
+	if stats.AvgPublishLatency < publisher.delay {
+		t.Errorf("Expected average publish latency >= %v, got %v", publisher.delay, stats.AvgPublishLatency)
+	}
+}
+
+func TestEngine_AdaptiveRate_SpeedsUpWithEmptyBacklog(t *testing.T) {
+	config := Config{
+		ProductionRate: 20 * time.Millisecond,
+		BatchSize:      1,
+		BatchTimeout:   50 * time.Millisecond,
+		MaxWorkers:     1,
+		AdaptiveRate: &AdaptiveRateConfig{
+			TargetBacklog:  5,
+			MinRate:        10 * time.Millisecond,
+			AdjustInterval: 10 * time.Millisecond,
+		},
+	}
+
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if rate := engine.Stats().CurrentRate; rate >= config.ProductionRate || rate < config.AdaptiveRate.MinRate {
+		t.Errorf("Expected rate in [%v, %v), got %v", config.AdaptiveRate.MinRate, config.ProductionRate, rate)
+	}
+}
+
+func TestEngine_AdaptiveBatch(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      100,
+		MaxWorkers:     1,
+		AdaptiveBatch: &AdaptiveBatchConfig{
+			TargetLatency: 10 * time.Millisecond,
+			MinSize:       10,
+			MaxSize:       200,
+			Window:        2,
+		},
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+	engine.startAdaptiveBatch()
+
+	// A publisher taking 0.2ms per reading meets the target at 50 readings
+	for i := 0; i < 20; i++ {
+		engine.observeBatchLatency(time.Duration(engine.BatchSize()) * 200 * time.Microsecond)
+	}
+	if size := engine.BatchSize(); size < 40 || size > 50 {
+		t.Errorf("Expected the batch size to settle just under 50, got %d", size)
+	}
+	if size := engine.Stats().BatchSize; size != engine.BatchSize() {
+		t.Errorf("Expected Stats to report batch size %d, got %d", engine.BatchSize(), size)
+	}
+
+	// Fast publishes grow the batch up to MaxSize
+	for i := 0; i < 40; i++ {
+		engine.observeBatchLatency(time.Millisecond)
+	}
+	if size := engine.BatchSize(); size != 200 {
+		t.Errorf("Expected the batch size to reach MaxSize 200, got %d", size)
+	}
+
+	// Very slow publishes shrink it down to MinSize
+	for i := 0; i < 40; i++ {
+		engine.observeBatchLatency(time.Second)
+	}
+	if size := engine.BatchSize(); size != 10 {
+		t.Errorf("Expected the batch size to drop to MinSize 10, got %d", size)
+	}
+}
+
+func TestEngine_AdaptiveBatch_InvalidConfig(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      10,
+		MaxWorkers:     1,
+		AdaptiveBatch:  &AdaptiveBatchConfig{MinSize: 5},
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Expected an error for an adaptive batch config without a target latency")
+	}
+}
+
+func TestRateEnvelope_RateAt(t *testing.T) {
+	envelope := RateEnvelope{
+		{At: 0, Rate: 100 * time.Millisecond},          // 10/s
+		{At: 10 * time.Second, Rate: time.Millisecond}, // 1000/s
+		{At: 20 * time.Second, Rate: time.Millisecond},
+		{At: 30 * time.Second, Rate: 100 * time.Millisecond},
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{-time.Second, 100 * time.Millisecond},
+		{0, 100 * time.Millisecond},
+		{5 * time.Second, time.Second / 505}, // Halfway: 505 readings per second
+		{15 * time.Second, time.Millisecond},
+		{25 * time.Second, time.Second / 505},
+		{time.Hour, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := envelope.RateAt(tt.elapsed)
+		if diff := got - tt.want; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("RateAt(%v) = %v, want %v", tt.elapsed, got, tt.want)
+		}
+	}
+
+	if err := envelope.Validate(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+	if err := (RateEnvelope{{At: time.Second, Rate: time.Millisecond}, {At: 0, Rate: time.Millisecond}}).Validate(); err == nil {
+		t.Error("Expected error for out-of-order points")
+	}
+	if err := (RateEnvelope{{At: 0, Rate: 0}}).Validate(); err == nil {
+		t.Error("Expected error for zero rate")
+	}
+}
+
+func TestEngine_RateEnvelope(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Hour, // Replaced by the envelope
+		BatchSize:      10,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     1,
+		RateEnvelope: RateEnvelope{
+			{At: 0, Rate: 20 * time.Millisecond},
+			{At: 50 * time.Millisecond, Rate: 2 * time.Millisecond},
+		},
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	stats := engine.Stats()
+	if stats.CurrentRate != 2*time.Millisecond {
+		t.Errorf("Expected rate to end at 2ms, got %v", stats.CurrentRate)
+	}
+	if stats.Generated < 20 {
+		t.Errorf("Expected the ramp to speed up generation, got %d readings", stats.Generated)
+	}
+}
+
+func TestEngine_SetProductionRate(t *testing.T) {
+	engine := NewEngine(DefaultConfig(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	engine.SetProductionRate(42 * time.Millisecond)
+	if got := engine.ProductionRate(); got != 42*time.Millisecond {
+		t.Errorf("Expected rate 42ms, got %v", got)
+	}
+
+	engine.SetProductionRate(0)
+	if got := engine.ProductionRate(); got != 42*time.Millisecond {
+		t.Errorf("Non-positive rate should be ignored, got %v", got)
+	}
+}
+
+func TestEngine_TraceBatches(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      2,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxWorkers:     1,
+		TraceBatches:   true,
+	}
+
+	publisher := &batchIDRecorder[float64]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if len(publisher.ids) == 0 {
+		t.Fatal("No batches were published")
+	}
+	seen := make(map[string]bool)
+	for _, id := range publisher.ids {
+		if len(id) != 36 {
+			t.Errorf("Expected UUID batch ID, got %q", id)
+		}
+		if seen[id] {
+			t.Errorf("Duplicate batch ID %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestEngine_BatchWindow(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(harnessEpoch),
+	}
+	publisher := &batchWindowRecorder[float64]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+	if err := engine.RunFor(context.Background(), 10*config.ProductionRate); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	if len(publisher.envelopes) != 3 {
+		t.Fatalf("Expected 3 batches, got %d", len(publisher.envelopes))
+	}
+	for i, envelope := range publisher.envelopes {
+		first, last := envelope.Items[0].Timestamp, envelope.Items[len(envelope.Items)-1].Timestamp
+		if !envelope.WindowStart.Equal(first) || !envelope.WindowEnd.Equal(last) {
+			t.Errorf("Batch %d: expected window [%v, %v], got [%v, %v]", i, first, last, envelope.WindowStart, envelope.WindowEnd)
+		}
+		if envelope.Count != len(envelope.Items) {
+			t.Errorf("Batch %d: expected count %d, got %d", i, len(envelope.Items), envelope.Count)
+		}
+	}
+}
+
+func TestBatchWindowOf(t *testing.T) {
+	items := []SensorData[float64]{
+		{Timestamp: harnessEpoch.Add(2 * time.Second)},
+		{Timestamp: harnessEpoch},
+		{Timestamp: harnessEpoch.Add(5 * time.Second)},
+		{Timestamp: harnessEpoch.Add(time.Second)},
+	}
+	window := BatchWindowOf(items)
+	if !window.Start.Equal(harnessEpoch) || !window.End.Equal(harnessEpoch.Add(5*time.Second)) {
+		t.Errorf("Expected the earliest and latest timestamps, got [%v, %v]", window.Start, window.End)
+	}
+	if window := BatchWindowOf[float64](nil); !window.Start.IsZero() || !window.End.IsZero() {
+		t.Errorf("Expected the zero window for an empty batch, got %+v", window)
+	}
+
+	// Markers do not widen the window of the readings, but bound a batch of
+	// markers alone
+	markers := []SensorData[float64]{
+		{Timestamp: harnessEpoch.Add(-time.Second), Quality: QualityOffline},
+		{Timestamp: harnessEpoch.Add(9 * time.Second), Quality: QualityHeartbeat},
+	}
+	window = BatchWindowOf(append(markers, items...))
+	if !window.Start.Equal(harnessEpoch) || !window.End.Equal(harnessEpoch.Add(5*time.Second)) {
+		t.Errorf("Expected markers to be left out of the window, got [%v, %v]", window.Start, window.End)
+	}
+	window = BatchWindowOf(markers)
+	if !window.Start.Equal(harnessEpoch.Add(-time.Second)) || !window.End.Equal(harnessEpoch.Add(9*time.Second)) {
+		t.Errorf("Expected the window of the markers alone, got [%v, %v]", window.Start, window.End)
+	}
+}
+
+// batchWindowRecorder wraps each published batch in a BatchEnvelope built
+// from the PublishBatch context
+type batchWindowRecorder[T any] struct {
+	envelopes []BatchEnvelope[T]
+}
+
+func (b *batchWindowRecorder[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	return nil
+}
+
+func (b *batchWindowRecorder[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	if _, ok := BatchWindowFromContext(ctx); !ok {
+		return errors.New("no batch window in context")
+	}
+	b.envelopes = append(b.envelopes, NewBatchEnvelope(ctx, data))
+	return nil
+}
+
+func (b *batchWindowRecorder[T]) Close() error {
+	return nil
+}
+
+// batchIDRecorder records the batch ID carried by each PublishBatch context
+type batchIDRecorder[T any] struct {
+	ids []string
+}
+
+func (b *batchIDRecorder[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	return nil
+}
+
+func (b *batchIDRecorder[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	id, _ := BatchIDFromContext(ctx)
+	b.ids = append(b.ids, id)
+	return nil
+}
+
+func (b *batchIDRecorder[T]) Close() error {
+	return nil
+}
+
+// slowMockPublisher simulates a backend with a fixed publish latency
+type slowMockPublisher[T any] struct {
+	delay time.Duration
+}
+
+func (s *slowMockPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func (s *slowMockPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func (s *slowMockPublisher[T]) Close() error {
+	return nil
+}
+
+// Benchmark tests
+func BenchmarkEngine_DataGeneration(b *testing.B) {
+	config := Config{
+		ProductionRate: 1 * time.Millisecond,
+		BatchSize:      100,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     4,
+	}
+
+	seeder := NewTestSeeder([]float64{1.0})
+	function := NewTestSensorFunction(1.0)
+	publisher := NewMockPublisher[float64]()
+
+	engine := NewEngine(config, seeder, function, publisher)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err := engine.Start(ctx)
+	if err != nil {
+		b.Fatalf("Engine start failed: %v", err)
+	}
+
+	b.StopTimer()
+	dataPoints := publisher.GetTotalDataPoints()
+	b.Logf("Generated %d data points in 1 second", dataPoints)
+	b.ReportMetric(float64(dataPoints), "data_points/sec")
+}
+
+// discardPublisher accepts and drops every reading
+type discardPublisher[T any] struct{}
+
+func (discardPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error { return nil }
+
+func (discardPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	return nil
+}
+
+func (discardPublisher[T]) Close() error { return nil }
+
+// BenchmarkEngine_PipelinePerReading measures the cost per reading of the
+// generate, batch and publish path through the engine's channels, without
+// the production ticker
+func BenchmarkEngine_PipelinePerReading(b *testing.B) {
+	config := Config{
+		ProductionRate: time.Hour, // Only used to detect slow cycles here
+		BatchSize:      100,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     4,
+	}
+	engine := NewEngine(config, NewRandomSeeder(0, 1), NewTestSensorFunction(1.0), discardPublisher[float64]{})
+
+	ctx := context.Background()
+	dataChan := make(chan SensorData[float64], 100)
+	batchChan := make(chan []SensorData[float64], 10)
+	var batchWG, publishWG sync.WaitGroup
+	batchWG.Add(1)
+	go engine.processBatches(ctx, dataChan, batchChan, &batchWG)
+	for range config.MaxWorkers {
+		publishWG.Add(1)
+		go engine.publishWorker(ctx, batchChan, &publishWG)
+	}
+
+	var pending chan reading[float64]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		r, _ := engine.generateReading(ctx, &pending)
+		engine.emit(ctx, dataChan, SensorData[float64]{
+			ID:        engine.idGenerator(uint64(i)),
+			Timestamp: r.timestamp,
+			Data:      r.data,
+			Quality:   engine.determineQuality(),
+		})
+	}
+	close(dataChan)
+	batchWG.Wait()
+	close(batchChan)
+	publishWG.Wait()
+	b.StopTimer()
+
+	if published := engine.Stats().Published; published != uint64(b.N) {
+		b.Fatalf("Expected %d published readings, got %d", b.N, published)
+	}
+}
+
+// BenchmarkEngine_BatchSizeOne compares the cost per reading of the
+// BatchSize 1 fast path, where the publish workers take readings straight
+// from the generator, with sending one-reading batches through the batch
+// processor as before
+func BenchmarkEngine_BatchSizeOne(b *testing.B) {
+	config := Config{
+		ProductionRate: time.Hour, // Only used to detect slow cycles here
+		BatchSize:      1,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     4,
+	}
+
+	for _, fastPath := range []bool{true, false} {
+		name := "batched"
+		if fastPath {
+			name = "fast_path"
+		}
+		b.Run(name, func(b *testing.B) {
+			engine := NewEngine(config, NewRandomSeeder(0, 1), NewTestSensorFunction(1.0), discardPublisher[float64]{})
+
+			ctx := context.Background()
+			dataChan := make(chan SensorData[float64], 100)
+			batchChan := make(chan []SensorData[float64], 10)
+			var batchWG, publishWG sync.WaitGroup
+			for range config.MaxWorkers {
+				publishWG.Add(1)
+				if fastPath {
+					go engine.publishReadings(ctx, dataChan, &publishWG)
+				} else {
+					go engine.publishWorker(ctx, batchChan, &publishWG)
+				}
+			}
+			if !fastPath {
+				batchWG.Add(1)
+				go engine.processBatches(ctx, dataChan, batchChan, &batchWG)
+			}
+
+			var pending chan reading[float64]
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				r, _ := engine.generateReading(ctx, &pending)
+				engine.emit(ctx, dataChan, SensorData[float64]{
+					ID:        engine.idGenerator(uint64(i)),
+					Timestamp: r.timestamp,
+					Data:      r.data,
+					Quality:   engine.determineQuality(),
+				})
+			}
+			close(dataChan)
+			batchWG.Wait()
+			close(batchChan)
+			publishWG.Wait()
+			b.StopTimer()
+
+			if published := engine.Stats().Published; published != uint64(b.N) {
+				b.Fatalf("Expected %d published readings, got %d", b.N, published)
+			}
+		})
+	}
+}
+
+// BenchmarkEngine_RunForPerReading measures the cost per reading of the
+// virtual-time pipeline used by RunFor and Backfill
+func BenchmarkEngine_RunForPerReading(b *testing.B) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      100,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	engine := NewEngine(config, NewRandomSeeder(0, 1), NewTestSensorFunction(1.0), discardPublisher[float64]{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := engine.RunFor(context.Background(), time.Duration(b.N)*config.ProductionRate); err != nil {
+		b.Fatalf("RunFor failed: %v", err)
+	}
+}
+
+// mutatingPublisher records a copy of every batch and then scribbles over
+// and appends to the slice it was given
+type mutatingPublisher[T any] struct {
+	mutex   sync.Mutex
+	batches [][]SensorData[T]
+}
+
+func (m *mutatingPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	return nil
+}
+
+func (m *mutatingPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	m.mutex.Lock()
+	m.batches = append(m.batches, append([]SensorData[T](nil), data...))
+	m.mutex.Unlock()
+
+	for i := range data {
+		data[i].ID = "mutated"
+	}
+	// Appending within spare capacity would corrupt a reused backing array
+	_ = append(data[:len(data):cap(data)], SensorData[T]{ID: "appended"})
+	return nil
+}
+
+func (m *mutatingPublisher[T]) Close() error {
+	return nil
+}
+
+func TestEngine_BatchesAreNotAliased(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      7,
+		BatchTimeout:   3 * time.Millisecond, // Mix of full and partial batches
+		MaxWorkers:     3,
+	}
+	publisher := &mutatingPublisher[float64]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	total := 0
+	for _, batch := range publisher.batches {
+		for _, d := range batch {
+			if d.ID == "mutated" || d.ID == "appended" {
+				t.Fatalf("Batch contains data written by the publisher to an earlier batch: %s", d.ID)
+			}
+			if seen[d.ID] {
+				t.Fatalf("Reading %s delivered twice", d.ID)
+			}
+			seen[d.ID] = true
+			total++
+		}
+	}
+	if total == 0 {
+		t.Fatal("Expected readings to be published")
+	}
+}
+
+// checkReordering verifies every ID appears once and none moved further than
+// window-1 positions, and reports whether any reading was out of order
+func checkReordering(t *testing.T, readings []SensorData[float64], window int) bool {
+	t.Helper()
+
+	seen := make(map[int]bool)
+	reordered := false
+	for pos, d := range readings {
+		var seq int
+		if _, err := fmt.Sscanf(d.ID, "sensor-%d", &seq); err != nil {
+			t.Fatalf("Unexpected ID %q", d.ID)
+		}
+		if seen[seq] {
+			t.Fatalf("Duplicate reading %s", d.ID)
+		}
+		seen[seq] = true
+		if seq != pos {
+			reordered = true
+		}
+		if distance := max(seq-pos, pos-seq); distance >= window {
+			t.Errorf("Reading %s moved %d positions, window is %d", d.ID, distance, window)
+		}
+	}
+	return reordered
+}
+
+func TestEngine_ReorderWindow(t *testing.T) {
+	config := Config{
+		ProductionRate:     time.Millisecond,
+		BatchSize:          10,
+		BatchTimeout:       time.Second,
+		MaxWorkers:         1,
+		ReorderWindow:      5,
+		ReorderProbability: 0.5,
+		Clock:              NewManualClock(time.Unix(0, 0)),
+		RandSource:         rand.NewPCG(1, 1),
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	if err := engine.RunFor(context.Background(), 103*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	var readings []SensorData[float64]
+	for _, batch := range publisher.batches {
+		readings = append(readings, batch...)
+	}
+	if len(readings) != 103 {
+		t.Fatalf("Expected all 103 readings including the partial window, got %d", len(readings))
+	}
+	if !checkReordering(t, readings, config.ReorderWindow) {
+		t.Error("Expected some readings to be out of order")
+	}
+}
+
+func TestEngine_ReorderWindowWhileRunning(t *testing.T) {
+	config := Config{
+		ProductionRate:     time.Millisecond,
+		BatchSize:          10,
+		BatchTimeout:       10 * time.Millisecond,
+		MaxWorkers:         1,
+		ReorderWindow:      4,
+		ReorderProbability: 1,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	var readings []SensorData[float64]
+	for _, batch := range publisher.batches {
+		readings = append(readings, batch...)
+	}
+	if len(readings) < 8 {
+		t.Fatalf("Expected at least two windows of readings, got %d", len(readings))
+	}
+	if !checkReordering(t, readings, config.ReorderWindow) {
+		t.Error("Expected some readings to be out of order")
+	}
+}
+
+func TestEngine_FlushWindowCountsQueuedReadings(t *testing.T) {
+	config := Config{ReorderWindow: 4, ReorderProbability: 1, RandSource: rand.NewPCG(1, 1)}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	window := make([]SensorData[float64], 3)
+	for i := range window {
+		window[i] = SensorData[float64]{ID: fmt.Sprintf("sensor-%d", i), Quality: QualityOK}
+	}
+	dataChan := make(chan SensorData[float64], 2)
+	engine.flushWindow(dataChan, window)
+
+	if len(dataChan) != 2 {
+		t.Fatalf("Expected the window to fill the channel, got %d readings", len(dataChan))
+	}
+	if stats := engine.Stats(); stats.Generated != 2 || stats.OKCount != 2 {
+		t.Errorf("Expected only the 2 queued readings to count as generated, got %d (%d OK)", stats.Generated, stats.OKCount)
+	}
+}
+
+// unevenPublisher blocks the first PublishBatch call until release is closed
+// and publishes every other batch immediately
+type unevenPublisher[T any] struct {
+	MockPublisher[T]
+	release  chan struct{}
+	calls    atomic.Int64
+	inFlight atomic.Int64
+	peak     atomic.Int64
+}
+
+func (u *unevenPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	n := u.inFlight.Add(1)
+	defer u.inFlight.Add(-1)
+	for {
+		peak := u.peak.Load()
+		if n <= peak || u.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	if u.calls.Add(1) == 1 {
+		<-u.release
+	}
+	return nil
+}
+
+func TestEngine_IdleWorkersDrainQueueDuringSlowPublish(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      2,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     3,
+	}
+	publisher := &unevenPublisher[float64]{release: make(chan struct{})}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- engine.Start(ctx) }()
+
+	// While the first batch is stuck, the other workers keep publishing
+	time.Sleep(150 * time.Millisecond)
+	during := publisher.calls.Load()
+	close(publisher.release)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if during < 20 {
+		t.Errorf("Expected idle workers to keep publishing during a slow publish, got %d calls", during)
+	}
+	if stats := engine.Stats(); stats.Backlog != 0 {
+		t.Errorf("Expected an empty backlog after shutdown, got %d", stats.Backlog)
+	}
+	if peak := publisher.peak.Load(); peak < 2 || peak > int64(config.MaxWorkers) {
+		t.Errorf("Expected between 2 and %d concurrent publishes, got %d", config.MaxWorkers, peak)
+	}
+}
+
+func TestEngine_BatchSizeOnePublishesSingly(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      1,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     1,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if publisher.publishCalled == 0 || publisher.batchCalled != 0 {
+		t.Errorf("Expected only Publish calls, got %d Publish and %d PublishBatch calls", publisher.publishCalled, publisher.batchCalled)
+	}
+	if stats := engine.Stats(); stats.Singles != uint64(publisher.publishCalled) || stats.Batches != 0 {
+		t.Errorf("Expected %d singles and no batches, got %+v", publisher.publishCalled, stats)
+	}
+}
+
+func TestEngine_BatchQueueSize(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      2,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     1,
+		BatchQueueSize: 3,
+	}
+	publisher := &unevenPublisher[float64]{release: make(chan struct{})}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- engine.Start(ctx) }()
+
+	// With the only worker blocked, the queue fills up to its capacity
+	time.Sleep(100 * time.Millisecond)
+	backlog := engine.Stats().Backlog
+	close(publisher.release)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if backlog != config.BatchQueueSize {
+		t.Errorf("Expected the backlog to reach the queue size %d, got %d", config.BatchQueueSize, backlog)
+	}
+}
+
+func TestEngine_NegativeBatchQueueSize(t *testing.T) {
+	config := DefaultConfig()
+	config.BatchQueueSize = -1
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Expected an error for a negative batch queue size")
+	}
+}
+
+func TestEngine_ZeroBatchTimeoutSendsOnlyFullBatches(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   0,
+		MaxWorkers:     1,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if len(publisher.batches) < 2 {
+		t.Fatalf("Expected several batches, got %d", len(publisher.batches))
+	}
+	// Only the batch flushed on shutdown may be partial
+	for i, batch := range publisher.batches[:len(publisher.batches)-1] {
+		if len(batch) != 5 {
+			t.Errorf("Batch %d has %d readings; without a timeout only full batches are sent", i, len(batch))
+		}
+	}
+}
+
+func TestEngine_NegativeBatchTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.BatchTimeout = -time.Second
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Expected an error for a negative batch timeout")
+	}
+}
+
+func TestEngine_Generate(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	config := DefaultConfig()
+	config.Clock = clock
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1, 2, 3}), NewTestSensorFunction(10), publisher)
+
+	for i, want := range []float64{10, 20, 30} {
+		clock.Advance(time.Second)
+		reading := engine.Generate()
+
+		if wantID := fmt.Sprintf("sensor-%d", i); reading.ID != wantID {
+			t.Errorf("Expected ID %s, got %s", wantID, reading.ID)
+		}
+		if reading.Data != want {
+			t.Errorf("Expected data %v, got %v", want, reading.Data)
+		}
+		if !reading.Timestamp.Equal(clock.Now()) {
+			t.Errorf("Expected the clock's time %v, got %v", clock.Now(), reading.Timestamp)
+		}
+		if reading.Quality == "" || !IsRegisteredQuality(reading.Quality) {
+			t.Errorf("Expected a registered quality, got %q", reading.Quality)
+		}
+	}
+
+	if stats := engine.Stats(); stats.Generated != 3 || stats.Published != 0 {
+		t.Errorf("Expected 3 generated and none published, got %+v", stats)
+	}
+	if publisher.GetTotalDataPoints() != 0 || publisher.closed {
+		t.Error("Generate must not publish or close the publisher")
+	}
+
+	// Runs continue the ID sequence
+	if err := engine.RunFor(context.Background(), config.ProductionRate); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if id := publisher.batches[0][0].ID; id != "sensor-3" {
+		t.Errorf("Expected the run to continue at sensor-3, got %s", id)
+	}
+}
+
+func TestEngine_GenerateEnvelope(t *testing.T) {
+	config := DefaultConfig()
+	engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+	if _, err := engine.GenerateEnvelope(); err == nil {
+		t.Error("Expected an error without Config.Envelope")
+	}
+
+	config.Envelope = &EnvelopeConfig{Source: "pump-7"}
+	engine = NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+	for seq := uint64(1); seq <= 2; seq++ {
+		env, err := engine.GenerateEnvelope()
+		if err != nil {
+			t.Fatalf("GenerateEnvelope failed: %v", err)
+		}
+		if env.Seq != seq || env.Source != "pump-7" || env.ID == "" {
+			t.Errorf("Unexpected envelope %+v", env)
+		}
+	}
+}
+
+func TestEngine_UniqueIDsAcrossRuns(t *testing.T) {
+	config := envelopeConfig(PublishModeBatch)
+	config.ProductionRate = time.Millisecond
+	config.BatchSize = 100
+	publisher := &envelopeRecorder[float64]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), publisher)
+
+	ids := make(map[string]bool)
+	seqs := make(map[uint64]bool)
+	record := func(env Envelope[float64]) {
+		if ids[env.ID] || seqs[env.Seq] {
+			t.Fatalf("Duplicate ID %s or seq %d", env.ID, env.Seq)
+		}
+		ids[env.ID] = true
+		seqs[env.Seq] = true
+	}
+
+	// Restarting the engine and generating on demand continue the sequence
+	const readings = 50000
+	for run := 0; run < 3; run++ {
+		publisher.calls = nil
+		if err := engine.RunFor(context.Background(), readings*config.ProductionRate); err != nil {
+			t.Fatalf("RunFor failed: %v", err)
+		}
+		for _, env := range publisher.envelopes() {
+			record(env)
+		}
+		env, err := engine.GenerateEnvelope()
+		if err != nil {
+			t.Fatalf("GenerateEnvelope failed: %v", err)
+		}
+		record(env)
+	}
+	if want := 3 * (readings + 1); len(ids) != want {
+		t.Errorf("Expected %d unique readings, got %d", want, len(ids))
+	}
+}
+
+func TestEngine_GenerateDuringStart(t *testing.T) {
+	config := DefaultConfig()
+	config.ProductionRate = 50 * time.Microsecond
+	config.MaxWorkers = 1
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewRandomSeederWithSource(0, 1, rand.NewPCG(1, 2)), NewTestSensorFunction(1), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var generated []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			generated = append(generated, engine.Generate().ID)
+		}
+	}()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	<-done
+
+	seen := make(map[string]bool)
+	for _, batch := range publisher.batches {
+		for _, data := range batch {
+			generated = append(generated, data.ID)
+		}
+	}
+	for _, id := range generated {
+		if seen[id] {
+			t.Fatalf("Duplicate ID %s between Start and Generate", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestEngine_IDSequenceExhausted(t *testing.T) {
+	engine := NewEngine(DefaultConfig(), NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+	engine.nextID.Store(math.MaxUint64 - 1)
+	if id := engine.Generate().ID; id != fmt.Sprintf("sensor-%d", uint64(math.MaxUint64-1)) {
+		t.Errorf("Expected the last usable sequence number, got %s", id)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic instead of wrapping around to used IDs")
+		}
+	}()
+	engine.Generate()
 }
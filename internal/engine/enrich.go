@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// EnrichFunc adds to each reading before it is published, for attaching
+// static reference data such as a location name or calibration constants
+type EnrichFunc[T any] func(SensorData[T]) SensorData[T]
+
+// SetEnrichFunc sets a function applied to every reading, heartbeats and
+// offline markers excepted, before it is redacted, filtered and handed to
+// the publisher. It must be called before Start.
+func (e *Engine[T]) SetEnrichFunc(enrich EnrichFunc[T]) {
+	e.enrich = enrich
+}
+
+// ReferenceTable holds static attributes per sensor: the outer key
+// identifies the sensor and the inner map holds attribute values by JSON
+// field name
+type ReferenceTable map[string]map[string]any
+
+// LoadReferenceTable loads a reference table from a JSON file of the form
+// {"sensor-1": {"location": "Boiler room", "offset": 0.3}, ...}
+func LoadReferenceTable(filename string) (ReferenceTable, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reference table: %w", err)
+	}
+	var table ReferenceTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse reference table: %w", err)
+	}
+	return table, nil
+}
+
+// EnrichFromTable returns an EnrichFunc that sets the attributes the table
+// holds for each reading's sensor on its Data. key picks the table entry of
+// a reading; nil uses the reading ID, which suits an IDGenerator that
+// yields device IDs. Readings without an entry are left as they are.
+//
+// Data may be a struct, a pointer to a struct (the pointee is copied, never
+// modified) or a map with string keys (attributes are added to a copy).
+// Struct attributes set the top-level field with that JSON name, like
+// MaskFields. Values are converted to the field or map value type up front,
+// so an attribute naming no field, or a value of the wrong type, such as a
+// string for a float64 field, is an error rather than a silently missing
+// attribute.
+func EnrichFromTable[T any](table ReferenceTable, key func(SensorData[T]) string) (EnrichFunc[T], error) {
+	dataType := reflect.TypeFor[T]()
+	target := dataType
+	if target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+
+	var fields map[string][]int
+	switch {
+	case target.Kind() == reflect.Struct:
+		fields = jsonFieldIndexes(target)
+	case dataType.Kind() == reflect.Map && dataType.Key().Kind() == reflect.String:
+	default:
+		return nil, fmt.Errorf("cannot enrich readings of type %v: data must be a struct, a pointer to a struct or a map with string keys", dataType)
+	}
+
+	// Convert every attribute once, so enrichment itself cannot fail
+	type attribute struct {
+		name  string
+		field []int
+		value reflect.Value
+	}
+	attributes := make(map[string][]attribute, len(table))
+	for id, entry := range table {
+		for _, name := range slices.Sorted(maps.Keys(entry)) {
+			a := attribute{name: name}
+			var valueType reflect.Type
+			if fields != nil {
+				index, ok := fields[name]
+				if !ok {
+					return nil, fmt.Errorf("reference table entry %s: %v has no field %s", id, target, name)
+				}
+				a.field = index
+				valueType = target.FieldByIndex(index).Type
+			} else {
+				valueType = dataType.Elem()
+			}
+			value, err := convertAttribute(entry[name], valueType)
+			if err != nil {
+				return nil, fmt.Errorf("reference table entry %s: attribute %s: %w", id, name, err)
+			}
+			a.value = value
+			attributes[id] = append(attributes[id], a)
+		}
+	}
+
+	if key == nil {
+		key = func(data SensorData[T]) string { return data.ID }
+	}
+	return func(data SensorData[T]) SensorData[T] {
+		entry, ok := attributes[key(data)]
+		if !ok {
+			return data
+		}
+
+		v := reflect.ValueOf(&data.Data).Elem()
+		switch {
+		case fields != nil && v.Kind() == reflect.Pointer:
+			if v.IsNil() {
+				return data
+			}
+			enriched := reflect.New(target)
+			enriched.Elem().Set(v.Elem())
+			v.Set(enriched)
+			v = enriched.Elem()
+		case fields == nil:
+			enriched := reflect.MakeMapWithSize(dataType, v.Len()+len(entry))
+			iter := v.MapRange()
+			for iter.Next() {
+				enriched.SetMapIndex(iter.Key(), iter.Value())
+			}
+			v.Set(enriched)
+		}
+
+		for _, a := range entry {
+			if fields != nil {
+				v.FieldByIndex(a.field).Set(a.value)
+			} else {
+				v.SetMapIndex(reflect.ValueOf(a.name).Convert(dataType.Key()), a.value)
+			}
+		}
+		return data
+	}, nil
+}
+
+// jsonFieldIndexes maps the JSON names of the exported top-level fields of
+// a struct type, named as in MaskFields, to their indexes
+func jsonFieldIndexes(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		fields[name] = field.Index
+	}
+	return fields
+}
+
+// convertAttribute converts a reference table value to t. JSON numbers
+// convert to any numeric type, and null gives the zero value.
+func convertAttribute(value any, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(t):
+		converted := reflect.New(t).Elem()
+		converted.Set(v)
+		return converted, nil
+	case isNumericKind(v.Kind()) && isNumericKind(t.Kind()), v.Kind() == reflect.String && t.Kind() == reflect.String:
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %v value %v as %v", v.Type(), value, t)
+}
+
+// isNumericKind reports whether k is an integer or floating-point kind
+func isNumericKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type stationReading struct {
+	Temperature float64 `json:"temperature"`
+	Location    string  `json:"location,omitempty"`
+	Offset      float64 `json:"offset,omitempty"`
+	Floor       int     `json:"floor,omitempty"`
+}
+
+func TestEnrichFromTable_JoinsReferenceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stations.json")
+	reference := `{
+		"boiler": {"location": "Boiler room", "offset": 0.5, "floor": 1},
+		"roof":   {"location": "Roof"}
+	}`
+	if err := os.WriteFile(path, []byte(reference), 0o644); err != nil {
+		t.Fatalf("Failed to write reference table: %v", err)
+	}
+	table, err := LoadReferenceTable(path)
+	if err != nil {
+		t.Fatalf("LoadReferenceTable failed: %v", err)
+	}
+	enrich, err := EnrichFromTable[stationReading](table, nil)
+	if err != nil {
+		t.Fatalf("EnrichFromTable failed: %v", err)
+	}
+
+	// Three devices take turns, one of them missing from the table
+	devices := []string{"boiler", "roof", "cellar"}
+	h := newHarness[stationReading](t, Config{ProductionRate: time.Second, BatchSize: 3, MaxWorkers: 1})
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) stationReading {
+		return stationReading{Temperature: 20}
+	})
+	published := h.run(NewTestSeeder([]float64{1}), function, 6,
+		WithIDGenerator[stationReading](func(seq uint64) string { return devices[seq%3] }),
+		WithEnrichFunc(enrich))
+
+	want := map[string]stationReading{
+		"boiler": {Temperature: 20, Location: "Boiler room", Offset: 0.5, Floor: 1},
+		"roof":   {Temperature: 20, Location: "Roof"},
+		"cellar": {Temperature: 20},
+	}
+	if len(published.data) != 6 {
+		t.Fatalf("Expected 6 readings, got %d", len(published.data))
+	}
+	for _, d := range published.data {
+		if d.Data != want[d.ID] {
+			t.Errorf("Expected %s enriched to %+v, got %+v", d.ID, want[d.ID], d.Data)
+		}
+	}
+}
+
+func TestEnrichFromTable_MapAndPointer(t *testing.T) {
+	table := ReferenceTable{"d-1": {"location": "Lab", "gain": 2.0}}
+
+	enrichMap, err := EnrichFromTable[map[string]any](table, nil)
+	if err != nil {
+		t.Fatalf("EnrichFromTable failed: %v", err)
+	}
+	original := map[string]any{"value": 1.0}
+	enriched := enrichMap(SensorData[map[string]any]{ID: "d-1", Data: original})
+	if enriched.Data["location"] != "Lab" || enriched.Data["gain"] != 2.0 || enriched.Data["value"] != 1.0 {
+		t.Errorf("Expected the attributes added to the map, got %v", enriched.Data)
+	}
+	if len(original) != 1 {
+		t.Error("EnrichFromTable must not modify the original map")
+	}
+
+	byDevice := func(data SensorData[*stationReading]) string { return "d-" + fmt.Sprint(data.Data.Floor) }
+	enrichPointer, err := EnrichFromTable(ReferenceTable{"d-1": {"location": "Lab"}}, byDevice)
+	if err != nil {
+		t.Fatalf("EnrichFromTable failed: %v", err)
+	}
+	reading := &stationReading{Floor: 1}
+	if got := enrichPointer(SensorData[*stationReading]{Data: reading}); got.Data.Location != "Lab" {
+		t.Errorf("Expected the entry picked by the key function, got %+v", got.Data)
+	}
+	if reading.Location != "" {
+		t.Error("EnrichFromTable must not modify the original pointee")
+	}
+}
+
+func TestEnrichFromTable_Invalid(t *testing.T) {
+	tests := map[string]ReferenceTable{
+		"unknown field": {"boiler": {"colour": "red"}},
+		"wrong type":    {"boiler": {"offset": "high"}},
+	}
+	for name, table := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := EnrichFromTable[stationReading](table, nil); err == nil {
+				t.Error("Expected an error for an attribute that cannot be set")
+			}
+		})
+	}
+	if _, err := EnrichFromTable[float64](ReferenceTable{}, nil); err == nil {
+		t.Error("Expected an error for readings that cannot carry attributes")
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"time"
+)
+
+// Envelope wraps a reading with stream-level fields for consumers that opt
+// in to a richer output schema. The reading's own fields are inlined, so an
+// envelope marshals as a SensorData object with extra keys.
+type Envelope[T any] struct {
+	SensorData[T]
+	Seq      uint64            `json:"seq"`                // Position of the reading in the published stream, from 1
+	Source   string            `json:"source,omitempty"`   // Producer of the stream
+	Version  string            `json:"version,omitempty"`  // Schema version of Data
+	Metadata map[string]string `json:"metadata,omitempty"` // Free-form labels
+	Unit     string            `json:"unit,omitempty"`     // Unit of a numeric Data value, such as "°C"
+	Units    map[string]string `json:"units,omitempty"`    // Units of the fields of Data, by JSON name
+}
+
+// EnvelopeConfig enables envelope mode and sets the fields shared by every
+// envelope of the stream
+type EnvelopeConfig struct {
+	Source   string
+	Version  string
+	Metadata map[string]string
+
+	// Unit and Units tell consumers what the values measure: Unit for a
+	// numeric Data, Units for the fields of a struct or map Data, keyed by
+	// JSON name as in MaskFields
+	Unit  string
+	Units map[string]string
+}
+
+// EnvelopePublisher is implemented by publishers that can publish readings
+// wrapped in envelopes. Config.Envelope requires the publisher to implement
+// it; the envelopes are handed over like batches are to PublishBatch.
+type EnvelopePublisher[T any] interface {
+	PublishEnvelopes(ctx context.Context, envelopes []Envelope[T]) error
+}
+
+// envelopePublisher returns the publisher's envelope support, or an error if
+// envelope mode is enabled and the publisher lacks it
+func (e *Engine[T]) envelopePublisher() (EnvelopePublisher[T], error) {
+	if e.config.Envelope == nil {
+		return nil, nil
+	}
+	publisher, ok := e.publisher.(EnvelopePublisher[T])
+	if !ok {
+		return nil, fmt.Errorf("envelope mode requires a publisher implementing PublishEnvelopes, got %T", e.publisher)
+	}
+	return publisher, nil
+}
+
+// validateUnits checks that units name fields of T, which must be a struct,
+// a pointer to a struct or a map with string keys to have any
+func validateUnits[T any](units map[string]string) error {
+	if len(units) == 0 {
+		return nil
+	}
+	target := reflect.TypeFor[T]()
+	if target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	switch {
+	case target.Kind() == reflect.Struct:
+		fields := jsonFieldIndexes(target)
+		for name := range units {
+			if _, ok := fields[name]; !ok {
+				return fmt.Errorf("envelope units: %v has no field %s", target, name)
+			}
+		}
+	case target.Kind() == reflect.Map && target.Key().Kind() == reflect.String:
+	default:
+		return fmt.Errorf("envelope units: %v has no fields; use Unit for a single value", reflect.TypeFor[T]())
+	}
+	return nil
+}
+
+// wrap builds the envelopes for batch, numbering them in publish order. The
+// metadata and units maps are copied once per batch and shared by its
+// envelopes.
+func (e *Engine[T]) wrap(batch []SensorData[T]) []Envelope[T] {
+	cfg := e.config.Envelope
+	metadata := maps.Clone(cfg.Metadata)
+	units := maps.Clone(cfg.Units)
+
+	last := e.envelopeSeq.Add(uint64(len(batch)))
+	if last < uint64(len(batch)) {
+		panic("engine: envelope sequence numbers exhausted")
+	}
+	first := last - uint64(len(batch)) + 1
+
+	envelopes := make([]Envelope[T], len(batch))
+	for i, data := range batch {
+		envelopes[i] = Envelope[T]{
+			SensorData: data,
+			Seq:        first + uint64(i),
+			Source:     cfg.Source,
+			Version:    cfg.Version,
+			Metadata:   metadata,
+			Unit:       cfg.Unit,
+			Units:      units,
+		}
+	}
+	return envelopes
+}
+
+// publishEnvelopes publishes batch in envelope mode, as one call per batch
+// or, if single is set, one call per reading
+func (e *Engine[T]) publishEnvelopes(ctx context.Context, publisher EnvelopePublisher[T], batch []SensorData[T], single bool) error {
+	envelopes := e.wrap(batch)
+
+	if !single {
+		return e.retryPublish(ctx, func() error {
+			start := time.Now()
+			err := publisher.PublishEnvelopes(ctx, envelopes)
+			e.recordBatchPublish(len(envelopes), time.Since(start), err)
+			return err
+		})
+	}
+
+	failed := 0
+	var firstErr error
+	for i := range envelopes {
+		err := e.retryPublish(ctx, func() error {
+			start := time.Now()
+			err := publisher.PublishEnvelopes(ctx, envelopes[i:i+1:i+1])
+			e.stats.recordSingle(time.Since(start), err)
+			return err
+		})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d single publishes failed: %w", failed, len(envelopes), firstErr)
+	}
+	return nil
+}
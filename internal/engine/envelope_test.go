@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelopeRecorder records the envelopes it receives
+type envelopeRecorder[T any] struct {
+	MockPublisher[T]
+	calls [][]Envelope[T]
+}
+
+func (r *envelopeRecorder[T]) PublishEnvelopes(ctx context.Context, envelopes []Envelope[T]) error {
+	r.calls = append(r.calls, envelopes)
+	return nil
+}
+
+func (r *envelopeRecorder[T]) envelopes() []Envelope[T] {
+	var all []Envelope[T]
+	for _, call := range r.calls {
+		all = append(all, call...)
+	}
+	return all
+}
+
+func envelopeConfig(mode PublishMode) Config {
+	return Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		PublishMode:    mode,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+		Envelope: &EnvelopeConfig{
+			Source:   "plant-7",
+			Version:  "2",
+			Metadata: map[string]string{"site": "north"},
+		},
+	}
+}
+
+func TestEngine_EnvelopeMode(t *testing.T) {
+	publisher := &envelopeRecorder[float64]{}
+	engine := NewEngine(envelopeConfig(PublishModeBatch), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	if err := engine.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if publisher.batchCalled != 0 {
+		t.Errorf("Expected no plain PublishBatch calls in envelope mode, got %d", publisher.batchCalled)
+	}
+	if len(publisher.calls) != 3 {
+		t.Errorf("Expected 3 envelope batches, got %d", len(publisher.calls))
+	}
+
+	envelopes := publisher.envelopes()
+	if len(envelopes) != 10 {
+		t.Fatalf("Expected 10 envelopes, got %d", len(envelopes))
+	}
+	for i, env := range envelopes {
+		if env.Seq != uint64(i+1) {
+			t.Errorf("Envelope %d: expected seq %d, got %d", i, i+1, env.Seq)
+		}
+		if env.Source != "plant-7" || env.Version != "2" || env.Metadata["site"] != "north" {
+			t.Errorf("Envelope %d: missing stream fields: %+v", i, env)
+		}
+		if env.ID == "" || env.Quality == "" {
+			t.Errorf("Envelope %d: missing reading fields: %+v", i, env.SensorData)
+		}
+	}
+	if stats := engine.Stats(); stats.Published != 10 || stats.Batches != 3 {
+		t.Errorf("Expected 10 published readings in 3 batches, got %+v", stats)
+	}
+}
+
+func TestEngine_EnvelopeModeSingle(t *testing.T) {
+	publisher := &envelopeRecorder[float64]{}
+	engine := NewEngine(envelopeConfig(PublishModeSingle), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	if err := engine.RunFor(context.Background(), 50*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if len(publisher.calls) != 5 {
+		t.Fatalf("Expected one PublishEnvelopes call per reading, got %d", len(publisher.calls))
+	}
+	for _, call := range publisher.calls {
+		if len(call) != 1 {
+			t.Errorf("Expected a single envelope per call, got %d", len(call))
+		}
+	}
+}
+
+func TestEngine_EnvelopeModeRequiresEnvelopePublisher(t *testing.T) {
+	engine := NewEngine(envelopeConfig(PublishModeBatch), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Expected an error for a publisher without PublishEnvelopes")
+	}
+}
+
+func TestEnvelope_MarshalInlinesReading(t *testing.T) {
+	env := Envelope[float64]{
+		SensorData: SensorData[float64]{ID: "sensor-1", Timestamp: time.Unix(0, 0).UTC(), Data: 21.5, Quality: QualityOK},
+		Seq:        7,
+		Source:     "plant-7",
+	}
+
+	got, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"id":"sensor-1","timestamp":"1970-01-01T00:00:00Z","data":21.5,"quality":"OK","seq":7,"source":"plant-7"}`
+	if string(got) != want {
+		t.Errorf("Unexpected JSON:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestEnvelope_MarshalUnits(t *testing.T) {
+	config := envelopeConfig(PublishModeBatch)
+	config.Envelope = &EnvelopeConfig{Unit: "°C"}
+	config.QualityProfile = QualityProfile{{Quality: QualityOK, Weight: 1}}
+	publisher := &envelopeRecorder[float64]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(21.5), publisher)
+	if err := engine.RunFor(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	envelopes := publisher.envelopes()
+	if len(envelopes) != 1 {
+		t.Fatalf("Expected 1 envelope, got %d", len(envelopes))
+	}
+	envelopes[0].Timestamp = time.Unix(0, 0).UTC()
+	got, err := json.Marshal(envelopes[0])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"id":"sensor-0","timestamp":"1970-01-01T00:00:00Z","data":21.5,"quality":"OK","seq":1,"unit":"°C"}`
+	if string(got) != want {
+		t.Errorf("Unexpected JSON:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestEnvelope_FieldUnits(t *testing.T) {
+	config := envelopeConfig(PublishModeBatch)
+	config.Envelope = &EnvelopeConfig{Units: map[string]string{"value": "%"}}
+	publisher := &envelopeRecorder[deviceReading]{}
+	function := NewFunction(func(input float64, _ time.Time) deviceReading { return deviceReading{Value: input} })
+	engine := NewEngine(config, NewTestSeeder([]float64{40}), function, publisher)
+	if err := engine.RunFor(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	got, err := json.Marshal(publisher.envelopes()[0])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(got), `"units":{"value":"%"}`) || strings.Contains(string(got), `"unit":`) {
+		t.Errorf("Expected only the field units in %s", got)
+	}
+
+	config.Envelope.Units = map[string]string{"humidity": "%"}
+	engine = NewEngine(config, NewTestSeeder([]float64{40}), function, publisher)
+	if err := engine.RunFor(context.Background(), 10*time.Millisecond); err == nil {
+		t.Error("Expected an error for units of a missing field")
+	}
+	numeric := NewEngine(config, NewTestSeeder([]float64{40}), NewTestSensorFunction(1.0), &envelopeRecorder[float64]{})
+	if err := numeric.RunFor(context.Background(), 10*time.Millisecond); err == nil {
+		t.Error("Expected an error for field units of a numeric reading")
+	}
+}
@@ -1,6 +1,11 @@
 package engine
 
 import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -54,3 +59,256 @@ func NewLambdaSensorFunction[T any](lambda func(float64, time.Time) T) *LambdaSe
 func (l *LambdaSensorFunction[T]) Generate(input float64, timestamp time.Time) T {
 	return l.lambda(input, timestamp)
 }
+
+// OutlierWrapper wraps a sensor function and, with a given probability,
+// passes its output through an inject function to produce an outlier
+type OutlierWrapper[T any] struct {
+	inner       SensorFunction[T]
+	probability float64
+	inject      func(T) T
+	injected    atomic.Uint64
+	rng         *rand.Rand // nil for the global source
+	mutex       sync.Mutex // Guards rng, whose sources are not safe for concurrent use
+}
+
+// NewOutlierWrapper creates a sensor function that turns a fraction of the
+// inner function's outputs into outliers. For struct outputs, inject should
+// modify the numeric field of interest.
+func NewOutlierWrapper[T any](inner SensorFunction[T], probability float64, inject func(T) T) *OutlierWrapper[T] {
+	return &OutlierWrapper[T]{
+		inner:       inner,
+		probability: probability,
+		inject:      inject,
+	}
+}
+
+// NewOutlierWrapperWithSource creates an outlier wrapper that decides which
+// outputs to replace from src, so a seeded source injects a reproducible
+// sequence of outliers
+func NewOutlierWrapperWithSource[T any](src rand.Source, inner SensorFunction[T], probability float64, inject func(T) T) *OutlierWrapper[T] {
+	o := NewOutlierWrapper(inner, probability, inject)
+	o.rng = rand.New(src)
+	return o
+}
+
+// Generate generates the inner output, occasionally replaced by an outlier
+func (o *OutlierWrapper[T]) Generate(input float64, timestamp time.Time) T {
+	value := o.inner.Generate(input, timestamp)
+
+	draw := 0.0
+	if o.rng != nil {
+		o.mutex.Lock()
+		draw = o.rng.Float64()
+		o.mutex.Unlock()
+	} else {
+		draw = rand.Float64()
+	}
+
+	if draw < o.probability {
+		o.injected.Add(1)
+		return o.inject(value)
+	}
+	return value
+}
+
+// Injected returns the number of outliers injected so far
+func (o *OutlierWrapper[T]) Injected() uint64 {
+	return o.injected.Load()
+}
+
+// LagFunction passes the seeder input through a first-order low-pass
+// filter before handing it to an inner function, so readings trail step
+// changes the way a sensor with thermal mass or filter delay does. Unlike a
+// moving average, which weighs a fixed window equally, the filter responds
+// exponentially: after a step the remaining gap shrinks by a constant factor
+// per time constant. The first input is passed through unfiltered.
+type LagFunction[T any] struct {
+	inner        SensorFunction[T]
+	alpha        float64       // Fixed smoothing factor, when timeConstant is 0
+	timeConstant time.Duration // Time to close 63% of a step, when set
+	mutex        sync.Mutex    // Guards the filter state
+	value        float64
+	last         time.Time
+	started      bool
+}
+
+// NewLagFunction creates a lagging function that closes a fraction alpha of
+// the gap to the input on every call. It panics if alpha is not in (0, 1].
+func NewLagFunction[T any](inner SensorFunction[T], alpha float64) *LagFunction[T] {
+	checkLagAlpha(alpha)
+	return &LagFunction[T]{
+		inner: inner,
+		alpha: alpha,
+	}
+}
+
+// NewLagFunctionWithTimeConstant creates a lagging function whose response
+// depends on the time between readings rather than their count: after a
+// step, the output closes about 63% of the gap in timeConstant, whatever the
+// production rate. It panics if timeConstant is not positive.
+func NewLagFunctionWithTimeConstant[T any](inner SensorFunction[T], timeConstant time.Duration) *LagFunction[T] {
+	if timeConstant <= 0 {
+		panic(fmt.Sprintf("engine: lag time constant must be positive, got %v", timeConstant))
+	}
+	return &LagFunction[T]{
+		inner:        inner,
+		timeConstant: timeConstant,
+	}
+}
+
+// Generate filters input and returns the inner function's output for it
+func (l *LagFunction[T]) Generate(input float64, timestamp time.Time) T {
+	l.mutex.Lock()
+	switch {
+	case !l.started:
+		l.value, l.started = input, true
+	case l.timeConstant > 0:
+		if elapsed := timestamp.Sub(l.last); elapsed > 0 {
+			alpha := 1 - math.Exp(-float64(elapsed)/float64(l.timeConstant))
+			l.value += (input - l.value) * alpha
+		}
+	default:
+		l.value += (input - l.value) * l.alpha
+	}
+	l.last = timestamp
+	value := l.value
+	l.mutex.Unlock()
+	return l.inner.Generate(value, timestamp)
+}
+
+// ScaleOutlier returns an inject function that multiplies values by factor
+func ScaleOutlier[N Number](factor N) func(N) N {
+	return func(v N) N {
+		return v * factor
+	}
+}
+
+// ReplaceOutlier returns an inject function that replaces values with a fixed extreme
+func ReplaceOutlier[N Number](extreme N) func(N) N {
+	return func(N) N {
+		return extreme
+	}
+}
+
+// Category is one possible output of a CategoricalFunction and its relative
+// weight
+type Category[T any] struct {
+	Value  T
+	Weight float64
+}
+
+// CategoricalFunction maps a seeder input in [0, 1] onto a set of weighted
+// categories. The input range is split into consecutive intervals sized by
+// the weights, in the order the categories are given, so a uniform input
+// selects each category with probability proportional to its weight and an
+// increasing input walks through the categories in order. Inputs outside
+// [0, 1] are clamped.
+type CategoricalFunction[T any] struct {
+	categories []Category[T]
+	cumulative []float64 // Upper bound of each category's interval
+}
+
+// NewCategoricalFunction creates a categorical function. Weights need not sum
+// to one; negative weights are treated as zero.
+func NewCategoricalFunction[T any](categories ...Category[T]) *CategoricalFunction[T] {
+	total := 0.0
+	for _, c := range categories {
+		total += max(c.Weight, 0)
+	}
+
+	cumulative := make([]float64, len(categories))
+	running := 0.0
+	for i, c := range categories {
+		running += max(c.Weight, 0)
+		if total > 0 {
+			cumulative[i] = running / total
+		}
+	}
+
+	return &CategoricalFunction[T]{
+		categories: append([]Category[T](nil), categories...),
+		cumulative: cumulative,
+	}
+}
+
+// Generate returns the category selected by input
+func (c *CategoricalFunction[T]) Generate(input float64, timestamp time.Time) T {
+	return c.Pick(input)
+}
+
+// Pick returns the category selected by input, for use inside functions that
+// build a larger reading
+func (c *CategoricalFunction[T]) Pick(input float64) T {
+	var zero T
+	if len(c.categories) == 0 {
+		return zero
+	}
+
+	for i, bound := range c.cumulative {
+		if input < bound {
+			return c.categories[i].Value
+		}
+	}
+	// Input at or above 1 selects the last category with a positive weight
+	for i := len(c.categories) - 1; i >= 0; i-- {
+		if c.categories[i].Weight > 0 {
+			return c.categories[i].Value
+		}
+	}
+	return c.categories[0].Value
+}
+
+// MixtureComponent is one sensor function of a MixtureFunction and its
+// relative weight
+type MixtureComponent[T any] struct {
+	Function SensorFunction[T]
+	Weight   float64
+}
+
+// MixtureFunction models a heterogeneous device population: on every call it
+// picks one of its functions at random, with probability proportional to its
+// weight, and returns that function's output. The seeder input is passed
+// through unchanged, so the choice does not consume it.
+type MixtureFunction[T any] struct {
+	picker *CategoricalFunction[SensorFunction[T]]
+	rng    *rand.Rand // nil for the global source
+	mutex  sync.Mutex // Guards rng, whose sources are not safe for concurrent use
+}
+
+// NewMixtureFunction creates a mixture of sensor functions. Weights need not
+// sum to one; negative weights are treated as zero.
+func NewMixtureFunction[T any](components ...MixtureComponent[T]) *MixtureFunction[T] {
+	categories := make([]Category[SensorFunction[T]], len(components))
+	for i, c := range components {
+		categories[i] = Category[SensorFunction[T]]{Value: c.Function, Weight: c.Weight}
+	}
+	return &MixtureFunction[T]{picker: NewCategoricalFunction(categories...)}
+}
+
+// NewMixtureFunctionWithSource creates a mixture of sensor functions that
+// draws its choices from src, so a seeded source selects a reproducible
+// sequence of functions
+func NewMixtureFunctionWithSource[T any](src rand.Source, components ...MixtureComponent[T]) *MixtureFunction[T] {
+	m := NewMixtureFunction(components...)
+	m.rng = rand.New(src)
+	return m
+}
+
+// Generate picks a function by weight and returns its output
+func (m *MixtureFunction[T]) Generate(input float64, timestamp time.Time) T {
+	draw := 0.0
+	if m.rng != nil {
+		m.mutex.Lock()
+		draw = m.rng.Float64()
+		m.mutex.Unlock()
+	} else {
+		draw = rand.Float64()
+	}
+
+	function := m.picker.Pick(draw)
+	if function == nil {
+		var zero T
+		return zero
+	}
+	return function.Generate(input, timestamp)
+}
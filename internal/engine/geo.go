@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// earthRadiusMeters is the mean Earth radius used for distance calculations
+const earthRadiusMeters = 6371000.0
+
+// GeoPoint is a latitude/longitude coordinate in degrees
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// GeoReading is a value tagged with the position where it was measured
+type GeoReading[T any] struct {
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+	Value T       `json:"value"`
+}
+
+// GeoInterpolation selects how positions between waypoints are computed
+type GeoInterpolation int
+
+const (
+	GeoInterpolationLinear      GeoInterpolation = iota // Straight line in lat/lng space
+	GeoInterpolationGreatCircle                         // Shortest path on the sphere
+)
+
+// GeoPathConfig describes a route travelled at constant speed
+type GeoPathConfig struct {
+	Waypoints     []GeoPoint
+	Speed         float64 // Meters per second
+	Loop          bool    // Return to the first waypoint and repeat; otherwise hold at the last
+	Interpolation GeoInterpolation
+}
+
+// GeoFunction wraps a sensor function and tags each reading with a position
+// moving along a path over the time elapsed since the first reading. Time
+// is taken from the reading timestamps, so tracks follow the engine's Clock
+// and the virtual time of RunFor and Backfill.
+type GeoFunction[T any] struct {
+	inner      SensorFunction[T]
+	config     GeoPathConfig
+	waypoints  []GeoPoint
+	cumulative []float64 // Distance from the first waypoint to each waypoint, in meters
+
+	mutex   sync.Mutex // Guards start
+	start   time.Time  // Timestamp of the first reading
+	started bool
+}
+
+// NewGeoFunction creates a location-tagging sensor function
+func NewGeoFunction[T any](inner SensorFunction[T], config GeoPathConfig) *GeoFunction[T] {
+	waypoints := append([]GeoPoint(nil), config.Waypoints...)
+	if config.Loop && len(waypoints) > 1 {
+		waypoints = append(waypoints, waypoints[0])
+	}
+
+	cumulative := make([]float64, len(waypoints))
+	for i := 1; i < len(waypoints); i++ {
+		cumulative[i] = cumulative[i-1] + haversine(waypoints[i-1], waypoints[i])
+	}
+
+	return &GeoFunction[T]{
+		inner:      inner,
+		config:     config,
+		waypoints:  waypoints,
+		cumulative: cumulative,
+	}
+}
+
+// Generate produces the inner reading tagged with the position reached at
+// timestamp. The first call starts the path at its first waypoint.
+func (g *GeoFunction[T]) Generate(input float64, timestamp time.Time) GeoReading[T] {
+	g.mutex.Lock()
+	if !g.started {
+		g.start, g.started = timestamp, true
+	}
+	start := g.start
+	g.mutex.Unlock()
+
+	pos := g.PositionAt(timestamp.Sub(start))
+	return GeoReading[T]{
+		Lat:   pos.Lat,
+		Lng:   pos.Lng,
+		Value: g.inner.Generate(input, timestamp),
+	}
+}
+
+// PositionAt returns the position reached after travelling for elapsed
+func (g *GeoFunction[T]) PositionAt(elapsed time.Duration) GeoPoint {
+	if len(g.waypoints) == 0 {
+		return GeoPoint{}
+	}
+
+	total := g.cumulative[len(g.cumulative)-1]
+	if total == 0 {
+		return g.waypoints[0]
+	}
+
+	distance := math.Max(0, g.config.Speed*elapsed.Seconds())
+	switch {
+	case g.config.Loop:
+		distance = math.Mod(distance, total)
+	case distance >= total:
+		return g.waypoints[len(g.waypoints)-1]
+	}
+
+	// Find the segment containing distance
+	i := 1
+	for i < len(g.cumulative)-1 && g.cumulative[i] <= distance {
+		i++
+	}
+	segment := g.cumulative[i] - g.cumulative[i-1]
+	if segment == 0 {
+		return g.waypoints[i]
+	}
+	fraction := (distance - g.cumulative[i-1]) / segment
+
+	if g.config.Interpolation == GeoInterpolationGreatCircle {
+		return slerp(g.waypoints[i-1], g.waypoints[i], fraction)
+	}
+	return GeoPoint{
+		Lat: g.waypoints[i-1].Lat + (g.waypoints[i].Lat-g.waypoints[i-1].Lat)*fraction,
+		Lng: g.waypoints[i-1].Lng + (g.waypoints[i].Lng-g.waypoints[i-1].Lng)*fraction,
+	}
+}
+
+// haversine returns the great-circle distance between two points in meters
+func haversine(a, b GeoPoint) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := radians(b.Lng - a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// slerp interpolates along the great circle between a and b
+func slerp(a, b GeoPoint, fraction float64) GeoPoint {
+	lat1, lng1 := radians(a.Lat), radians(a.Lng)
+	lat2, lng2 := radians(b.Lat), radians(b.Lng)
+
+	angle := haversine(a, b) / earthRadiusMeters
+	if angle == 0 {
+		return a
+	}
+
+	wa := math.Sin((1-fraction)*angle) / math.Sin(angle)
+	wb := math.Sin(fraction*angle) / math.Sin(angle)
+
+	x := wa*math.Cos(lat1)*math.Cos(lng1) + wb*math.Cos(lat2)*math.Cos(lng2)
+	y := wa*math.Cos(lat1)*math.Sin(lng1) + wb*math.Cos(lat2)*math.Sin(lng2)
+	z := wa*math.Sin(lat1) + wb*math.Sin(lat2)
+
+	return GeoPoint{
+		Lat: degrees(math.Atan2(z, math.Sqrt(x*x+y*y))),
+		Lng: degrees(math.Atan2(y, x)),
+	}
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EngineDefinitions returns the engines defined by the file: the entries of
+// Engines, or the file itself when it defines a single engine. Unnamed
+// engines are named engine-1, engine-2 and so on, in file order.
+func (c *ConfigFile) EngineDefinitions() []ConfigFile {
+	definitions := c.Engines
+	if len(definitions) == 0 {
+		definitions = []ConfigFile{*c}
+	}
+
+	named := make([]ConfigFile, len(definitions))
+	for i, definition := range definitions {
+		if definition.Name == "" {
+			definition.Name = fmt.Sprintf("engine-%d", i+1)
+		}
+		named[i] = definition
+	}
+	return named
+}
+
+// EngineGroup runs several engines side by side under one context. The
+// engines are independent: one that stops with an error leaves the others
+// running, and cancelling the context stops them all.
+type EngineGroup[T any] struct {
+	Names   []string
+	Engines []*Engine[T]
+}
+
+// EngineBuilder returns the sensor function and publisher of one engine
+// definition. Each engine needs its own publisher, since the engine closes
+// it on shutdown.
+type EngineBuilder[T any] func(definition *ConfigFile) (SensorFunction[T], Publisher[T], error)
+
+// CreateEnginesFromConfig creates a group with one engine per definition in
+// the file, in file order. A file defining a single engine gives a group of
+// one. If any definition is invalid, the publishers built so far are closed
+// and an error naming the definition is returned.
+func CreateEnginesFromConfig[T any](filename string, build EngineBuilder[T]) (*EngineGroup[T], error) {
+	configFile, err := LoadConfigFromFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return NewEngineGroup(configFile, build)
+}
+
+// NewEngineGroup creates a group from a loaded config file, like
+// CreateEnginesFromConfig
+func NewEngineGroup[T any](configFile *ConfigFile, build EngineBuilder[T]) (*EngineGroup[T], error) {
+	group := &EngineGroup[T]{}
+	seen := make(map[string]bool)
+	for _, definition := range configFile.EngineDefinitions() {
+		e, err := createEngine(&definition, build)
+		if err == nil && seen[definition.Name] {
+			e.publisher.Close()
+			err = errors.New("duplicate engine name")
+		}
+		if err != nil {
+			group.closePublishers()
+			return nil, fmt.Errorf("engine %s: %w", definition.Name, err)
+		}
+		seen[definition.Name] = true
+		group.Names = append(group.Names, definition.Name)
+		group.Engines = append(group.Engines, e)
+	}
+	return group, nil
+}
+
+// createEngine builds the engine of one definition
+func createEngine[T any](definition *ConfigFile, build EngineBuilder[T]) (*Engine[T], error) {
+	if err := checkSerializable[T](definition.Output.Type); err != nil {
+		return nil, err
+	}
+	engineConfig, err := definition.ToEngineConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert engine config: %w", err)
+	}
+	seeder, err := definition.CreateSeeder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create seeder: %w", err)
+	}
+	function, publisher, err := build(definition)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(engineConfig, seeder, function, publisher), nil
+}
+
+// closePublishers closes the publishers of engines that never started
+func (g *EngineGroup[T]) closePublishers() {
+	for _, e := range g.Engines {
+		e.publisher.Close()
+	}
+}
+
+// Start runs every engine concurrently until ctx is done and returns once
+// all of them have shut down. The errors of engines that failed are joined,
+// each prefixed with the engine's name; onExit, if not nil, is also called
+// as each engine stops, so failures can be reported while the others keep
+// running.
+func (g *EngineGroup[T]) Start(ctx context.Context, onExit func(name string, err error)) error {
+	errs := make([]error, len(g.Engines))
+
+	var wg sync.WaitGroup
+	for i, e := range g.Engines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := e.Start(ctx)
+			if onExit != nil {
+				onExit(g.Names[i], err)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("engine %s: %w", g.Names[i], err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stats returns the counters of all engines added together. Latencies are
+// averaged over the engines that published, and CurrentRate is the
+// shortest production interval of the group.
+func (g *EngineGroup[T]) Stats() Stats {
+	var total Stats
+	var publishing, backends int
+	var latency, backendLatency time.Duration
+	for _, e := range g.Engines {
+		stats := e.Stats()
+		total.Generated += stats.Generated
+		total.Heartbeats += stats.Heartbeats
+		total.Outages += stats.Outages
+		total.OfflineTicks += stats.OfflineTicks
+		total.Published += stats.Published
+		total.Batches += stats.Batches
+		total.Singles += stats.Singles
+		total.PublishErrors += stats.PublishErrors
+		total.Dropped += stats.Dropped
+		total.SlowCycles += stats.SlowCycles
+		total.Stalls += stats.Stalls
+		total.Filtered += stats.Filtered
+		total.RangeViolations += stats.RangeViolations
+		total.Unchanged += stats.Unchanged
+		total.OKCount += stats.OKCount
+		total.NoisyCount += stats.NoisyCount
+		total.PartialCount += stats.PartialCount
+		total.CorruptCount += stats.CorruptCount
+		total.BytesPublished += stats.BytesPublished
+		total.Backlog += stats.Backlog
+		total.PrimaryPublished += stats.PrimaryPublished
+		total.FallbackPublished += stats.FallbackPublished
+		if stats.AvgPublishLatency > 0 {
+			latency += stats.AvgPublishLatency
+			publishing++
+		}
+		if stats.BackendLatency > 0 {
+			backendLatency += stats.BackendLatency
+			backends++
+		}
+		total.BatchSize = max(total.BatchSize, stats.BatchSize)
+		if total.CurrentRate == 0 || stats.CurrentRate < total.CurrentRate {
+			total.CurrentRate = stats.CurrentRate
+		}
+	}
+	if publishing > 0 {
+		total.AvgPublishLatency = latency / time.Duration(publishing)
+	}
+	if backends > 0 {
+		total.BackendLatency = backendLatency / time.Duration(backends)
+	}
+	return total
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const groupConfig = `{
+	"engines": [
+		{
+			"name": "healthy",
+			"engine": {"production_rate": "5ms", "batch_size": 5, "batch_timeout": "20ms", "max_workers": 1},
+			"seeder": {"type": "linear", "params": {"slope": 1}},
+			"output": {"type": "ok"}
+		},
+		{
+			"name": "broken",
+			"engine": {"production_rate": "5ms", "batch_size": 1, "batch_timeout": "20ms", "max_workers": 1, "max_consecutive_publish_errors": 2},
+			"seeder": {"type": "random"},
+			"output": {"type": "failing"}
+		}
+	]
+}`
+
+// writeConfig writes a config file into a temporary directory
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestEngineGroup_FailureIsolation(t *testing.T) {
+	healthy := NewMockPublisher[float64]()
+	group, err := CreateEnginesFromConfig(writeConfig(t, groupConfig), func(definition *ConfigFile) (SensorFunction[float64], Publisher[float64], error) {
+		if definition.Output.Type == "failing" {
+			return NewTestSensorFunction(1), &failingMockPublisher[float64]{}, nil
+		}
+		return NewTestSensorFunction(1), healthy, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateEnginesFromConfig failed: %v", err)
+	}
+	if strings.Join(group.Names, ",") != "healthy,broken" {
+		t.Fatalf("Expected engines in file order, got %v", group.Names)
+	}
+
+	var mutex sync.Mutex
+	exited := map[string]time.Duration{}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = group.Start(ctx, func(name string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		exited[name] = time.Since(start)
+	})
+
+	if !errors.Is(err, ErrTooManyPublishErrors) || !strings.Contains(err.Error(), "engine broken:") {
+		t.Errorf("Expected the broken engine's error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "healthy") {
+		t.Errorf("The healthy engine should stop without an error, got %v", err)
+	}
+	if exited["broken"] >= 250*time.Millisecond {
+		t.Errorf("Expected the broken engine to stop early, it stopped after %v", exited["broken"])
+	}
+	if exited["healthy"] < 250*time.Millisecond {
+		t.Errorf("Expected the healthy engine to run until cancelled, it stopped after %v", exited["healthy"])
+	}
+	if !healthy.closed {
+		t.Error("Expected the healthy engine's publisher to be closed on shutdown")
+	}
+
+	total := group.Stats()
+	first, second := group.Engines[0].Stats(), group.Engines[1].Stats()
+	if total.Generated != first.Generated+second.Generated || total.PublishErrors != second.PublishErrors {
+		t.Errorf("Expected summed stats, got %+v from %+v and %+v", total, first, second)
+	}
+	if total.Published == 0 || total.Published != uint64(healthy.GetTotalDataPoints()) {
+		t.Errorf("Expected %d published readings, got %d", healthy.GetTotalDataPoints(), total.Published)
+	}
+}
+
+func TestCreateEnginesFromConfig_InvalidDefinition(t *testing.T) {
+	config := `{"engines": [
+		{"engine": {"production_rate": "5ms", "batch_size": 1, "batch_timeout": "20ms", "max_workers": 1}, "seeder": {"type": "time"}},
+		{"engine": {"production_rate": "5ms", "batch_size": 1, "batch_timeout": "20ms", "max_workers": 1}, "seeder": {"type": "bogus"}}
+	]}`
+
+	var built []*MockPublisher[float64]
+	_, err := CreateEnginesFromConfig(writeConfig(t, config), func(*ConfigFile) (SensorFunction[float64], Publisher[float64], error) {
+		publisher := NewMockPublisher[float64]()
+		built = append(built, publisher)
+		return NewTestSensorFunction(1), publisher, nil
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "engine engine-2:") {
+		t.Fatalf("Expected an error naming engine-2, got %v", err)
+	}
+	if len(built) != 1 || !built[0].closed {
+		t.Error("Expected the publisher of the valid engine to be closed")
+	}
+}
+
+func TestCreateEnginesFromConfig_SingleEngine(t *testing.T) {
+	config := `{"engine": {"production_rate": "5ms", "batch_size": 1, "batch_timeout": "20ms", "max_workers": 1}, "seeder": {"type": "time"}}`
+
+	group, err := CreateEnginesFromConfig(writeConfig(t, config), func(*ConfigFile) (SensorFunction[float64], Publisher[float64], error) {
+		return NewTestSensorFunction(1), NewMockPublisher[float64](), nil
+	})
+	if err != nil {
+		t.Fatalf("CreateEnginesFromConfig failed: %v", err)
+	}
+	if len(group.Engines) != 1 || group.Names[0] != "engine-1" {
+		t.Errorf("Expected a group of one named engine-1, got %v", group.Names)
+	}
+}
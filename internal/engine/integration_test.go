@@ -2,6 +2,10 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -122,90 +126,67 @@ func TestEngine_Integration_CustomStruct(t *testing.T) {
 }
 
 func TestEngine_Integration_Batching(t *testing.T) {
-	// Test batching behavior
-	config := DefaultConfig()
-	config.ProductionRate = 5 * time.Millisecond
-	config.BatchSize = 10
-	config.BatchTimeout = 25 * time.Millisecond
-
-	seeder := NewLinearSeeder(1.0, 0.0)
-
-	sensorFunc := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
-		return input
-	})
-
-	publisher := &mockIntegrationPublisher[float64]{
-		data:       make([]SensorData[float64], 0),
-		batchSizes: make([]int, 0),
+	tests := []struct {
+		name         string
+		batchTimeout time.Duration
+		ticks        int
+		batchSizes   []int
+	}{
+		// The timeout fires every 5 readings; the first batch is one short
+		// because the timer starts a tick before the first reading
+		{"Timeout", 25 * time.Millisecond, 40, []int{4, 5, 5, 5, 5, 5, 5, 5, 1}},
+		{"Size", time.Second, 25, []int{10, 10, 5}},
 	}
 
-	engine := NewEngine(config, seeder, sensorFunc, publisher)
-
-	// Run long enough for multiple batches
-	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer cancel()
-
-	err := engine.Start(ctx)
-	if err != nil {
-		t.Errorf("Engine start failed: %v", err)
-	}
-
-	// Verify batching occurred
-	if len(publisher.batchSizes) == 0 {
-		t.Error("No batches were published")
-	}
-
-	// Verify batch sizes are reasonable
-	for _, batchSize := range publisher.batchSizes {
-		if batchSize <= 0 || batchSize > config.BatchSize {
-			t.Errorf("Batch size %d out of expected range [1, %d]", batchSize, config.BatchSize)
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.ProductionRate = 5 * time.Millisecond
+			config.BatchSize = 10
+			config.BatchTimeout = tt.batchTimeout
+
+			h := newHarness[float64](t, config)
+			sensorFunc := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
+				return input
+			})
+			publisher := h.run(NewLinearSeederWithClock(1.0, 0.0, h.Clock), sensorFunc, tt.ticks)
+
+			if !slices.Equal(publisher.batchSizes, tt.batchSizes) {
+				t.Errorf("Expected batch sizes %v, got %v", tt.batchSizes, publisher.batchSizes)
+			}
+			for i, data := range publisher.data {
+				elapsed := time.Duration(i+1) * config.ProductionRate
+				if data.ID != fmt.Sprintf("sensor-%d", i) || data.Data != elapsed.Seconds() {
+					t.Errorf("Reading %d: expected sensor-%d with value %v, got %s with %v", i, i, elapsed.Seconds(), data.ID, data.Data)
+				}
+			}
+		})
 	}
 }
 
 func TestEngine_Integration_QualitySimulation(t *testing.T) {
-	// Test quality simulation
 	config := DefaultConfig()
 	config.ProductionRate = 10 * time.Millisecond
 	config.BatchSize = 20
 
-	seeder := NewTimeSeeder(1.0, 0.1, 0.0)
-
+	h := newHarness[float64](t, config)
 	sensorFunc := NewLambdaSensorFunction(func(input float64, timestamp time.Time) float64 {
 		return input
 	})
+	publisher := h.run(NewTimeSeederWithClock(1.0, 0.1, 0.0, h.Clock), sensorFunc, 100)
 
-	publisher := &mockIntegrationPublisher[float64]{
-		data: make([]SensorData[float64], 0),
-	}
-
-	engine := NewEngine(config, seeder, sensorFunc, publisher)
-
-	// Run long enough to see different quality levels
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
-	defer cancel()
-
-	err := engine.Start(ctx)
-	if err != nil {
-		t.Errorf("Engine start failed: %v", err)
-	}
-
-	// Verify quality levels are present
 	qualityCounts := make(map[Quality]int)
 	for _, data := range publisher.data {
 		qualityCounts[data.Quality]++
 	}
 
-	// Should have at least some data with different quality levels
-	if len(qualityCounts) == 0 {
-		t.Error("No quality levels found")
+	// The harness seed draws these counts from the default 92/5/2/1 profile
+	want := map[Quality]int{QualityOK: 90, QualityNoisy: 9, QualityPartial: 1}
+	if !maps.Equal(qualityCounts, want) {
+		t.Errorf("Expected quality counts %v, got %v", want, qualityCounts)
 	}
-
-	// Most data should be OK quality
-	okCount := qualityCounts[QualityOK]
-	totalCount := len(publisher.data)
-	if totalCount > 0 && float64(okCount)/float64(totalCount) < 0.5 {
-		t.Errorf("Expected at least 50%% OK quality, got %.2f%%", float64(okCount)/float64(totalCount)*100)
+	if want := []int{20, 20, 20, 20, 20}; !slices.Equal(publisher.batchSizes, want) {
+		t.Errorf("Expected batch sizes %v, got %v", want, publisher.batchSizes)
 	}
 }
 
@@ -284,19 +265,24 @@ func TestEngine_Integration_ErrorHandling(t *testing.T) {
 	}
 }
 
-// Mock publisher for integration tests
+// Mock publisher for integration tests, safe for concurrent use
 type mockIntegrationPublisher[T any] struct {
+	mutex      sync.Mutex
 	data       []SensorData[T]
 	batchSizes []int
 }
 
 func (m *mockIntegrationPublisher[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.data = append(m.data, data)
 	m.batchSizes = append(m.batchSizes, 1)
 	return nil
 }
 
 func (m *mockIntegrationPublisher[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.data = append(m.data, data...)
 	m.batchSizes = append(m.batchSizes, len(data))
 	return nil
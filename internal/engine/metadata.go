@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataPlaceholder matches {name} and {env:NAME} placeholders, and the
+// escaped braces {{ and }}
+var metadataPlaceholder = regexp.MustCompile(`\{\{|\}\}|\{([a-z_]+)(:[^{}]*)?\}`)
+
+// ResolveMetadata fills runtime values into the placeholders of metadata
+// values and returns the result as a new map. The supported placeholders are
+//
+//	{hostname}    the host name reported by the kernel
+//	{pid}         the process ID
+//	{start_time}  the time of resolution in RFC 3339 format, UTC
+//	{env:NAME}    the value of environment variable NAME
+//
+// Unknown placeholders and unset environment variables are errors, so a
+// typo does not silently produce an empty label. Write {{ and }} for
+// literal braces, as in "{{pid}}" for the text {pid}.
+func ResolveMetadata(metadata map[string]string) (map[string]string, error) {
+	return resolveMetadata(metadata, time.Now(), os.LookupEnv)
+}
+
+// resolveMetadata resolves metadata with a fixed start time and environment
+func resolveMetadata(metadata map[string]string, start time.Time, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+
+	resolved := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		var err error
+		resolved[key] = metadataPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+			if placeholder == "{{" || placeholder == "}}" {
+				return placeholder[:1]
+			}
+			match := metadataPlaceholder.FindStringSubmatch(placeholder)
+			name, arg := match[1], strings.TrimPrefix(match[2], ":")
+			hasArg := match[2] != ""
+
+			var result string
+			switch {
+			case name == "hostname" && !hasArg:
+				hostname, hostErr := os.Hostname()
+				if hostErr != nil && err == nil {
+					err = fmt.Errorf("metadata %q: %w", key, hostErr)
+				}
+				result = hostname
+			case name == "pid" && !hasArg:
+				result = strconv.Itoa(os.Getpid())
+			case name == "start_time" && !hasArg:
+				result = start.UTC().Format(time.RFC3339)
+			case name == "env" && arg != "":
+				env, ok := lookupEnv(arg)
+				if !ok && err == nil {
+					err = fmt.Errorf("metadata %q: environment variable %s is not set", key, arg)
+				}
+				result = env
+			default:
+				if err == nil {
+					err = fmt.Errorf("metadata %q: unknown placeholder %s", key, placeholder)
+				}
+			}
+			return result
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
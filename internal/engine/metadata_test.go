@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveMetadata_Placeholders(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("No hostname: %v", err)
+	}
+	env := map[string]string{"SITE": "north"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	resolved, err := resolveMetadata(map[string]string{
+		"producer": "{hostname}/{pid}",
+		"started":  "{start_time}",
+		"site":     "plant-{env:SITE}",
+		"version":  "1.0",
+		"literal":  "{{pid}} is {pid}, {{",
+	}, start, lookup)
+	if err != nil {
+		t.Fatalf("Failed to resolve metadata: %v", err)
+	}
+
+	want := map[string]string{
+		"producer": hostname + "/" + strconv.Itoa(os.Getpid()),
+		"started":  "2024-01-02T02:04:05Z",
+		"site":     "plant-north",
+		"version":  "1.0",
+		"literal":  "{pid} is " + strconv.Itoa(os.Getpid()) + ", {",
+	}
+	for key, value := range want {
+		if resolved[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, resolved[key])
+		}
+	}
+}
+
+func TestResolveMetadata_Errors(t *testing.T) {
+	lookup := func(string) (string, bool) { return "", false }
+
+	tests := map[string]string{
+		"{env:MISSING}": "MISSING is not set",
+		"{user}":        "unknown placeholder {user}",
+		"{pid:1}":       "unknown placeholder {pid:1}",
+	}
+	for value, message := range tests {
+		_, err := resolveMetadata(map[string]string{"key": value}, time.Now(), lookup)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: expected error containing %q, got %v", value, message, err)
+		}
+	}
+}
+
+func TestConfigFile_ToEngineConfig_MetadataEnvelope(t *testing.T) {
+	t.Setenv("GOSENSE_TEST_SITE", "north")
+
+	config := DefaultConfigFile()
+	config.Output.Metadata = map[string]string{"site": "{env:GOSENSE_TEST_SITE}", "note": "{unused}"}
+
+	// Metadata without an envelope is never published, so it is not resolved
+	engineConfig, err := config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.Envelope != nil {
+		t.Error("Expected no envelope unless output.envelope is set")
+	}
+
+	delete(config.Output.Metadata, "note")
+	config.Output.Envelope = true
+	engineConfig, err = config.ToEngineConfig()
+	if err != nil {
+		t.Fatalf("Failed to convert engine config: %v", err)
+	}
+	if engineConfig.Envelope == nil || engineConfig.Envelope.Metadata["site"] != "north" {
+		t.Errorf("Expected envelope metadata site=north, got %+v", engineConfig.Envelope)
+	}
+
+	config.Output.Metadata["host"] = "{host}"
+	if _, err := config.ToEngineConfig(); err == nil {
+		t.Error("Expected error for an unknown placeholder")
+	}
+}
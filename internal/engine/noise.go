@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ar1Noise is an AR(1) noise process: each value is rho times the previous
+// one plus a normal innovation with standard deviation stdDev
+type ar1Noise struct {
+	rho    float64
+	stdDev float64
+	mutex  sync.Mutex // Guards value, since Generate may run alongside Start
+	value  float64
+}
+
+// next advances the process by one step with a standard normal draw
+func (n *ar1Noise) next(draw float64) float64 {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.value = n.rho*n.value + n.stdDev*draw
+	return n.value
+}
+
+// WithAR1Noise adds autocorrelated noise to every generated reading:
+// noise[t] = rho*noise[t-1] + eps, where eps is normal with standard
+// deviation stdDev. Real sensor noise drifts rather than jumping
+// independently between readings, which makes this the better input for
+// testing filters and smoothers. The process carries over between readings
+// and runs, draws from Config.RandSource and is part of the engine's
+// MarshalState. For integer types the noise is truncated towards zero
+// before it is added. It panics unless -1 < rho < 1 and stdDev >= 0.
+func WithAR1Noise[N Number](rho, stdDev float64) Option[N] {
+	if !(rho > -1 && rho < 1) || !(stdDev >= 0) {
+		panic(fmt.Sprintf("engine: AR(1) noise needs -1 < rho < 1 and stdDev >= 0, got rho %v and stdDev %v", rho, stdDev))
+	}
+	return func(e *Engine[N]) {
+		e.noise = &ar1Noise{rho: rho, stdDev: stdDev}
+		e.addOffset = func(v N, noise float64) N {
+			return v + N(noise)
+		}
+	}
+}
+
+// withNoise returns data with the next noise value added, if noise is
+// enabled
+func (e *Engine[T]) withNoise(data T) T {
+	if e.noise == nil {
+		return data
+	}
+	return e.addOffset(data, e.noise.next(e.randNormFloat64()))
+}
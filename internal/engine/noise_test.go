@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// lag1Autocorrelation estimates the lag-1 autocorrelation of values
+func lag1Autocorrelation(values []float64) float64 {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var num, den float64
+	for i, v := range values {
+		den += (v - mean) * (v - mean)
+		if i > 0 {
+			num += (v - mean) * (values[i-1] - mean)
+		}
+	}
+	return num / den
+}
+
+func TestWithAR1Noise_Autocorrelation(t *testing.T) {
+	for _, rho := range []float64{0, 0.5, 0.9} {
+		h := newHarness[float64](t, Config{
+			ProductionRate: time.Millisecond,
+			BatchSize:      100,
+			MaxWorkers:     1,
+		})
+		// A constant zero signal leaves only the noise
+		published := h.run(NewTestSeeder([]float64{0}), NewTestSensorFunction(1), 20000, WithAR1Noise[float64](rho, 2))
+
+		values := make([]float64, len(published.data))
+		for i, d := range published.data {
+			values[i] = d.Data
+		}
+		if got := lag1Autocorrelation(values); got < rho-0.05 || got > rho+0.05 {
+			t.Errorf("rho %v: estimated lag-1 autocorrelation %.3f", rho, got)
+		}
+	}
+}
+
+func TestWithAR1Noise_InvalidParameters(t *testing.T) {
+	for _, params := range [][2]float64{{1, 1}, {-1, 1}, {0.5, -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for rho %v and stdDev %v", params[0], params[1])
+				}
+			}()
+			WithAR1Noise[float64](params[0], params[1])
+		}()
+	}
+}
+
+// TestEngine_GenerateDuringStartWithNoise runs with -race to check that the
+// AR(1) noise state is safe to share with Generate
+func TestEngine_GenerateDuringStartWithNoise(t *testing.T) {
+	config := Config{ProductionRate: time.Millisecond, BatchSize: 10, BatchTimeout: 5 * time.Millisecond, MaxWorkers: 1}
+	engine := NewEngineWithOptions(config, NewRandomSeeder(0, 1), NewTestSensorFunction(1), discardPublisher[float64]{}, WithAR1Noise[float64](0.9, 0.1))
+	generateDuringStart(t, engine)
+}
+
+// generateDuringStart calls Generate in a loop while engine runs for a short
+// while, so that -race sees any state they share unguarded
+func generateDuringStart[T any](t *testing.T, engine *Engine[T]) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			engine.Generate()
+		}
+	}()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	<-done
+}
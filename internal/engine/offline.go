@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// OfflineWindow is a fixed outage, as offsets from when the engine starts
+type OfflineWindow struct {
+	From time.Duration // Offset at which the sensor goes offline
+	To   time.Duration // Offset at which it reconnects
+}
+
+// OfflineSchedule takes the simulated sensor off the network for extended
+// periods, for testing how consumers handle outages rather than the odd
+// missing reading. While offline nothing is generated, heartbeats included,
+// and the production ticks that pass are counted in Stats.OfflineTicks.
+// Outages come from fixed Windows, from a random failure model, or both:
+// with MeanTimeToFailure set, the sensor stays up for exponentially
+// distributed periods with that mean and then stays down for periods with a
+// mean of MeanTimeToRepair, drawn from Config.RandSource. Offsets are
+// measured like RateEnvelope: wall time since Start, or virtual time since
+// the first reading for RunFor and Backfill.
+type OfflineSchedule struct {
+	Windows           []OfflineWindow
+	MeanTimeToFailure time.Duration // Mean time online between random outages (0 for none)
+	MeanTimeToRepair  time.Duration // Mean duration of a random outage
+
+	// Markers emits a QualityOffline reading when an outage starts and a
+	// QualityOnline reading when the sensor reconnects, with a zero Data
+	// value, so consumers can tell an outage from a quiet sensor
+	Markers bool
+}
+
+// Validate checks that windows end after they start and the random model
+// has a repair time
+func (s *OfflineSchedule) Validate() error {
+	for i, w := range s.Windows {
+		if w.From < 0 || w.To <= w.From {
+			return fmt.Errorf("offline window %d must satisfy 0 <= from < to, got %v to %v", i, w.From, w.To)
+		}
+	}
+	if s.MeanTimeToFailure < 0 || s.MeanTimeToRepair < 0 {
+		return fmt.Errorf("mean time to failure and repair must not be negative, got %v and %v", s.MeanTimeToFailure, s.MeanTimeToRepair)
+	}
+	if s.MeanTimeToFailure > 0 && s.MeanTimeToRepair == 0 {
+		return fmt.Errorf("mean time to repair must be positive with a mean time to failure of %v", s.MeanTimeToFailure)
+	}
+	return nil
+}
+
+// isStatusMarker reports whether q marks a synthetic status reading rather
+// than a measurement
+func isStatusMarker(q Quality) bool {
+	return q == QualityHeartbeat || q == QualityOffline || q == QualityOnline
+}
+
+// offlineState follows an OfflineSchedule through one run
+type offlineState struct {
+	schedule *OfflineSchedule
+	draw     func() float64 // Uniform draw in [0, 1) for the random model
+
+	offline     bool          // Whether the sensor was offline at the last update
+	failed      bool          // Whether the random model has the sensor down
+	nextFailure time.Duration // Offset of the next random transition
+	outages     int           // Outages started in this run, for marker IDs
+}
+
+// newOfflineState starts following schedule, nil if there is none
+func newOfflineState(schedule *OfflineSchedule, draw func() float64) *offlineState {
+	if schedule == nil {
+		return nil
+	}
+	o := &offlineState{schedule: schedule, draw: draw}
+	if schedule.MeanTimeToFailure > 0 {
+		o.nextFailure = o.exponential(schedule.MeanTimeToFailure)
+	}
+	return o
+}
+
+// exponential draws a duration from the exponential distribution with the
+// given mean
+func (o *offlineState) exponential(mean time.Duration) time.Duration {
+	return time.Duration(-math.Log(1-o.draw()) * float64(mean))
+}
+
+// update moves the state to elapsed and reports whether the sensor is
+// offline and whether that changed since the last update
+func (o *offlineState) update(elapsed time.Duration) (offline, changed bool) {
+	if o.schedule.MeanTimeToFailure > 0 {
+		for elapsed >= o.nextFailure {
+			o.failed = !o.failed
+			mean := o.schedule.MeanTimeToFailure
+			if o.failed {
+				mean = o.schedule.MeanTimeToRepair
+			}
+			// Durations are at least a nanosecond so the loop always ends
+			o.nextFailure += max(o.exponential(mean), 1)
+		}
+	}
+
+	offline = o.failed
+	for _, w := range o.schedule.Windows {
+		if elapsed >= w.From && elapsed < w.To {
+			offline = true
+		}
+	}
+	changed = offline != o.offline
+	o.offline = offline
+	if changed && offline {
+		o.outages++
+	}
+	return offline, changed
+}
+
+// marker returns the status reading announcing the current state, with
+// IDs like offline-1 and online-1 numbering the outages of the run
+func (o *offlineState) marker() (quality Quality, id string) {
+	quality = QualityOnline
+	if o.offline {
+		quality = QualityOffline
+	}
+	return quality, fmt.Sprintf("%s-%d", strings.ToLower(string(quality)), o.outages)
+}
+
+// checkOffline advances the run's offline state to elapsed, counting the
+// outage and building a status marker when it starts or ends. It reports
+// whether the sensor is offline, in which case the tick must generate
+// nothing, and the marker to emit, if any.
+func (e *Engine[T]) checkOffline(o *offlineState, elapsed time.Duration, now time.Time) (offline bool, marker *SensorData[T]) {
+	if o == nil {
+		return false, nil
+	}
+	offline, changed := o.update(elapsed)
+	if changed && offline {
+		e.stats.outages.Add(1)
+	}
+	if offline {
+		e.stats.offlineTicks.Add(1)
+	}
+	if changed && o.schedule.Markers {
+		quality, id := o.marker()
+		marker = &SensorData[T]{ID: id, Timestamp: now, Quality: quality}
+	}
+	return offline, marker
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOfflineSchedule_Windows(t *testing.T) {
+	h := newHarness[float64](t, Config{
+		ProductionRate: time.Second,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		Offline: &OfflineSchedule{
+			Windows: []OfflineWindow{{From: 10 * time.Second, To: 20 * time.Second}, {From: 30 * time.Second, To: 35 * time.Second}},
+			Markers: true,
+		},
+	})
+	engine := NewEngine(h.config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), h.Publisher)
+	if err := engine.RunFor(context.Background(), 50*time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	// RunFor's first reading is one step after the clock's start
+	first := harnessEpoch.Add(time.Second)
+	var markers []string
+	var online []time.Duration
+	for _, d := range h.Publisher.data {
+		offset := d.Timestamp.Sub(first)
+		if d.Quality == QualityOffline || d.Quality == QualityOnline {
+			markers = append(markers, d.ID+"@"+offset.String())
+			continue
+		}
+		if (offset >= 10*time.Second && offset < 20*time.Second) || (offset >= 30*time.Second && offset < 35*time.Second) {
+			t.Errorf("Reading %s generated while offline at %v", d.ID, offset)
+		}
+		online = append(online, offset)
+	}
+
+	wantMarkers := []string{"offline-1@10s", "online-1@20s", "offline-2@30s", "online-2@35s"}
+	if !slices.Equal(markers, wantMarkers) {
+		t.Errorf("Expected markers %v, got %v", wantMarkers, markers)
+	}
+	if len(online) != 35 || !slices.Contains(online, 20*time.Second) || online[len(online)-1] != 49*time.Second {
+		t.Errorf("Expected generation to resume after each outage, got readings at %v", online)
+	}
+	if stats := engine.Stats(); stats.Outages != 2 || stats.OfflineTicks != 15 || stats.Generated != 35 {
+		t.Errorf("Expected 2 outages, 15 offline ticks and 35 readings, got %+v", stats)
+	}
+}
+
+func TestOfflineSchedule_Random(t *testing.T) {
+	h := newHarness[float64](t, Config{
+		ProductionRate: 100 * time.Millisecond,
+		BatchSize:      100,
+		MaxWorkers:     1,
+		Offline:        &OfflineSchedule{MeanTimeToFailure: 10 * time.Second, MeanTimeToRepair: 5 * time.Second},
+	})
+	engine := NewEngine(h.config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), h.Publisher)
+	if err := engine.RunFor(context.Background(), 2*time.Hour); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	// Down a third of the time, in about one outage every 15 seconds
+	stats := engine.Stats()
+	offline := float64(stats.OfflineTicks) / float64(stats.OfflineTicks+stats.Generated)
+	if offline < 0.28 || offline > 0.39 {
+		t.Errorf("Expected the sensor offline about a third of the time, got %.3f", offline)
+	}
+	if stats.Outages < 400 || stats.Outages > 560 {
+		t.Errorf("Expected about 480 outages, got %d", stats.Outages)
+	}
+	for _, d := range h.Publisher.data {
+		if d.Quality == QualityOffline || d.Quality == QualityOnline {
+			t.Fatalf("Expected no markers unless enabled, got %+v", d)
+		}
+	}
+}
+
+// orderedPublisher records readings in publish order and is safe for
+// concurrent use
+type orderedPublisher struct {
+	MockPublisher[float64]
+	mutex sync.Mutex
+	data  []SensorData[float64]
+}
+
+func (p *orderedPublisher) Publish(ctx context.Context, data SensorData[float64]) error {
+	return p.PublishBatch(ctx, []SensorData[float64]{data})
+}
+
+func (p *orderedPublisher) PublishBatch(ctx context.Context, data []SensorData[float64]) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.data = append(p.data, data...)
+	return nil
+}
+
+func TestOfflineSchedule_Start(t *testing.T) {
+	config := Config{
+		ProductionRate:    time.Millisecond,
+		BatchSize:         1,
+		MaxWorkers:        1,
+		HeartbeatInterval: 20 * time.Millisecond,
+		QualityProfile:    QualityProfile{{Quality: QualityOK, Weight: 1}},
+		Offline: &OfflineSchedule{
+			Windows: []OfflineWindow{{From: 40 * time.Millisecond, To: 100 * time.Millisecond}},
+			Markers: true,
+		},
+	}
+	publisher := &orderedPublisher{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 160*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	var qualities []Quality
+	for _, d := range publisher.data {
+		if len(qualities) == 0 || qualities[len(qualities)-1] != d.Quality {
+			qualities = append(qualities, d.Quality)
+		}
+	}
+	// Nothing, heartbeats included, comes between the two markers
+	want := []Quality{QualityOK, QualityOffline, QualityOnline, QualityOK}
+	if !slices.Equal(qualities, want) {
+		t.Errorf("Expected runs of %v, got %v", want, qualities)
+	}
+	if stats := engine.Stats(); stats.Outages != 1 || stats.Heartbeats != 0 {
+		t.Errorf("Expected one outage and no heartbeats, got %+v", stats)
+	}
+}
+
+func TestOfflineSchedule_Invalid(t *testing.T) {
+	tests := map[string]OfflineSchedule{
+		"empty window":    {Windows: []OfflineWindow{{From: time.Second, To: time.Second}}},
+		"negative window": {Windows: []OfflineWindow{{From: -time.Second, To: time.Second}}},
+		"no repair time":  {MeanTimeToFailure: time.Minute},
+		"negative mttr":   {MeanTimeToFailure: time.Minute, MeanTimeToRepair: -time.Second},
+	}
+	for name, schedule := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Offline = &schedule
+			engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+			if err := engine.RunFor(context.Background(), time.Second); err == nil {
+				t.Error("Expected an error for an invalid offline schedule")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+)
+
+// Logger receives the engine's log messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// stdoutLogger prints messages to standard output, one per line
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+// discardLogger drops all messages
+type discardLogger struct{}
+
+func (discardLogger) Printf(string, ...any) {}
+
+// IDGenerator returns the ID of the reading with the given sequence number
+type IDGenerator func(seq uint64) string
+
+// DefaultIDGenerator returns IDs of the form sensor-<seq>
+func DefaultIDGenerator(seq uint64) string {
+	return fmt.Sprintf("sensor-%d", seq)
+}
+
+// Option configures an Engine built with NewEngineWithOptions
+type Option[T any] func(*Engine[T])
+
+// WithLogger sends the engine's log messages to logger instead of standard
+// output. A nil logger discards them.
+func WithLogger[T any](logger Logger) Option[T] {
+	return func(e *Engine[T]) {
+		if logger == nil {
+			logger = discardLogger{}
+		}
+		e.logger = logger
+	}
+}
+
+// WithOnPublishError calls fn with the batch and error whenever publishing a
+// batch fails. It runs on the publish worker, so it should return quickly.
+func WithOnPublishError[T any](fn func(batch []SensorData[T], err error)) Option[T] {
+	return func(e *Engine[T]) {
+		e.onPublishError = fn
+	}
+}
+
+// WithIDGenerator sets how reading IDs are derived from their sequence number
+func WithIDGenerator[T any](generator IDGenerator) Option[T] {
+	return func(e *Engine[T]) {
+		e.idGenerator = generator
+	}
+}
+
+// WithQualityProfile sets the distribution of generated qualities, like
+// Config.QualityProfile
+func WithQualityProfile[T any](profile QualityProfile) Option[T] {
+	return func(e *Engine[T]) {
+		e.config.QualityProfile = profile
+	}
+}
+
+// WithEnrichFunc applies enrich to every reading before it is published,
+// like SetEnrichFunc
+func WithEnrichFunc[T any](enrich EnrichFunc[T]) Option[T] {
+	return func(e *Engine[T]) {
+		e.enrich = enrich
+	}
+}
+
+// WithRedactFunc applies redact to every reading before it is published, like
+// SetRedactFunc
+func WithRedactFunc[T any](redact RedactFunc[T]) Option[T] {
+	return func(e *Engine[T]) {
+		e.redact = redact
+	}
+}
+
+// WithDeadLetterPublisher sends readings rejected by Config.QualityFilter to
+// publisher, like SetDeadLetterPublisher
+func WithDeadLetterPublisher[T any](publisher Publisher[T]) Option[T] {
+	return func(e *Engine[T]) {
+		e.deadLetter = publisher
+	}
+}
+
+// WithPublishRetry retries failed publisher calls as long as strategy
+// allows before a batch counts as failed. Retries block the publish worker,
+// so readings back up in the batch queue meanwhile.
+func WithPublishRetry[T any](strategy BackoffStrategy) Option[T] {
+	return func(e *Engine[T]) {
+		e.publishRetry = strategy
+	}
+}
+
+// NewEngineWithOptions creates a new generic sensor engine configured by
+// functional options. It panics if seeder, function or publisher is nil;
+// the configuration is validated when the engine starts.
+func NewEngineWithOptions[T any](
+	config Config,
+	seeder Seeder,
+	function SensorFunction[T],
+	publisher Publisher[T],
+	opts ...Option[T],
+) *Engine[T] {
+	switch {
+	case seeder == nil:
+		panic("engine: NewEngine called with a nil seeder")
+	case function == nil:
+		panic("engine: NewEngine called with a nil sensor function")
+	case publisher == nil:
+		panic("engine: NewEngine called with a nil publisher")
+	}
+
+	e := &Engine[T]{
+		config:      config,
+		seeder:      seeder,
+		function:    function,
+		publisher:   publisher,
+		logger:      stdoutLogger{},
+		idGenerator: DefaultIDGenerator,
+		debugOut:    os.Stderr,
+		clock:       config.Clock,
+		rateChanged: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.clock == nil {
+		e.clock = systemClock{}
+	}
+	if e.config.RandSource != nil {
+		e.rng = rand.New(e.config.RandSource)
+	}
+	e.rate.Store(int64(e.config.ProductionRate))
+	return e
+}
+
+// retryPublish calls publish, retrying it according to the WithPublishRetry
+// strategy, if any
+func (e *Engine[T]) retryPublish(ctx context.Context, publish func() error) error {
+	return Retry(ctx, e.publishRetry, nil, publish)
+}
+
+// logf logs a message through the configured logger
+func (e *Engine[T]) logf(format string, args ...any) {
+	e.logger.Printf(format, args...)
+}
+
+// reportPublishError logs a failed batch publish and passes it to the
+// OnPublishError callback, if any
+func (e *Engine[T]) reportPublishError(batchID string, batch []SensorData[T], err error) {
+	if batchID != "" {
+		e.logf("Error publishing batch %s: %v", batchID, err)
+	} else {
+		e.logf("Error publishing batch: %v", err)
+	}
+	if e.onPublishError != nil {
+		e.onPublishError(batch, err)
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEngineWithOptions_Defaults(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	if err := engine.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if got := publisher.batches[0][0].ID; got != "sensor-0" {
+		t.Errorf("Expected default ID sensor-0, got %s", got)
+	}
+}
+
+func TestNewEngineWithOptions_IDGeneratorAndQualityProfile(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher,
+		WithIDGenerator[float64](func(seq uint64) string { return fmt.Sprintf("pump-%03d", seq) }),
+		WithQualityProfile[float64](QualityProfile{{Quality: QualityCorrupt, Weight: 1}}),
+	)
+
+	if err := engine.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	for i, d := range publisher.batches[0] {
+		if want := fmt.Sprintf("pump-%03d", i); d.ID != want {
+			t.Errorf("Expected ID %s, got %s", want, d.ID)
+		}
+		if d.Quality != QualityCorrupt {
+			t.Errorf("Expected quality %s from the profile, got %s", QualityCorrupt, d.Quality)
+		}
+	}
+}
+
+func TestNewEngineWithOptions_LoggerAndOnPublishError(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	var logs bytes.Buffer
+	var failed [][]SensorData[float64]
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), &failingMockPublisher[float64]{},
+		WithLogger[float64](log.New(&logs, "", 0)),
+		WithOnPublishError(func(batch []SensorData[float64], err error) {
+			if err.Error() != "batch publish failed" {
+				t.Errorf("Unexpected error %v", err)
+			}
+			failed = append(failed, batch)
+		}),
+	)
+
+	if err := engine.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed batches, got %d", len(failed))
+	}
+	if len(failed[0]) != 5 {
+		t.Errorf("Expected the failed batch to hold 5 readings, got %d", len(failed[0]))
+	}
+	if n := strings.Count(logs.String(), "Error publishing batch: batch publish failed"); n != 2 {
+		t.Errorf("Expected 2 logged publish errors, got %d in:\n%s", n, logs.String())
+	}
+}
+
+func TestWithLogger_NilDiscards(t *testing.T) {
+	config := Config{
+		ProductionRate: 10 * time.Millisecond,
+		BatchSize:      5,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+	}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), &failingMockPublisher[float64]{},
+		WithLogger[float64](nil))
+
+	// Failed batches are logged, which must not dereference the nil logger
+	if err := engine.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+}
+
+func TestNewEngine_NilArgumentsPanic(t *testing.T) {
+	config := DefaultConfig()
+	seeder := NewTestSeeder([]float64{1.0})
+	function := NewTestSensorFunction(1.0)
+	publisher := NewMockPublisher[float64]()
+
+	tests := []struct {
+		name    string
+		create  func()
+		message string
+	}{
+		{"Seeder", func() { NewEngine(config, nil, function, publisher) }, "nil seeder"},
+		{"Function", func() { NewEngine[float64](config, seeder, nil, publisher) }, "nil sensor function"},
+		{"Publisher", func() { NewEngine(config, seeder, function, nil) }, "nil publisher"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if message, _ := r.(string); !strings.Contains(message, tt.message) {
+					t.Errorf("Expected a panic mentioning %q, got %v", tt.message, r)
+				}
+			}()
+			tt.create()
+		})
+	}
+}
+
+func TestEngine_InvalidConfigRejectedAtStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		message string
+	}{
+		{"ProductionRate", func(c *Config) { c.ProductionRate = 0 }, "production rate must be positive"},
+		{"BatchSize", func(c *Config) { c.BatchSize = 0 }, "batch size must be at least 1"},
+		{"MaxWorkers", func(c *Config) { c.MaxWorkers = -1 }, "max workers must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(&config)
+			engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+			err := engine.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ApplyOverrides applies overrides of the form path=value, in order, with
+// SetField
+func (c *ConfigFile) ApplyOverrides(overrides []string) error {
+	for _, override := range overrides {
+		path, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("override %q is not of the form path=value", override)
+		}
+		if err := c.SetField(path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetField overrides the field at a dotted path of JSON names, such as
+// engine.batch_size or seeder.params.amplitude, parsing value for the
+// field's type. Map entries are created as needed and entries of engines
+// are addressed by index, as in engines.0.engine.batch_size. Values of
+// free-form entries such as params are parsed as JSON when valid, so 2.0 is
+// a number and true a boolean, and taken as strings otherwise.
+func (c *ConfigFile) SetField(path, value string) error {
+	if err := setField(reflect.ValueOf(c).Elem(), strings.Split(path, "."), 0, value); err != nil {
+		return fmt.Errorf("invalid override %s=%s: %w", path, value, err)
+	}
+	return nil
+}
+
+// setField sets the field reached by segments[i:] from v
+func setField(v reflect.Value, segments []string, i int, value string) error {
+	if i == len(segments) {
+		return setValue(v, value)
+	}
+	segment := segments[i]
+	at := strings.Join(segments[:i+1], ".")
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), segments, i, value)
+	case reflect.Struct:
+		for f := range v.NumField() {
+			name, _, _ := strings.Cut(v.Type().Field(f).Tag.Get("json"), ",")
+			if name == segment {
+				return setField(v.Field(f), segments, i+1, value)
+			}
+		}
+		return fmt.Errorf("unknown field %s", at)
+	case reflect.Map:
+		if i != len(segments)-1 {
+			return fmt.Errorf("%s is a map entry and has no fields", at)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		entry := reflect.New(v.Type().Elem()).Elem()
+		if err := setValue(entry, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(segment), entry)
+		return nil
+	case reflect.Slice:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= v.Len() {
+			return fmt.Errorf("%s: no entry %s among %d", strings.Join(segments[:i], "."), segment, v.Len())
+		}
+		return setField(v.Index(index), segments, i+1, value)
+	default:
+		return fmt.Errorf("%s has no fields", strings.Join(segments[:i], "."))
+	}
+}
+
+// setValue parses value into v according to v's type
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		v.SetBool(b)
+	case reflect.Interface:
+		var parsed any
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+		if parsed == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(parsed))
+		}
+	default:
+		return fmt.Errorf("cannot set a field of type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigFile_ApplyOverrides(t *testing.T) {
+	config := DefaultConfigFile()
+	config.Engines = []ConfigFile{*DefaultConfigFile()}
+
+	err := config.ApplyOverrides([]string{
+		"engine.batch_size=50",
+		"engine.production_rate=20ms",
+		"engine.debug_tee=true",
+		"seeder.params.amplitude=2.0",
+		"seeder.params.label=north",
+		"seeder.function.type=lambda",
+		"output.metadata.site={hostname}",
+		"engines.0.engine.max_workers=7",
+		"engine.batch_size=60", // Later overrides win
+	})
+	if err != nil {
+		t.Fatalf("ApplyOverrides failed: %v", err)
+	}
+
+	if config.Engine.BatchSize != 60 || config.Engine.ProductionRate != "20ms" || !config.Engine.DebugTee {
+		t.Errorf("Engine fields not overridden: %+v", config.Engine)
+	}
+	if amplitude, ok := config.Seeder.Params["amplitude"].(float64); !ok || amplitude != 2 {
+		t.Errorf("Expected amplitude 2.0 as a number, got %#v", config.Seeder.Params["amplitude"])
+	}
+	if label := config.Seeder.Params["label"]; label != "north" {
+		t.Errorf("Expected a non-JSON value to be a string, got %#v", label)
+	}
+	if config.Seeder.Function == nil || config.Seeder.Function.Type != "lambda" {
+		t.Errorf("Expected the function to be created, got %+v", config.Seeder.Function)
+	}
+	if config.Output.Metadata["site"] != "{hostname}" {
+		t.Errorf("Expected metadata override, got %v", config.Output.Metadata)
+	}
+	if config.Engines[0].Engine.MaxWorkers != 7 {
+		t.Errorf("Expected engines.0 override, got %d", config.Engines[0].Engine.MaxWorkers)
+	}
+}
+
+func TestConfigFile_ApplyOverridesErrors(t *testing.T) {
+	tests := []struct {
+		override string
+		want     string
+	}{
+		{"engine.batch_size", "not of the form path=value"},
+		{"engine.batch_size=big", "expected an integer"},
+		{"engine.debug_tee=maybe", "expected true or false"},
+		{"engine.bogus=1", "unknown field engine.bogus"},
+		{"engine.batch_size.x=1", "engine.batch_size has no fields"},
+		{"seeder.params.a.b=1", "seeder.params.a is a map entry"},
+		{"engines.3.name=x", "no entry 3 among 1"},
+		{"engine=1", "cannot set"},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfigFile()
+		config.Engines = []ConfigFile{{}}
+		err := config.ApplyOverrides([]string{tt.override})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.override, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// qualityRegistry holds every quality value that may appear in a profile
+var qualityRegistry = struct {
+	sync.RWMutex
+	qualities []Quality
+}{
+	qualities: []Quality{QualityOK, QualityNoisy, QualityPartial, QualityCorrupt, QualityHeartbeat, QualityOffline, QualityOnline},
+}
+
+// RegisterQuality adds a domain-specific quality value, such as
+// "CALIBRATING" or "STALE", so it can be used in a QualityProfile.
+// Registering a value twice is a no-op.
+func RegisterQuality(q Quality) error {
+	if q == "" {
+		return errors.New("quality must not be empty")
+	}
+
+	qualityRegistry.Lock()
+	defer qualityRegistry.Unlock()
+	for _, existing := range qualityRegistry.qualities {
+		if existing == q {
+			return nil
+		}
+	}
+	qualityRegistry.qualities = append(qualityRegistry.qualities, q)
+	return nil
+}
+
+// IsRegisteredQuality reports whether q is a built-in or registered quality
+func IsRegisteredQuality(q Quality) bool {
+	qualityRegistry.RLock()
+	defer qualityRegistry.RUnlock()
+	for _, existing := range qualityRegistry.qualities {
+		if existing == q {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredQualities returns the built-in and registered qualities in
+// registration order
+func RegisteredQualities() []Quality {
+	qualityRegistry.RLock()
+	defer qualityRegistry.RUnlock()
+	return append([]Quality(nil), qualityRegistry.qualities...)
+}
+
+// QualityScores maps qualities to numeric scores, 1 for the best data and 0
+// for the worst
+type QualityScores map[Quality]float64
+
+// DefaultQualityScores returns the scores Quality.Score returns: OK 1.0,
+// NOISY 0.7, PARTIAL 0.4 and CORRUPT 0.0
+func DefaultQualityScores() QualityScores {
+	return QualityScores{
+		QualityOK:      1.0,
+		QualityNoisy:   0.7,
+		QualityPartial: 0.4,
+		QualityCorrupt: 0.0,
+	}
+}
+
+// Score returns the score of q in s, falling back to q.Score() for
+// qualities s does not list
+func (s QualityScores) Score(q Quality) float64 {
+	if score, ok := s[q]; ok {
+		return score
+	}
+	return q.Score()
+}
+
+// defaultQualityScores holds the scores returned by Quality.Score
+var defaultQualityScores = DefaultQualityScores()
+
+// Score returns q as a number for use as a feature, with the fixed scores
+// of DefaultQualityScores. Qualities without a score, such as HEARTBEAT and
+// those added with RegisterQuality, score 0; list them in
+// Config.QualityScores to score them.
+func (q Quality) Score() float64 {
+	return defaultQualityScores[q]
+}
+
+// scoreQuality sets the quality score of reading when IncludeQualityScore
+// is set
+func (e *Engine[T]) scoreQuality(reading *SensorData[T]) {
+	if !e.config.IncludeQualityScore {
+		return
+	}
+	score := e.config.QualityScores.Score(reading.Quality)
+	reading.QualityScore = &score
+}
+
+// QualityWeight is the relative frequency of one quality in a profile
+type QualityWeight struct {
+	Quality Quality
+	Weight  float64
+}
+
+// QualityProfile is the distribution generated readings draw their quality
+// from. Weights are relative and need not sum to one.
+type QualityProfile []QualityWeight
+
+// DefaultQualityProfile returns the distribution used when Config has no
+// profile: 92% OK, 5% NOISY, 2% PARTIAL and 1% CORRUPT
+func DefaultQualityProfile() QualityProfile {
+	return QualityProfile{
+		{Quality: QualityCorrupt, Weight: 0.01},
+		{Quality: QualityPartial, Weight: 0.02},
+		{Quality: QualityNoisy, Weight: 0.05},
+		{Quality: QualityOK, Weight: 0.92},
+	}
+}
+
+var defaultQualityProfile = DefaultQualityProfile()
+
+// Validate checks that every quality is registered and describes a
+// measurement rather than a status marker such as HEARTBEAT, that weights
+// are not negative and that at least one weight is positive
+func (p QualityProfile) Validate() error {
+	total := 0.0
+	for _, w := range p {
+		if !IsRegisteredQuality(w.Quality) {
+			return fmt.Errorf("quality profile references unregistered quality %q", w.Quality)
+		}
+		if isStatusMarker(w.Quality) {
+			return fmt.Errorf("quality %q marks synthetic status readings and cannot be generated", w.Quality)
+		}
+		if w.Weight < 0 {
+			return fmt.Errorf("quality %q has negative weight %v", w.Quality, w.Weight)
+		}
+		total += w.Weight
+	}
+	if total <= 0 {
+		return errors.New("quality profile has no positive weights")
+	}
+	return nil
+}
+
+// pick returns the quality selected by r, a uniform value in [0, 1)
+func (p QualityProfile) pick(r float64) Quality {
+	total := 0.0
+	for _, w := range p {
+		total += w.Weight
+	}
+
+	target := r * total
+	for _, w := range p {
+		if target < w.Weight {
+			return w.Quality
+		}
+		target -= w.Weight
+	}
+	// Rounding can leave target just above the last weight
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Weight > 0 {
+			return p[i].Quality
+		}
+	}
+	return QualityOK
+}
+
+// determineQuality randomly determines the quality of sensor data according
+// to the configured quality profile
+func (e *Engine[T]) determineQuality() Quality {
+	profile := e.config.QualityProfile
+	if profile == nil {
+		profile = defaultQualityProfile
+	}
+	return profile.pick(e.randFloat64())
+}
+
+// QualityFilter decides whether a reading of the given quality is published
+type QualityFilter func(Quality) bool
+
+// AllowQualities returns a QualityFilter that publishes only the listed
+// qualities
+func AllowQualities(qualities ...Quality) QualityFilter {
+	allowed := make(map[Quality]bool, len(qualities))
+	for _, q := range qualities {
+		allowed[q] = true
+	}
+	return func(q Quality) bool {
+		return allowed[q]
+	}
+}
+
+// SetDeadLetterPublisher sets where readings rejected by Config.QualityFilter
+// are sent instead of being dropped. Start closes it along with the main
+// publisher. It must be called before Start.
+func (e *Engine[T]) SetDeadLetterPublisher(publisher Publisher[T]) {
+	e.deadLetter = publisher
+}
+
+// filterQuality removes the readings rejected by the quality filter from
+// batch, counting them and sending them to the dead-letter publisher, if
+// any. Heartbeats and offline markers are always kept. The batch is
+// compacted in place.
+func (e *Engine[T]) filterQuality(ctx context.Context, batch []SensorData[T]) []SensorData[T] {
+	kept := batch[:0]
+	var rejected []SensorData[T]
+	for _, data := range batch {
+		if isStatusMarker(data.Quality) || e.config.QualityFilter(data.Quality) {
+			kept = append(kept, data)
+		} else {
+			rejected = append(rejected, data)
+		}
+	}
+
+	if len(rejected) > 0 {
+		e.stats.filtered.Add(uint64(len(rejected)))
+		if e.deadLetter != nil {
+			if err := e.deadLetter.PublishBatch(ctx, rejected); err != nil {
+				e.logf("Error publishing %d filtered readings to dead letter: %v", len(rejected), err)
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,316 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"log"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegisterQuality(t *testing.T) {
+	if err := RegisterQuality(""); err == nil {
+		t.Error("Expected error for empty quality")
+	}
+
+	calibrating := Quality("CALIBRATING")
+	if err := RegisterQuality(calibrating); err != nil {
+		t.Fatalf("RegisterQuality failed: %v", err)
+	}
+	if err := RegisterQuality(calibrating); err != nil {
+		t.Fatalf("Registering twice should be a no-op: %v", err)
+	}
+	if !IsRegisteredQuality(calibrating) {
+		t.Error("Expected CALIBRATING to be registered")
+	}
+
+	count := 0
+	for _, q := range RegisteredQualities() {
+		if q == calibrating {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected CALIBRATING once in registry, got %d", count)
+	}
+}
+
+func TestQuality_Score(t *testing.T) {
+	want := map[Quality]float64{
+		QualityOK:        1.0,
+		QualityNoisy:     0.7,
+		QualityPartial:   0.4,
+		QualityCorrupt:   0.0,
+		QualityHeartbeat: 0.0,
+		"UNKNOWN":        0.0,
+	}
+	for quality, score := range want {
+		if got := quality.Score(); got != score {
+			t.Errorf("%s.Score() = %v, want %v", quality, got, score)
+		}
+	}
+}
+
+func TestQualityScores_Custom(t *testing.T) {
+	scores := QualityScores{QualityNoisy: 0.9, QualityCorrupt: -1, "DRIFTING": 0.55}
+	want := map[Quality]float64{
+		QualityOK:      1.0, // Not listed, so the default applies
+		QualityNoisy:   0.9,
+		QualityPartial: 0.4,
+		QualityCorrupt: -1,
+		"DRIFTING":     0.55,
+	}
+	for quality, score := range want {
+		if got := scores.Score(quality); got != score {
+			t.Errorf("Score(%s) = %v, want %v", quality, got, score)
+		}
+	}
+	if got := QualityScores(nil).Score(QualityNoisy); got != 0.7 {
+		t.Errorf("Expected nil scores to use the defaults, got %v", got)
+	}
+}
+
+func TestEngine_IncludeQualityScore(t *testing.T) {
+	config := Config{
+		ProductionRate:      time.Millisecond,
+		BatchSize:           10,
+		MaxWorkers:          1,
+		IncludeQualityScore: true,
+		QualityScores:       QualityScores{QualityPartial: 0.25},
+	}
+	h := newHarness[float64](t, config)
+	published := h.run(NewTestSeeder([]float64{1}), NewTestSensorFunction(1), 500)
+
+	seen := make(map[Quality]bool)
+	for _, data := range published.data {
+		seen[data.Quality] = true
+		if data.QualityScore == nil || *data.QualityScore != config.QualityScores.Score(data.Quality) {
+			t.Fatalf("Reading %s of quality %s: unexpected score %v", data.ID, data.Quality, data.QualityScore)
+		}
+	}
+	if !seen[QualityOK] || !seen[QualityPartial] {
+		t.Errorf("Expected both OK and PARTIAL readings among %v", seen)
+	}
+
+	config.IncludeQualityScore = false
+	h = newHarness[float64](t, config)
+	for _, data := range h.run(NewTestSeeder([]float64{1}), NewTestSensorFunction(1), 2).data {
+		if data.QualityScore != nil {
+			t.Errorf("Expected no score without IncludeQualityScore, got %v", *data.QualityScore)
+		}
+	}
+}
+
+func TestQualityProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile QualityProfile
+		wantErr bool
+	}{
+		{"default", DefaultQualityProfile(), false},
+		{"unregistered", QualityProfile{{Quality: "NOT_REGISTERED", Weight: 1}}, true},
+		{"heartbeat", QualityProfile{{Quality: QualityOK, Weight: 1}, {Quality: QualityHeartbeat, Weight: 1}}, true},
+		{"offline", QualityProfile{{Quality: QualityOffline, Weight: 1}}, true},
+		{"negative weight", QualityProfile{{Quality: QualityOK, Weight: -1}}, true},
+		{"no positive weight", QualityProfile{{Quality: QualityOK, Weight: 0}}, true},
+		{"empty", QualityProfile{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQualityProfile_Pick(t *testing.T) {
+	profile := QualityProfile{
+		{Quality: QualityOK, Weight: 3},
+		{Quality: QualityNoisy, Weight: 0},
+		{Quality: QualityCorrupt, Weight: 1},
+	}
+
+	tests := []struct {
+		r    float64
+		want Quality
+	}{
+		{0, QualityOK},
+		{0.74, QualityOK},
+		{0.75, QualityCorrupt},
+		{0.9999, QualityCorrupt},
+	}
+	for _, tt := range tests {
+		if got := profile.pick(tt.r); got != tt.want {
+			t.Errorf("pick(%v) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestEngine_CustomQualityProfile(t *testing.T) {
+	stale := Quality("STALE")
+	if err := RegisterQuality(stale); err != nil {
+		t.Fatalf("RegisterQuality failed: %v", err)
+	}
+
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      10,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     1,
+		QualityProfile: QualityProfile{{Quality: stale, Weight: 1}},
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if publisher.GetTotalDataPoints() == 0 {
+		t.Fatal("Expected readings to be published")
+	}
+	for _, batch := range publisher.batches {
+		for _, data := range batch {
+			if data.Quality != stale {
+				t.Errorf("Expected only STALE readings, got %s", data.Quality)
+			}
+		}
+	}
+}
+
+func TestEngine_InvalidQualityProfile(t *testing.T) {
+	config := DefaultConfig()
+	config.QualityProfile = QualityProfile{{Quality: "UNKNOWN", Weight: 1}}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Fatal("Expected Start to reject an unregistered quality")
+	}
+}
+
+func TestEngine_QualityFilter(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      10,
+		BatchTimeout:   time.Second,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+		QualityProfile: QualityProfile{
+			{Quality: QualityOK, Weight: 0.5},
+			{Quality: QualityNoisy, Weight: 0.2},
+			{Quality: QualityCorrupt, Weight: 0.3},
+		},
+		QualityFilter: AllowQualities(QualityOK, QualityNoisy),
+	}
+	publisher := NewMockPublisher[float64]()
+	deadLetter := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher)
+	engine.SetDeadLetterPublisher(deadLetter)
+
+	if err := engine.RunFor(context.Background(), 500*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	for _, batch := range publisher.batches {
+		if len(batch) == 0 {
+			t.Error("Filtered batches should not be published empty")
+		}
+		for _, data := range batch {
+			if data.Quality == QualityCorrupt {
+				t.Fatalf("Filtered quality reached the publisher: %s", data.ID)
+			}
+		}
+	}
+	for _, batch := range deadLetter.batches {
+		for _, data := range batch {
+			if data.Quality != QualityCorrupt {
+				t.Errorf("Dead letter received allowed quality %s", data.Quality)
+			}
+		}
+	}
+
+	stats := engine.Stats()
+	if stats.Filtered == 0 {
+		t.Fatal("Expected filtered readings to be counted")
+	}
+	if got := uint64(deadLetter.GetTotalDataPoints()); got != stats.Filtered {
+		t.Errorf("Expected %d readings in dead letter, got %d", stats.Filtered, got)
+	}
+	if got := uint64(publisher.GetTotalDataPoints()); got+stats.Filtered != stats.Generated {
+		t.Errorf("Published %d + filtered %d should equal generated %d", got, stats.Filtered, stats.Generated)
+	}
+	if !deadLetter.IsClosed() {
+		t.Error("Expected dead-letter publisher to be closed")
+	}
+}
+
+func TestEngine_QualityFilterReportsPublishedReadings(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Second,
+		BatchSize:      3,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+		QualityProfile: QualityProfile{{Quality: QualityOK, Weight: 1}},
+		QualityFilter:  AllowQualities(QualityOK),
+	}
+	// The range guard flags 50 CORRUPT, so the filter drops it
+	var reported [][]SensorData[float64]
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1, 50, 3}), NewTestSensorFunction(1.0), &failingMockPublisher[float64]{},
+		WithLogger[float64](log.New(io.Discard, "", 0)),
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeFlag)),
+		WithOnPublishError(func(batch []SensorData[float64], err error) {
+			reported = append(reported, slices.Clone(batch))
+		}))
+
+	if err := engine.RunFor(context.Background(), 3*time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("Expected one failed batch, got %d", len(reported))
+	}
+	var values []float64
+	for _, d := range reported[0] {
+		values = append(values, d.Data)
+	}
+	if !slices.Equal(values, []float64{1, 3}) {
+		t.Errorf("Expected the failure to report the published readings 1 and 3, got %v", values)
+	}
+}
+
+func TestEngine_QualityFilterBatchWindow(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Second,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		Clock:          NewManualClock(time.Unix(0, 0)),
+		QualityProfile: QualityProfile{{Quality: QualityOK, Weight: 1}},
+		QualityFilter:  AllowQualities(QualityOK),
+	}
+	// The range guard flags the first and last readings CORRUPT, so the
+	// filter drops them and the window shrinks to the middle two
+	publisher := &batchWindowRecorder[float64]{}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{50, 1, 2, 60}), NewTestSensorFunction(1.0), publisher,
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeFlag)))
+
+	if err := engine.RunFor(context.Background(), 4*time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	if len(publisher.envelopes) != 1 {
+		t.Fatalf("Expected one batch, got %d", len(publisher.envelopes))
+	}
+	envelope := publisher.envelopes[0]
+	if envelope.Count != 2 || len(envelope.Items) != 2 {
+		t.Fatalf("Expected 2 readings after filtering, got count %d with %d items", envelope.Count, len(envelope.Items))
+	}
+	first, last := envelope.Items[0].Timestamp, envelope.Items[1].Timestamp
+	if !envelope.WindowStart.Equal(first) || !envelope.WindowEnd.Equal(last) {
+		t.Errorf("Expected window [%v, %v] of the published readings, got [%v, %v]", first, last, envelope.WindowStart, envelope.WindowEnd)
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RangeAction is what the engine does with a reading outside a RangeGuard
+type RangeAction string
+
+const (
+	RangeFlag   RangeAction = "flag"   // Publish the reading with quality CORRUPT
+	RangeDrop   RangeAction = "drop"   // Discard the reading
+	RangeStrict RangeAction = "strict" // Stop the engine with an error wrapping ErrRangeViolation
+)
+
+// ErrRangeViolation is returned, wrapped, by Start, RunFor and Backfill when
+// a RangeGuard in strict mode stops them
+var ErrRangeViolation = errors.New("reading outside the guarded range")
+
+// RangeGuard checks every generated reading against an expected range, so
+// an engine used as a test fixture fails fast on a broken sensor function.
+// Readings for which Extract reports no value, and heartbeats, are not
+// checked.
+type RangeGuard[T any] struct {
+	Extract func(T) (float64, bool) // Returns the value to check and whether the reading has one
+	Min     float64
+	Max     float64
+	Action  RangeAction // Defaults to RangeFlag
+}
+
+// NewNumericRangeGuard creates a guard for readings whose value is itself a
+// number
+func NewNumericRangeGuard[N Number](min, max float64, action RangeAction) RangeGuard[N] {
+	return RangeGuard[N]{
+		Extract: func(v N) (float64, bool) {
+			return float64(v), true
+		},
+		Min:    min,
+		Max:    max,
+		Action: action,
+	}
+}
+
+// WithRangeGuard checks every generated reading against guard. Violations
+// are counted in Stats.RangeViolations whatever the action. It panics if
+// Extract is nil or the action is unknown.
+func WithRangeGuard[T any](guard RangeGuard[T]) Option[T] {
+	if guard.Extract == nil {
+		panic("engine: RangeGuard needs an Extract function")
+	}
+	switch guard.Action {
+	case "":
+		guard.Action = RangeFlag
+	case RangeFlag, RangeDrop, RangeStrict:
+	default:
+		panic(fmt.Sprintf("engine: unknown range action %q", guard.Action))
+	}
+	return func(e *Engine[T]) {
+		e.rangeGuard = &guard
+	}
+}
+
+// outOfRange reports whether a reading violates the range guard, if any,
+// counting violations
+func (e *Engine[T]) outOfRange(data SensorData[T]) bool {
+	guard := e.rangeGuard
+	if guard == nil {
+		return false
+	}
+	value, ok := guard.Extract(data.Data)
+	if !ok || (value >= guard.Min && value <= guard.Max) {
+		return false
+	}
+	e.stats.rangeViolations.Add(1)
+	return true
+}
+
+// guardRange applies the range guard, if any, to a generated reading. It
+// reports false when the reading must not be published.
+func (e *Engine[T]) guardRange(data *SensorData[T]) bool {
+	if !e.outOfRange(*data) {
+		return true
+	}
+
+	guard := e.rangeGuard
+	switch guard.Action {
+	case RangeDrop:
+		return false
+	case RangeStrict:
+		value, _ := guard.Extract(data.Data)
+		e.abort(fmt.Errorf("%w: value %v at %v is outside [%v, %v]",
+			ErrRangeViolation, value, data.Timestamp, guard.Min, guard.Max))
+		return false
+	default:
+		data.Quality = QualityCorrupt
+		return true
+	}
+}
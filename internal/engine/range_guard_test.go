@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// rangeHarness prepares a run whose seeder cycles through 1, 2, 50, 3, with
+// 50 outside the guarded range [0, 10]
+func rangeHarness(t *testing.T) (*harness[float64], Seeder) {
+	h := newHarness[float64](t, Config{
+		ProductionRate: time.Second,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		QualityProfile: QualityProfile{{Quality: QualityOK, Weight: 1}},
+	})
+	return h, NewTestSeeder([]float64{1, 2, 50, 3})
+}
+
+func TestRangeGuard_Flag(t *testing.T) {
+	h, seeder := rangeHarness(t)
+	published := h.run(seeder, NewTestSensorFunction(1), 8, WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeFlag)))
+
+	if len(published.data) != 8 {
+		t.Fatalf("Expected every reading published, got %d", len(published.data))
+	}
+	for _, d := range published.data {
+		want := QualityOK
+		if d.Data == 50 {
+			want = QualityCorrupt
+		}
+		if d.Quality != want {
+			t.Errorf("Reading %s with value %v: expected quality %s, got %s", d.ID, d.Data, want, d.Quality)
+		}
+	}
+}
+
+func TestRangeGuard_Drop(t *testing.T) {
+	h, seeder := rangeHarness(t)
+	engine := NewEngineWithOptions(h.config, seeder, NewTestSensorFunction(1), h.Publisher,
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeDrop)))
+	if err := engine.RunFor(context.Background(), 8*time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	var values []float64
+	for _, d := range h.Publisher.data {
+		values = append(values, d.Data)
+	}
+	if len(values) != 6 || values[2] != 3 {
+		t.Errorf("Expected the out-of-range values dropped, got %v", values)
+	}
+	if h.Publisher.data[2].ID != "sensor-2" {
+		t.Errorf("Dropped readings should not use up IDs, got %s after a drop", h.Publisher.data[2].ID)
+	}
+	if stats := engine.Stats(); stats.RangeViolations != 2 || stats.Generated != 8 {
+		t.Errorf("Expected 2 violations among 8 generated readings, got %+v", stats)
+	}
+}
+
+func TestRangeGuard_Strict(t *testing.T) {
+	h, seeder := rangeHarness(t)
+	engine := NewEngineWithOptions(h.config, seeder, NewTestSensorFunction(1), h.Publisher,
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeStrict)))
+
+	err := engine.RunFor(context.Background(), 8*time.Second)
+	if !errors.Is(err, ErrRangeViolation) {
+		t.Fatalf("Expected ErrRangeViolation, got %v", err)
+	}
+	if generated := engine.Stats().Generated; generated != 3 {
+		t.Errorf("Expected the run to stop at the third reading, generated %d", generated)
+	}
+	for _, d := range h.Publisher.data {
+		if d.Data == 50 {
+			t.Error("The violating reading must not be published")
+		}
+	}
+}
+
+func TestRangeGuard_StrictStopsStart(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      1,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+	engine := NewEngineWithOptions(config, NewTestSeeder([]float64{1, 2, 50}), NewTestSensorFunction(1), NewMockPublisher[float64](),
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeStrict)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := engine.Start(ctx); !errors.Is(err, ErrRangeViolation) {
+		t.Fatalf("Expected ErrRangeViolation, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Start to stop on the violation, it ran for %v", elapsed)
+	}
+}
+
+func TestWithRangeGuard_Invalid(t *testing.T) {
+	for name, guard := range map[string]RangeGuard[float64]{
+		"nil extract":    {Min: 0, Max: 1},
+		"unknown action": NewNumericRangeGuard[float64](0, 1, "explode"),
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			WithRangeGuard(guard)
+		}()
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RedactFunc transforms each reading before it is published, for masking
+// sensitive fields such as device serials
+type RedactFunc[T any] func(SensorData[T]) SensorData[T]
+
+// SetRedactFunc sets a function applied to every reading right before it is
+// handed to the publisher. It must be called before Start.
+func (e *Engine[T]) SetRedactFunc(redact RedactFunc[T]) {
+	e.redact = redact
+}
+
+// MaskFields returns a RedactFunc that zeroes the fields of Data whose JSON
+// names (the json tag name, or the Go field name when there is no tag) are
+// listed, including fields of nested structs. Combined with omitempty a
+// masked field is left out of the payload entirely. Data may be a struct, a
+// pointer to a struct (the pointee is copied, never modified) or a map with
+// string keys (masked keys are removed from a copy).
+func MaskFields[T any](jsonNames ...string) RedactFunc[T] {
+	names := make(map[string]bool, len(jsonNames))
+	for _, name := range jsonNames {
+		names[name] = true
+	}
+
+	return func(data SensorData[T]) SensorData[T] {
+		v := reflect.ValueOf(&data.Data).Elem()
+		if masked, ok := maskValue(v, names); ok {
+			v.Set(masked)
+		}
+		return data
+	}
+}
+
+// maskValue returns a masked copy of v and whether v was of a maskable kind
+func maskValue(v reflect.Value, names map[string]bool) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		masked := reflect.New(v.Type()).Elem()
+		masked.Set(v)
+		maskStruct(masked, names)
+		return masked, true
+
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return v, false
+		}
+		masked := reflect.New(v.Type().Elem())
+		masked.Elem().Set(v.Elem())
+		maskStruct(masked.Elem(), names)
+		return masked, true
+
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v, false
+		}
+		masked := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if !names[iter.Key().String()] {
+				masked.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		return masked, true
+	}
+	return v, false
+}
+
+// maskStruct zeroes the named fields of a settable struct in place and masks
+// nested structs
+func maskStruct(v reflect.Value, names map[string]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+
+		fv := v.Field(i)
+		if names[name] {
+			fv.SetZero()
+			continue
+		}
+		if masked, ok := maskValue(fv, names); ok {
+			fv.Set(masked)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type deviceReading struct {
+	Serial   string        `json:"serial,omitempty"`
+	Value    float64       `json:"value"`
+	Location deviceAddress `json:"location"`
+}
+
+type deviceAddress struct {
+	Street string `json:"street,omitempty"`
+	City   string `json:"city"`
+}
+
+func TestMaskFields_Struct(t *testing.T) {
+	redact := MaskFields[deviceReading]("serial", "street")
+	data := SensorData[deviceReading]{
+		ID:   "d-1",
+		Data: deviceReading{Serial: "SN-123", Value: 4.2, Location: deviceAddress{Street: "1 Main St", City: "Springfield"}},
+	}
+
+	masked := redact(data)
+	if masked.Data.Serial != "" || masked.Data.Location.Street != "" {
+		t.Errorf("Expected serial and street to be masked, got %+v", masked.Data)
+	}
+	if masked.Data.Value != 4.2 || masked.Data.Location.City != "Springfield" || masked.ID != "d-1" {
+		t.Errorf("Unmasked fields changed: %+v", masked)
+	}
+
+	payload, _ := json.Marshal(masked)
+	if strings.Contains(string(payload), "serial") || strings.Contains(string(payload), "SN-123") {
+		t.Errorf("Masked omitempty field should be absent from payload: %s", payload)
+	}
+}
+
+func TestMaskFields_PointerIsCopied(t *testing.T) {
+	original := &deviceReading{Serial: "SN-123", Value: 1}
+	masked := MaskFields[*deviceReading]("serial")(SensorData[*deviceReading]{Data: original})
+
+	if masked.Data.Serial != "" {
+		t.Errorf("Expected serial to be masked, got %q", masked.Data.Serial)
+	}
+	if original.Serial != "SN-123" {
+		t.Error("MaskFields must not modify the original pointee")
+	}
+}
+
+func TestMaskFields_Map(t *testing.T) {
+	original := map[string]any{"serial": "SN-123", "value": 1.0}
+	masked := MaskFields[map[string]any]("serial")(SensorData[map[string]any]{Data: original})
+
+	if _, ok := masked.Data["serial"]; ok {
+		t.Error("Expected serial key to be removed")
+	}
+	if masked.Data["value"] != 1.0 {
+		t.Error("Expected other keys to be kept")
+	}
+	if _, ok := original["serial"]; !ok {
+		t.Error("MaskFields must not modify the original map")
+	}
+}
+
+// payloadRecorder records the JSON payload of every published reading
+type payloadRecorder[T any] struct {
+	mutex    sync.Mutex
+	payloads []string
+}
+
+func (p *payloadRecorder[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	return p.PublishBatch(ctx, []SensorData[T]{data})
+}
+
+func (p *payloadRecorder[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, d := range data {
+		payload, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		p.payloads = append(p.payloads, string(payload))
+	}
+	return nil
+}
+
+func (p *payloadRecorder[T]) Close() error {
+	return nil
+}
+
+func TestEngine_SetRedactFunc(t *testing.T) {
+	config := Config{
+		ProductionRate: 2 * time.Millisecond,
+		BatchSize:      5,
+		BatchTimeout:   10 * time.Millisecond,
+		MaxWorkers:     2,
+	}
+	function := NewLambdaSensorFunction(func(input float64, timestamp time.Time) deviceReading {
+		return deviceReading{Serial: "SN-SECRET", Value: input}
+	})
+	publisher := &payloadRecorder[deviceReading]{}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), function, publisher)
+	engine.SetRedactFunc(MaskFields[deviceReading]("serial"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	if len(publisher.payloads) == 0 {
+		t.Fatal("Expected readings to be published")
+	}
+	for _, payload := range publisher.payloads {
+		if strings.Contains(payload, "SN-SECRET") {
+			t.Fatalf("Serial leaked into published payload: %s", payload)
+		}
+	}
+}
+
+func BenchmarkMaskFields_Struct(b *testing.B) {
+	mask := MaskFields[deviceReading]("serial", "street")
+	data := SensorData[deviceReading]{
+		ID:   "sensor-1",
+		Data: deviceReading{Serial: "SN-123", Value: 21.5, Location: deviceAddress{Street: "1 Main St", City: "Springfield"}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		mask(data)
+	}
+}
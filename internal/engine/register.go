@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatusBit is one named flag of a status register and the condition that
+// raises it
+type StatusBit struct {
+	Name string
+	Bit  int // Position in the register, 0 for the least significant bit
+	// Set reports whether the flag is raised for a seeder input
+	Set func(input float64, timestamp time.Time) bool
+}
+
+// StatusRegister is a packed status word, as read from a Modbus holding
+// register or PLC status word, alongside its decoded flags
+type StatusRegister struct {
+	Value uint64          `json:"value"`
+	Flags map[string]bool `json:"flags"`
+}
+
+// StatusRegisterFunction models the bit-packed status registers industrial
+// devices expose: every flag is evaluated against the seeder input and the
+// raised ones are packed into a word of the register's width. Bits not
+// assigned to a flag are always clear.
+type StatusRegisterFunction struct {
+	width int
+	bits  []StatusBit
+}
+
+// NewStatusRegisterFunction creates a status register of width bits, at
+// most 64, holding the given flags. It panics if a flag has no name or
+// condition, or if two flags share a name or a bit that does not fit the
+// register.
+func NewStatusRegisterFunction(width int, bits ...StatusBit) *StatusRegisterFunction {
+	if width < 1 || width > 64 {
+		panic(fmt.Sprintf("engine: status register width must be between 1 and 64, got %d", width))
+	}
+	names := make(map[string]bool)
+	var used uint64
+	for _, b := range bits {
+		switch {
+		case b.Name == "":
+			panic("engine: status bit needs a name")
+		case b.Set == nil:
+			panic(fmt.Sprintf("engine: status bit %s needs a condition", b.Name))
+		case b.Bit < 0 || b.Bit >= width:
+			panic(fmt.Sprintf("engine: status bit %s at %d does not fit a %d-bit register", b.Name, b.Bit, width))
+		case names[b.Name]:
+			panic(fmt.Sprintf("engine: duplicate status bit name %s", b.Name))
+		case used&(1<<b.Bit) != 0:
+			panic(fmt.Sprintf("engine: status bit %s reuses bit %d", b.Name, b.Bit))
+		}
+		names[b.Name] = true
+		used |= 1 << b.Bit
+	}
+	return &StatusRegisterFunction{width: width, bits: append([]StatusBit(nil), bits...)}
+}
+
+// Generate evaluates every flag for input and returns the packed register
+func (r *StatusRegisterFunction) Generate(input float64, timestamp time.Time) StatusRegister {
+	flags := make(map[string]bool, len(r.bits))
+	for _, b := range r.bits {
+		flags[b.Name] = b.Set(input, timestamp)
+	}
+	return StatusRegister{Value: r.Encode(flags), Flags: flags}
+}
+
+// Encode packs flags into a register value. Names that are not flags of the
+// register are ignored.
+func (r *StatusRegisterFunction) Encode(flags map[string]bool) uint64 {
+	var value uint64
+	for _, b := range r.bits {
+		if flags[b.Name] {
+			value |= 1 << b.Bit
+		}
+	}
+	return value
+}
+
+// Decode unpacks a register value into the state of every flag. Set bits
+// not assigned to a flag are ignored.
+func (r *StatusRegisterFunction) Decode(value uint64) map[string]bool {
+	flags := make(map[string]bool, len(r.bits))
+	for _, b := range r.bits {
+		flags[b.Name] = value&(1<<b.Bit) != 0
+	}
+	return flags
+}
+
+// InputAbove returns a status bit condition raised when the seeder input
+// exceeds threshold
+func InputAbove(threshold float64) func(float64, time.Time) bool {
+	return func(input float64, timestamp time.Time) bool { return input > threshold }
+}
+
+// InputBelow returns a status bit condition raised when the seeder input is
+// under threshold
+func InputBelow(threshold float64) func(float64, time.Time) bool {
+	return func(input float64, timestamp time.Time) bool { return input < threshold }
+}
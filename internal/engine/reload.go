@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ConfigReload describes what reloading the config file of a running engine
+// changed
+type ConfigReload struct {
+	Applied []string // Settings applied to the running engine, as "name: old -> new"
+	Restart []string // Changed settings that only take effect after a restart
+}
+
+// Changed reports whether the reloaded definition differs from the running one
+func (r ConfigReload) Changed() bool {
+	return len(r.Applied) > 0 || len(r.Restart) > 0
+}
+
+// Reload applies the hot-reloadable settings of updated to the running
+// engine, whose definition is current: the production rate, batch size and
+// batch timeout, each only when it changed in the file. Any other
+// difference, such as the seeder or the output, is reported as needing a
+// restart. If updated is invalid an error is returned and nothing is
+// applied.
+func (e *Engine[T]) Reload(current, updated *ConfigFile) (ConfigReload, error) {
+	before, err := current.ToEngineConfig()
+	if err != nil {
+		return ConfigReload{}, fmt.Errorf("current config: %w", err)
+	}
+	after, err := reloadableConfig(updated)
+	if err != nil {
+		return ConfigReload{}, err
+	}
+
+	var reload ConfigReload
+	if after.ProductionRate != before.ProductionRate {
+		e.SetProductionRate(after.ProductionRate)
+		reload.applied("production_rate", before.ProductionRate, after.ProductionRate)
+	}
+	if after.BatchSize != before.BatchSize {
+		// A batch size of 1 skips the batch processor, so switching to or
+		// from it changes the pipeline
+		if before.BatchSize > 1 && after.BatchSize > 1 {
+			e.SetBatchSize(after.BatchSize)
+			reload.applied("batch_size", before.BatchSize, after.BatchSize)
+		} else {
+			reload.restart("batch_size", before.BatchSize, after.BatchSize)
+		}
+	}
+	if after.BatchTimeout != before.BatchTimeout {
+		// A timer only exists if the engine started with a batch timeout,
+		// and SetBatchTimeout cannot turn it off
+		if before.BatchTimeout > 0 && after.BatchTimeout > 0 {
+			e.SetBatchTimeout(after.BatchTimeout)
+			reload.applied("batch_timeout", before.BatchTimeout, after.BatchTimeout)
+		} else {
+			reload.restart("batch_timeout", before.BatchTimeout, after.BatchTimeout)
+		}
+	}
+
+	fixed := func(c *ConfigFile) EngineConfig {
+		settings := c.Engine
+		settings.ProductionRate, settings.BatchSize, settings.BatchTimeout = "", 0, ""
+		return settings
+	}
+	if !reflect.DeepEqual(fixed(current), fixed(updated)) {
+		reload.Restart = append(reload.Restart, "engine settings other than production_rate, batch_size and batch_timeout")
+	}
+	if current.Seeder.Type != updated.Seeder.Type {
+		reload.restart("seeder type", current.Seeder.Type, updated.Seeder.Type)
+	} else if !reflect.DeepEqual(current.Seeder, updated.Seeder) {
+		reload.Restart = append(reload.Restart, "seeder params")
+	}
+	if current.Output.Type != updated.Output.Type {
+		reload.restart("output type", current.Output.Type, updated.Output.Type)
+	} else if !reflect.DeepEqual(current.Output, updated.Output) {
+		reload.Restart = append(reload.Restart, "output settings")
+	}
+	return reload, nil
+}
+
+// reloadableConfig converts a reloaded definition, rejecting values the
+// setters would silently ignore
+func reloadableConfig(definition *ConfigFile) (Config, error) {
+	config, err := definition.ToEngineConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	if config.ProductionRate <= 0 {
+		return Config{}, fmt.Errorf("production rate must be positive, got %v", config.ProductionRate)
+	}
+	if config.BatchSize < 1 {
+		return Config{}, fmt.Errorf("batch size must be at least 1, got %d", config.BatchSize)
+	}
+	if config.BatchTimeout < 0 {
+		return Config{}, fmt.Errorf("batch timeout must not be negative, got %v", config.BatchTimeout)
+	}
+	return config, nil
+}
+
+func (r *ConfigReload) applied(name string, old, new any) {
+	r.Applied = append(r.Applied, fmt.Sprintf("%s: %v -> %v", name, old, new))
+}
+
+func (r *ConfigReload) restart(name string, old, new any) {
+	r.Restart = append(r.Restart, fmt.Sprintf("%s: %v -> %v", name, old, new))
+}
+
+// Reload reloads every engine of the group from updated, matching engine
+// definitions to the running engines by name, and returns what changed per
+// engine. Engines added to or removed from the file are reported as needing
+// a restart. Every definition is checked before anything is applied, so an
+// invalid file leaves all engines unchanged.
+func (g *EngineGroup[T]) Reload(current, updated *ConfigFile) (map[string]ConfigReload, error) {
+	running := make(map[string]ConfigFile)
+	for _, definition := range current.EngineDefinitions() {
+		running[definition.Name] = definition
+	}
+	reloaded := make(map[string]ConfigFile)
+	for _, definition := range updated.EngineDefinitions() {
+		if _, err := reloadableConfig(&definition); err != nil {
+			return nil, fmt.Errorf("engine %s: %w", definition.Name, err)
+		}
+		reloaded[definition.Name] = definition
+	}
+
+	changes := make(map[string]ConfigReload)
+	for i, name := range g.Names {
+		before, ok := running[name]
+		after, found := reloaded[name]
+		if !ok {
+			continue
+		}
+		if !found {
+			changes[name] = ConfigReload{Restart: []string{"engine removed from the config"}}
+			continue
+		}
+		reload, err := g.Engines[i].Reload(&before, &after)
+		if err != nil {
+			return changes, fmt.Errorf("engine %s: %w", name, err)
+		}
+		if reload.Changed() {
+			changes[name] = reload
+		}
+	}
+	for name := range reloaded {
+		if !slices.Contains(g.Names, name) {
+			changes[name] = ConfigReload{Restart: []string{"engine added to the config"}}
+		}
+	}
+	return changes, nil
+}
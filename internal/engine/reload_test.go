@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// parseGroupConfig parses groupConfig, letting edit change it first
+func parseGroupConfig(t *testing.T, edit func(*ConfigFile)) *ConfigFile {
+	t.Helper()
+	var config ConfigFile
+	if err := json.Unmarshal([]byte(groupConfig), &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if edit != nil {
+		edit(&config)
+	}
+	return &config
+}
+
+func newReloadGroup(t *testing.T, config *ConfigFile) *EngineGroup[float64] {
+	t.Helper()
+	group, err := NewEngineGroup(config, func(*ConfigFile) (SensorFunction[float64], Publisher[float64], error) {
+		return NewTestSensorFunction(1), NewMockPublisher[float64](), nil
+	})
+	if err != nil {
+		t.Fatalf("NewEngineGroup failed: %v", err)
+	}
+	return group
+}
+
+func TestEngineGroup_Reload(t *testing.T) {
+	current := parseGroupConfig(t, nil)
+	group := newReloadGroup(t, current)
+
+	updated := parseGroupConfig(t, func(c *ConfigFile) {
+		c.Engines[0].Engine.ProductionRate = "10ms"
+		c.Engines[0].Engine.BatchSize = 20
+		c.Engines[0].Engine.BatchTimeout = "50ms"
+		c.Engines[1].Seeder.Type = "normal"
+		c.Engines = append(c.Engines, ConfigFile{
+			Name:   "extra",
+			Engine: EngineConfig{ProductionRate: "1s", BatchSize: 1, BatchTimeout: "1s", MaxWorkers: 1},
+		})
+	})
+	changes, err := group.Reload(current, updated)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	healthy := group.Engines[0]
+	if healthy.ProductionRate() != 10*time.Millisecond || healthy.BatchSize() != 20 || healthy.BatchTimeout() != 50*time.Millisecond {
+		t.Errorf("Expected the new rate, batch size and timeout, got %v, %d and %v",
+			healthy.ProductionRate(), healthy.BatchSize(), healthy.BatchTimeout())
+	}
+	want := []string{"production_rate: 5ms -> 10ms", "batch_size: 5 -> 20", "batch_timeout: 20ms -> 50ms"}
+	if got := changes["healthy"]; !slices.Equal(got.Applied, want) || len(got.Restart) != 0 {
+		t.Errorf("Expected %v applied to healthy, got %+v", want, got)
+	}
+
+	broken := changes["broken"]
+	if len(broken.Applied) != 0 || !slices.Equal(broken.Restart, []string{"seeder type: random -> normal"}) {
+		t.Errorf("Expected the seeder change to need a restart, got %+v", broken)
+	}
+	if group.Engines[1].ProductionRate() != 5*time.Millisecond {
+		t.Errorf("Expected the broken engine's rate unchanged, got %v", group.Engines[1].ProductionRate())
+	}
+	if extra := changes["extra"]; len(extra.Restart) != 1 {
+		t.Errorf("Expected the added engine to need a restart, got %+v", extra)
+	}
+
+	// Reloading the same file changes nothing
+	changes, err = group.Reload(updated, updated)
+	if err != nil || len(changes) != 1 {
+		t.Errorf("Expected only the added engine reported, got %+v (%v)", changes, err)
+	}
+}
+
+func TestEngineGroup_ReloadInvalid(t *testing.T) {
+	current := parseGroupConfig(t, nil)
+	group := newReloadGroup(t, current)
+
+	updated := parseGroupConfig(t, func(c *ConfigFile) {
+		c.Engines[0].Engine.ProductionRate = "1ms"
+		c.Engines[1].Engine.BatchSize = 0
+	})
+	if _, err := group.Reload(current, updated); err == nil {
+		t.Fatal("Expected an error for a batch size of 0")
+	}
+	if rate := group.Engines[0].ProductionRate(); rate != 5*time.Millisecond {
+		t.Errorf("Expected no engine changed by an invalid file, got rate %v", rate)
+	}
+}
+
+func TestEngine_ReloadBatchTimeoutNeedsRestart(t *testing.T) {
+	current := parseGroupConfig(t, nil).EngineDefinitions()[0]
+	updated := current
+	updated.Engine.BatchTimeout = "0s"
+
+	engine := newReloadGroup(t, &current).Engines[0]
+	reload, err := engine.Reload(&current, &updated)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(reload.Applied) != 0 || !slices.Equal(reload.Restart, []string{"batch_timeout: 20ms -> 0s"}) {
+		t.Errorf("Expected turning off the batch timeout to need a restart, got %+v", reload)
+	}
+}
+
+func TestEngine_ReloadBatchSizeOneNeedsRestart(t *testing.T) {
+	current := parseGroupConfig(t, nil).EngineDefinitions()[0]
+	updated := current
+	updated.Engine.BatchSize = 1
+
+	engine := newReloadGroup(t, &current).Engines[0]
+	reload, err := engine.Reload(&current, &updated)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(reload.Applied) != 0 || !slices.Equal(reload.Restart, []string{"batch_size: 5 -> 1"}) {
+		t.Errorf("Expected switching to single publishes to need a restart, got %+v", reload)
+	}
+}
+
+func TestEngine_SetBatchSizeWhileRunning(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      1000,
+		BatchTimeout:   time.Hour,
+		MaxWorkers:     1,
+	}
+	publisher := NewMockPublisher[float64]()
+	engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), publisher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	time.AfterFunc(20*time.Millisecond, func() { engine.SetBatchSize(5) })
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if len(publisher.batches) < 3 {
+		t.Fatalf("Expected batches of the new size while running, got %d batches", len(publisher.batches))
+	}
+	for _, batch := range publisher.batches[1 : len(publisher.batches)-1] {
+		if len(batch) != 5 {
+			t.Errorf("Expected batches of 5 after SetBatchSize, got %d", len(batch))
+		}
+	}
+}
@@ -1,37 +1,80 @@
 package engine
 
 import (
+	"fmt"
 	"math"
 	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 )
 
-// TimeSeeder generates values based on time
+// TimeSeeder generates a sine wave over the time elapsed since it was created.
+// Frequency is in cycles per second, so a frequency of 0.1 gives a 10 second period.
 type TimeSeeder struct {
 	amplitude float64
 	frequency float64
 	offset    float64
+	phase     float64 // Radians added to the wave's angle
+	clock     Clock
+	mutex     sync.Mutex // Guards start, which RestoreState replaces
+	start     time.Time
 }
 
 // NewTimeSeeder creates a new time-based seeder
 func NewTimeSeeder(amplitude, frequency, offset float64) *TimeSeeder {
+	return NewTimeSeederWithClock(amplitude, frequency, offset, systemClock{})
+}
+
+// NewTimeSeederWithPhase creates a time-based seeder whose wave is shifted
+// by phase radians. The phase is relative to the seeder's own start, not
+// the epoch, so two seeders created together with phases 0 and math.Pi/2
+// stay a quarter period apart, as sensors at different positions along a
+// travelling wave would.
+func NewTimeSeederWithPhase(amplitude, frequency, offset, phase float64) *TimeSeeder {
+	t := NewTimeSeeder(amplitude, frequency, offset)
+	t.phase = phase
+	return t
+}
+
+// NewTimeSeederWithClock creates a time-based seeder that reads the time
+// from clock, starting the wave at the clock's current time
+func NewTimeSeederWithClock(amplitude, frequency, offset float64, clock Clock) *TimeSeeder {
 	return &TimeSeeder{
 		amplitude: amplitude,
 		frequency: frequency,
 		offset:    offset,
+		clock:     clock,
+		start:     clock.Now(),
 	}
 }
 
-// Generate generates a value based on current time
+// Generate generates a value based on the time elapsed since creation
 func (t *TimeSeeder) Generate() float64 {
-	now := float64(time.Now().UnixNano()) / 1e9 // Convert to seconds with higher precision
-	return t.amplitude*math.Sin(t.frequency*now) + t.offset
+	return t.GenerateAt(t.clock.Now())
+}
+
+// GenerateAt generates the value the seeder has at instant now
+func (t *TimeSeeder) GenerateAt(now time.Time) float64 {
+	t.mutex.Lock()
+	start := t.start
+	t.mutex.Unlock()
+	return t.at(now.Sub(start).Seconds())
+}
+
+// at returns the value of the wave the given number of seconds after start
+func (t *TimeSeeder) at(elapsed float64) float64 {
+	return t.amplitude*math.Sin(2*math.Pi*t.frequency*elapsed+t.phase) + t.offset
 }
 
 // RandomSeeder generates random values within a range
 type RandomSeeder struct {
-	min float64
-	max float64
+	min   float64
+	max   float64
+	src   rand.Source // Kept for snapshots
+	rng   *rand.Rand  // nil for the global source
+	mutex sync.Mutex  // Guards rng, whose sources are not safe for concurrent use
 }
 
 // NewRandomSeeder creates a new random seeder
@@ -42,8 +85,24 @@ func NewRandomSeeder(min, max float64) *RandomSeeder {
 	}
 }
 
+// NewRandomSeederWithSource creates a random seeder drawing from src, so a
+// seeded source produces a reproducible sequence
+func NewRandomSeederWithSource(min, max float64, src rand.Source) *RandomSeeder {
+	return &RandomSeeder{
+		min: min,
+		max: max,
+		src: src,
+		rng: rand.New(src),
+	}
+}
+
 // Generate generates a random value between min and max
 func (r *RandomSeeder) Generate() float64 {
+	if r.rng != nil {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		return r.min + r.rng.Float64()*(r.max-r.min)
+	}
 	return r.min + rand.Float64()*(r.max-r.min)
 }
 
@@ -51,21 +110,37 @@ func (r *RandomSeeder) Generate() float64 {
 type LinearSeeder struct {
 	slope  float64
 	offset float64
+	clock  Clock
+	mutex  sync.Mutex // Guards start, which RestoreState replaces
 	start  time.Time
 }
 
 // NewLinearSeeder creates a new linear seeder
 func NewLinearSeeder(slope, offset float64) *LinearSeeder {
+	return NewLinearSeederWithClock(slope, offset, systemClock{})
+}
+
+// NewLinearSeederWithClock creates a linear seeder that reads the time from
+// clock, starting at the clock's current time
+func NewLinearSeederWithClock(slope, offset float64, clock Clock) *LinearSeeder {
 	return &LinearSeeder{
 		slope:  slope,
 		offset: offset,
-		start:  time.Now(),
+		clock:  clock,
+		start:  clock.Now(),
 	}
 }
 
 // Generate generates a value that increases linearly
 func (l *LinearSeeder) Generate() float64 {
-	elapsed := float64(time.Since(l.start).Seconds())
+	return l.GenerateAt(l.clock.Now())
+}
+
+// GenerateAt generates the value the seeder has at instant now
+func (l *LinearSeeder) GenerateAt(now time.Time) float64 {
+	l.mutex.Lock()
+	elapsed := now.Sub(l.start).Seconds()
+	l.mutex.Unlock()
 	return l.slope*elapsed + l.offset
 }
 
@@ -90,17 +165,472 @@ func (c *CustomSeeder) Generate() float64 {
 type NormalSeeder struct {
 	mean   float64
 	stdDev float64
+	src    rand.Source // Kept for snapshots
+	rng    *rand.Rand  // nil for the global source
+	mutex  sync.Mutex  // Guards rng, whose sources are not safe for concurrent use
 }
 
-// NewNormalSeeder creates a new normal distribution seeder
+// NewNormalSeeder creates a new normal distribution seeder. A stdDev of 0
+// always returns the mean. It panics if stdDev is negative or NaN.
 func NewNormalSeeder(mean, stdDev float64) *NormalSeeder {
+	checkStdDev(stdDev)
 	return &NormalSeeder{
 		mean:   mean,
 		stdDev: stdDev,
 	}
 }
 
+// NewNormalSeederWithSource creates a normal distribution seeder drawing
+// from src, so a seeded source produces a reproducible sequence. It panics
+// if stdDev is negative or NaN.
+func NewNormalSeederWithSource(mean, stdDev float64, src rand.Source) *NormalSeeder {
+	checkStdDev(stdDev)
+	return &NormalSeeder{
+		mean:   mean,
+		stdDev: stdDev,
+		src:    src,
+		rng:    rand.New(src),
+	}
+}
+
+// checkStdDev panics on a standard deviation that is not a non-negative
+// number. A negative value would silently behave like its absolute value.
+func checkStdDev(stdDev float64) {
+	if !(stdDev >= 0) {
+		panic(fmt.Sprintf("engine: normal seeder standard deviation must be non-negative, got %v", stdDev))
+	}
+}
+
 // Generate generates a value from a normal distribution
 func (n *NormalSeeder) Generate() float64 {
+	if n.rng != nil {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		return n.rng.NormFloat64()*n.stdDev + n.mean
+	}
 	return rand.NormFloat64()*n.stdDev + n.mean
 }
+
+// ReduceSeeder combines several seeders by applying a reduce function to
+// their values on every call
+type ReduceSeeder struct {
+	seeders []Seeder
+	reduce  func([]float64) float64
+	mutex   sync.Mutex // Guards values
+	values  []float64
+}
+
+// NewReduceSeeder creates a seeder that reduces the outputs of the inner seeders
+func NewReduceSeeder(reduce func([]float64) float64, seeders ...Seeder) *ReduceSeeder {
+	return &ReduceSeeder{
+		seeders: seeders,
+		reduce:  reduce,
+		values:  make([]float64, len(seeders)),
+	}
+}
+
+// Generate calls every inner seeder and reduces their values
+func (r *ReduceSeeder) Generate() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, s := range r.seeders {
+		r.values[i] = s.Generate()
+	}
+	return r.reduce(r.values)
+}
+
+// GenerateAt evaluates time-based inner seeders at instant now and calls the
+// others as usual before reducing
+func (r *ReduceSeeder) GenerateAt(now time.Time) float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, s := range r.seeders {
+		if clocked, ok := s.(ClockedSeeder); ok {
+			r.values[i] = clocked.GenerateAt(now)
+		} else {
+			r.values[i] = s.Generate()
+		}
+	}
+	return r.reduce(r.values)
+}
+
+// LagSeeder makes an inner seeder's values trail changes in its output the
+// way a real sensor lags the quantity it measures, by passing them through a
+// first-order low-pass filter: y += (x - y) * alpha. A step in the input is
+// approached exponentially, closing a fraction alpha of the remaining gap on
+// every call. The first value is passed through unfiltered.
+type LagSeeder struct {
+	inner   Seeder
+	alpha   float64
+	mutex   sync.Mutex // Guards value and started
+	value   float64
+	started bool
+}
+
+// NewLagSeeder creates a lagging seeder. Smaller alphas lag more; 1 disables
+// the lag. It panics if alpha is not in (0, 1].
+func NewLagSeeder(inner Seeder, alpha float64) *LagSeeder {
+	checkLagAlpha(alpha)
+	return &LagSeeder{
+		inner: inner,
+		alpha: alpha,
+	}
+}
+
+// checkLagAlpha panics on a smoothing factor outside (0, 1], which would
+// freeze or overshoot the output
+func checkLagAlpha(alpha float64) {
+	if !(alpha > 0 && alpha <= 1) {
+		panic(fmt.Sprintf("engine: lag alpha must be in (0, 1], got %v", alpha))
+	}
+}
+
+// Generate returns the filtered value of the inner seeder
+func (l *LagSeeder) Generate() float64 {
+	return l.filter(l.inner.Generate())
+}
+
+// GenerateAt filters the inner seeder's value at instant now, or its next
+// value if it is not time-based
+func (l *LagSeeder) GenerateAt(now time.Time) float64 {
+	if clocked, ok := l.inner.(ClockedSeeder); ok {
+		return l.filter(clocked.GenerateAt(now))
+	}
+	return l.filter(l.inner.Generate())
+}
+
+// filter moves the output a fraction alpha of the way towards x
+func (l *LagSeeder) filter(x float64) float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.started {
+		l.value, l.started = x, true
+		return x
+	}
+	l.value += (x - l.value) * l.alpha
+	return l.value
+}
+
+// MarketConfig shapes the values of a MarketSeeder: a base level moved by a
+// fast market cycle, a slow trend and uniform noise, clamped to [Min, Max].
+// Periods are counted in Generate calls.
+type MarketConfig struct {
+	Base           float64 // Level the value moves around
+	CycleAmplitude float64 // Amplitude of the market cycle
+	CyclePeriod    float64 // Calls per market cycle
+	TrendAmplitude float64 // Amplitude of the slow trend
+	TrendPeriod    float64 // Calls per trend cycle
+	Noise          float64 // Width of the uniform noise band centred on 0
+	Min            float64 // Lower bound of the values
+	Max            float64 // Upper bound of the values; no clamping unless Max > Min
+}
+
+// DefaultMarketConfig returns a market sentiment between 0 (bear) and 1
+// (bull) that cycles every ~630 calls and trends over ~6300
+func DefaultMarketConfig() MarketConfig {
+	return MarketConfig{
+		Base:           0.5,
+		CycleAmplitude: 0.3,
+		CyclePeriod:    200 * math.Pi,
+		TrendAmplitude: 0.2,
+		TrendPeriod:    2000 * math.Pi,
+		Noise:          0.2,
+		Min:            0,
+		Max:            1,
+	}
+}
+
+// MarketSeeder simulates market sentiment for financial metrics, advancing
+// its cycles by one step per call
+type MarketSeeder struct {
+	config MarketConfig
+	src    rand.Source // Kept for snapshots
+	rng    *rand.Rand  // nil for the global source
+	mutex  sync.Mutex  // Guards step and rng
+	step   uint64
+}
+
+// NewMarketSeeder creates a market seeder. It panics if a period is not
+// positive.
+func NewMarketSeeder(config MarketConfig) *MarketSeeder {
+	checkMarketConfig(config)
+	return &MarketSeeder{config: config}
+}
+
+// NewMarketSeederWithSource creates a market seeder drawing its noise from
+// src, so a seeded source produces a reproducible sequence. It panics if a
+// period is not positive.
+func NewMarketSeederWithSource(config MarketConfig, src rand.Source) *MarketSeeder {
+	checkMarketConfig(config)
+	return &MarketSeeder{config: config, src: src, rng: rand.New(src)}
+}
+
+// checkMarketConfig panics on periods that would divide by zero or run the
+// cycles backwards
+func checkMarketConfig(config MarketConfig) {
+	if !(config.CyclePeriod > 0) || !(config.TrendPeriod > 0) {
+		panic(fmt.Sprintf("engine: market seeder periods must be positive, got cycle %v and trend %v", config.CyclePeriod, config.TrendPeriod))
+	}
+}
+
+// Generate returns the next market sentiment value
+func (m *MarketSeeder) Generate() float64 {
+	m.mutex.Lock()
+	m.step++
+	step := float64(m.step)
+	var draw float64
+	if m.rng != nil {
+		draw = m.rng.Float64()
+	} else {
+		draw = rand.Float64()
+	}
+	m.mutex.Unlock()
+
+	c := m.config
+	value := c.Base +
+		c.CycleAmplitude*math.Sin(2*math.Pi*step/c.CyclePeriod) +
+		c.TrendAmplitude*math.Sin(2*math.Pi*step/c.TrendPeriod) +
+		(draw-0.5)*c.Noise
+	if c.Max > c.Min {
+		value = math.Max(c.Min, math.Min(c.Max, value))
+	}
+	return value
+}
+
+// HistogramSeeder samples an empirical distribution given as a histogram,
+// such as one observed from a real sensor: bin i spans [edges[i],
+// edges[i+1]) and is picked with probability proportional to weights[i],
+// and the value is uniform within the bin
+type HistogramSeeder struct {
+	edges      []float64
+	cumulative []float64   // Running totals of the weights
+	src        rand.Source // Kept for snapshots
+	rng        *rand.Rand  // nil for the global source
+	mutex      sync.Mutex  // Guards rng, whose sources are not safe for concurrent use
+}
+
+// NewHistogramSeeder creates a histogram seeder from bin edges and one
+// weight per bin, such as observed counts. It panics if the histogram is
+// invalid; see ValidateHistogram.
+func NewHistogramSeeder(edges, weights []float64) *HistogramSeeder {
+	return newHistogramSeeder(edges, weights, nil)
+}
+
+// NewHistogramSeederWithSource creates a histogram seeder drawing from src,
+// so a seeded source produces a reproducible sequence. It panics if the
+// histogram is invalid; see ValidateHistogram.
+func NewHistogramSeederWithSource(edges, weights []float64, src rand.Source) *HistogramSeeder {
+	return newHistogramSeeder(edges, weights, src)
+}
+
+func newHistogramSeeder(edges, weights []float64, src rand.Source) *HistogramSeeder {
+	if err := ValidateHistogram(edges, weights); err != nil {
+		panic("engine: " + err.Error())
+	}
+	h := &HistogramSeeder{
+		edges:      slices.Clone(edges),
+		cumulative: make([]float64, len(weights)),
+		src:        src,
+	}
+	total := 0.0
+	for i, weight := range weights {
+		total += weight
+		h.cumulative[i] = total
+	}
+	if src != nil {
+		h.rng = rand.New(src)
+	}
+	return h
+}
+
+// ValidateHistogram checks that edges are finite and strictly increasing,
+// that there is one weight per bin, one fewer than edges, and that the
+// weights are finite, non-negative and not all zero
+func ValidateHistogram(edges, weights []float64) error {
+	if len(weights) == 0 || len(edges) != len(weights)+1 {
+		return fmt.Errorf("histogram needs one more edge than weights and at least one bin, got %d edges and %d weights", len(edges), len(weights))
+	}
+	for i, edge := range edges {
+		if math.IsNaN(edge) || math.IsInf(edge, 0) || (i > 0 && edge <= edges[i-1]) {
+			return fmt.Errorf("histogram edges must be finite and strictly increasing, got %v", edges)
+		}
+	}
+	total := 0.0
+	for _, weight := range weights {
+		if !(weight >= 0) || math.IsInf(weight, 0) {
+			return fmt.Errorf("histogram weights must be finite and non-negative, got %v", weights)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("histogram weights must not all be zero")
+	}
+	return nil
+}
+
+// Generate draws a value from the histogram
+func (h *HistogramSeeder) Generate() float64 {
+	var draw float64
+	if h.rng != nil {
+		h.mutex.Lock()
+		draw = h.rng.Float64()
+		h.mutex.Unlock()
+	} else {
+		draw = rand.Float64()
+	}
+
+	// One draw picks the bin and, by where it falls within the bin's share
+	// of the total weight, the position within the bin
+	target := draw * h.cumulative[len(h.cumulative)-1]
+	bin := sort.Search(len(h.cumulative), func(i int) bool { return h.cumulative[i] > target })
+	bin = min(bin, len(h.cumulative)-1)
+	low := 0.0
+	if bin > 0 {
+		low = h.cumulative[bin-1]
+	}
+	fraction := (target - low) / (h.cumulative[bin] - low)
+	return h.edges[bin] + fraction*(h.edges[bin+1]-h.edges[bin])
+}
+
+// MixtureSeederComponent is one seeder of a MixtureSeeder and its relative
+// weight
+type MixtureSeederComponent struct {
+	Seeder Seeder
+	Weight float64
+}
+
+// MixtureSeeder samples a mixture distribution, such as a bimodal one from
+// two NormalSeeders for a process with two operating regimes: on every call
+// it picks one of its seeders at random, with probability proportional to
+// its weight, and returns that seeder's value. It is the seeder analog of
+// MixtureFunction. Only the picked seeder is called, so stateful components
+// advance only when picked.
+type MixtureSeeder struct {
+	picker *CategoricalFunction[Seeder]
+	rng    *rand.Rand // nil for the global source
+	mutex  sync.Mutex // Guards rng, whose sources are not safe for concurrent use
+}
+
+// NewMixtureSeeder creates a mixture of seeders. Weights need not sum to
+// one. It panics if there are no components, a component has no seeder, or
+// the weights are negative, not finite or all zero.
+func NewMixtureSeeder(components ...MixtureSeederComponent) *MixtureSeeder {
+	if err := validateMixtureSeeder(components); err != nil {
+		panic("engine: " + err.Error())
+	}
+	categories := make([]Category[Seeder], len(components))
+	for i, c := range components {
+		categories[i] = Category[Seeder]{Value: c.Seeder, Weight: c.Weight}
+	}
+	return &MixtureSeeder{picker: NewCategoricalFunction(categories...)}
+}
+
+// NewMixtureSeederWithSource creates a mixture of seeders that draws its
+// choices from src, so a seeded source selects a reproducible sequence of
+// components. It panics like NewMixtureSeeder.
+func NewMixtureSeederWithSource(src rand.Source, components ...MixtureSeederComponent) *MixtureSeeder {
+	m := NewMixtureSeeder(components...)
+	m.rng = rand.New(src)
+	return m
+}
+
+// validateMixtureSeeder checks that there is at least one component, every
+// component has a seeder, and the weights are finite, non-negative and not
+// all zero
+func validateMixtureSeeder(components []MixtureSeederComponent) error {
+	if len(components) == 0 {
+		return fmt.Errorf("mixture seeder needs at least one component")
+	}
+	total := 0.0
+	for i, c := range components {
+		if c.Seeder == nil {
+			return fmt.Errorf("mixture seeder component %d has no seeder", i)
+		}
+		if !(c.Weight >= 0) || math.IsInf(c.Weight, 0) {
+			return fmt.Errorf("mixture seeder weights must be finite and non-negative, got %v for component %d", c.Weight, i)
+		}
+		total += c.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("mixture seeder weights must not all be zero")
+	}
+	return nil
+}
+
+// pick draws a component by weight
+func (m *MixtureSeeder) pick() Seeder {
+	var draw float64
+	if m.rng != nil {
+		m.mutex.Lock()
+		draw = m.rng.Float64()
+		m.mutex.Unlock()
+	} else {
+		draw = rand.Float64()
+	}
+	return m.picker.Pick(draw)
+}
+
+// Generate picks a seeder by weight and returns its value
+func (m *MixtureSeeder) Generate() float64 {
+	return m.pick().Generate()
+}
+
+// GenerateAt picks a seeder by weight and evaluates it at instant now, or
+// takes its next value if it is not time-based
+func (m *MixtureSeeder) GenerateAt(now time.Time) float64 {
+	seeder := m.pick()
+	if clocked, ok := seeder.(ClockedSeeder); ok {
+		return clocked.GenerateAt(now)
+	}
+	return seeder.Generate()
+}
+
+// ReduceSum returns the sum of values
+func ReduceSum(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// ReduceMean returns the arithmetic mean of values, or 0 for no values
+func ReduceMean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return ReduceSum(values) / float64(len(values))
+}
+
+// ReduceMax returns the largest of values, or 0 for no values
+func ReduceMax(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	result := values[0]
+	for _, v := range values[1:] {
+		result = math.Max(result, v)
+	}
+	return result
+}
+
+// ReduceMin returns the smallest of values, or 0 for no values
+func ReduceMin(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	result := values[0]
+	for _, v := range values[1:] {
+		result = math.Min(result, v)
+	}
+	return result
+}
+
+// ReduceProduct returns the product of values
+func ReduceProduct(values []float64) float64 {
+	product := 1.0
+	for _, v := range values {
+		product *= v
+	}
+	return product
+}
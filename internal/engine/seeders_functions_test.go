@@ -2,6 +2,12 @@ package engine
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -23,6 +29,56 @@ func TestTimeSeeder(t *testing.T) {
 	}
 }
 
+func TestTimeSeeder_Period(t *testing.T) {
+	seeder := NewTimeSeeder(2.0, 0.1, 5.0) // 10 second period
+
+	const tolerance = 1e-9
+	if v := seeder.at(0); math.Abs(v-5.0) > tolerance {
+		t.Errorf("Expected offset 5.0 at start, got %f", v)
+	}
+	if v := seeder.at(2.5); math.Abs(v-7.0) > tolerance {
+		t.Errorf("Expected peak 7.0 at quarter period, got %f", v)
+	}
+	if v := seeder.at(7.5); math.Abs(v-3.0) > tolerance {
+		t.Errorf("Expected trough 3.0 at three-quarter period, got %f", v)
+	}
+	for _, elapsed := range []float64{0.7, 3.3, 8.1} {
+		if a, b := seeder.at(elapsed), seeder.at(elapsed+10); math.Abs(a-b) > tolerance {
+			t.Errorf("Expected 10s period: at(%.1f)=%f, at(%.1f)=%f", elapsed, a, elapsed+10, b)
+		}
+	}
+
+	// A freshly created seeder starts near the offset regardless of wall-clock time
+	if v := seeder.Generate(); math.Abs(v-5.0) > 0.1 {
+		t.Errorf("Expected value near offset right after creation, got %f", v)
+	}
+}
+
+func TestTimeSeeder_Phase(t *testing.T) {
+	base := NewTimeSeeder(1.0, 0.25, 10.0) // 4 second period
+	shifted := NewTimeSeederWithPhase(1.0, 0.25, 10.0, math.Pi/2)
+
+	const tolerance = 1e-9
+	if v := shifted.at(0); math.Abs(v-11.0) > tolerance {
+		t.Errorf("Expected a quarter-period shift to start at the peak, got %f", v)
+	}
+	// The shifted wave leads the base wave by a quarter period, 1 second
+	for _, elapsed := range []float64{0, 0.4, 1.7, 3.2} {
+		if a, b := shifted.at(elapsed), base.at(elapsed+1); math.Abs(a-b) > tolerance {
+			t.Errorf("Expected shifted.at(%.1f)=%f to equal base.at(%.1f)=%f", elapsed, a, elapsed+1, b)
+		}
+	}
+
+	config := &ConfigFile{Seeder: SeederConfig{Type: "time", Params: map[string]interface{}{"frequency": 0.25, "offset": 10.0, "phase": math.Pi}}}
+	seeder, err := config.CreateSeeder()
+	if err != nil {
+		t.Fatalf("CreateSeeder failed: %v", err)
+	}
+	if v := seeder.(*TimeSeeder).at(1); math.Abs(v-9.0) > tolerance {
+		t.Errorf("Expected the configured phase to put the trough at 1s, got %f", v)
+	}
+}
+
 func TestRandomSeeder(t *testing.T) {
 	min, max := 10.0, 20.0
 	seeder := NewRandomSeeder(min, max)
@@ -75,6 +131,33 @@ func TestNormalSeeder(t *testing.T) {
 	}
 }
 
+func TestNormalSeeder_ZeroStdDevReturnsMean(t *testing.T) {
+	seeder := NewNormalSeeder(42, 0)
+	for range 100 {
+		if v := seeder.Generate(); v != 42 {
+			t.Fatalf("Expected the mean with zero stdDev, got %v", v)
+		}
+	}
+}
+
+func TestNormalSeeder_InvalidStdDevPanics(t *testing.T) {
+	for _, stdDev := range []float64{-1, math.NaN()} {
+		t.Run(fmt.Sprint(stdDev), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewNormalSeeder to panic for stdDev %v", stdDev)
+				}
+			}()
+			NewNormalSeeder(0, stdDev)
+		})
+	}
+
+	config := &ConfigFile{Seeder: SeederConfig{Type: "normal", Params: map[string]interface{}{"std_dev": -2.0}}}
+	if _, err := config.CreateSeeder(); err == nil {
+		t.Error("Expected an error for a negative std_dev in the config")
+	}
+}
+
 func TestCustomSeeder(t *testing.T) {
 	calls := 0
 	seeder := NewCustomSeeder(func() float64 {
@@ -94,6 +177,304 @@ func TestCustomSeeder(t *testing.T) {
 	}
 }
 
+func TestReduceSeeder(t *testing.T) {
+	inner := func() []Seeder {
+		return []Seeder{
+			NewCustomSeeder(func() float64 { return 2.0 }),
+			NewCustomSeeder(func() float64 { return -3.0 }),
+			NewCustomSeeder(func() float64 { return 4.0 }),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		reduce func([]float64) float64
+		want   float64
+	}{
+		{"sum", ReduceSum, 3.0},
+		{"mean", ReduceMean, 1.0},
+		{"max", ReduceMax, 4.0},
+		{"min", ReduceMin, -3.0},
+		{"product", ReduceProduct, -24.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seeder := NewReduceSeeder(tt.reduce, inner()...)
+			if got := seeder.Generate(); got != tt.want {
+				t.Errorf("Expected %f, got %f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReduceSeeder_CallsEveryInnerSeeder(t *testing.T) {
+	calls := make([]int, 3)
+	seeders := make([]Seeder, len(calls))
+	for i := range seeders {
+		seeders[i] = NewCustomSeeder(func() float64 {
+			calls[i]++
+			return float64(i)
+		})
+	}
+
+	seeder := NewReduceSeeder(ReduceMax, seeders...)
+	for i := 0; i < 5; i++ {
+		seeder.Generate()
+	}
+
+	for i, c := range calls {
+		if c != 5 {
+			t.Errorf("Inner seeder %d called %d times, expected 5", i, c)
+		}
+	}
+}
+
+func TestMarketSeeder(t *testing.T) {
+	config := MarketConfig{
+		Base:           10,
+		CycleAmplitude: 2,
+		CyclePeriod:    4,
+		TrendAmplitude: 1,
+		TrendPeriod:    8,
+	}
+	seeder := NewMarketSeeder(config)
+
+	// Without noise the value is the base plus both cycles at each step
+	for step := 1; step <= 8; step++ {
+		want := 10 + 2*math.Sin(2*math.Pi*float64(step)/4) + math.Sin(2*math.Pi*float64(step)/8)
+		if got := seeder.Generate(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Step %d: expected %v, got %v", step, want, got)
+		}
+	}
+}
+
+func TestMarketSeeder_DefaultStaysInBounds(t *testing.T) {
+	seeder := NewMarketSeederWithSource(DefaultMarketConfig(), rand.NewPCG(1, 2))
+	low, high := math.Inf(1), math.Inf(-1)
+	for range 10000 {
+		v := seeder.Generate()
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	if low < 0 || high > 1 {
+		t.Errorf("Expected values in [0, 1], got [%v, %v]", low, high)
+	}
+	if high-low < 0.5 {
+		t.Errorf("Expected the cycles to span most of the range, got [%v, %v]", low, high)
+	}
+
+	again := NewMarketSeederWithSource(DefaultMarketConfig(), rand.NewPCG(1, 2))
+	seeder = NewMarketSeederWithSource(DefaultMarketConfig(), rand.NewPCG(1, 2))
+	for i := range 100 {
+		if a, b := seeder.Generate(), again.Generate(); a != b {
+			t.Fatalf("Value %d differs between equally seeded seeders: %v and %v", i, a, b)
+		}
+	}
+}
+
+func TestMarketSeeder_InvalidPeriodPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a zero cycle period")
+		}
+	}()
+	config := DefaultMarketConfig()
+	config.CyclePeriod = 0
+	NewMarketSeeder(config)
+}
+
+func TestHistogramSeeder_MatchesWeights(t *testing.T) {
+	edges := []float64{0, 1, 2, 4}
+	weights := []float64{1, 0, 3}
+	seeder := NewHistogramSeederWithSource(edges, weights, rand.NewPCG(1, 2))
+
+	const samples = 100000
+	counts := make([]int, len(weights))
+	upperSum := 0.0
+	for range samples {
+		v := seeder.Generate()
+		if v < 0 || v >= 4 {
+			t.Fatalf("Sample %v outside the histogram [0, 4)", v)
+		}
+		bin := 0
+		for v >= edges[bin+1] {
+			bin++
+		}
+		counts[bin]++
+		if bin == 2 {
+			upperSum += v
+		}
+	}
+
+	for i, weight := range weights {
+		want := weight / 4
+		if got := float64(counts[i]) / samples; math.Abs(got-want) > 0.01 {
+			t.Errorf("Bin %d: expected a share of %.2f, got %.3f", i, want, got)
+		}
+	}
+	// Values are uniform within a bin, so the widest bin averages its middle
+	if mean := upperSum / float64(counts[2]); math.Abs(mean-3) > 0.02 {
+		t.Errorf("Expected values in [2, 4) to average 3, got %.3f", mean)
+	}
+}
+
+func TestMixtureSeeder_Bimodal(t *testing.T) {
+	seeder := NewMixtureSeederWithSource(rand.NewPCG(1, 2),
+		MixtureSeederComponent{Seeder: NewNormalSeederWithSource(0, 1, rand.NewPCG(3, 4)), Weight: 7},
+		MixtureSeederComponent{Seeder: NewNormalSeederWithSource(20, 1, rand.NewPCG(5, 6)), Weight: 3},
+	)
+
+	// Histogram of unit bins over [-5, 25)
+	const samples = 100000
+	histogram := make([]int, 30)
+	for range samples {
+		if bin := int(math.Floor(seeder.Generate())) + 5; bin >= 0 && bin < len(histogram) {
+			histogram[bin]++
+		}
+	}
+
+	// Local maxima well above their surroundings mark the modes
+	var modes []int
+	for bin := 1; bin < len(histogram)-1; bin++ {
+		if histogram[bin] > samples/100 && histogram[bin] >= histogram[bin-1] && histogram[bin] > histogram[bin+1] {
+			modes = append(modes, bin-5)
+		}
+	}
+	if len(modes) != 2 || modes[0] < -1 || modes[0] > 0 || modes[1] < 19 || modes[1] > 20 {
+		t.Fatalf("Expected modes near 0 and 20, got %v in %v", modes, histogram)
+	}
+
+	share := func(from, to int) float64 {
+		total := 0
+		for _, count := range histogram[from+5 : to+5] {
+			total += count
+		}
+		return float64(total) / samples
+	}
+	if low, high := share(-5, 5), share(15, 25); math.Abs(low-0.7) > 0.01 || math.Abs(high-0.3) > 0.01 {
+		t.Errorf("Expected 70%% of values around 0 and 30%% around 20, got %.3f and %.3f", low, high)
+	}
+	if valley := share(5, 15); valley > 0.001 {
+		t.Errorf("Expected almost nothing between the modes, got %.4f", valley)
+	}
+}
+
+func TestMixtureSeeder_InvalidPanics(t *testing.T) {
+	tests := map[string][]MixtureSeederComponent{
+		"no components":   nil,
+		"nil seeder":      {{Weight: 1}},
+		"negative weight": {{Seeder: NewLinearSeeder(1, 0), Weight: -1}},
+		"zero weights":    {{Seeder: NewLinearSeeder(1, 0), Weight: 0}},
+	}
+	for name, components := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic for an invalid mixture")
+				}
+			}()
+			NewMixtureSeeder(components...)
+		})
+	}
+}
+
+func TestHistogramSeeder_InvalidPanics(t *testing.T) {
+	tests := map[string][2][]float64{
+		"edge count":       {{0, 1}, {1, 1}},
+		"decreasing edges": {{0, 2, 1}, {1, 1}},
+		"negative weight":  {{0, 1, 2}, {1, -1}},
+		"all weights zero": {{0, 1}, {0}},
+		"no bins":          {{0}, {}},
+		"infinite edge":    {{0, math.Inf(1)}, {1}},
+	}
+	for name, histogram := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic for an invalid histogram")
+				}
+			}()
+			NewHistogramSeeder(histogram[0], histogram[1])
+		})
+	}
+}
+
+func TestGeoFunction_LinearPath(t *testing.T) {
+	a, b := GeoPoint{Lat: 0, Lng: 0}, GeoPoint{Lat: 0, Lng: 1}
+	distance := haversine(a, b)
+
+	geo := NewGeoFunction[float64](NewTestSensorFunction(2.0), GeoPathConfig{
+		Waypoints: []GeoPoint{a, b},
+		Speed:     distance / 10, // Reach b after 10 seconds
+	})
+
+	const tolerance = 1e-9
+	if pos := geo.PositionAt(5 * time.Second); math.Abs(pos.Lng-0.5) > tolerance || math.Abs(pos.Lat) > tolerance {
+		t.Errorf("Expected halfway position (0, 0.5), got %+v", pos)
+	}
+	if pos := geo.PositionAt(30 * time.Second); pos != b {
+		t.Errorf("Expected to hold at the last waypoint, got %+v", pos)
+	}
+
+	// The path starts at the first reading's timestamp, however far it is
+	// from the wall clock
+	start := time.Unix(0, 0)
+	reading := geo.Generate(3.0, start)
+	if reading.Value != 6.0 {
+		t.Errorf("Expected inner function value 6.0, got %f", reading.Value)
+	}
+	if reading.Lat != a.Lat || reading.Lng != a.Lng {
+		t.Errorf("Expected the first reading at the first waypoint, got %+v", reading)
+	}
+	if reading := geo.Generate(3.0, start.Add(5*time.Second)); math.Abs(reading.Lng-0.5) > tolerance {
+		t.Errorf("Expected halfway position 5s after the first reading, got %+v", reading)
+	}
+}
+
+func TestGeoFunction_Loop(t *testing.T) {
+	a, b := GeoPoint{Lat: 0, Lng: 0}, GeoPoint{Lat: 0, Lng: 1}
+	distance := haversine(a, b)
+
+	geo := NewGeoFunction[float64](NewTestSensorFunction(1.0), GeoPathConfig{
+		Waypoints: []GeoPoint{a, b},
+		Speed:     distance / 10,
+		Loop:      true,
+	})
+
+	const tolerance = 1e-9
+	// Outbound leg takes 10s, return leg another 10s
+	if pos := geo.PositionAt(15 * time.Second); math.Abs(pos.Lng-0.5) > tolerance {
+		t.Errorf("Expected to be halfway back at 15s, got %+v", pos)
+	}
+	if pos := geo.PositionAt(22 * time.Second); math.Abs(pos.Lng-0.2) > tolerance {
+		t.Errorf("Expected second lap at 22s, got %+v", pos)
+	}
+}
+
+func TestGeoFunction_GreatCircle(t *testing.T) {
+	a, b := GeoPoint{Lat: 45, Lng: 0}, GeoPoint{Lat: 45, Lng: 90}
+	halfway := 5 * time.Second
+	config := GeoPathConfig{
+		Waypoints: []GeoPoint{a, b},
+		Speed:     haversine(a, b) / 10,
+	}
+
+	linear := NewGeoFunction[float64](NewTestSensorFunction(1.0), config)
+	config.Interpolation = GeoInterpolationGreatCircle
+	greatCircle := NewGeoFunction[float64](NewTestSensorFunction(1.0), config)
+
+	if pos := linear.PositionAt(halfway); math.Abs(pos.Lat-45) > 1e-9 {
+		t.Errorf("Linear interpolation should stay on latitude 45, got %+v", pos)
+	}
+	pos := greatCircle.PositionAt(halfway)
+	if pos.Lat <= 45 {
+		t.Errorf("Great-circle route should bow towards the pole, got %+v", pos)
+	}
+	if math.Abs(haversine(a, pos)-haversine(pos, b)) > 1 {
+		t.Errorf("Great-circle midpoint should be equidistant, got %+v", pos)
+	}
+}
+
 func TestBasicSensorFunction(t *testing.T) {
 	// Test with string output
 	function := NewBasicSensorFunction(func(input float64, timestamp time.Time) string {
@@ -156,6 +537,7 @@ func TestLambdaSensorFunction(t *testing.T) {
 func BenchmarkTimeSeeder(b *testing.B) {
 	seeder := NewTimeSeeder(1.0, 0.1, 0.5)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		seeder.Generate()
@@ -165,6 +547,7 @@ func BenchmarkTimeSeeder(b *testing.B) {
 func BenchmarkRandomSeeder(b *testing.B) {
 	seeder := NewRandomSeeder(0.0, 100.0)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		seeder.Generate()
@@ -174,6 +557,7 @@ func BenchmarkRandomSeeder(b *testing.B) {
 func BenchmarkNormalSeeder(b *testing.B) {
 	seeder := NewNormalSeeder(50.0, 10.0)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		seeder.Generate()
@@ -185,8 +569,422 @@ func BenchmarkBasicSensorFunction(b *testing.B) {
 		return input * 2.0
 	})
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		function.Generate(1.0, time.Now())
 	}
 }
+
+func TestOutlierWrapper(t *testing.T) {
+	wrapper := NewOutlierWrapper[float64](NewTestSensorFunction(1.0), 0.1, ReplaceOutlier(1e6))
+
+	const calls = 10000
+	outliers := 0
+	for i := 0; i < calls; i++ {
+		if wrapper.Generate(5.0, time.Now()) == 1e6 {
+			outliers++
+		}
+	}
+
+	if uint64(outliers) != wrapper.Injected() {
+		t.Errorf("Injected() = %d, but %d outliers were observed", wrapper.Injected(), outliers)
+	}
+	if rate := float64(outliers) / calls; rate < 0.08 || rate > 0.12 {
+		t.Errorf("Expected outlier rate near 0.1, got %f", rate)
+	}
+}
+
+func TestOutlierWrapper_WithSource(t *testing.T) {
+	outliers := func() []bool {
+		wrapper := NewOutlierWrapperWithSource[float64](rand.NewPCG(1, 2), NewTestSensorFunction(1.0), 0.5, ReplaceOutlier(1e6))
+		injected := make([]bool, 100)
+		for i := range injected {
+			injected[i] = wrapper.Generate(5.0, time.Now()) == 1e6
+		}
+		return injected
+	}
+
+	if first, second := outliers(), outliers(); !slices.Equal(first, second) {
+		t.Errorf("Expected the same source to inject the same outliers, got %v and %v", first, second)
+	}
+}
+
+func TestOutlierWrapper_StructField(t *testing.T) {
+	type reading struct {
+		Value float64
+		Unit  string
+	}
+
+	inner := NewFunction(func(input float64, timestamp time.Time) reading {
+		return reading{Value: input, Unit: "bar"}
+	})
+	scale := ScaleOutlier(100.0)
+	wrapper := NewOutlierWrapper(inner, 1.0, func(r reading) reading {
+		r.Value = scale(r.Value)
+		return r
+	})
+
+	got := wrapper.Generate(2.0, time.Now())
+	if got.Value != 200.0 || got.Unit != "bar" {
+		t.Errorf("Expected scaled value with unit kept, got %+v", got)
+	}
+	if wrapper.Injected() != 1 {
+		t.Errorf("Expected 1 injected outlier, got %d", wrapper.Injected())
+	}
+}
+
+func TestCategoricalFunction_Distribution(t *testing.T) {
+	function := NewCategoricalFunction(
+		Category[string]{Value: "ok", Weight: 0.7},
+		Category[string]{Value: "warning", Weight: 0.2},
+		Category[string]{Value: "critical", Weight: 0.1},
+	)
+	seeder := NewRandomSeederWithSource(0, 1, rand.NewPCG(7, 7))
+
+	const samples = 100000
+	counts := make(map[string]int)
+	for range samples {
+		counts[function.Generate(seeder.Generate(), time.Now())]++
+	}
+
+	want := map[string]float64{"ok": 0.7, "warning": 0.2, "critical": 0.1}
+	for category, weight := range want {
+		got := float64(counts[category]) / samples
+		if math.Abs(got-weight) > 0.01 {
+			t.Errorf("Category %s: expected frequency %.2f, got %.4f", category, weight, got)
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("Unexpected categories: %v", counts)
+	}
+}
+
+func TestCategoricalFunction_Pick(t *testing.T) {
+	function := NewCategoricalFunction(
+		Category[string]{Value: "low", Weight: 1},
+		Category[string]{Value: "never", Weight: 0},
+		Category[string]{Value: "high", Weight: 3},
+	)
+
+	tests := []struct {
+		input float64
+		want  string
+	}{
+		{-1, "low"},
+		{0, "low"},
+		{0.2499, "low"},
+		{0.25, "high"},
+		{1, "high"},
+		{5, "high"},
+	}
+	for _, tt := range tests {
+		if got := function.Pick(tt.input); got != tt.want {
+			t.Errorf("Pick(%v) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	if got := NewCategoricalFunction[string]().Pick(0.5); got != "" {
+		t.Errorf("Expected zero value with no categories, got %q", got)
+	}
+}
+
+func TestMixtureFunction_SelectionFrequencies(t *testing.T) {
+	constant := func(kind string) SensorFunction[string] {
+		return NewLambdaSensorFunction(func(float64, time.Time) string { return kind })
+	}
+	function := NewMixtureFunctionWithSource(rand.NewPCG(3, 5),
+		MixtureComponent[string]{Function: constant("type-a"), Weight: 7},
+		MixtureComponent[string]{Function: constant("type-b"), Weight: 3},
+	)
+
+	const samples = 100000
+	counts := make(map[string]int)
+	for range samples {
+		counts[function.Generate(0.5, time.Now())]++
+	}
+
+	want := map[string]float64{"type-a": 0.7, "type-b": 0.3}
+	for kind, weight := range want {
+		got := float64(counts[kind]) / samples
+		if math.Abs(got-weight) > 0.01 {
+			t.Errorf("Function %s: expected frequency %.2f, got %.4f", kind, weight, got)
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("Unexpected outputs: %v", counts)
+	}
+}
+
+func TestMixtureFunction_PassesInputThrough(t *testing.T) {
+	function := NewMixtureFunction(
+		MixtureComponent[float64]{Function: NewTestSensorFunction(2), Weight: 1},
+	)
+	if got := function.Generate(21, time.Now()); got != 42 {
+		t.Errorf("Expected the seeder input to reach the chosen function, got %v", got)
+	}
+
+	if got := NewMixtureFunction[float64]().Generate(21, time.Now()); got != 0 {
+		t.Errorf("Expected zero value with no functions, got %v", got)
+	}
+}
+
+func TestLagSeeder_StepResponse(t *testing.T) {
+	// Hold 0 for one reading, then step to 10
+	step := 0.0
+	input := NewCustomSeeder(func() float64 {
+		value := step
+		step = 10
+		return value
+	})
+	const alpha = 0.25
+	seeder := NewLagSeeder(input, alpha)
+
+	if first := seeder.Generate(); first != 0 {
+		t.Fatalf("Expected the first value to pass through, got %v", first)
+	}
+	previousGap := 10.0
+	for n := 1; n <= 20; n++ {
+		value := seeder.Generate()
+		want := 10 * (1 - math.Pow(1-alpha, float64(n)))
+		if math.Abs(value-want) > 1e-9 {
+			t.Errorf("Step %d: expected %v, got %v", n, want, value)
+		}
+		gap := 10 - value
+		if ratio := gap / previousGap; math.Abs(ratio-(1-alpha)) > 1e-9 {
+			t.Errorf("Step %d: expected the gap to shrink by %v, got ratio %v", n, 1-alpha, ratio)
+		}
+		previousGap = gap
+	}
+}
+
+func TestLagSeeder_InvalidAlpha(t *testing.T) {
+	for _, alpha := range []float64{0, -0.5, 1.5, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for alpha %v", alpha)
+				}
+			}()
+			NewLagSeeder(NewCustomSeeder(func() float64 { return 0 }), alpha)
+		}()
+	}
+}
+
+func TestLagFunction_TimeConstant(t *testing.T) {
+	identity := NewFunction(func(input float64, _ time.Time) float64 { return input })
+	lag := NewLagFunctionWithTimeConstant[float64](identity, time.Second)
+
+	start := time.Unix(0, 0)
+	if first := lag.Generate(20, start); first != 20 {
+		t.Fatalf("Expected the first input to pass through, got %v", first)
+	}
+
+	// After one time constant about 63% of a step has been covered, however
+	// many readings it took to get there
+	for _, steps := range []int{1, 10, 100} {
+		lag := NewLagFunctionWithTimeConstant[float64](identity, time.Second)
+		lag.Generate(20, start)
+		var value float64
+		for i := 1; i <= steps; i++ {
+			value = lag.Generate(30, start.Add(time.Duration(i)*time.Second/time.Duration(steps)))
+		}
+		if want := 30 - 10*math.Exp(-1); math.Abs(value-want) > 1e-9 {
+			t.Errorf("%d steps: expected %v after one time constant, got %v", steps, want, value)
+		}
+	}
+}
+
+func TestLagFunction_Alpha(t *testing.T) {
+	identity := NewFunction(func(input float64, _ time.Time) float64 { return input })
+	lag := NewLagFunction[float64](identity, 0.5)
+
+	now := time.Now()
+	got := []float64{lag.Generate(0, now), lag.Generate(8, now), lag.Generate(8, now), lag.Generate(8, now)}
+	if want := []float64{0, 4, 6, 7}; !equalFloats(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestMixtureFunction_ConcurrentGenerate runs with -race to check a seeded
+// source is safe to share between workers and Engine.Generate
+func TestMixtureFunction_ConcurrentGenerate(t *testing.T) {
+	function := NewMixtureFunctionWithSource(rand.NewPCG(1, 2),
+		MixtureComponent[float64]{Function: NewTestSensorFunction(1), Weight: 1},
+		MixtureComponent[float64]{Function: NewTestSensorFunction(2), Weight: 1},
+	)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				function.Generate(1, time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestLagFunction_ConcurrentGenerate runs with -race to check the filter
+// state is safe to share between workers and Engine.Generate
+func TestLagFunction_ConcurrentGenerate(t *testing.T) {
+	identity := NewFunction(func(input float64, _ time.Time) float64 { return input })
+	lag := NewLagFunctionWithTimeConstant[float64](identity, time.Second)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				lag.Generate(float64(i), time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestSeeders_ConcurrentGenerate hammers every stateful seeder from several
+// goroutines, taking and restoring snapshots at the same time. Run it with
+// -race to check the seeders' locking.
+func TestSeeders_ConcurrentGenerate(t *testing.T) {
+	csv, err := NewCSVSeederFromReader(strings.NewReader("1\n2\n3\n"), CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeders := map[string]StatefulSeeder{
+		"time":      NewTimeSeeder(1, 0.1, 0),
+		"linear":    NewLinearSeeder(1, 0),
+		"random":    NewRandomSeederWithSource(0, 1, rand.NewPCG(1, 2)),
+		"normal":    NewNormalSeederWithSource(0, 1, rand.NewPCG(3, 4)),
+		"csv":       csv,
+		"lag":       NewLagSeeder(NewRandomSeederWithSource(0, 1, rand.NewPCG(5, 6)), 0.5),
+		"reduce":    NewReduceSeeder(ReduceMean, NewNormalSeederWithSource(0, 1, rand.NewPCG(7, 8)), NewLinearSeeder(1, 0)),
+		"market":    NewMarketSeederWithSource(DefaultMarketConfig(), rand.NewPCG(9, 10)),
+		"histogram": NewHistogramSeederWithSource([]float64{0, 1, 2}, []float64{1, 3}, rand.NewPCG(11, 12)),
+	}
+
+	for name, seeder := range seeders {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 500 {
+						if v := seeder.Generate(); math.IsNaN(v) {
+							t.Errorf("Generated NaN")
+							return
+						}
+						if clocked, ok := seeder.(ClockedSeeder); ok {
+							clocked.GenerateAt(time.Now())
+						}
+					}
+				}()
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					state, err := seeder.MarshalState()
+					if err != nil {
+						t.Errorf("MarshalState failed: %v", err)
+						return
+					}
+					if err := seeder.RestoreState(state); err != nil {
+						t.Errorf("RestoreState failed: %v", err)
+						return
+					}
+				}
+			}()
+			wg.Wait()
+		})
+	}
+}
+
+// pumpStatus is a 16-bit pump status word with fault flags on scattered bits
+func pumpStatus() *StatusRegisterFunction {
+	return NewStatusRegisterFunction(16,
+		StatusBit{Name: "running", Bit: 0, Set: InputAbove(0.1)},
+		StatusBit{Name: "overtemp", Bit: 3, Set: InputAbove(0.9)},
+		StatusBit{Name: "low_pressure", Bit: 7, Set: InputBelow(0.2)},
+		StatusBit{Name: "comms_fault", Bit: 15, Set: func(input float64, timestamp time.Time) bool { return timestamp.IsZero() }},
+	)
+}
+
+func TestStatusRegisterFunction_RoundTrip(t *testing.T) {
+	register := pumpStatus()
+	names := []string{"running", "overtemp", "low_pressure", "comms_fault"}
+
+	for combination := range 1 << len(names) {
+		flags := make(map[string]bool)
+		for i, name := range names {
+			flags[name] = combination&(1<<i) != 0
+		}
+		value := register.Encode(flags)
+		if value >= 1<<16 {
+			t.Fatalf("Expected a 16-bit value for %v, got %#x", flags, value)
+		}
+		if decoded := register.Decode(value); !maps.Equal(decoded, flags) {
+			t.Errorf("Decode(Encode(%v)) = %v", flags, decoded)
+		}
+	}
+
+	// Bits without a flag are ignored when decoding
+	if decoded := register.Decode(0xFFFF &^ (1<<0 | 1<<3 | 1<<7 | 1<<15)); slices.Contains(slices.Collect(maps.Values(decoded)), true) {
+		t.Errorf("Expected unassigned bits ignored, got %v", decoded)
+	}
+}
+
+func TestStatusRegisterFunction_Generate(t *testing.T) {
+	register := pumpStatus()
+	now := time.Now()
+
+	tests := []struct {
+		input     float64
+		timestamp time.Time
+		want      uint64
+	}{
+		{0.5, now, 1 << 0},
+		{0.95, now, 1<<0 | 1<<3},
+		{0.05, now, 1 << 7},
+		{0.15, time.Time{}, 1<<0 | 1<<7 | 1<<15},
+	}
+	for _, tt := range tests {
+		reading := register.Generate(tt.input, tt.timestamp)
+		if reading.Value != tt.want {
+			t.Errorf("Generate(%v) = %#04x, want %#04x", tt.input, reading.Value, tt.want)
+		}
+		if !maps.Equal(reading.Flags, register.Decode(reading.Value)) {
+			t.Errorf("Flags %v do not match value %#04x", reading.Flags, reading.Value)
+		}
+	}
+}
+
+func TestStatusRegisterFunction_InvalidPanics(t *testing.T) {
+	set := InputAbove(0)
+	tests := map[string]struct {
+		width int
+		bits  []StatusBit
+	}{
+		"width":        {65, nil},
+		"bit too high": {8, []StatusBit{{Name: "a", Bit: 8, Set: set}}},
+		"negative bit": {8, []StatusBit{{Name: "a", Bit: -1, Set: set}}},
+		"shared bit":   {8, []StatusBit{{Name: "a", Bit: 1, Set: set}, {Name: "b", Bit: 1, Set: set}}},
+		"shared name":  {8, []StatusBit{{Name: "a", Bit: 1, Set: set}, {Name: "a", Bit: 2, Set: set}}},
+		"no name":      {8, []StatusBit{{Bit: 1, Set: set}}},
+		"no condition": {8, []StatusBit{{Name: "a", Bit: 1}}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic for an invalid register")
+				}
+			}()
+			NewStatusRegisterFunction(tt.width, tt.bits...)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShortIDGenerator_SortableAndUnique(t *testing.T) {
+	clock := NewManualClock(harnessEpoch)
+	generate := NewShortIDGenerator(clock, rand.NewPCG(1, 2))
+
+	var ids []string
+	for seq := uint64(0); seq < 20; seq++ {
+		ids = append(ids, generate(seq))
+		switch seq {
+		case 5:
+			clock.Advance(time.Millisecond)
+		case 10:
+			clock.Advance(time.Hour)
+		case 15:
+			// The clock stepping back does not break the order
+			clock.Advance(-time.Minute)
+		}
+	}
+
+	for _, id := range ids {
+		if len(id) != 24 {
+			t.Errorf("Expected 24-character IDs, got %q", id)
+		}
+	}
+	if !slices.IsSorted(ids) {
+		t.Errorf("Expected IDs in generation order, got %v", ids)
+	}
+	if len(slices.Compact(slices.Clone(ids))) != len(ids) {
+		t.Errorf("Expected unique IDs, got %v", ids)
+	}
+}
+
+func TestShortIDGenerator_Instances(t *testing.T) {
+	clock := NewManualClock(harnessEpoch)
+	a := NewShortIDGenerator(clock, rand.NewPCG(1, 2))
+	b := NewShortIDGenerator(clock, rand.NewPCG(1, 2))
+	if a(7) != b(7) {
+		t.Errorf("Expected the same clock and seed to give the same IDs, got %s and %s", a(7), b(7))
+	}
+
+	// Restarted engines count from 0 again but draw a new instance number
+	first := NewShortIDGenerator(clock, nil)
+	second := NewShortIDGenerator(clock, nil)
+	if first(0) == second(0) {
+		t.Errorf("Expected generators to differ across restarts, both gave %s", first(0))
+	}
+}
+
+func TestEngine_WithShortIDs(t *testing.T) {
+	config := Config{
+		ProductionRate: 100 * time.Microsecond,
+		BatchSize:      50,
+		MaxWorkers:     1,
+	}
+	h := newHarness[float64](t, config)
+	published := h.run(NewTestSeeder([]float64{1}), NewTestSensorFunction(1), 200,
+		WithIDGenerator[float64](NewShortIDGenerator(h.Clock, rand.NewPCG(3, 4))))
+
+	ids := make([]string, len(published.data))
+	for i, d := range published.data {
+		ids[i] = d.ID
+	}
+	if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+		t.Errorf("Expected unique IDs in publish order, got %v", ids)
+	}
+}
@@ -0,0 +1,394 @@
+package engine
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// StatefulSeeder is implemented by seeders whose output depends on state
+// that builds up while they run, such as a start time, a read position or
+// a random source. A seeder restored from a snapshot produces the same
+// values the snapshotted seeder would have produced next. States are JSON.
+type StatefulSeeder interface {
+	Seeder
+	MarshalState() ([]byte, error)
+	RestoreState(state []byte) error
+}
+
+// EngineState is a checkpoint of an engine, as produced by MarshalState
+type EngineState struct {
+	NextID      uint64          `json:"next_id"`          // Sequence number of the next reading
+	EnvelopeSeq uint64          `json:"envelope_seq"`     // Last envelope sequence number handed out
+	Rand        []byte          `json:"rand,omitempty"`   // State of Config.RandSource
+	Seeder      json.RawMessage `json:"seeder,omitempty"` // State of a StatefulSeeder
+	Noise       float64         `json:"noise,omitempty"`  // Last value of the WithAR1Noise process
+	DriftStart  time.Time       `json:"drift_start"`      // Start of the WithDrift offset, zero before the first reading
+}
+
+// MarshalState checkpoints the engine so that a new engine built the same
+// way can resume where this one left off: reading IDs, envelope sequence
+// numbers, the RandSource, the noise and drift and, if it is a StatefulSeeder,
+// the seeder. The RandSource must implement encoding.BinaryMarshaler, as the
+// math/rand/v2 sources do. Call it while the engine is not running for an
+// exact checkpoint.
+func (e *Engine[T]) MarshalState() ([]byte, error) {
+	state := EngineState{
+		NextID:      e.nextID.Load(),
+		EnvelopeSeq: e.envelopeSeq.Load(),
+	}
+	if e.noise != nil {
+		e.noise.mutex.Lock()
+		state.Noise = e.noise.value
+		e.noise.mutex.Unlock()
+	}
+	if e.drift != nil {
+		e.drift.mutex.Lock()
+		state.DriftStart = e.drift.start
+		e.drift.mutex.Unlock()
+	}
+
+	var err error
+	if state.Rand, err = marshalSource(e.config.RandSource); err != nil {
+		return nil, fmt.Errorf("engine random source: %w", err)
+	}
+	if seeder, ok := e.seeder.(StatefulSeeder); ok {
+		if state.Seeder, err = seeder.MarshalState(); err != nil {
+			return nil, fmt.Errorf("seeder: %w", err)
+		}
+	}
+	return json.Marshal(state)
+}
+
+// RestoreState resumes from a checkpoint taken by MarshalState. Call it
+// before the engine is started.
+func (e *Engine[T]) RestoreState(data []byte) error {
+	var state EngineState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid engine state: %w", err)
+	}
+
+	if err := restoreSource(e.config.RandSource, state.Rand); err != nil {
+		return fmt.Errorf("engine random source: %w", err)
+	}
+	if len(state.Seeder) > 0 {
+		seeder, ok := e.seeder.(StatefulSeeder)
+		if !ok {
+			return fmt.Errorf("state has a seeder state but %T is not a StatefulSeeder", e.seeder)
+		}
+		if err := seeder.RestoreState(state.Seeder); err != nil {
+			return fmt.Errorf("seeder: %w", err)
+		}
+	}
+
+	e.nextID.Store(state.NextID)
+	e.envelopeSeq.Store(state.EnvelopeSeq)
+	if e.noise != nil {
+		e.noise.mutex.Lock()
+		e.noise.value = state.Noise
+		e.noise.mutex.Unlock()
+	}
+	if e.drift != nil {
+		e.drift.mutex.Lock()
+		e.drift.start = state.DriftStart
+		e.drift.mutex.Unlock()
+	}
+	return nil
+}
+
+// marshalSource returns the state of a random source, or nil for the global
+// source
+func marshalSource(src rand.Source) ([]byte, error) {
+	if src == nil {
+		return nil, nil
+	}
+	marshaler, ok := src.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support snapshots", src)
+	}
+	return marshaler.MarshalBinary()
+}
+
+// restoreSource restores the state of a random source taken by
+// marshalSource
+func restoreSource(src rand.Source, state []byte) error {
+	if src == nil || state == nil {
+		if (src == nil) != (state == nil) {
+			return fmt.Errorf("snapshot and target disagree on whether a seeded source is used")
+		}
+		return nil
+	}
+	unmarshaler, ok := src.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T does not support snapshots", src)
+	}
+	return unmarshaler.UnmarshalBinary(state)
+}
+
+// startState is the state of seeders measuring time from a start instant
+type startState struct {
+	Start time.Time `json:"start"`
+}
+
+// MarshalState returns the instant the wave started at
+func (t *TimeSeeder) MarshalState() ([]byte, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return json.Marshal(startState{Start: t.start})
+}
+
+// RestoreState continues the wave from a snapshotted start instant
+func (t *TimeSeeder) RestoreState(data []byte) error {
+	var state startState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.start = state.Start
+	return nil
+}
+
+// MarshalState returns the instant the line started at
+func (l *LinearSeeder) MarshalState() ([]byte, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return json.Marshal(startState{Start: l.start})
+}
+
+// RestoreState continues the line from a snapshotted start instant
+func (l *LinearSeeder) RestoreState(data []byte) error {
+	var state startState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.start = state.Start
+	return nil
+}
+
+// sourceState is the state of seeders drawing from a random source
+type sourceState struct {
+	Source []byte `json:"source,omitempty"`
+}
+
+// MarshalState returns the state of the seeder's random source
+func (r *RandomSeeder) MarshalState() ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	source, err := marshalSource(r.src)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sourceState{Source: source})
+}
+
+// RestoreState restores the seeder's random source
+func (r *RandomSeeder) RestoreState(data []byte) error {
+	var state sourceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return restoreSource(r.src, state.Source)
+}
+
+// MarshalState returns the state of the seeder's random source
+func (n *NormalSeeder) MarshalState() ([]byte, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	source, err := marshalSource(n.src)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sourceState{Source: source})
+}
+
+// RestoreState restores the seeder's random source
+func (n *NormalSeeder) RestoreState(data []byte) error {
+	var state sourceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return restoreSource(n.src, state.Source)
+}
+
+// MarshalState returns the state of the seeder's random source
+func (h *HistogramSeeder) MarshalState() ([]byte, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	source, err := marshalSource(h.src)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sourceState{Source: source})
+}
+
+// RestoreState restores the seeder's random source
+func (h *HistogramSeeder) RestoreState(data []byte) error {
+	var state sourceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return restoreSource(h.src, state.Source)
+}
+
+// marketState is the position and random source of a MarketSeeder
+type marketState struct {
+	Step   uint64 `json:"step"`
+	Source []byte `json:"source,omitempty"`
+}
+
+// MarshalState returns the seeder's step count and random source
+func (m *MarketSeeder) MarshalState() ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	source, err := marshalSource(m.src)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(marketState{Step: m.step, Source: source})
+}
+
+// RestoreState restores the seeder's step count and random source
+func (m *MarketSeeder) RestoreState(data []byte) error {
+	var state marketState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err := restoreSource(m.src, state.Source); err != nil {
+		return err
+	}
+	m.step = state.Step
+	return nil
+}
+
+// csvState is the read position of a CSVSeeder
+type csvState struct {
+	Next int `json:"next"`
+	Len  int `json:"len"` // Guards against restoring into a different file
+}
+
+// MarshalState returns the seeder's position in the column
+func (c *CSVSeeder) MarshalState() ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return json.Marshal(csvState{Next: c.next, Len: len(c.values)})
+}
+
+// RestoreState moves the seeder to a snapshotted position. The column must
+// have as many values as when the snapshot was taken.
+func (c *CSVSeeder) RestoreState(data []byte) error {
+	var state csvState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if state.Len != len(c.values) || state.Next < 0 || state.Next > len(c.values) {
+		return fmt.Errorf("CSV state for %d values at %d does not match a column of %d values", state.Len, state.Next, len(c.values))
+	}
+	c.next = state.Next
+	return nil
+}
+
+// lagState is the filter state of a LagSeeder and its inner seeder's state
+type lagState struct {
+	Value   float64         `json:"value"`
+	Started bool            `json:"started"`
+	Inner   json.RawMessage `json:"inner,omitempty"`
+}
+
+// MarshalState returns the filter output and the inner seeder's state, if
+// it has one
+func (l *LagSeeder) MarshalState() ([]byte, error) {
+	inner, err := marshalInner(l.inner)
+	if err != nil {
+		return nil, err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return json.Marshal(lagState{Value: l.value, Started: l.started, Inner: inner})
+}
+
+// RestoreState restores the filter output and the inner seeder
+func (l *LagSeeder) RestoreState(data []byte) error {
+	var state lagState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if err := restoreInner(l.inner, state.Inner); err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.value, l.started = state.Value, state.Started
+	return nil
+}
+
+// reduceState holds the states of a ReduceSeeder's inner seeders, null for
+// those without state
+type reduceState struct {
+	Seeders []json.RawMessage `json:"seeders"`
+}
+
+// MarshalState returns the states of the inner seeders
+func (r *ReduceSeeder) MarshalState() ([]byte, error) {
+	state := reduceState{Seeders: make([]json.RawMessage, len(r.seeders))}
+	for i, s := range r.seeders {
+		inner, err := marshalInner(s)
+		if err != nil {
+			return nil, fmt.Errorf("seeder %d: %w", i, err)
+		}
+		state.Seeders[i] = inner
+	}
+	return json.Marshal(state)
+}
+
+// RestoreState restores the inner seeders
+func (r *ReduceSeeder) RestoreState(data []byte) error {
+	var state reduceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if len(state.Seeders) != len(r.seeders) {
+		return fmt.Errorf("state has %d seeders, reduce seeder has %d", len(state.Seeders), len(r.seeders))
+	}
+	for i, s := range r.seeders {
+		if err := restoreInner(s, state.Seeders[i]); err != nil {
+			return fmt.Errorf("seeder %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// marshalInner returns the state of a wrapped seeder, or nil if it has none
+func marshalInner(s Seeder) (json.RawMessage, error) {
+	stateful, ok := s.(StatefulSeeder)
+	if !ok {
+		return nil, nil
+	}
+	return stateful.MarshalState()
+}
+
+// restoreInner restores a wrapped seeder from a state taken by marshalInner
+func restoreInner(s Seeder, state json.RawMessage) error {
+	if len(state) == 0 || string(state) == "null" {
+		return nil
+	}
+	stateful, ok := s.(StatefulSeeder)
+	if !ok {
+		return fmt.Errorf("state given for %T, which is not a StatefulSeeder", s)
+	}
+	return stateful.RestoreState(state)
+}
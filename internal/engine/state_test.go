@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statefulRun builds a seeded engine with noise whose seeder has every kind
+// of state, publishing into the returned publisher
+func statefulRun(t *testing.T, clock *ManualClock) (*Engine[float64], *MockPublisher[float64]) {
+	t.Helper()
+
+	csv, err := NewCSVSeederFromReader(strings.NewReader("1\n2\n3\n4\n5\n6\n7\n"), CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeder := NewReduceSeeder(ReduceSum,
+		NewLinearSeederWithClock(2, 0, clock),
+		NewTimeSeederWithClock(1, 0.3, 0, clock),
+		NewRandomSeederWithSource(0, 1, rand.NewPCG(5, 6)),
+		NewLagSeeder(NewNormalSeederWithSource(0, 1, rand.NewPCG(7, 8)), 0.3),
+		csv,
+	)
+
+	config := Config{
+		ProductionRate: 50 * time.Millisecond,
+		BatchSize:      4,
+		MaxWorkers:     1,
+		Clock:          clock,
+		RandSource:     rand.NewPCG(3, 4),
+	}
+	publisher := NewMockPublisher[float64]()
+	return NewEngineWithOptions(config, seeder, NewTestSensorFunction(1), publisher, WithAR1Noise[float64](0.5, 0.1)), publisher
+}
+
+func TestEngine_StateRoundTrip(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The original run checkpoints after one second and keeps going
+	clock := NewManualClock(epoch)
+	original, publisher := statefulRun(t, clock)
+	if err := original.RunFor(context.Background(), time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	state, err := original.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState failed: %v", err)
+	}
+	checkpoint := clock.Now()
+	publisher.batches = nil
+	if err := original.RunFor(context.Background(), time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	want := publisher.batches
+
+	// A fresh engine, built later, resumes from the checkpoint
+	resumedClock := NewManualClock(epoch.Add(time.Hour))
+	resumed, resumedPublisher := statefulRun(t, resumedClock)
+	if err := resumed.RestoreState(state); err != nil {
+		t.Fatalf("RestoreState failed: %v", err)
+	}
+	resumedClock.Set(checkpoint)
+	if err := resumed.RunFor(context.Background(), time.Second); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+	got := resumedPublisher.batches
+
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Resumed output differs:\ngot:  %s\nwant: %s", gotJSON, wantJSON)
+	}
+	if got[0][0].ID != "sensor-20" {
+		t.Errorf("Expected IDs to continue at sensor-20, got %s", got[0][0].ID)
+	}
+}
+
+func TestEngine_RestoreStateMismatch(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	original, _ := statefulRun(t, clock)
+	state, err := original.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState failed: %v", err)
+	}
+
+	// A plain seeder cannot take the reduce seeder's state
+	engine := NewEngine(original.config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+	if err := engine.RestoreState(state); err == nil {
+		t.Error("Expected an error restoring seeder state into a stateless seeder")
+	}
+
+	csv, _ := NewCSVSeederFromReader(strings.NewReader("1\n2\n"), CSVOptions{})
+	if err := csv.RestoreState([]byte(`{"next":1,"len":7}`)); err == nil {
+		t.Error("Expected an error restoring the position of a different CSV column")
+	}
+}
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of the engine counters
+type Stats struct {
+	Generated         uint64        `json:"generated"`           // Readings produced by the generator
+	Heartbeats        uint64        `json:"heartbeats"`          // Heartbeat readings emitted during silences
+	Outages           uint64        `json:"outages"`             // Outages started by the OfflineSchedule
+	OfflineTicks      uint64        `json:"offline_ticks"`       // Production ticks that generated nothing while offline
+	Published         uint64        `json:"published"`           // Readings successfully published
+	Batches           uint64        `json:"batches"`             // Batches successfully published
+	Singles           uint64        `json:"singles"`             // Readings successfully published one at a time
+	PublishErrors     uint64        `json:"publish_errors"`      // Failed publish calls
+	Dropped           uint64        `json:"dropped"`             // Readings evicted by MaxBufferedReadings
+	SlowCycles        uint64        `json:"slow_cycles"`         // Generation cycles slower than the production interval
+	Stalls            uint64        `json:"stalls"`              // Stalls detected by the StallTimeout watchdog
+	Filtered          uint64        `json:"filtered"`            // Readings rejected by QualityFilter
+	RangeViolations   uint64        `json:"range_violations"`    // Readings outside the RangeGuard
+	Unchanged         uint64        `json:"unchanged"`           // Readings suppressed by EmitOnChangeOf
+	OKCount           uint64        `json:"ok_count"`            // Generated readings of quality OK
+	NoisyCount        uint64        `json:"noisy_count"`         // Generated readings of quality NOISY
+	PartialCount      uint64        `json:"partial_count"`       // Generated readings of quality PARTIAL
+	CorruptCount      uint64        `json:"corrupt_count"`       // Generated readings of quality CORRUPT
+	AvgPublishLatency time.Duration `json:"avg_publish_latency"` // Mean duration of a publish call
+	CurrentRate       time.Duration `json:"current_rate"`        // Current production interval
+	BatchSize         int           `json:"batch_size"`          // Current batch size, tuned with AdaptiveBatch
+	BytesPublished    uint64        `json:"bytes_published"`     // Payload bytes reported by the publisher
+	BackendLatency    time.Duration `json:"backend_latency"`     // Mean backend operation duration reported by the publisher
+	Backlog           int           `json:"backlog"`             // Batches queued for the publisher workers
+	PrimaryPublished  uint64        `json:"primary_published"`   // Readings a FallbackCounter delivered to its primary
+	FallbackPublished uint64        `json:"fallback_published"`  // Readings a FallbackCounter diverted to its fallback
+}
+
+// FallbackCounter is implemented by publishers that divert readings to a
+// fallback destination when their primary one fails. The engine reports the
+// counts in Stats.
+type FallbackCounter interface {
+	FallbackCounts() (primary, fallback uint64)
+}
+
+// engineStats holds the live counters backing Stats
+type engineStats struct {
+	generated       atomic.Uint64
+	heartbeats      atomic.Uint64
+	outages         atomic.Uint64
+	offlineTicks    atomic.Uint64
+	published       atomic.Uint64
+	batches         atomic.Uint64
+	singles         atomic.Uint64
+	publishErrors   atomic.Uint64
+	dropped         atomic.Uint64
+	slowCycles      atomic.Uint64
+	stalls          atomic.Uint64
+	filtered        atomic.Uint64
+	rangeViolations atomic.Uint64
+	unchanged       atomic.Uint64
+	qualities       [4]atomic.Uint64 // Generated readings per quality, in qualityIndex order
+	publishCalls    atomic.Uint64
+	publishLatency  atomic.Int64 // Cumulative publish duration in nanoseconds
+}
+
+// qualityIndex returns the index of q in engineStats.qualities, or -1 for
+// qualities that are not counted
+func qualityIndex(q Quality) int {
+	switch q {
+	case QualityOK:
+		return 0
+	case QualityNoisy:
+		return 1
+	case QualityPartial:
+		return 2
+	case QualityCorrupt:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// recordQuality counts a generated reading of quality q
+func (s *engineStats) recordQuality(q Quality) {
+	if i := qualityIndex(q); i >= 0 {
+		s.qualities[i].Add(1)
+	}
+}
+
+// recordPublish records the outcome and duration of a PublishBatch call
+func (s *engineStats) recordPublish(items int, latency time.Duration, err error) {
+	s.publishCalls.Add(1)
+	s.publishLatency.Add(int64(latency))
+	if err != nil {
+		s.publishErrors.Add(1)
+		return
+	}
+	s.batches.Add(1)
+	s.published.Add(uint64(items))
+}
+
+// recordSingle records the outcome and duration of a single-reading publish call
+func (s *engineStats) recordSingle(latency time.Duration, err error) {
+	s.publishCalls.Add(1)
+	s.publishLatency.Add(int64(latency))
+	if err != nil {
+		s.publishErrors.Add(1)
+		return
+	}
+	s.singles.Add(1)
+	s.published.Add(1)
+}
+
+// Stats returns a snapshot of the engine counters. It is safe to call
+// concurrently with Start.
+func (e *Engine[T]) Stats() Stats {
+	stats := Stats{
+		Generated:       e.stats.generated.Load(),
+		Heartbeats:      e.stats.heartbeats.Load(),
+		Outages:         e.stats.outages.Load(),
+		OfflineTicks:    e.stats.offlineTicks.Load(),
+		Published:       e.stats.published.Load(),
+		Batches:         e.stats.batches.Load(),
+		Singles:         e.stats.singles.Load(),
+		PublishErrors:   e.stats.publishErrors.Load(),
+		Dropped:         e.stats.dropped.Load(),
+		SlowCycles:      e.stats.slowCycles.Load(),
+		Stalls:          e.stats.stalls.Load(),
+		Filtered:        e.stats.filtered.Load(),
+		RangeViolations: e.stats.rangeViolations.Load(),
+		Unchanged:       e.stats.unchanged.Load(),
+		OKCount:         e.stats.qualities[0].Load(),
+		NoisyCount:      e.stats.qualities[1].Load(),
+		PartialCount:    e.stats.qualities[2].Load(),
+		CorruptCount:    e.stats.qualities[3].Load(),
+		CurrentRate:     e.ProductionRate(),
+		BatchSize:       e.BatchSize(),
+	}
+	if calls := e.stats.publishCalls.Load(); calls > 0 {
+		stats.AvgPublishLatency = time.Duration(e.stats.publishLatency.Load() / int64(calls))
+	}
+	if m := e.config.PublishMetrics; m != nil {
+		snapshot := m.Snapshot()
+		stats.BytesPublished = snapshot.Bytes
+		stats.BackendLatency = snapshot.AvgLatency
+	}
+	if batchChan := e.batchChan.Load(); batchChan != nil {
+		stats.Backlog = len(*batchChan)
+	}
+	if counter, ok := e.publisher.(FallbackCounter); ok {
+		stats.PrimaryPublished, stats.FallbackPublished = counter.FallbackCounts()
+	}
+	return stats
+}
+
+// PublishMetrics is a PublisherObserver that aggregates what a publisher
+// reports. It is safe for concurrent use.
+type PublishMetrics struct {
+	operations atomic.Uint64
+	errors     atomic.Uint64
+	bytes      atomic.Uint64
+	latency    atomic.Int64 // Cumulative operation duration in nanoseconds
+}
+
+// PublishMetricsSnapshot is a point-in-time copy of PublishMetrics
+type PublishMetricsSnapshot struct {
+	Operations uint64        `json:"operations"`
+	Errors     uint64        `json:"errors"`
+	Bytes      uint64        `json:"bytes"` // Bytes of successful operations
+	AvgLatency time.Duration `json:"avg_latency"`
+}
+
+// ObservePublish records one publisher operation
+func (m *PublishMetrics) ObservePublish(bytes int, duration time.Duration, err error) {
+	m.operations.Add(1)
+	m.latency.Add(int64(duration))
+	if err != nil {
+		m.errors.Add(1)
+		return
+	}
+	m.bytes.Add(uint64(bytes))
+}
+
+// Snapshot returns the current totals
+func (m *PublishMetrics) Snapshot() PublishMetricsSnapshot {
+	snapshot := PublishMetricsSnapshot{
+		Operations: m.operations.Load(),
+		Errors:     m.errors.Load(),
+		Bytes:      m.bytes.Load(),
+	}
+	if snapshot.Operations > 0 {
+		snapshot.AvgLatency = time.Duration(m.latency.Load() / int64(snapshot.Operations))
+	}
+	return snapshot
+}
+
+// StatsHandler returns an HTTP handler that serves the current stats as JSON
+func (e *Engine[T]) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(e.Stats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// ServeStats exposes the engine stats as JSON at /stats on addr. The server
+// runs in the background and is shut down when Start returns.
+func (e *Engine[T]) ServeStats(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for stats: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/stats", e.StatsHandler())
+	server := &http.Server{Handler: mux}
+
+	e.statsMu.Lock()
+	if e.statsServer != nil {
+		e.statsMu.Unlock()
+		listener.Close()
+		return errors.New("stats server already running")
+	}
+	e.statsServer = server
+	e.statsMu.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logf("Stats server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// stopStatsServer gracefully shuts down the stats server started by ServeStats
+func (e *Engine[T]) stopStatsServer() {
+	e.statsMu.Lock()
+	server := e.statsServer
+	e.statsServer = nil
+	e.statsMu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		e.logf("Error shutting down stats server: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEngine_StatsHandler(t *testing.T) {
+	config := Config{
+		ProductionRate: 5 * time.Millisecond,
+		BatchSize:      2,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxWorkers:     1,
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.StatsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	var stats Stats
+	if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.Generated == 0 || stats.Published == 0 {
+		t.Errorf("Expected generated and published counts, got %+v", stats)
+	}
+	if stats.CurrentRate != config.ProductionRate {
+		t.Errorf("Expected current rate %v, got %v", config.ProductionRate, stats.CurrentRate)
+	}
+}
+
+func TestEngine_ServeStatsLifecycle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	engine := NewEngine(DefaultConfig(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+	if err := engine.ServeStats(addr); err != nil {
+		t.Fatalf("ServeStats failed: %v", err)
+	}
+	if err := engine.ServeStats(addr); err == nil {
+		t.Error("Expected error starting a second stats server")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- engine.Start(ctx) }()
+
+	resp, err := http.Get("http://" + addr + "/stats")
+	if err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	var stats Stats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Errorf("Failed to decode stats: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Engine start failed: %v", err)
+	}
+
+	client := &http.Client{Timeout: 200 * time.Millisecond}
+	if resp, err := client.Get("http://" + addr + "/stats"); err == nil {
+		resp.Body.Close()
+		t.Error("Expected stats server to stop with the engine")
+	}
+}
+
+func TestEngine_StatsIncludePublishMetrics(t *testing.T) {
+	metrics := &PublishMetrics{}
+	metrics.ObservePublish(100, 10*time.Millisecond, nil)
+	metrics.ObservePublish(50, 30*time.Millisecond, nil)
+	metrics.ObservePublish(70, 20*time.Millisecond, errors.New("backend down"))
+
+	config := DefaultConfig()
+	config.PublishMetrics = metrics
+	engine := NewEngine(config, NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64]())
+
+	stats := engine.Stats()
+	if stats.BytesPublished != 150 {
+		t.Errorf("Expected 150 bytes, got %d", stats.BytesPublished)
+	}
+	if stats.BackendLatency != 20*time.Millisecond {
+		t.Errorf("Expected 20ms backend latency, got %v", stats.BackendLatency)
+	}
+	if snapshot := metrics.Snapshot(); snapshot.Errors != 1 || snapshot.Operations != 3 {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+}
+
+// countingFallbackPublisher reports fixed fallback counts
+type countingFallbackPublisher struct {
+	MockPublisher[float64]
+}
+
+func (c *countingFallbackPublisher) FallbackCounts() (primary, fallback uint64) {
+	return 40, 2
+}
+
+func TestEngine_StatsIncludeFallbackCounts(t *testing.T) {
+	engine := NewEngine(DefaultConfig(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), &countingFallbackPublisher{})
+
+	if stats := engine.Stats(); stats.PrimaryPublished != 40 || stats.FallbackPublished != 2 {
+		t.Errorf("Expected 40 primary and 2 fallback readings, got %d and %d", stats.PrimaryPublished, stats.FallbackPublished)
+	}
+}
+
+func TestEngine_StatsQualityCounts(t *testing.T) {
+	h := newHarness[float64](t, Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      100,
+		MaxWorkers:     1,
+		QualityProfile: QualityProfile{
+			{Quality: QualityOK, Weight: 0.7},
+			{Quality: QualityNoisy, Weight: 0.2},
+			{Quality: QualityPartial, Weight: 0.1},
+		},
+	})
+	engine := NewEngineWithOptions(h.config, NewTestSeeder([]float64{1, 2, 3, 50}), NewTestSensorFunction(1), h.Publisher,
+		WithRangeGuard(NewNumericRangeGuard[float64](0, 10, RangeFlag)))
+	if err := engine.RunFor(context.Background(), 10000*time.Millisecond); err != nil {
+		t.Fatalf("RunFor failed: %v", err)
+	}
+
+	// The counts match what was published, including qualities assigned
+	// by the range guard
+	published := make(map[Quality]uint64)
+	for _, d := range h.Publisher.data {
+		published[d.Quality]++
+	}
+	stats := engine.Stats()
+	got := map[Quality]uint64{
+		QualityOK:      stats.OKCount,
+		QualityNoisy:   stats.NoisyCount,
+		QualityPartial: stats.PartialCount,
+		QualityCorrupt: stats.CorruptCount,
+	}
+	if !maps.Equal(got, published) {
+		t.Errorf("Expected quality counts %v, got %v", published, got)
+	}
+	if stats.CorruptCount != 2500 {
+		t.Errorf("Expected every fourth reading flagged corrupt, got %d", stats.CorruptCount)
+	}
+	// The profile is realized among the other readings
+	if ok := float64(stats.OKCount) / 7500; ok < 0.67 || ok > 0.73 {
+		t.Errorf("Expected about 70%% OK among unflagged readings, got %.3f", ok)
+	}
+}
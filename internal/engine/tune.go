@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// TuneOptions controls the search performed by RecommendConfig. Zero values
+// fall back to DefaultTuneOptions.
+type TuneOptions struct {
+	BatchSizes    []int
+	MaxWorkers    []int
+	BatchTimeouts []time.Duration
+	TrialDuration time.Duration // How long each combination is run, at least 20 readings
+}
+
+// DefaultTuneOptions returns a grid of twelve one-second trials
+func DefaultTuneOptions() TuneOptions {
+	return TuneOptions{
+		BatchSizes:    []int{10, 50, 100},
+		MaxWorkers:    []int{1, 4},
+		BatchTimeouts: []time.Duration{50 * time.Millisecond, 200 * time.Millisecond},
+		TrialDuration: time.Second,
+	}
+}
+
+// TrialResult is the outcome of running one configuration
+type TrialResult struct {
+	Config     Config
+	Stats      Stats
+	Throughput float64       // Readings published per second
+	Latency    time.Duration // Mean time from generation to publish
+	Achieved   bool          // Whether the target rate was sustained
+}
+
+// Footprint is the maximum number of readings the configuration holds in
+// flight, used as a proxy for memory use
+func (r TrialResult) Footprint() int {
+	return r.Config.BatchSize * r.Config.MaxWorkers
+}
+
+// TuneResult is the outcome of RecommendConfig
+type TuneResult struct {
+	Config   Config        // Recommended configuration
+	Achieved bool          // False when no combination sustained the target rate
+	Trials   []TrialResult // Every trial, in the order they were run
+}
+
+const (
+	// tuneTolerance is the fraction of the expected readings a trial must
+	// publish to count as sustaining the target rate
+	tuneTolerance = 0.9
+
+	// minTrialReadings is the number of readings each trial runs for at least
+	minTrialReadings = 20
+)
+
+// RecommendConfig runs short trials across a grid of BatchSize, MaxWorkers
+// and BatchTimeout values with readings produced every targetRate, and
+// recommends the combination that sustains the target rate with the lowest
+// end-to-end latency, preferring the smaller in-flight footprint on ties.
+// When no combination sustains the rate, the one with the highest throughput
+// is recommended and Achieved is false.
+//
+// The publisher is shared by all trials and is not closed.
+func RecommendConfig[T any](ctx context.Context, seeder Seeder, function SensorFunction[T], publisher Publisher[T], targetRate time.Duration, options TuneOptions) (TuneResult, error) {
+	if targetRate <= 0 {
+		return TuneResult{}, errors.New("target rate must be positive")
+	}
+
+	defaults := DefaultTuneOptions()
+	if len(options.BatchSizes) == 0 {
+		options.BatchSizes = defaults.BatchSizes
+	}
+	if len(options.MaxWorkers) == 0 {
+		options.MaxWorkers = defaults.MaxWorkers
+	}
+	if len(options.BatchTimeouts) == 0 {
+		options.BatchTimeouts = defaults.BatchTimeouts
+	}
+	if options.TrialDuration <= 0 {
+		options.TrialDuration = defaults.TrialDuration
+	}
+	options.TrialDuration = max(options.TrialDuration, minTrialReadings*targetRate)
+
+	var result TuneResult
+	for _, batchSize := range options.BatchSizes {
+		for _, workers := range options.MaxWorkers {
+			for _, timeout := range options.BatchTimeouts {
+				config := Config{
+					ProductionRate: targetRate,
+					BatchSize:      batchSize,
+					BatchTimeout:   timeout,
+					MaxWorkers:     workers,
+				}
+				trial, err := runTrial(ctx, config, seeder, function, publisher, options.TrialDuration)
+				if err != nil {
+					return result, fmt.Errorf("trial %+v failed: %w", config, err)
+				}
+				result.Trials = append(result.Trials, trial)
+			}
+		}
+	}
+
+	best := bestTrial(result.Trials)
+	result.Config = best.Config
+	result.Achieved = best.Achieved
+	return result, nil
+}
+
+// bestTrial returns the trial RecommendConfig recommends: of those that
+// sustained the target rate, the one with the lowest latency and then the
+// smallest footprint, or else the one with the highest throughput. Ties keep
+// the earlier trial.
+func bestTrial(trials []TrialResult) TrialResult {
+	ranked := append([]TrialResult(nil), trials...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Achieved != b.Achieved {
+			return a.Achieved
+		}
+		if !a.Achieved {
+			return a.Throughput > b.Throughput
+		}
+		if a.Latency != b.Latency {
+			return a.Latency < b.Latency
+		}
+		return a.Footprint() < b.Footprint()
+	})
+	return ranked[0]
+}
+
+// runTrial runs the engine with config for duration and measures it
+func runTrial[T any](ctx context.Context, config Config, seeder Seeder, function SensorFunction[T], publisher Publisher[T], duration time.Duration) (TrialResult, error) {
+	probe := &tuneProbe[T]{inner: publisher}
+	engine := NewEngine(config, seeder, function, probe)
+
+	trialCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	start := time.Now()
+	if err := engine.Start(trialCtx); err != nil {
+		return TrialResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return TrialResult{}, err
+	}
+	elapsed := time.Since(start)
+
+	stats := engine.Stats()
+	trial := TrialResult{
+		Config:     config,
+		Stats:      stats,
+		Throughput: float64(stats.Published) / elapsed.Seconds(),
+	}
+	if items := probe.items.Load(); items > 0 {
+		trial.Latency = time.Duration(probe.latency.Load() / items)
+	}
+
+	// Readings generated in the final BatchTimeout may still be waiting for
+	// their batch when the trial ends, so they are not expected
+	expected := (elapsed - config.BatchTimeout) / config.ProductionRate
+	trial.Achieved = float64(stats.Published) >= float64(expected)*tuneTolerance
+	return trial, nil
+}
+
+// tuneProbe forwards to the publisher under test, measuring how long each
+// reading took to reach it. Close is a no-op so the publisher can be reused
+// across trials.
+type tuneProbe[T any] struct {
+	inner   Publisher[T]
+	items   atomic.Int64
+	latency atomic.Int64 // Cumulative generation-to-publish time in nanoseconds
+}
+
+func (p *tuneProbe[T]) Publish(ctx context.Context, data SensorData[T]) error {
+	err := p.inner.Publish(ctx, data)
+	if err == nil {
+		p.observe(data)
+	}
+	return err
+}
+
+func (p *tuneProbe[T]) PublishBatch(ctx context.Context, data []SensorData[T]) error {
+	err := p.inner.PublishBatch(ctx, data)
+	if err == nil {
+		for _, d := range data {
+			p.observe(d)
+		}
+	}
+	return err
+}
+
+func (p *tuneProbe[T]) Close() error {
+	return nil
+}
+
+func (p *tuneProbe[T]) observe(data SensorData[T]) {
+	p.items.Add(1)
+	p.latency.Add(int64(time.Since(data.Timestamp)))
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecommendConfig_RunsEveryTrial(t *testing.T) {
+	options := TuneOptions{
+		BatchSizes:    []int{10},
+		MaxWorkers:    []int{1, 4},
+		BatchTimeouts: []time.Duration{5 * time.Millisecond},
+		TrialDuration: 100 * time.Millisecond,
+	}
+	publisher := &slowMockPublisher[float64]{delay: 10 * time.Millisecond}
+
+	result, err := RecommendConfig(context.Background(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), publisher, 2*time.Millisecond, options)
+	if err != nil {
+		t.Fatalf("RecommendConfig failed: %v", err)
+	}
+
+	// Which trial wins depends on the machine's speed, which bestTrial's
+	// test covers; here only the outcome's consistency is checked
+	if len(result.Trials) != 2 {
+		t.Fatalf("Expected 2 trials, got %d", len(result.Trials))
+	}
+	if workers := []int{result.Trials[0].Config.MaxWorkers, result.Trials[1].Config.MaxWorkers}; workers[0] != 1 || workers[1] != 4 {
+		t.Errorf("Expected trials with 1 and 4 workers in order, got %v", workers)
+	}
+	if best := bestTrial(result.Trials); result.Config.MaxWorkers != best.Config.MaxWorkers || result.Achieved != best.Achieved {
+		t.Errorf("Expected the best trial, with %d workers, to be recommended, got %d", best.Config.MaxWorkers, result.Config.MaxWorkers)
+	}
+	if result.Config.ProductionRate != 2*time.Millisecond {
+		t.Errorf("Expected target rate to be kept, got %v", result.Config.ProductionRate)
+	}
+	for _, trial := range result.Trials {
+		if trial.Stats.Published > 0 && trial.Latency <= 0 {
+			t.Errorf("Expected latency to be measured for %+v", trial.Config)
+		}
+	}
+}
+
+func TestBestTrial(t *testing.T) {
+	trial := func(workers int, achieved bool, throughput float64, latency time.Duration) TrialResult {
+		return TrialResult{
+			Config:     Config{BatchSize: 10, MaxWorkers: workers},
+			Throughput: throughput,
+			Latency:    latency,
+			Achieved:   achieved,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		trials []TrialResult
+		want   int
+	}{
+		{"achieved beats throughput", []TrialResult{trial(1, false, 900, time.Millisecond), trial(2, true, 500, time.Second)}, 2},
+		{"lowest latency", []TrialResult{trial(1, true, 500, 20*time.Millisecond), trial(4, true, 500, 10*time.Millisecond)}, 4},
+		{"smallest footprint on ties", []TrialResult{trial(4, true, 500, 10*time.Millisecond), trial(1, true, 500, 10*time.Millisecond)}, 1},
+		{"highest throughput when none achieved", []TrialResult{trial(1, false, 250, time.Millisecond), trial(4, false, 480, time.Second)}, 4},
+		{"earlier trial on full ties", []TrialResult{trial(2, false, 250, 0), trial(3, false, 250, 0)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestTrial(tt.trials).Config.MaxWorkers; got != tt.want {
+				t.Errorf("Expected the trial with %d workers, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecommendConfig_InvalidTarget(t *testing.T) {
+	_, err := RecommendConfig(context.Background(), NewTestSeeder([]float64{1.0}), NewTestSensorFunction(1.0), NewMockPublisher[float64](), 0, TuneOptions{})
+	if err == nil {
+		t.Fatal("Expected error for zero target rate")
+	}
+}
@@ -2,6 +2,11 @@ package engine
 
 import (
 	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,6 +16,9 @@ type SensorData[T any] struct {
 	Timestamp time.Time `json:"timestamp"`
 	Data      T         `json:"data"`
 	Quality   Quality   `json:"quality"`
+	Input     *float64  `json:"input,omitempty"` // Seeder value the reading was computed from, with Config.IncludeInput
+
+	QualityScore *float64 `json:"quality_score,omitempty"` // Quality as a number, with Config.IncludeQualityScore
 }
 
 // Quality represents the quality of sensor data
@@ -21,31 +29,243 @@ const (
 	QualityNoisy   Quality = "NOISY"
 	QualityPartial Quality = "PARTIAL"
 	QualityCorrupt Quality = "CORRUPT"
+
+	// QualityHeartbeat marks a synthetic keep-alive reading with a zero Data value
+	QualityHeartbeat Quality = "HEARTBEAT"
+
+	// QualityOffline and QualityOnline mark, with a zero Data value, the
+	// start and end of an outage simulated by an OfflineSchedule
+	QualityOffline Quality = "OFFLINE"
+	QualityOnline  Quality = "ONLINE"
 )
 
-// Seeder generates input values for sensor functions
+// Number is the set of numeric types that numeric helpers operate on
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Seeder generates input values for sensor functions. The built-in seeders
+// are safe for concurrent use; custom seeders with mutable state should be
+// too, or be documented as single-goroutine only.
 type Seeder interface {
 	Generate() float64
 }
 
+// ClockedSeeder is implemented by seeders whose output depends on time, so
+// they can be evaluated at an arbitrary instant instead of the current time
+type ClockedSeeder interface {
+	Seeder
+	GenerateAt(t time.Time) float64
+}
+
 // SensorFunction defines the interface for sensor data generation functions
 type SensorFunction[T any] interface {
 	Generate(input float64, timestamp time.Time) T
 }
 
-// Publisher defines the interface for publishing sensor data
+// Publisher defines the interface for publishing sensor data. The engine
+// hands ownership of each batch to PublishBatch and never uses the slice
+// again, so implementations may retain or modify it.
 type Publisher[T any] interface {
 	Publish(ctx context.Context, data SensorData[T]) error
 	PublishBatch(ctx context.Context, data []SensorData[T]) error
 	Close() error
 }
 
+// PublisherObserver receives the outcome of every backend operation a
+// publisher performs: the serialized payload size in bytes, how long the
+// operation took and its error
+type PublisherObserver interface {
+	ObservePublish(bytes int, duration time.Duration, err error)
+}
+
+// PublisherObserverFunc adapts a function to a PublisherObserver
+type PublisherObserverFunc func(bytes int, duration time.Duration, err error)
+
+// ObservePublish calls f
+func (f PublisherObserverFunc) ObservePublish(bytes int, duration time.Duration, err error) {
+	f(bytes, duration, err)
+}
+
+// PublishMode selects how batches are handed to the publisher
+type PublishMode string
+
+const (
+	PublishModeBatch  PublishMode = "batch"  // One PublishBatch call per batch (default), except in Start with a BatchSize of 1
+	PublishModeSingle PublishMode = "single" // One Publish call per reading
+)
+
 // Config holds the engine configuration
 type Config struct {
 	ProductionRate time.Duration // How often to generate data
-	BatchSize      int           // Number of messages to batch together
-	BatchTimeout   time.Duration // How long to wait before publishing a batch
+	BatchSize      int           // Number of messages to batch together; 1 makes Start publish each reading with Publish, skipping batching
+	BatchTimeout   time.Duration // How long to wait before publishing a batch; 0 only sends full batches
 	MaxWorkers     int           // Number of concurrent workers
+	PublishMode    PublishMode   // Batch (default) or Single
+
+	// MinBatchSize avoids publishing tiny batches when BatchTimeout fires:
+	// a smaller batch keeps collecting readings and is sent as soon as it
+	// reaches MinBatchSize, or once MaxBatchWait has passed since the last
+	// batch was sent, whichever comes first. MaxBatchWait must be at least
+	// BatchTimeout when MinBatchSize is set. 0 flushes at every timeout.
+	MinBatchSize int
+	MaxBatchWait time.Duration
+
+	// BatchQueueSize is the number of assembled batches that may wait for a
+	// publisher worker (0 for the default of 10). Workers take batches from
+	// this shared queue as they become free, so a worker stuck on a slow
+	// publish does not hold up the others: a larger queue lets idle workers
+	// keep draining batches during bursts and latency spikes, at the cost of
+	// more readings held in memory. Once the queue is full the batch
+	// processor, and in turn the generator, blocks until a worker frees a
+	// slot.
+	BatchQueueSize int
+
+	// MaxBufferedReadings caps the total number of readings held between the
+	// generator and the publisher workers (queued readings, the batch being
+	// assembled and queued batches). By default a slow publisher applies
+	// backpressure: the generator blocks once the channels are full. With a
+	// cap the generator never blocks on a slow publisher; instead it drops the
+	// oldest buffered readings and counts them in Stats.Dropped. The cap
+	// should be at least BatchSize. 0 disables the cap.
+	MaxBufferedReadings int
+
+	// HeartbeatInterval emits a QualityHeartbeat reading whenever no reading
+	// has been generated for this long, so consumers can tell a quiet
+	// producer from a dead one. 0 disables heartbeats.
+	HeartbeatInterval time.Duration
+
+	// Offline simulates the sensor going fully offline for extended
+	// periods and reconnecting (nil to disable)
+	Offline *OfflineSchedule
+
+	// SeederTimeout bounds how long a single seeder and function call may
+	// take. A tick whose call has not returned in time is skipped and counted
+	// in Stats.SlowCycles; the next tick waits for the outstanding call
+	// rather than starting another one. 0 waits indefinitely.
+	SeederTimeout time.Duration
+
+	// StallTimeout starts a watchdog in Start that warns, counts a stall in
+	// Stats.Stalls and calls the WithOnStall callback whenever the generator
+	// has produced nothing for this long, as when a seeder blocks waiting
+	// for input. Offline ticks count as progress. It should be several
+	// production intervals long. 0 disables the watchdog.
+	StallTimeout time.Duration
+
+	// StopOnStall also stops Start with an error wrapping ErrStalled when
+	// the watchdog detects a stall. A generator blocked inside the seeder
+	// cannot be interrupted, so pair it with ShutdownTimeout for Start to
+	// return.
+	StopOnStall bool
+
+	// ReorderWindow emits readings out of order for testing consumers:
+	// readings are held in windows of this size and, within each window, each
+	// reading is swapped with a random later one with probability
+	// ReorderProbability. No reading moves more than ReorderWindow-1
+	// positions, and IDs keep their generation order so the reordering can be
+	// detected downstream. This deliberately violates timestamp ordering and
+	// delays readings until their window fills. RunFor and Backfill publish
+	// a partial window at the end; Start queues it on shutdown as far as
+	// the reading buffer has room and discards the rest, which are not
+	// counted as generated. Use it for testing only. Values below 2 disable
+	// reordering.
+	ReorderWindow      int
+	ReorderProbability float64
+
+	// TraceBatches assigns a batch ID to every batch, passes it to the
+	// publisher through the context and logs it when the batch is published
+	TraceBatches bool
+
+	// Clock supplies reading timestamps (nil for the wall clock). Pair a
+	// ManualClock with RunFor for reproducible output.
+	Clock Clock
+
+	// RandSource drives quality selection and batch IDs (nil for the global
+	// source). A seeded source makes them reproducible.
+	RandSource rand.Source
+
+	// QualityProfile sets the distribution of generated qualities and may
+	// include qualities added with RegisterQuality (nil for the default)
+	QualityProfile QualityProfile
+
+	// PublishMetrics, when also passed to the publisher as its observer, adds
+	// the publisher's byte and latency counts to Stats
+	PublishMetrics *PublishMetrics
+
+	// RateEnvelope schedules the production rate over the run, replacing
+	// ProductionRate once the engine starts. It should not be combined with
+	// AdaptiveRate or SetProductionRate, which it overrides on every tick.
+	RateEnvelope RateEnvelope
+
+	// QualityFilter, when set, drops readings whose quality it rejects before
+	// they are published, counting them in Stats.Filtered. Rejected readings
+	// go to the dead-letter publisher, if one is set. Heartbeats and offline
+	// markers are never filtered.
+	QualityFilter QualityFilter
+
+	// AdaptiveRate enables automatic tuning of ProductionRate (nil to disable)
+	AdaptiveRate *AdaptiveRateConfig
+
+	// AdaptiveBatch enables automatic tuning of the batch size, starting
+	// from BatchSize, to keep publish latency near a target (nil to
+	// disable). Only batch publishes are measured, so it has no effect with
+	// PublishModeSingle.
+	AdaptiveBatch *AdaptiveBatchConfig
+
+	// Envelope, when set, publishes readings wrapped in Envelope values
+	// through the publisher's PublishEnvelopes method instead of as plain
+	// SensorData. The publisher must implement EnvelopePublisher. Readings
+	// sent to the dead-letter publisher are not wrapped.
+	Envelope *EnvelopeConfig
+
+	// DebugTee prints every batch to stderr, one JSON line per reading, in
+	// addition to handing it to the publisher. It is a debugging aid for
+	// watching what a remote publisher receives, not a fan-out: nothing is
+	// printed, and nothing is marshaled, while it is off.
+	DebugTee bool
+
+	// IncludeInput records the seeder value each reading was computed from
+	// in SensorData.Input, to tell whether an anomaly came from the seeder
+	// or the sensor function. It exposes an internal detail of the
+	// simulation, so it is off by default.
+	IncludeInput bool
+
+	// IncludeQualityScore records the quality of each reading as a number
+	// in SensorData.QualityScore, for consumers such as ML feature pipelines
+	// that treat quality quantitatively. Scores come from QualityScores,
+	// falling back to Quality.Score for qualities it does not list.
+	IncludeQualityScore bool
+	QualityScores       QualityScores
+
+	// MaxDuration bounds how long Start runs: when set, Start stops on its
+	// own once this much time has passed, even if ctx has no deadline. The
+	// effective deadline is the earlier of MaxDuration and ctx's deadline.
+	// It does not apply to RunFor and Backfill, which run on virtual time.
+	// 0 runs until ctx is done.
+	MaxDuration time.Duration
+
+	// ShutdownTimeout bounds how long Start waits for its workers to drain
+	// once ctx is done. When it passes, Start logs a warning, abandons the
+	// workers still running, such as one stuck in a publish that ignores
+	// its context, and returns an error wrapping ErrShutdownTimeout without
+	// closing the publishers. 0 waits indefinitely.
+	ShutdownTimeout time.Duration
+
+	// WarmupGenerations calls the seeder this many times, discarding the
+	// results, before the first reading is generated. Stateful seeders such
+	// as random walks and moving averages use it as a burn-in so the first
+	// published readings are not biased by their initial state. The warmup
+	// runs once per engine, on the first Start, RunFor or Backfill.
+	WarmupGenerations int
+
+	// MaxConsecutivePublishErrors stops the engine after this many publish
+	// failures in a row, so a misconfigured publisher surfaces as an error
+	// instead of a run that logs failures forever. Any successful publish
+	// resets the count. Start, RunFor and Backfill shut down gracefully and
+	// return an error wrapping ErrTooManyPublishErrors. 0 disables the limit.
+	MaxConsecutivePublishErrors int
 }
 
 // Engine is the generic sensor engine
@@ -54,19 +274,56 @@ type Engine[T any] struct {
 	seeder    Seeder
 	function  SensorFunction[T]
 	publisher Publisher[T]
+
+	logger         Logger
+	onPublishError func([]SensorData[T], error)
+	onStall        func(time.Duration)
+	idGenerator    IDGenerator
+	enrich         EnrichFunc[T]
+	redact         RedactFunc[T]
+	deadLetter     Publisher[T]
+	publishRetry   BackoffStrategy    // Retries failed publisher calls, nil for none
+	noise          *ar1Noise          // Noise added to readings, nil when disabled
+	drift          *drift             // Calibration drift added to readings, nil when disabled
+	addOffset      func(T, float64) T // Adds noise or drift to a reading
+	rangeGuard     *RangeGuard[T]
+	changeTrigger  *changeTrigger[T]
+	debugOut       io.Writer // Destination of DebugTee output
+	debugMu        sync.Mutex
+
+	clock Clock
+	rng   *rand.Rand // nil for the global source
+	rngMu sync.Mutex
+
+	stats       engineStats
+	rate        atomic.Int64 // Current production interval in nanoseconds
+	rateChanged chan struct{}
+	buffered    atomic.Int64  // Readings generated but not yet taken by a publish worker
+	slowStreak  int           // Consecutive slow generation cycles, owned by generateData
+	envelopeSeq atomic.Uint64 // Last envelope sequence number handed out
+	nextID      atomic.Uint64 // Sequence number of the next reading, carried across runs
+	warmup      sync.Once
+
+	batchSize     atomic.Int64  // Current batch size with AdaptiveBatch or SetBatchSize, 0 before either
+	batchTimeout  atomic.Int64  // Batch timeout set with SetBatchTimeout in nanoseconds, 0 for Config.BatchTimeout
+	adaptiveBatch adaptiveBatch // Latency samples of the batch size controller
+
+	publishFailures atomic.Int64            // Consecutive failed publishes
+	abort           context.CancelCauseFunc // Stops the current run early
+
+	batchChan   atomic.Pointer[chan []SensorData[T]] // Set while running, used for backlog depth
+	statsMu     sync.Mutex
+	statsServer *http.Server
 }
 
-// NewEngine creates a new generic sensor engine
+// NewEngine creates a new generic sensor engine. It panics if seeder,
+// function or publisher is nil; the configuration is validated when the
+// engine starts.
 func NewEngine[T any](
 	config Config,
 	seeder Seeder,
 	function SensorFunction[T],
 	publisher Publisher[T],
 ) *Engine[T] {
-	return &Engine[T]{
-		config:    config,
-		seeder:    seeder,
-		function:  function,
-		publisher: publisher,
-	}
+	return NewEngineWithOptions(config, seeder, function, publisher)
 }
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrStalled is returned, wrapped, by Start when it stops because the
+// generator stalled and Config.StopOnStall is set
+var ErrStalled = errors.New("generator stalled")
+
+// WithOnStall calls fn whenever the stall watchdog finds that the generator
+// has produced nothing for Config.StallTimeout, with how long it has been
+// stalled. It is called once per stall and runs on the watchdog goroutine.
+func WithOnStall[T any](fn func(stalled time.Duration)) Option[T] {
+	return func(e *Engine[T]) {
+		e.onStall = fn
+	}
+}
+
+// generatorProgress counts the production ticks the generator has
+// completed. Ticks skipped by an OfflineSchedule count, since an outage is
+// not a stall.
+func (e *Engine[T]) generatorProgress() uint64 {
+	return e.stats.generated.Load() + e.stats.offlineTicks.Load()
+}
+
+// watchStalls reports a stall whenever the generator makes no progress for
+// Config.StallTimeout, checking a few times per timeout. A stall is reported
+// once, and again only after the generator has recovered and stalled anew.
+func (e *Engine[T]) watchStalls(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	timeout := e.config.StallTimeout
+	ticker := time.NewTicker(max(timeout/4, time.Millisecond))
+	defer ticker.Stop()
+
+	progress := e.generatorProgress()
+	since := time.Now()
+	stalled := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if current := e.generatorProgress(); current != progress {
+				progress, since, stalled = current, now, false
+				continue
+			}
+			if !stalled && now.Sub(since) >= timeout {
+				stalled = true
+				e.reportStall(now.Sub(since))
+			}
+		}
+	}
+}
+
+// reportStall counts and logs a stall, calls the OnStall callback, if any,
+// and stops the run when Config.StopOnStall is set
+func (e *Engine[T]) reportStall(stalled time.Duration) {
+	e.stats.stalls.Add(1)
+	e.logf("Warning: the generator has produced nothing for %v; the seeder or sensor function may be blocked", stalled.Round(time.Millisecond))
+	if e.onStall != nil {
+		e.onStall(stalled)
+	}
+	if e.config.StopOnStall {
+		e.abort(fmt.Errorf("%w: nothing generated for %v", ErrStalled, stalled.Round(time.Millisecond)))
+	}
+}
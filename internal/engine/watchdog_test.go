@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingSeeder returns values until limit have been generated and then
+// blocks, like a seeder waiting on input that never arrives, until release
+// is closed
+type blockingSeeder struct {
+	limit     int64
+	generated atomic.Int64
+	release   chan struct{}
+}
+
+func newBlockingSeeder(t *testing.T, limit int64) *blockingSeeder {
+	s := &blockingSeeder{limit: limit, release: make(chan struct{})}
+	t.Cleanup(s.unblock)
+	return s
+}
+
+func (s *blockingSeeder) Generate() float64 {
+	if s.generated.Add(1) > s.limit {
+		<-s.release
+	}
+	return 1
+}
+
+func (s *blockingSeeder) unblock() {
+	select {
+	case <-s.release:
+	default:
+		close(s.release)
+	}
+}
+
+func TestEngine_StallWatchdog(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      1,
+		MaxWorkers:     1,
+		StallTimeout:   30 * time.Millisecond,
+	}
+	seeder := newBlockingSeeder(t, 5)
+	var mutex sync.Mutex
+	var stalls []time.Duration
+	engine := NewEngineWithOptions(config, seeder, NewTestSensorFunction(1), NewMockPublisher[float64](),
+		WithLogger[float64](log.New(io.Discard, "", 0)),
+		WithOnStall[float64](func(stalled time.Duration) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			stalls = append(stalls, stalled)
+		}))
+
+	// The seeder recovers before the run ends, so Start can return
+	time.AfterFunc(150*time.Millisecond, seeder.unblock)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(stalls) != 1 || stalls[0] < config.StallTimeout {
+		t.Fatalf("Expected the watchdog to fire once after at least %v, got %v", config.StallTimeout, stalls)
+	}
+	stats := engine.Stats()
+	if stats.Stalls != 1 {
+		t.Errorf("Expected 1 stall counted, got %d", stats.Stalls)
+	}
+	if stats.Generated <= 5 {
+		t.Errorf("Expected generation to resume after the stall, got %d readings", stats.Generated)
+	}
+}
+
+func TestEngine_StopOnStall(t *testing.T) {
+	config := Config{
+		ProductionRate:  time.Millisecond,
+		BatchSize:       1,
+		MaxWorkers:      1,
+		StallTimeout:    20 * time.Millisecond,
+		StopOnStall:     true,
+		ShutdownTimeout: 20 * time.Millisecond,
+	}
+	engine := NewEngineWithOptions(config, newBlockingSeeder(t, 0), NewTestSensorFunction(1), NewMockPublisher[float64](),
+		WithLogger[float64](log.New(io.Discard, "", 0)))
+
+	start := time.Now()
+	err := engine.Start(context.Background())
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("Expected an error wrapping ErrStalled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Start to stop soon after the stall, took %v", elapsed)
+	}
+}
+
+func TestEngine_StallWatchdogQuietWhileGenerating(t *testing.T) {
+	config := Config{
+		ProductionRate: time.Millisecond,
+		BatchSize:      10,
+		MaxWorkers:     1,
+		StallTimeout:   20 * time.Millisecond,
+		Offline:        &OfflineSchedule{Windows: []OfflineWindow{{From: 10 * time.Millisecond, To: 60 * time.Millisecond}}},
+	}
+	engine := NewEngine(config, NewTestSeeder([]float64{1}), NewTestSensorFunction(1), NewMockPublisher[float64]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if stalls := engine.Stats().Stalls; stalls != 0 {
+		t.Errorf("Expected no stalls from a healthy or offline generator, got %d", stalls)
+	}
+}
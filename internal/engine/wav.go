@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// wavFormatIEEEFloat is the WAVE format tag for 32-bit floating point samples
+const wavFormatIEEEFloat = 3
+
+// wavHeaderSize is the size of the RIFF chunk before the samples, counted
+// from the WAVE tag
+const wavHeaderSize = 4 + (8 + 18) + (8 + 4) + 8
+
+// ExportWAV runs a seeder for the given duration at sampleRate samples per
+// second and writes the values as a mono 32-bit float WAV file. Seeders that
+// implement ClockedSeeder are evaluated at the virtual sample times, so the
+// export does not take duration of wall-clock time; other seeders are simply
+// called once per sample. Audio tools expect samples in [-1, 1], so scale the
+// seeder accordingly if the file is meant for listening.
+func ExportWAV(s Seeder, sampleRate int, duration time.Duration, path string) error {
+	if sampleRate <= 0 || sampleRate > math.MaxUint32/4 {
+		return fmt.Errorf("invalid sample rate: %d", sampleRate)
+	}
+	samples := int(duration.Seconds() * float64(sampleRate))
+	if samples <= 0 {
+		return fmt.Errorf("duration %v is too short for sample rate %d", duration, sampleRate)
+	}
+	// The RIFF chunk size, which includes the samples, is 32 bits
+	if samples > (math.MaxUint32-wavHeaderSize)/4 {
+		return fmt.Errorf("%d samples exceed the 4 GiB limit of a WAV file", samples)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	dataSize := uint32(samples * 4)
+
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(wavHeaderSize + dataSize),
+		[4]byte{'W', 'A', 'V', 'E'},
+
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(18),
+		uint16(wavFormatIEEEFloat),
+		uint16(1), // Channels
+		uint32(sampleRate),
+		uint32(sampleRate * 4), // Byte rate
+		uint16(4),              // Block align
+		uint16(32),             // Bits per sample
+		uint16(0),              // Extension size
+
+		[4]byte{'f', 'a', 'c', 't'},
+		uint32(4),
+		uint32(samples),
+
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return fmt.Errorf("failed to write WAV header: %w", err)
+		}
+	}
+
+	clocked, isClocked := s.(ClockedSeeder)
+	start := time.Now()
+	var buf [4]byte
+	for i := 0; i < samples; i++ {
+		var value float64
+		if isClocked {
+			offset := time.Duration(float64(i) * float64(time.Second) / float64(sampleRate))
+			value = clocked.GenerateAt(start.Add(offset))
+		} else {
+			value = s.Generate()
+		}
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(value)))
+		if _, err := w.Write(buf[:]); err != nil {
+			return fmt.Errorf("failed to write WAV samples: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write WAV file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signal.wav")
+	seeder := NewTimeSeeder(0.5, 441, 0) // 100 samples per period at 44.1kHz
+
+	if err := ExportWAV(seeder, 44100, 10*time.Millisecond, path); err != nil {
+		t.Fatalf("ExportWAV failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read WAV file: %v", err)
+	}
+
+	const headerSize = 12 + 8 + 18 + 8 + 4 + 8
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Fatal("Missing RIFF/WAVE header")
+	}
+	if got := binary.LittleEndian.Uint32(data[4:8]); int(got) != len(data)-8 {
+		t.Errorf("RIFF size %d does not match file size %d", got, len(data)-8)
+	}
+	if format := binary.LittleEndian.Uint16(data[20:22]); format != wavFormatIEEEFloat {
+		t.Errorf("Expected IEEE float format, got %d", format)
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:28]); rate != 44100 {
+		t.Errorf("Expected sample rate 44100, got %d", rate)
+	}
+
+	samples := (len(data) - headerSize) / 4
+	if samples != 441 {
+		t.Fatalf("Expected 441 samples, got %d", samples)
+	}
+
+	sample := func(i int) float64 {
+		offset := headerSize + i*4
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[offset : offset+4])))
+	}
+	// Samples are evaluated at virtual sample times, so the waveform is exact
+	// up to the phase offset between seeder creation and export
+	peak, trough := math.Inf(-1), math.Inf(1)
+	for i := 0; i < samples; i++ {
+		peak = math.Max(peak, sample(i))
+		trough = math.Min(trough, sample(i))
+	}
+	if math.Abs(peak-0.5) > 0.01 || math.Abs(trough+0.5) > 0.01 {
+		t.Errorf("Expected range [-0.5, 0.5], got [%f, %f]", trough, peak)
+	}
+	for _, i := range []int{0, 17, 250} {
+		if a, b := sample(i), sample(i+100); math.Abs(a-b) > 1e-3 {
+			t.Errorf("Expected a 100-sample period: sample %d=%f, sample %d=%f", i, a, i+100, b)
+		}
+	}
+}
+
+func TestExportWAV_InvalidArguments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signal.wav")
+	seeder := NewRandomSeeder(-1, 1)
+
+	if err := ExportWAV(seeder, 0, time.Second, path); err == nil {
+		t.Error("Expected error for zero sample rate")
+	}
+	if err := ExportWAV(seeder, 8000, 0, path); err == nil {
+		t.Error("Expected error for zero duration")
+	}
+	if err := ExportWAV(seeder, 48000, 7*time.Hour, path); err == nil {
+		t.Error("Expected error for data over the 4 GiB size limit")
+	}
+}
@@ -0,0 +1,159 @@
+package publisher
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// DeltaReading is a delta-encoded numeric value. On a keyframe Delta holds
+// the absolute value; otherwise it holds the change since the previous
+// reading of the same stream. Consumers reconstruct absolute values by
+// resetting to Delta on each keyframe and adding Delta to the running value
+// otherwise.
+type DeltaReading struct {
+	Delta    float64 `json:"delta"`
+	Keyframe bool    `json:"keyframe"`
+}
+
+// DeltaOption configures a DeltaPublisher
+type DeltaOption[T any] func(*DeltaPublisher[T])
+
+// WithDeltaStream tracks previous values per stream, as named by stream,
+// instead of treating every reading as part of a single stream
+func WithDeltaStream[T any](stream func(engine.SensorData[T]) string) DeltaOption[T] {
+	return func(d *DeltaPublisher[T]) {
+		d.stream = stream
+	}
+}
+
+// deltaState is the encoder state of one stream
+type deltaState struct {
+	previous float64
+	count    int // Values encoded since the last keyframe
+}
+
+// DeltaPublisher delta-encodes a numeric value of each reading before
+// forwarding it, which shrinks payloads for slowly changing signals. Every
+// keyframeInterval values of a stream, and for its first value, an absolute
+// keyframe is sent instead so consumers can resynchronize. Readings without
+// a value, such as heartbeats, are forwarded with a zero delta that leaves
+// the reconstructed value unchanged.
+//
+// Deltas are only meaningful in order, so publishes to the next publisher
+// are serialized.
+type DeltaPublisher[T any] struct {
+	next             engine.Publisher[DeltaReading]
+	extract          func(T) (float64, bool)
+	keyframeInterval int
+	stream           func(engine.SensorData[T]) string
+
+	mutex   sync.Mutex
+	streams map[string]deltaState
+}
+
+// NewDeltaPublisher creates a delta encoder in front of next. extract
+// returns the numeric field to encode and whether the reading has one.
+// keyframeInterval is the number of values between keyframes; below 1 only
+// the first value of each stream is a keyframe.
+func NewDeltaPublisher[T any](next engine.Publisher[DeltaReading], extract func(T) (float64, bool), keyframeInterval int, opts ...DeltaOption[T]) *DeltaPublisher[T] {
+	d := &DeltaPublisher[T]{
+		next:             next,
+		extract:          extract,
+		keyframeInterval: keyframeInterval,
+		streams:          make(map[string]deltaState),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// NewNumericDeltaPublisher creates a delta encoder for readings whose value
+// is itself a number
+func NewNumericDeltaPublisher[N engine.Number](next engine.Publisher[DeltaReading], keyframeInterval int, opts ...DeltaOption[N]) *DeltaPublisher[N] {
+	return NewDeltaPublisher(next, func(v N) (float64, bool) {
+		return float64(v), true
+	}, keyframeInterval, opts...)
+}
+
+// Publish encodes and forwards a single sensor data point
+func (d *DeltaPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pending := make(map[string]deltaState)
+	if err := d.next.Publish(ctx, d.encode(data, pending)); err != nil {
+		return err
+	}
+	d.commit(pending)
+	return nil
+}
+
+// PublishBatch encodes and forwards a batch of sensor data points
+func (d *DeltaPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pending := make(map[string]deltaState)
+	encoded := make([]engine.SensorData[DeltaReading], len(data))
+	for i, reading := range data {
+		encoded[i] = d.encode(reading, pending)
+	}
+	if err := d.next.PublishBatch(ctx, encoded); err != nil {
+		return err
+	}
+	d.commit(pending)
+	return nil
+}
+
+// encode converts one reading, recording its stream's new state in pending.
+// The state is only committed once the next publisher has accepted the
+// readings, so a failed or retried publish is encoded against the values
+// the consumer last received.
+func (d *DeltaPublisher[T]) encode(data engine.SensorData[T], pending map[string]deltaState) engine.SensorData[DeltaReading] {
+	encoded := engine.SensorData[DeltaReading]{
+		ID:        data.ID,
+		Timestamp: data.Timestamp,
+		Quality:   data.Quality,
+	}
+	if data.Quality == engine.QualityHeartbeat {
+		return encoded
+	}
+	value, ok := d.extract(data.Data)
+	if !ok {
+		return encoded
+	}
+
+	var key string
+	if d.stream != nil {
+		key = d.stream(data)
+	}
+	state, seen := pending[key]
+	if !seen {
+		state, seen = d.streams[key]
+	}
+
+	if !seen || (d.keyframeInterval > 0 && state.count >= d.keyframeInterval) {
+		encoded.Data = DeltaReading{Delta: value, Keyframe: true}
+		state.count = 0
+	} else {
+		encoded.Data = DeltaReading{Delta: value - state.previous}
+	}
+	state.previous = value
+	state.count++
+	pending[key] = state
+	return encoded
+}
+
+// commit stores the stream states of a forwarded publish
+func (d *DeltaPublisher[T]) commit(pending map[string]deltaState) {
+	maps.Copy(d.streams, pending)
+}
+
+// Close closes the next publisher
+func (d *DeltaPublisher[T]) Close() error {
+	return d.next.Close()
+}
@@ -0,0 +1,149 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func TestDeltaPublisher_KeyframesAndReconstruction(t *testing.T) {
+	sink := NewRingBufferPublisher[DeltaReading](16)
+	delta := NewNumericDeltaPublisher[float64](sink, 3)
+
+	values := []float64{20, 20.5, 21, 20.75, 20.75, 22, 23}
+	batch := make([]engine.SensorData[float64], len(values))
+	for i, v := range values {
+		batch[i] = engine.SensorData[float64]{ID: "s", Timestamp: time.Now(), Data: v, Quality: engine.QualityOK}
+	}
+	if err := delta.PublishBatch(context.Background(), batch[:4]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, d := range batch[4:] {
+		if err := delta.Publish(context.Background(), d); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	encoded := sink.Snapshot()
+	if len(encoded) != len(values) {
+		t.Fatalf("Expected %d readings, got %d", len(values), len(encoded))
+	}
+	var current float64
+	for i, d := range encoded {
+		if wantKeyframe := i%3 == 0; d.Data.Keyframe != wantKeyframe {
+			t.Errorf("Reading %d: expected keyframe=%v, got %+v", i, wantKeyframe, d.Data)
+		}
+		if d.Data.Keyframe {
+			current = d.Data.Delta
+		} else {
+			current += d.Data.Delta
+		}
+		if math.Abs(current-values[i]) > 1e-9 {
+			t.Errorf("Reading %d: reconstructed %v, expected %v", i, current, values[i])
+		}
+	}
+}
+
+func TestDeltaPublisher_StreamsAndHeartbeats(t *testing.T) {
+	sink := NewRingBufferPublisher[DeltaReading](16)
+	delta := NewNumericDeltaPublisher(sink, 0, WithDeltaStream(func(d engine.SensorData[int]) string {
+		return d.ID
+	}))
+
+	batch := []engine.SensorData[int]{
+		{ID: "a", Data: 10, Quality: engine.QualityOK},
+		{ID: "b", Data: 100, Quality: engine.QualityOK},
+		{ID: "a", Quality: engine.QualityHeartbeat},
+		{ID: "a", Data: 13, Quality: engine.QualityOK},
+		{ID: "b", Data: 90, Quality: engine.QualityOK},
+	}
+	if err := delta.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []DeltaReading{
+		{Delta: 10, Keyframe: true},
+		{Delta: 100, Keyframe: true},
+		{},
+		{Delta: 3},
+		{Delta: -10},
+	}
+	for i, d := range sink.Snapshot() {
+		if d.Data != want[i] {
+			t.Errorf("Reading %d: expected %+v, got %+v", i, want[i], d.Data)
+		}
+		if d.ID != batch[i].ID || d.Quality != batch[i].Quality {
+			t.Errorf("Reading %d: expected ID and quality to be kept, got %+v", i, d)
+		}
+	}
+}
+
+// flakyDeltaSink records delta readings, or fails while down is set
+type flakyDeltaSink struct {
+	MockPublisher[DeltaReading]
+	down bool
+}
+
+func (f *flakyDeltaSink) Publish(ctx context.Context, data engine.SensorData[DeltaReading]) error {
+	if f.down {
+		return errors.New("sink unavailable")
+	}
+	return f.MockPublisher.Publish(ctx, data)
+}
+
+func (f *flakyDeltaSink) PublishBatch(ctx context.Context, data []engine.SensorData[DeltaReading]) error {
+	if f.down {
+		return errors.New("sink unavailable")
+	}
+	return f.MockPublisher.PublishBatch(ctx, data)
+}
+
+func TestDeltaPublisher_FailedForwardKeepsState(t *testing.T) {
+	sink := &flakyDeltaSink{}
+	delta := NewNumericDeltaPublisher[float64](sink, 0)
+	ctx := context.Background()
+	reading := func(v float64) engine.SensorData[float64] {
+		return engine.SensorData[float64]{ID: "s", Data: v, Quality: engine.QualityOK}
+	}
+
+	if err := delta.Publish(ctx, reading(10)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink.down = true
+	batch := []engine.SensorData[float64]{reading(12), reading(15)}
+	if err := delta.PublishBatch(ctx, batch); err == nil {
+		t.Fatal("Expected the failed forward to be returned")
+	}
+	if err := delta.Publish(ctx, reading(20)); err == nil {
+		t.Fatal("Expected the failed forward to be returned")
+	}
+
+	// The retry is encoded against the last value the sink received
+	sink.down = false
+	if err := delta.PublishBatch(ctx, batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := delta.Publish(ctx, reading(14)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []float64{10, 12, 15, 14}
+	var current float64
+	for i, d := range sink.PublishedData {
+		if d.Data.Keyframe {
+			current = d.Data.Delta
+		} else {
+			current += d.Data.Delta
+		}
+		if math.Abs(current-want[i]) > 1e-9 {
+			t.Errorf("Reading %d: reconstructed %v, expected %v", i, current, want[i])
+		}
+	}
+	if len(sink.PublishedData) != len(want) {
+		t.Errorf("Expected %d readings, got %d", len(want), len(sink.PublishedData))
+	}
+}
@@ -0,0 +1,41 @@
+package publisher
+
+import "github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+
+// DestinationFunc computes where a reading is published: a Kafka topic, an
+// MQTT topic or a NATS subject, for example sensors/<device>/temp. An empty
+// result selects the publisher's default destination. Only the Kafka
+// publisher takes one, through WithTopicFunc; the other publishers in this
+// package have a single destination and no option for it.
+//
+// Publishers that honor a DestinationFunc call it for every reading and
+// split each batch into one write per destination, in order of first
+// appearance. That costs a map lookup per reading and, more significantly,
+// one backend round trip per distinct destination in the batch, so batches
+// spread across many destinations are published less efficiently. Without
+// a DestinationFunc every reading goes to the default destination and
+// batches are written whole.
+type DestinationFunc[T any] func(engine.SensorData[T]) string
+
+// ConstantDestination returns a DestinationFunc sending every reading to
+// destination
+func ConstantDestination[T any](destination string) DestinationFunc[T] {
+	return func(engine.SensorData[T]) string {
+		return destination
+	}
+}
+
+// groupByDestination splits items by destination, returning the
+// destinations in order of first appearance and the items for each
+func groupByDestination[M any](items []M, destination func(M) string) ([]string, map[string][]M) {
+	var order []string
+	groups := make(map[string][]M)
+	for _, item := range items {
+		dest := destination(item)
+		if _, ok := groups[dest]; !ok {
+			order = append(order, dest)
+		}
+		groups[dest] = append(groups[dest], item)
+	}
+	return order, groups
+}
@@ -0,0 +1,67 @@
+package publisher
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+type deviceReading struct {
+	DeviceID string  `json:"device_id"`
+	Celsius  float64 `json:"celsius"`
+}
+
+func TestGroupByDestination_KeepsFirstAppearanceOrder(t *testing.T) {
+	items := []string{"b1", "a1", "b2", "c1", "a2"}
+	order, groups := groupByDestination(items, func(item string) string { return item[:1] })
+
+	if want := []string{"b", "a", "c"}; !slices.Equal(order, want) {
+		t.Errorf("Expected destinations %v, got %v", want, order)
+	}
+	if !slices.Equal(groups["a"], []string{"a1", "a2"}) || !slices.Equal(groups["b"], []string{"b1", "b2"}) {
+		t.Errorf("Expected items grouped in order, got %v", groups)
+	}
+}
+
+func TestGenericKafkaPublisher_HierarchicalDestination(t *testing.T) {
+	destination := DestinationFunc[deviceReading](func(data engine.SensorData[deviceReading]) string {
+		return "sensors." + data.Data.DeviceID + ".temp"
+	})
+	publisher := NewGenericKafkaPublisher[deviceReading]([]string{"localhost:9092"}, "sensors", WithTopicFunc(destination))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	batch := []engine.SensorData[deviceReading]{
+		{ID: "1", Data: deviceReading{DeviceID: "dev-a", Celsius: 21}},
+		{ID: "2", Data: deviceReading{DeviceID: "dev-b", Celsius: 19}},
+		{ID: "3", Data: deviceReading{DeviceID: "dev-a", Celsius: 22}},
+	}
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.calls) != 2 {
+		t.Fatalf("Expected one write per destination, got %d", len(writer.calls))
+	}
+	if topic := writer.calls[0][0].Topic; topic != "sensors.dev-a.temp" || len(writer.calls[0]) != 2 {
+		t.Errorf("Expected 2 messages for sensors.dev-a.temp first, got %d for %s", len(writer.calls[0]), topic)
+	}
+	if topic := writer.calls[1][0].Topic; topic != "sensors.dev-b.temp" {
+		t.Errorf("Expected sensors.dev-b.temp second, got %s", topic)
+	}
+}
+
+func TestGenericKafkaPublisher_ConstantDestination(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors", WithTopicFunc(ConstantDestination[float64]("sensors-eu")))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	if err := publisher.PublishBatch(context.Background(), make([]engine.SensorData[float64], 3)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(writer.calls) != 1 || len(writer.calls[0]) != 3 || writer.calls[0][0].Topic != "sensors-eu" {
+		t.Errorf("Expected a single write of 3 messages to sensors-eu, got %v", writer.calls)
+	}
+}
@@ -0,0 +1,333 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// ElasticsearchOption configures an ElasticsearchPublisher
+type ElasticsearchOption func(*elasticsearchOptions)
+
+type elasticsearchOptions struct {
+	client    *http.Client
+	username  string
+	password  string
+	apiKey    string
+	flushSize int
+	observer  engine.PublisherObserver
+}
+
+// WithElasticsearchClient uses the given client for all requests
+func WithElasticsearchClient(client *http.Client) ElasticsearchOption {
+	return func(o *elasticsearchOptions) {
+		o.client = client
+	}
+}
+
+// WithElasticsearchBasicAuth authenticates requests with a username and
+// password
+func WithElasticsearchBasicAuth(username, password string) ElasticsearchOption {
+	return func(o *elasticsearchOptions) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithElasticsearchAPIKey authenticates requests with an encoded API key, as
+// returned by Elasticsearch's create API key endpoint. It takes precedence
+// over basic auth.
+func WithElasticsearchAPIKey(apiKey string) ElasticsearchOption {
+	return func(o *elasticsearchOptions) {
+		o.apiKey = apiKey
+	}
+}
+
+// WithElasticsearchFlushSize sets how many readings published one at a time
+// are buffered before they are sent in a single bulk request (default 100).
+// The readings of a failed flush are kept for the next one, up to ten
+// flushes' worth.
+func WithElasticsearchFlushSize(size int) ElasticsearchOption {
+	return func(o *elasticsearchOptions) {
+		o.flushSize = size
+	}
+}
+
+// WithElasticsearchObserver reports the payload size, duration and outcome
+// of every bulk request to observer
+func WithElasticsearchObserver(observer engine.PublisherObserver) ElasticsearchOption {
+	return func(o *elasticsearchOptions) {
+		o.observer = observer
+	}
+}
+
+// BulkError reports the documents of a bulk request that Elasticsearch
+// rejected while accepting the request itself
+type BulkError struct {
+	Failed int    // Documents rejected
+	Total  int    // Documents in the request
+	First  string // Reason given for the first rejected document
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d documents failed to index: %s", e.Failed, e.Total, e.First)
+}
+
+// bulkResponse is the part of a _bulk response needed to find failures
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// ElasticsearchPublisher indexes readings through the Elasticsearch _bulk
+// API. Batches are sent as they arrive; readings published one at a time
+// are buffered up to the flush size, and Close sends whatever is left.
+type ElasticsearchPublisher[T any] struct {
+	endpoint string
+	index    string
+	client   *http.Client
+	options  elasticsearchOptions
+
+	mutex   sync.Mutex
+	pending []engine.SensorData[T]
+}
+
+// NewElasticsearchPublisher creates a publisher indexing into index on the
+// cluster at baseURL. Parts of index in braces are Go time layouts filled in
+// from each reading's timestamp in UTC, so "sensors-{2006.01.02}" writes to
+// one index per day, such as sensors-2024.01.02.
+func NewElasticsearchPublisher[T any](baseURL, index string, opts ...ElasticsearchOption) *ElasticsearchPublisher[T] {
+	options := elasticsearchOptions{flushSize: 100}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	client := options.client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+
+	return &ElasticsearchPublisher[T]{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/_bulk",
+		index:    index,
+		client:   client,
+		options:  options,
+	}
+}
+
+// Publish buffers a single sensor data point, sending the buffer once it
+// reaches the flush size
+func (e *ElasticsearchPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	e.mutex.Lock()
+	e.pending = append(e.pending, data)
+	if len(e.pending) < e.options.flushSize {
+		e.mutex.Unlock()
+		return nil
+	}
+	batch := e.pending
+	e.pending = nil
+	e.mutex.Unlock()
+
+	if err := e.bulk(ctx, batch); err != nil {
+		return e.requeue(batch, err)
+	}
+	return nil
+}
+
+// elasticsearchMaxPendingFlushes bounds the readings kept for retry after
+// failed flushes, as a multiple of the flush size
+const elasticsearchMaxPendingFlushes = 10
+
+// requeue puts the readings of a failed flush back in front of the pending
+// buffer, so the next flush retries them, and returns err annotated with
+// what became of them. Readings Elasticsearch answered for, as reported by
+// a BulkError, are not retried, and the oldest readings are dropped to keep
+// the buffer within bounds.
+func (e *ElasticsearchPublisher[T]) requeue(batch []engine.SensorData[T], err error) error {
+	var bulkErr *BulkError
+	if errors.As(err, &bulkErr) {
+		return fmt.Errorf("failed to flush %d buffered readings: %w", len(batch), err)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	pending := append(batch, e.pending...)
+	limit := max(e.options.flushSize, 1) * elasticsearchMaxPendingFlushes
+	dropped := max(len(pending)-limit, 0)
+	e.pending = pending[dropped:]
+	if dropped > 0 {
+		return fmt.Errorf("failed to flush %d buffered readings, dropped the oldest %d: %w", len(batch), dropped, err)
+	}
+	return fmt.Errorf("failed to flush %d buffered readings, kept for retry: %w", len(batch), err)
+}
+
+// PublishBatch indexes a batch of sensor data points
+func (e *ElasticsearchPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	return e.bulk(ctx, data)
+}
+
+// indexFor returns the index a reading is written to
+func (e *ElasticsearchPublisher[T]) indexFor(timestamp time.Time) string {
+	if !strings.Contains(e.index, "{") {
+		return e.index
+	}
+
+	var name strings.Builder
+	rest := e.index
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			break
+		}
+		name.WriteString(rest[:open])
+		name.WriteString(timestamp.UTC().Format(rest[open+1 : open+end]))
+		rest = rest[open+end+1:]
+	}
+	name.WriteString(rest)
+	return name.String()
+}
+
+// encode builds the NDJSON body of a bulk request
+func (e *ElasticsearchPublisher[T]) encode(data []engine.SensorData[T]) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, d := range data {
+		action, err := json.Marshal(map[string]map[string]string{
+			"index": {"_index": e.indexFor(d.Timestamp)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		document, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(document)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// bulk sends data in one bulk request and reports the outcome to the
+// observer, if any
+func (e *ElasticsearchPublisher[T]) bulk(ctx context.Context, data []engine.SensorData[T]) error {
+	if len(data) == 0 {
+		return nil
+	}
+	payload, err := e.encode(data)
+	if err != nil {
+		return err
+	}
+
+	if e.options.observer == nil {
+		return e.send(ctx, payload, len(data))
+	}
+
+	start := time.Now()
+	err = e.send(ctx, payload, len(data))
+	e.options.observer.ObservePublish(len(payload), time.Since(start), err)
+	return err
+}
+
+// send POSTs a bulk payload and checks the per-document results
+func (e *ElasticsearchPublisher[T]) send(ctx context.Context, payload []byte, count int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case e.options.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.options.apiKey)
+	case e.options.username != "":
+		req.SetBasicAuth(e.options.username, e.options.password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &httpStatusError{statusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read bulk response: %w", err)
+	}
+	var result bulkResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	bulkErr := &BulkError{Total: count}
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status < 300 && outcome.Error == nil {
+				continue
+			}
+			bulkErr.Failed++
+			if bulkErr.First == "" {
+				bulkErr.First = fmt.Sprintf("status %d", outcome.Status)
+				if outcome.Error != nil {
+					bulkErr.First = fmt.Sprintf("%s: %s", outcome.Error.Type, outcome.Error.Reason)
+				}
+			}
+		}
+	}
+	if bulkErr.Failed == 0 {
+		return nil
+	}
+	return bulkErr
+}
+
+// elasticsearchCloseTimeout bounds the final flush in Close
+const elasticsearchCloseTimeout = 10 * time.Second
+
+// Close sends any buffered readings. The pending buffer is emptied first,
+// so calling Close more than once is safe.
+func (e *ElasticsearchPublisher[T]) Close() error {
+	e.mutex.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), elasticsearchCloseTimeout)
+	defer cancel()
+	if err := e.bulk(ctx, batch); err != nil {
+		return fmt.Errorf("failed to flush pending readings: %w", err)
+	}
+	if e.options.client == nil {
+		// Only release the connections of a client this publisher created
+		e.client.CloseIdleConnections()
+	}
+	return nil
+}
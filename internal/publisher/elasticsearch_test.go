@@ -0,0 +1,206 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// bulkServer records the NDJSON lines of every _bulk request and answers
+// with response, or with 503 for the first failures requests
+type bulkServer struct {
+	mutex    sync.Mutex
+	requests [][]string
+	auth     []string
+	response string
+	failures int
+}
+
+func (b *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	b.mutex.Lock()
+	b.requests = append(b.requests, lines)
+	b.auth = append(b.auth, r.Header.Get("Authorization"))
+	fail := b.failures > 0
+	if fail {
+		b.failures--
+	}
+	b.mutex.Unlock()
+
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	response := b.response
+	if response == "" {
+		response = `{"errors":false,"items":[]}`
+	}
+	w.Write([]byte(response))
+}
+
+func TestElasticsearchPublisher_BulkFormatAndDailyIndex(t *testing.T) {
+	recorder := &bulkServer{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	publisher := NewElasticsearchPublisher[float64](server.URL+"/", "sensors-{2006.01.02}", WithElasticsearchBasicAuth("elastic", "secret"))
+	batch := []engine.SensorData[float64]{
+		{ID: "sensor-0", Timestamp: time.Date(2024, 1, 2, 23, 59, 0, 0, time.UTC), Data: 21.5, Quality: engine.QualityOK},
+		{ID: "sensor-1", Timestamp: time.Date(2024, 1, 3, 0, 1, 0, 0, time.UTC), Data: 21.7, Quality: engine.QualityOK},
+	}
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+
+	if len(recorder.requests) != 1 {
+		t.Fatalf("Expected 1 bulk request, got %d", len(recorder.requests))
+	}
+	lines := recorder.requests[0]
+	want := []string{
+		`{"index":{"_index":"sensors-2024.01.02"}}`,
+		`{"id":"sensor-0","timestamp":"2024-01-02T23:59:00Z","data":21.5,"quality":"OK"}`,
+		`{"index":{"_index":"sensors-2024.01.03"}}`,
+		`{"id":"sensor-1","timestamp":"2024-01-03T00:01:00Z","data":21.7,"quality":"OK"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected bulk body:\ngot:  %v\nwant: %v", lines, want)
+	}
+	if !strings.HasPrefix(recorder.auth[0], "Basic ") {
+		t.Errorf("Expected basic auth, got %q", recorder.auth[0])
+	}
+}
+
+func TestElasticsearchPublisher_PartialFailure(t *testing.T) {
+	recorder := &bulkServer{response: `{"errors":true,"items":[
+		{"index":{"status":201}},
+		{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [data]"}}},
+		{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}
+	]}`}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	publisher := NewElasticsearchPublisher[float64](server.URL, "sensors", WithElasticsearchAPIKey("a2V5"))
+	err := publisher.PublishBatch(context.Background(), make([]engine.SensorData[float64], 3))
+
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("Expected a BulkError, got %v", err)
+	}
+	if bulkErr.Failed != 2 || bulkErr.Total != 3 {
+		t.Errorf("Expected 2 of 3 documents to fail, got %d of %d", bulkErr.Failed, bulkErr.Total)
+	}
+	if !strings.Contains(bulkErr.First, "mapper_parsing_exception") {
+		t.Errorf("Expected the first failure reason, got %q", bulkErr.First)
+	}
+	if recorder.auth[0] != "ApiKey a2V5" {
+		t.Errorf("Expected API key auth, got %q", recorder.auth[0])
+	}
+}
+
+func TestElasticsearchPublisher_BuffersSinglesUntilClose(t *testing.T) {
+	recorder := &bulkServer{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	publisher := NewElasticsearchPublisher[float64](server.URL, "sensors", WithElasticsearchFlushSize(2))
+	for i := range 3 {
+		if err := publisher.Publish(context.Background(), engine.SensorData[float64]{Data: float64(i)}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if len(recorder.requests) != 1 || len(recorder.requests[0]) != 4 {
+		t.Fatalf("Expected one flushed request of 2 documents, got %v", recorder.requests)
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(recorder.requests) != 2 || len(recorder.requests[1]) != 2 {
+		t.Fatalf("Expected Close to flush the remaining document, got %v", recorder.requests)
+	}
+	var last engine.SensorData[float64]
+	if err := json.Unmarshal([]byte(recorder.requests[1][1]), &last); err != nil || last.Data != 2 {
+		t.Errorf("Expected the last reading to be flushed, got %v (%v)", recorder.requests[1][1], err)
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+	if len(recorder.requests) != 2 {
+		t.Errorf("Expected no request from a second Close, got %d requests", len(recorder.requests))
+	}
+}
+
+func TestElasticsearchPublisher_FailedFlushIsRetried(t *testing.T) {
+	recorder := &bulkServer{failures: 1}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	publisher := NewElasticsearchPublisher[float64](server.URL, "sensors", WithElasticsearchFlushSize(2))
+	ctx := context.Background()
+	publisher.Publish(ctx, engine.SensorData[float64]{Data: 0})
+	if err := publisher.Publish(ctx, engine.SensorData[float64]{Data: 1}); err == nil {
+		t.Fatal("Expected the failed flush to be reported")
+	}
+	if err := publisher.Publish(ctx, engine.SensorData[float64]{Data: 2}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if len(recorder.requests) != 2 || len(recorder.requests[1]) != 6 {
+		t.Fatalf("Expected the retry to carry all 3 documents, got %v", recorder.requests)
+	}
+	for i, line := range []string{recorder.requests[1][1], recorder.requests[1][3], recorder.requests[1][5]} {
+		var data engine.SensorData[float64]
+		if err := json.Unmarshal([]byte(line), &data); err != nil || data.Data != float64(i) {
+			t.Errorf("Document %d: expected reading %d in order, got %v (%v)", i, i, line, err)
+		}
+	}
+}
+
+func TestElasticsearchPublisher_FailedFlushIsBounded(t *testing.T) {
+	recorder := &bulkServer{failures: 100}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	publisher := NewElasticsearchPublisher[float64](server.URL, "sensors", WithElasticsearchFlushSize(1))
+	var err error
+	for i := range elasticsearchMaxPendingFlushes + 5 {
+		err = publisher.Publish(context.Background(), engine.SensorData[float64]{Data: float64(i)})
+	}
+	if err == nil || !strings.Contains(err.Error(), "dropped the oldest 1") {
+		t.Errorf("Expected the dropped reading to be reported, got %v", err)
+	}
+	if got := len(publisher.pending); got != elasticsearchMaxPendingFlushes {
+		t.Errorf("Expected %d readings kept for retry, got %d", elasticsearchMaxPendingFlushes, got)
+	}
+}
+
+func TestElasticsearchPublisher_CloseLeavesInjectedClient(t *testing.T) {
+	transport := &idleClosingTransport{RoundTripper: http.DefaultTransport}
+	publisher := NewElasticsearchPublisher[float64]("http://localhost:9200", "sensors", WithElasticsearchClient(&http.Client{Transport: transport}))
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if transport.closed != 0 {
+		t.Errorf("Expected the injected client's connections to be left open, closed %d times", transport.closed)
+	}
+}
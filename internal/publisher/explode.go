@@ -0,0 +1,163 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// ExplodeConfig selects the fields an ExplodePublisher splits readings into
+type ExplodeConfig struct {
+	// Fields lists the JSON names of the numeric fields to split out, in
+	// order; empty splits out every numeric top-level field
+	Fields []string
+
+	// SeriesID names the record of a field; nil uses "<id>.<field>"
+	SeriesID func(id, field string) string
+}
+
+// explodeField is a numeric field of the reading type
+type explodeField struct {
+	name  string
+	index []int
+}
+
+// ExplodePublisher splits every multi-field reading into one single-value
+// record per numeric field before forwarding it, for time-series databases
+// that store one series per field. Each record keeps the reading's
+// timestamp and quality, and is tagged with the field name through its ID.
+//
+// Heartbeats and offline markers carry no values, so each is forwarded as a
+// single zero-valued record under the reading's own ID rather than as a
+// zero in every series. Readings with a nil pointer Data are dropped.
+type ExplodePublisher[T any] struct {
+	next     engine.Publisher[float64]
+	fields   []explodeField
+	seriesID func(id, field string) string
+}
+
+// NewExplodePublisher creates a splitter in front of next. T must be a
+// struct or a pointer to a struct; a field in config.Fields that does not
+// exist or is not numeric is an error.
+func NewExplodePublisher[T any](next engine.Publisher[float64], config ExplodeConfig) (*ExplodePublisher[T], error) {
+	target := reflect.TypeFor[T]()
+	if target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot explode readings of type %v: data must be a struct or a pointer to a struct", reflect.TypeFor[T]())
+	}
+
+	var numeric []explodeField
+	byName := make(map[string]explodeField)
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+		if !field.IsExported() || !isNumericKind(field.Type.Kind()) {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tagName, _, _ := strings.Cut(tag, ","); tagName == "-" {
+				continue
+			} else if tagName != "" {
+				name = tagName
+			}
+		}
+		f := explodeField{name: name, index: field.Index}
+		numeric = append(numeric, f)
+		byName[name] = f
+	}
+
+	p := &ExplodePublisher[T]{next: next, fields: numeric, seriesID: config.SeriesID}
+	if len(config.Fields) > 0 {
+		p.fields = make([]explodeField, len(config.Fields))
+		for i, name := range config.Fields {
+			f, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%v has no numeric field %s", target, name)
+			}
+			p.fields[i] = f
+		}
+	}
+	if len(p.fields) == 0 {
+		return nil, fmt.Errorf("%v has no numeric fields to explode", target)
+	}
+	if p.seriesID == nil {
+		p.seriesID = func(id, field string) string { return id + "." + field }
+	}
+	return p, nil
+}
+
+// isNumericKind reports whether k is an integer or floating-point kind
+func isNumericKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// Publish splits and forwards a single sensor data point
+func (p *ExplodePublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	records := p.explode(nil, data)
+	switch len(records) {
+	case 0:
+		return nil
+	case 1:
+		return p.next.Publish(ctx, records[0])
+	}
+	return p.next.PublishBatch(ctx, records)
+}
+
+// PublishBatch splits and forwards a batch of sensor data points
+func (p *ExplodePublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	records := make([]engine.SensorData[float64], 0, len(data)*len(p.fields))
+	for _, reading := range data {
+		records = p.explode(records, reading)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return p.next.PublishBatch(ctx, records)
+}
+
+// explode appends the records of one reading to records
+func (p *ExplodePublisher[T]) explode(records []engine.SensorData[float64], data engine.SensorData[T]) []engine.SensorData[float64] {
+	record := engine.SensorData[float64]{
+		ID:           data.ID,
+		Timestamp:    data.Timestamp,
+		Quality:      data.Quality,
+		Input:        data.Input,
+		QualityScore: data.QualityScore,
+	}
+	switch data.Quality {
+	case engine.QualityHeartbeat, engine.QualityOffline, engine.QualityOnline:
+		return append(records, record)
+	}
+
+	v := reflect.ValueOf(data.Data)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return records
+		}
+		v = v.Elem()
+	}
+	for _, f := range p.fields {
+		field := v.FieldByIndex(f.index)
+		switch {
+		case field.CanInt():
+			record.Data = float64(field.Int())
+		case field.CanUint():
+			record.Data = float64(field.Uint())
+		default:
+			record.Data = field.Float()
+		}
+		record.ID = p.seriesID(data.ID, f.name)
+		records = append(records, record)
+	}
+	return records
+}
+
+// Close closes the next publisher
+func (p *ExplodePublisher[T]) Close() error {
+	return p.next.Close()
+}
@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// weather has three numeric fields and one that is not
+type weather struct {
+	Temperature float64 `json:"temperature"`
+	Humidity    float32 `json:"humidity"`
+	Pressure    uint16  `json:"pressure"`
+	Station     string  `json:"station"`
+}
+
+func TestExplodePublisher_OneRecordPerField(t *testing.T) {
+	sink := NewRingBufferPublisher[float64](16)
+	explode, err := NewExplodePublisher[weather](sink, ExplodeConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create explode publisher: %v", err)
+	}
+
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	readings := []engine.SensorData[weather]{
+		{ID: "a", Timestamp: timestamp, Data: weather{21.5, 40, 1013, "north"}, Quality: engine.QualityOK},
+		{ID: "b", Timestamp: timestamp, Data: weather{19, 55, 1009, "south"}, Quality: engine.QualityNoisy},
+	}
+	if err := explode.PublishBatch(context.Background(), readings); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := explode.Publish(context.Background(), engine.SensorData[weather]{ID: "c", Quality: engine.QualityHeartbeat}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records := sink.Snapshot()
+	var got []string
+	for _, r := range records {
+		got = append(got, r.ID)
+	}
+	want := []string{"a.temperature", "a.humidity", "a.pressure", "b.temperature", "b.humidity", "b.pressure", "c"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Expected records %v, got %v", want, got)
+	}
+	if records[0].Data != 21.5 || records[1].Data != 40 || records[5].Data != 1009 {
+		t.Errorf("Expected the field values, got %+v", records)
+	}
+	if records[4].Quality != engine.QualityNoisy || !records[4].Timestamp.Equal(timestamp) {
+		t.Errorf("Expected records to keep the reading's quality and timestamp, got %+v", records[4])
+	}
+}
+
+func TestExplodePublisher_Fields(t *testing.T) {
+	sink := NewRingBufferPublisher[float64](16)
+	explode, err := NewExplodePublisher[*weather](sink, ExplodeConfig{
+		Fields:   []string{"pressure", "temperature"},
+		SeriesID: func(id, field string) string { return field },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create explode publisher: %v", err)
+	}
+
+	if err := explode.Publish(context.Background(), engine.SensorData[*weather]{ID: "a", Data: &weather{Temperature: 3, Pressure: 990}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := explode.Publish(context.Background(), engine.SensorData[*weather]{ID: "b"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records := sink.Snapshot()
+	if len(records) != 2 || records[0].ID != "pressure" || records[0].Data != 990 || records[1].ID != "temperature" || records[1].Data != 3 {
+		t.Errorf("Expected only the listed fields, in order, got %+v", records)
+	}
+
+	for _, fields := range [][]string{{"station"}, {"wind"}} {
+		if _, err := NewExplodePublisher[weather](sink, ExplodeConfig{Fields: fields}); err == nil {
+			t.Errorf("Expected an error for field %v", fields)
+		}
+	}
+	if _, err := NewExplodePublisher[float64](sink, ExplodeConfig{}); err == nil {
+		t.Error("Expected an error for readings without fields")
+	}
+}
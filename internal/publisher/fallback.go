@@ -0,0 +1,180 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// ReplayFunc sends what a fallback publisher stored while its primary was
+// failing to the recovered primary
+type ReplayFunc[T any] func(ctx context.Context, primary engine.Publisher[T]) error
+
+// FallbackOption configures a FallbackPublisher
+type FallbackOption[T any] func(*FallbackPublisher[T])
+
+// WithFallbackReplay calls replay after the first successful primary
+// publish following a failure, to move readings stored by the fallback back
+// to the primary. A failed replay is tried again after the next successful
+// publish, so replay should tolerate sending a reading twice.
+func WithFallbackReplay[T any](replay ReplayFunc[T]) FallbackOption[T] {
+	return func(f *FallbackPublisher[T]) {
+		f.replay = replay
+	}
+}
+
+// FallbackPublisher publishes to a primary publisher and, when that fails,
+// to a fallback publisher instead, so an outage of the primary, such as a
+// Kafka cluster, degrades to local storage rather than lost readings. A
+// publish only fails when both publishers fail. It implements
+// engine.FallbackCounter, so the engine's Stats show where readings went.
+type FallbackPublisher[T any] struct {
+	primary  engine.Publisher[T]
+	fallback engine.Publisher[T]
+	replay   ReplayFunc[T]
+
+	degraded      atomic.Bool  // The fallback stored readings that were not replayed yet
+	replayMutex   sync.RWMutex // Held for writing by a replay, for reading by fallback publishes
+	primaryCount  atomic.Uint64
+	fallbackCount atomic.Uint64
+}
+
+// NewFallbackPublisher creates a publisher that uses fallback while primary
+// fails
+func NewFallbackPublisher[T any](primary, fallback engine.Publisher[T], opts ...FallbackOption[T]) *FallbackPublisher[T] {
+	f := &FallbackPublisher[T]{primary: primary, fallback: fallback}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Publish publishes a single sensor data point
+func (f *FallbackPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	return f.publish(ctx, 1,
+		func(p engine.Publisher[T]) error { return p.Publish(ctx, data) })
+}
+
+// PublishBatch publishes a batch of sensor data points
+func (f *FallbackPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	return f.publish(ctx, len(data),
+		func(p engine.Publisher[T]) error { return p.PublishBatch(ctx, data) })
+}
+
+// PublishEnvelopes publishes a batch of readings wrapped in envelopes, for
+// engines in envelope mode. Both publishers must implement
+// engine.EnvelopePublisher.
+func (f *FallbackPublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	return f.publish(ctx, len(envelopes), func(p engine.Publisher[T]) error {
+		envelopePublisher, ok := p.(engine.EnvelopePublisher[T])
+		if !ok {
+			return fmt.Errorf("publisher %T does not support envelopes", p)
+		}
+		return envelopePublisher.PublishEnvelopes(ctx, envelopes)
+	})
+}
+
+// publish sends count readings with send to the primary, falling back to
+// the fallback publisher
+func (f *FallbackPublisher[T]) publish(ctx context.Context, count int, send func(engine.Publisher[T]) error) error {
+	primaryErr := send(f.primary)
+	if primaryErr == nil {
+		f.primaryCount.Add(uint64(count))
+		if f.replay != nil && f.degraded.Load() {
+			f.replayStored(ctx)
+		}
+		return nil
+	}
+
+	f.replayMutex.RLock()
+	defer f.replayMutex.RUnlock()
+	if err := send(f.fallback); err != nil {
+		return errors.Join(fmt.Errorf("primary: %w", primaryErr), fmt.Errorf("fallback: %w", err))
+	}
+	// Marked after storing, under the lock, so a replay either includes
+	// these readings or a later one will
+	f.degraded.Store(true)
+	f.fallbackCount.Add(uint64(count))
+	return nil
+}
+
+// replayStored runs the replay function once per recovery. Publishes skip
+// it while another one is replaying or writing to the fallback, and fallback
+// writes wait for a running replay.
+func (f *FallbackPublisher[T]) replayStored(ctx context.Context) {
+	if !f.replayMutex.TryLock() {
+		return
+	}
+	defer f.replayMutex.Unlock()
+
+	if !f.degraded.CompareAndSwap(true, false) {
+		return
+	}
+	if err := f.replay(ctx, f.primary); err != nil {
+		f.degraded.Store(true)
+	}
+}
+
+// FallbackCounts returns how many readings went to the primary and how many
+// to the fallback publisher
+func (f *FallbackPublisher[T]) FallbackCounts() (primary, fallback uint64) {
+	return f.primaryCount.Load(), f.fallbackCount.Load()
+}
+
+// Close closes both publishers
+func (f *FallbackPublisher[T]) Close() error {
+	return errors.Join(f.primary.Close(), f.fallback.Close())
+}
+
+// replayBatchSize is the number of readings ReplayNDJSONFile sends per batch
+const replayBatchSize = 100
+
+// ReplayNDJSONFile returns a replay function for a FallbackPublisher whose
+// fallback is a FilePublisher writing NDJSON to path. It publishes the
+// readings in the file to the primary in batches and then empties the file,
+// which the file publisher keeps appending to. If publishing fails part way,
+// the file is left as it is and replayed in full next time.
+func ReplayNDJSONFile[T any](path string) ReplayFunc[T] {
+	return func(ctx context.Context, primary engine.Publisher[T]) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open fallback file: %w", err)
+		}
+		defer file.Close()
+
+		batch := make([]engine.SensorData[T], 0, replayBatchSize)
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var data engine.SensorData[T]
+			if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+				return fmt.Errorf("invalid record in fallback file: %w", err)
+			}
+			if batch = append(batch, data); len(batch) == replayBatchSize {
+				if err := primary.PublishBatch(ctx, batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read fallback file: %w", err)
+		}
+		if len(batch) > 0 {
+			if err := primary.PublishBatch(ctx, batch); err != nil {
+				return err
+			}
+		}
+		return os.Truncate(path, 0)
+	}
+}
@@ -0,0 +1,109 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// switchablePublisher records readings, or fails while down is set
+type switchablePublisher struct {
+	MockPublisher[float64]
+	down bool
+}
+
+func (s *switchablePublisher) Publish(ctx context.Context, data engine.SensorData[float64]) error {
+	if s.down {
+		return errors.New("broker unavailable")
+	}
+	return s.MockPublisher.Publish(ctx, data)
+}
+
+func (s *switchablePublisher) PublishBatch(ctx context.Context, data []engine.SensorData[float64]) error {
+	if s.down {
+		return errors.New("broker unavailable")
+	}
+	return s.MockPublisher.PublishBatch(ctx, data)
+}
+
+func TestFallbackPublisher_DivertsWhilePrimaryFails(t *testing.T) {
+	primary := &switchablePublisher{}
+	fallback := NewMockPublisher[float64]()
+	publisher := NewFallbackPublisher[float64](primary, fallback)
+	ctx := context.Background()
+
+	publisher.PublishBatch(ctx, testReadings("a", "b"))
+	primary.down = true
+	if err := publisher.PublishBatch(ctx, testReadings("c", "d", "e")); err != nil {
+		t.Fatalf("Expected the fallback to absorb the failure, got %v", err)
+	}
+	primary.down = false
+	publisher.Publish(ctx, testReadings("f")[0])
+
+	if got := len(primary.PublishedData); got != 3 {
+		t.Errorf("Expected 3 readings on the primary, got %d", got)
+	}
+	if got := len(fallback.PublishedData); got != 3 {
+		t.Errorf("Expected 3 readings on the fallback, got %d", got)
+	}
+	var counter engine.FallbackCounter = publisher
+	if primaryCount, fallbackCount := counter.FallbackCounts(); primaryCount != 3 || fallbackCount != 3 {
+		t.Errorf("Expected counts 3 and 3, got %d and %d", primaryCount, fallbackCount)
+	}
+}
+
+func TestFallbackPublisher_BothFail(t *testing.T) {
+	primary := &switchablePublisher{down: true}
+	fallback := &switchablePublisher{down: true}
+	publisher := NewFallbackPublisher[float64](primary, fallback)
+
+	if err := publisher.PublishBatch(context.Background(), testReadings("a")); err == nil {
+		t.Fatal("Expected an error when both publishers fail")
+	}
+	if primaryCount, fallbackCount := publisher.FallbackCounts(); primaryCount != 0 || fallbackCount != 0 {
+		t.Errorf("Expected nothing counted, got %d and %d", primaryCount, fallbackCount)
+	}
+}
+
+func TestFallbackPublisher_ReplaysFileAfterRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fallback.ndjson")
+	file, err := NewFilePublisher[float64](path)
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	primary := &switchablePublisher{down: true}
+	publisher := NewFallbackPublisher[float64](primary, file, WithFallbackReplay(ReplayNDJSONFile[float64](path)))
+	defer publisher.Close()
+	ctx := context.Background()
+
+	publisher.PublishBatch(ctx, testReadings("a", "b"))
+	publisher.PublishBatch(ctx, testReadings("c"))
+	primary.down = false
+	if err := publisher.PublishBatch(ctx, testReadings("d")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var ids []string
+	for _, data := range primary.PublishedData {
+		ids = append(ids, data.ID)
+	}
+	if want := []string{"d", "a", "b", "c"}; !slices.Equal(ids, want) {
+		t.Errorf("Expected %v on the primary after replay, got %v", want, ids)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Errorf("Failed to stat the fallback file: %v", err)
+	} else if info.Size() != 0 {
+		t.Errorf("Expected the replayed file to be emptied, got %d bytes", info.Size())
+	}
+
+	// Nothing new was stored, so the next publish does not replay again
+	publisher.PublishBatch(ctx, testReadings("e"))
+	if got := len(primary.PublishedData); got != 5 {
+		t.Errorf("Expected 5 readings without a second replay, got %d", got)
+	}
+}
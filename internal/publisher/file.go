@@ -0,0 +1,384 @@
+package publisher
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// FileFormat selects how a FilePublisher lays out readings in the file
+type FileFormat string
+
+const (
+	// FileFormatNDJSON writes one JSON object per line, appending to an
+	// existing file. Every complete line is a valid record, so a file
+	// remains usable if the process is killed before Close; prefer it
+	// whenever crash safety matters.
+	FileFormatNDJSON FileFormat = "ndjson"
+
+	// FileFormatJSONArray writes a single well-formed JSON array, replacing
+	// any existing file. The closing bracket is written by Close, so a
+	// process killed before Close leaves an unterminated array.
+	FileFormatJSONArray FileFormat = "json_array"
+
+	// FileFormatCBOR writes a CBOR sequence (RFC 8742), one CBOR item per
+	// reading back to back, appending to an existing file like NDJSON. See
+	// CBORSerializer for the encoding.
+	FileFormatCBOR FileFormat = "cbor"
+)
+
+// FileOption configures a FilePublisher
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	format        FileFormat
+	json          JSONOptions
+	observer      engine.PublisherObserver
+	rotation      *FileRotation
+	batchEnvelope bool
+}
+
+// FileRotation splits a FilePublisher's output into a series of files.
+// Readings are always written to the configured path; on rotation the file
+// is finalized, renamed to <name>-<opened>-<n><ext>, where opened is the UTC
+// time the file was opened, and a fresh file is opened at the path. Rotation
+// happens under the publisher's lock between two records, so no reading is
+// lost or split across files, and each rotated file is complete on its own,
+// including the brackets of a JSON array.
+type FileRotation struct {
+	MaxFileBytes   int64         // Rotate before a record would grow the file past this size; 0 for no limit
+	RotateInterval time.Duration // Rotate once the file has been open this long, checked when readings are written; 0 for no limit
+	Gzip           bool          // Compress rotated files to <name>.gz and remove the uncompressed file
+}
+
+// WithFileFormat selects the file layout (default FileFormatNDJSON)
+func WithFileFormat(format FileFormat) FileOption {
+	return func(o *fileOptions) {
+		o.format = format
+	}
+}
+
+// WithFileJSONOptions sets how readings are serialized in the JSON formats.
+// Indentation is ignored for NDJSON, which requires one record per line.
+func WithFileJSONOptions(options JSONOptions) FileOption {
+	return func(o *fileOptions) {
+		o.json = options
+	}
+}
+
+// WithFileBatchEnvelope writes each batch as one record, an
+// engine.BatchEnvelope with the batch's window and count, instead of one
+// record per reading
+func WithFileBatchEnvelope() FileOption {
+	return func(o *fileOptions) {
+		o.batchEnvelope = true
+	}
+}
+
+// WithFileObserver reports the bytes written, duration and outcome of every
+// Publish and PublishBatch call to observer
+func WithFileObserver(observer engine.PublisherObserver) FileOption {
+	return func(o *fileOptions) {
+		o.observer = observer
+	}
+}
+
+// WithFileRotation rotates the output file by size, age or both. The file
+// left at the path when the publisher is closed is not rotated.
+func WithFileRotation(rotation FileRotation) FileOption {
+	return func(o *fileOptions) {
+		o.rotation = &rotation
+	}
+}
+
+// FilePublisher writes readings to a local file
+type FilePublisher[T any] struct {
+	path      string
+	flags     int
+	file      *os.File
+	writer    *bufio.Writer
+	options   fileOptions
+	records   int       // Records written to the current file, used to place array separators
+	size      int64     // Size of the current file
+	opened    time.Time // When the current file was opened
+	rotated   int       // Files rotated so far
+	mutex     sync.Mutex
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFilePublisher creates a file publisher writing to path
+func NewFilePublisher[T any](path string, opts ...FileOption) (*FilePublisher[T], error) {
+	options := fileOptions{format: FileFormatNDJSON}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch options.format {
+	case FileFormatNDJSON:
+		flags |= os.O_APPEND
+		options.json.Indent = ""
+	case FileFormatJSONArray:
+		flags |= os.O_TRUNC
+	case FileFormatCBOR:
+		flags |= os.O_APPEND
+	default:
+		return nil, fmt.Errorf("unknown file format: %s", options.format)
+	}
+
+	f := &FilePublisher[T]{
+		path:    path,
+		flags:   flags,
+		options: options,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file at the path and starts a JSON array in it
+func (f *FilePublisher[T]) open() error {
+	file, err := os.OpenFile(f.path, f.flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	f.file = file
+	f.writer = bufio.NewWriter(file)
+	f.records = 0
+	f.size = info.Size()
+	f.opened = time.Now()
+	if f.options.format == FileFormatJSONArray {
+		if err := f.flush("["); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// finish flushes the current file, terminating a JSON array, and closes it
+func (f *FilePublisher[T]) finish() error {
+	end := ""
+	if f.options.format == FileFormatJSONArray {
+		end = "]\n"
+		if f.records > 0 {
+			end = "\n]\n"
+		}
+	}
+	err := f.flush(end)
+	if closeErr := f.file.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	return err
+}
+
+// rotationDue reports whether the current file should be rotated before a
+// record of n bytes is written to it. A file is never rotated empty.
+func (f *FilePublisher[T]) rotationDue(n int) bool {
+	rotation := f.options.rotation
+	if rotation == nil || f.records == 0 {
+		return false
+	}
+	return (rotation.MaxFileBytes > 0 && f.size+int64(n) > rotation.MaxFileBytes) ||
+		(rotation.RotateInterval > 0 && time.Since(f.opened) >= rotation.RotateInterval)
+}
+
+// rotate finalizes the current file, moves it aside and opens a new one at
+// the path. The mutex must be held.
+func (f *FilePublisher[T]) rotate() error {
+	if err := f.finish(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(f.path)
+	stem := strings.TrimSuffix(f.path, ext) + "-" + f.opened.UTC().Format("20060102T150405")
+	var rotatedPath string
+	for {
+		f.rotated++
+		rotatedPath = fmt.Sprintf("%s-%03d%s", stem, f.rotated, ext)
+		if _, err := os.Stat(rotatedPath); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if err := os.Rename(f.path, rotatedPath); err != nil {
+		return fmt.Errorf("failed to rotate output file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if f.options.rotation.Gzip {
+		if err := gzipFile(rotatedPath); err != nil {
+			return fmt.Errorf("failed to compress rotated file: %w", err)
+		}
+	}
+	return nil
+}
+
+// gzipFile compresses path to path.gz and removes path
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(out)
+	if _, err := io.Copy(writer, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Publish writes a single sensor data point
+func (f *FilePublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	return f.PublishBatch(ctx, []engine.SensorData[T]{data})
+}
+
+// PublishBatch writes a batch of sensor data points and flushes them to the
+// file
+func (f *FilePublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	start := time.Now()
+	var written int
+	var err error
+	if f.options.batchEnvelope {
+		written, err = writeRecords(f, []engine.BatchEnvelope[T]{engine.NewBatchEnvelope(ctx, data)})
+	} else {
+		written, err = writeRecords(f, data)
+	}
+	if f.options.observer != nil {
+		f.options.observer.ObservePublish(written, time.Since(start), err)
+	}
+	return err
+}
+
+// PublishEnvelopes writes a batch of readings wrapped in envelopes, for
+// engines in envelope mode, and flushes them to the file
+func (f *FilePublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	start := time.Now()
+	var written int
+	var err error
+	if f.options.batchEnvelope {
+		written, err = writeRecords(f, []envelopeBatch[T]{newEnvelopeBatch(ctx, envelopes)})
+	} else {
+		written, err = writeRecords(f, envelopes)
+	}
+	if f.options.observer != nil {
+		f.options.observer.ObservePublish(written, time.Since(start), err)
+	}
+	return err
+}
+
+// writeRecords serializes and flushes records, returning the number of bytes
+// written. Records are all serialized before any is written, so a batch that
+// fails to serialize leaves nothing behind in the file.
+func writeRecords[T, R any](f *FilePublisher[T], records []R) (int, error) {
+	marshaled := make([][]byte, len(records))
+	for i, d := range records {
+		record, err := f.marshal(d)
+		if err != nil {
+			return 0, err
+		}
+		marshaled[i] = record
+	}
+
+	written := 0
+	for _, record := range marshaled {
+		// Allow for the separator or newline written with the record
+		if f.rotationDue(len(record) + 2) {
+			if err := f.rotate(); err != nil {
+				return written, err
+			}
+		}
+
+		start := written
+		if f.options.format == FileFormatJSONArray {
+			separator := ",\n"
+			if f.records == 0 {
+				separator = "\n"
+			}
+			n, _ := f.writer.WriteString(separator)
+			written += n
+		}
+		n, _ := f.writer.Write(record)
+		written += n
+		if f.options.format == FileFormatNDJSON {
+			n, _ = f.writer.WriteString("\n")
+			written += n
+		}
+		f.size += int64(written - start)
+		f.records++
+	}
+
+	if err := f.writer.Flush(); err != nil {
+		return written, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return written, nil
+}
+
+// marshal serializes a record in the file format
+func (f *FilePublisher[T]) marshal(v any) ([]byte, error) {
+	if f.options.format == FileFormatCBOR {
+		return cborEncMode.Marshal(v)
+	}
+	return f.options.json.Marshal(v)
+}
+
+// flush writes s and flushes the buffer
+func (f *FilePublisher[T]) flush(s string) error {
+	n, _ := f.writer.WriteString(s)
+	f.size += int64(n)
+	if err := f.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// Close finalizes and closes the file. In JSONArray mode it terminates the
+// array. It is safe to call more than once; later calls return the result of
+// the first.
+func (f *FilePublisher[T]) Close() error {
+	f.closeOnce.Do(func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		f.closeErr = f.finish()
+	})
+	return f.closeErr
+}
@@ -0,0 +1,319 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func testReadings(ids ...string) []engine.SensorData[float64] {
+	readings := make([]engine.SensorData[float64], len(ids))
+	for i, id := range ids {
+		readings[i] = engine.SensorData[float64]{ID: id, Timestamp: time.Now(), Data: float64(i), Quality: engine.QualityOK}
+	}
+	return readings
+}
+
+func TestFilePublisher_NDJSONAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+
+	for _, ids := range [][]string{{"a", "b"}, {"c"}} {
+		publisher, err := NewFilePublisher[float64](path)
+		if err != nil {
+			t.Fatalf("NewFilePublisher failed: %v", err)
+		}
+		if err := publisher.PublishBatch(context.Background(), testReadings(ids...)); err != nil {
+			t.Fatalf("PublishBatch failed: %v", err)
+		}
+		if err := publisher.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var d engine.SensorData[float64]
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, d.ID)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[2] != "c" {
+		t.Errorf("Expected records a, b, c across both runs, got %v", ids)
+	}
+}
+
+func TestFilePublisher_JSONArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+
+	publisher, err := NewFilePublisher[float64](path, WithFileFormat(FileFormatJSONArray))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	if err := publisher.PublishBatch(context.Background(), testReadings("a", "b")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), testReadings("c")[0]); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Second Close should be a no-op, got %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readings []engine.SensorData[float64]
+	if err := json.Unmarshal(content, &readings); err != nil {
+		t.Fatalf("File is not a valid JSON array: %v\n%s", err, content)
+	}
+	if len(readings) != 3 {
+		t.Errorf("Expected 3 readings, got %d", len(readings))
+	}
+}
+
+func TestFilePublisher_MarshalErrorWritesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+
+	publisher, err := NewFilePublisher[float64](path, WithFileFormat(FileFormatJSONArray))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	// JSON has no NaN, so the second reading fails to serialize
+	batch := testReadings("a", "b")
+	batch[1].Data = math.NaN()
+	if err := publisher.PublishBatch(context.Background(), batch); err == nil {
+		t.Fatal("Expected an error for a reading that cannot be serialized")
+	}
+	if err := publisher.PublishBatch(context.Background(), testReadings("c")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readings []engine.SensorData[float64]
+	if err := json.Unmarshal(content, &readings); err != nil {
+		t.Fatalf("File is not a valid JSON array: %v\n%s", err, content)
+	}
+	if len(readings) != 1 || readings[0].ID != "c" {
+		t.Errorf("Expected only the reading of the later batch, got %+v", readings)
+	}
+}
+
+func TestFilePublisher_EmptyJSONArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+
+	publisher, err := NewFilePublisher[float64](path, WithFileFormat(FileFormatJSONArray))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	var readings []engine.SensorData[float64]
+	if err := json.Unmarshal(content, &readings); err != nil || len(readings) != 0 {
+		t.Errorf("Expected an empty JSON array, got %q (err %v)", content, err)
+	}
+}
+
+func TestFilePublisher_JSONOptionsAndObserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	metrics := &engine.PublishMetrics{}
+
+	publisher, err := NewFilePublisher[string](path,
+		WithFileJSONOptions(JSONOptions{DisableHTMLEscape: true, Indent: "  "}),
+		WithFileObserver(metrics),
+	)
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	data := engine.SensorData[string]{ID: "html", Data: "<b>", Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	publisher.Close()
+
+	content, _ := os.ReadFile(path)
+	if bytes.Count(content, []byte("\n")) != 1 {
+		t.Errorf("NDJSON must stay one record per line even with Indent, got %q", content)
+	}
+	if !bytes.Contains(content, []byte(`"data":"<b>"`)) {
+		t.Errorf("Expected unescaped HTML, got %s", content)
+	}
+	if snapshot := metrics.Snapshot(); snapshot.Bytes != uint64(len(content)) || snapshot.Operations != 1 {
+		t.Errorf("Expected one observed write of %d bytes, got %+v", len(content), snapshot)
+	}
+}
+
+func TestFilePublisher_UnknownFormat(t *testing.T) {
+	if _, err := NewFilePublisher[float64](filepath.Join(t.TempDir(), "x"), WithFileFormat("xml")); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestFilePublisher_PublishEnvelopes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	publisher, err := NewFilePublisher[float64](path)
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+
+	envelopes := []engine.Envelope[float64]{
+		{SensorData: testReadings("a")[0], Seq: 1, Version: "2"},
+		{SensorData: testReadings("b")[0], Seq: 2, Version: "2"},
+	}
+	if err := publisher.PublishEnvelopes(context.Background(), envelopes); err != nil {
+		t.Fatalf("PublishEnvelopes failed: %v", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var env engine.Envelope[float64]
+	if err := json.Unmarshal(lines[1], &env); err != nil {
+		t.Fatalf("Invalid envelope line %q: %v", lines[1], err)
+	}
+	if env.ID != "b" || env.Seq != 2 || env.Version != "2" {
+		t.Errorf("Unexpected envelope %+v", env)
+	}
+}
+
+func TestFilePublisher_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	publisher, err := NewFilePublisher[float64](path, WithFileFormat(FileFormatJSONArray),
+		WithFileRotation(FileRotation{MaxFileBytes: 250}))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	ids := numberedIDs(10)
+	publisher.PublishBatch(context.Background(), testReadings(ids[:6]...))
+	publisher.PublishBatch(context.Background(), testReadings(ids[6:]...))
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "out-*.json"))
+	if len(files) < 2 {
+		t.Fatalf("Expected several rotated files, got %v", files)
+	}
+	// Every file is a complete array within the limit, and together they
+	// hold every reading once, in order
+	var got []string
+	for _, file := range append(files, path) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if len(content) > 250 {
+			t.Errorf("%s has %d bytes, over the 250 byte limit", file, len(content))
+		}
+		var readings []engine.SensorData[float64]
+		if err := json.Unmarshal(content, &readings); err != nil {
+			t.Fatalf("%s is not a valid JSON array: %v", file, err)
+		}
+		for _, r := range readings {
+			got = append(got, r.ID)
+		}
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("Expected readings %v across the files, got %v", ids, got)
+	}
+}
+
+func TestFilePublisher_RotatesByIntervalWithGzip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.ndjson")
+	publisher, err := NewFilePublisher[float64](path,
+		WithFileRotation(FileRotation{RotateInterval: time.Nanosecond, Gzip: true}))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	publisher.PublishBatch(context.Background(), testReadings("a", "b", "c"))
+	publisher.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "out-*.ndjson.gz"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 compressed rotated files, got %v", files)
+	}
+	if uncompressed, _ := filepath.Glob(filepath.Join(dir, "out-*.ndjson")); len(uncompressed) != 0 {
+		t.Errorf("Expected rotated files to be removed after compression, got %v", uncompressed)
+	}
+
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", files[0], err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Invalid gzip file: %v", err)
+	}
+	var data engine.SensorData[float64]
+	if err := json.NewDecoder(reader).Decode(&data); err != nil || data.ID != "a" {
+		t.Errorf("Expected reading a in the first rotated file, got %q (%v)", data.ID, err)
+	}
+}
+
+func TestFilePublisher_BatchEnvelope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	publisher, err := NewFilePublisher[float64](path, WithFileBatchEnvelope())
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	readings := testReadings("a", "b", "c")
+	readings[0].Timestamp, readings[2].Timestamp = readings[2].Timestamp.Add(time.Second), readings[0].Timestamp
+	publisher.PublishBatch(context.Background(), readings)
+	publisher.Close()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var envelope engine.BatchEnvelope[float64]
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		t.Fatalf("Expected a single batch envelope line: %v", err)
+	}
+	if envelope.Count != 3 || len(envelope.Items) != 3 {
+		t.Errorf("Expected 3 items, got count=%d items=%d", envelope.Count, len(envelope.Items))
+	}
+	if !envelope.WindowStart.Equal(readings[2].Timestamp) || !envelope.WindowEnd.Equal(readings[0].Timestamp) {
+		t.Errorf("Expected window [%v, %v], got [%v, %v]", readings[2].Timestamp, readings[0].Timestamp, envelope.WindowStart, envelope.WindowEnd)
+	}
+}
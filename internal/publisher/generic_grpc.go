@@ -1,13 +1,19 @@
 package publisher
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
 )
 
 // SensorDataService defines the gRPC service interface
@@ -17,22 +23,133 @@ type SensorDataServiceClient interface {
 	Close() error
 }
 
-// GenericGRPCPublisher is a generic gRPC publisher
+// GRPCOption configures a GenericGRPCPublisher
+type GRPCOption func(*grpcOptions)
+
+type grpcOptions struct {
+	backoff      engine.BackoffStrategy
+	client       GRPCClientFactory
+	waitForReady bool
+	reconnect    *backoff.Config
+}
+
+// WithGRPCBackoff retries failed calls as long as strategy allows
+func WithGRPCBackoff(strategy engine.BackoffStrategy) GRPCOption {
+	return func(o *grpcOptions) {
+		o.backoff = strategy
+	}
+}
+
+// GRPCClientFactory builds the client a GenericGRPCPublisher sends through
+// on its connection. The call options must be passed to every call.
+type GRPCClientFactory func(conn *grpc.ClientConn, opts ...grpc.CallOption) SensorDataServiceClient
+
+// WithGRPCClient replaces the placeholder client, which only prints what it
+// would send, with a real one such as NewRawGRPCClient or a generated
+// client adapted to SensorDataServiceClient
+func WithGRPCClient(factory GRPCClientFactory) GRPCOption {
+	return func(o *grpcOptions) {
+		o.client = factory
+	}
+}
+
+// WithWaitForReady makes calls wait, up to their context's deadline, for
+// the connection to become ready instead of failing at once while the
+// server is unreachable, so batches published during a server restart are
+// delivered once it is back rather than dropped
+func WithWaitForReady() GRPCOption {
+	return func(o *grpcOptions) {
+		o.waitForReady = true
+	}
+}
+
+// WithGRPCReconnectBackoff sets the delays between attempts to reconnect
+// to the server after the connection is lost, growing from base to max.
+// The default follows gRPC's, from 1s up to 2 minutes.
+func WithGRPCReconnectBackoff(base, max time.Duration) GRPCOption {
+	return func(o *grpcOptions) {
+		config := backoff.DefaultConfig
+		config.BaseDelay = base
+		config.MaxDelay = max
+		o.reconnect = &config
+	}
+}
+
+// GenericGRPCPublisher is a generic gRPC publisher. It watches its channel
+// and reconnects whenever the connection is lost, so a server restart only
+// fails the calls made while the server is down, or delays them with
+// WithWaitForReady.
 type GenericGRPCPublisher[T any] struct {
-	client SensorDataServiceClient
+	conn      *grpc.ClientConn
+	client    SensorDataServiceClient
+	options   grpcOptions
+	stop      context.CancelFunc // Stops the connection monitor
+	closeOnce sync.Once
 }
 
 // NewGenericGRPCPublisher creates a new generic gRPC publisher
-func NewGenericGRPCPublisher[T any](address string) (*GenericGRPCPublisher[T], error) {
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewGenericGRPCPublisher[T any](address string, opts ...GRPCOption) (*GenericGRPCPublisher[T], error) {
+	var options grpcOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if options.reconnect != nil {
+		dialOptions = append(dialOptions, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           *options.reconnect,
+			MinConnectTimeout: 20 * time.Second,
+		}))
+	}
+	conn, err := grpc.Dial(address, dialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to gRPC server: %w", err)
 	}
 
-	client := &GRPCClient{conn: conn}
-	return &GenericGRPCPublisher[T]{
-		client: client,
-	}, nil
+	var client SensorDataServiceClient = &GRPCClient{conn: conn}
+	if options.client != nil {
+		var callOptions []grpc.CallOption
+		if options.waitForReady {
+			callOptions = append(callOptions, grpc.WaitForReady(true))
+		}
+		client = options.client(conn, callOptions...)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	g := &GenericGRPCPublisher[T]{
+		conn:    conn,
+		client:  client,
+		options: options,
+		stop:    stop,
+	}
+	go g.monitor(ctx)
+	return g, nil
+}
+
+// State returns the state of the connection to the server: READY while
+// connected, CONNECTING or TRANSIENT_FAILURE while (re)connecting, and
+// SHUTDOWN once the publisher is closed
+func (g *GenericGRPCPublisher[T]) State() connectivity.State {
+	return g.conn.GetState()
+}
+
+// monitor keeps the channel connected until ctx is done. gRPC retries a
+// failed connection with backoff by itself, but lets it go idle once
+// connected; reconnecting at once means a restarted server is detected
+// before the next publish rather than by it.
+func (g *GenericGRPCPublisher[T]) monitor(ctx context.Context) {
+	for {
+		state := g.conn.GetState()
+		switch state {
+		case connectivity.Shutdown:
+			return
+		case connectivity.Idle:
+			g.conn.Connect()
+		}
+		if !g.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
 }
 
 // Publish publishes a single sensor data point
@@ -41,7 +158,9 @@ func (g *GenericGRPCPublisher[T]) Publish(ctx context.Context, data engine.Senso
 	if err != nil {
 		return err
 	}
-	return g.client.SendSensorData(ctx, payload)
+	return engine.Retry(ctx, g.options.backoff, nil, func() error {
+		return g.client.SendSensorData(ctx, payload)
+	})
 }
 
 // PublishBatch publishes a batch of sensor data points
@@ -54,12 +173,20 @@ func (g *GenericGRPCPublisher[T]) PublishBatch(ctx context.Context, data []engin
 		}
 		payloads[i] = payload
 	}
-	return g.client.SendSensorDataBatch(ctx, payloads)
+	return engine.Retry(ctx, g.options.backoff, nil, func() error {
+		return g.client.SendSensorDataBatch(ctx, payloads)
+	})
 }
 
-// Close closes the gRPC publisher
+// Close closes the gRPC publisher. It is safe to call more than once; only
+// the first call closes the client and reports its error.
 func (g *GenericGRPCPublisher[T]) Close() error {
-	return g.client.Close()
+	var err error
+	g.closeOnce.Do(func() {
+		g.stop()
+		err = g.client.Close()
+	})
+	return err
 }
 
 // GRPCClient is a simple gRPC client implementation
@@ -90,3 +217,75 @@ func (c *GRPCClient) Close() error {
 	}
 	return nil
 }
+
+// rawCodec passes byte slices through gRPC unchanged, for services whose
+// messages are the JSON payloads themselves
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	}
+	return nil, fmt.Errorf("raw codec cannot marshal %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "raw" }
+
+// RawGRPCCodec returns the codec NewRawGRPCClient sends with, for servers
+// to pass to grpc.ForceServerCodec
+func RawGRPCCodec() encoding.Codec {
+	return rawCodec{}
+}
+
+// RawGRPCClient calls the unary methods SendSensorData and
+// SendSensorDataBatch of a service without generated code: requests are the
+// JSON readings as raw bytes, a JSON array for batches, and responses are
+// ignored. Servers read them with RawGRPCCodec.
+type RawGRPCClient struct {
+	conn    *grpc.ClientConn
+	service string
+	options []grpc.CallOption
+}
+
+// NewRawGRPCClient returns a GRPCClientFactory for the given fully
+// qualified service name, such as "sensors.SensorDataService"
+func NewRawGRPCClient(service string) GRPCClientFactory {
+	return func(conn *grpc.ClientConn, opts ...grpc.CallOption) SensorDataServiceClient {
+		return &RawGRPCClient{
+			conn:    conn,
+			service: service,
+			options: append(opts, grpc.ForceCodec(rawCodec{})),
+		}
+	}
+}
+
+// SendSensorData sends a single sensor data point
+func (c *RawGRPCClient) SendSensorData(ctx context.Context, data []byte) error {
+	var reply []byte
+	return c.conn.Invoke(ctx, "/"+c.service+"/SendSensorData", data, &reply, c.options...)
+}
+
+// SendSensorDataBatch sends a batch of sensor data points as a JSON array
+func (c *RawGRPCClient) SendSensorDataBatch(ctx context.Context, data [][]byte) error {
+	batch := append([]byte("["), bytes.Join(data, []byte(","))...)
+	batch = append(batch, ']')
+	var reply []byte
+	return c.conn.Invoke(ctx, "/"+c.service+"/SendSensorDataBatch", batch, &reply, c.options...)
+}
+
+// Close closes the gRPC connection
+func (c *RawGRPCClient) Close() error {
+	return c.conn.Close()
+}
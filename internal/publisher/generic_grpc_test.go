@@ -0,0 +1,173 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// rawSensorServer is an in-process gRPC server for NewRawGRPCClient that
+// can be stopped and restarted on the same address
+type rawSensorServer struct {
+	t       *testing.T
+	address string
+	server  *grpc.Server
+
+	mutex    sync.Mutex
+	readings []string // IDs received, in order
+}
+
+func newRawSensorServer(t *testing.T) *rawSensorServer {
+	s := &rawSensorServer{t: t, address: "127.0.0.1:0"}
+	s.start()
+	t.Cleanup(s.stop)
+	return s
+}
+
+func (s *rawSensorServer) start() {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		s.t.Fatalf("Failed to listen on %s: %v", s.address, err)
+	}
+	s.address = listener.Addr().String()
+
+	handler := func(batch bool) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+		return func(srv any, ctx context.Context, decode func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			var payload []byte
+			if err := decode(&payload); err != nil {
+				return nil, err
+			}
+			var readings []engine.SensorData[float64]
+			if !batch {
+				payload = append(append([]byte("["), payload...), ']')
+			}
+			if err := json.Unmarshal(payload, &readings); err != nil {
+				return nil, err
+			}
+			s.mutex.Lock()
+			for _, r := range readings {
+				s.readings = append(s.readings, r.ID)
+			}
+			s.mutex.Unlock()
+			return []byte{}, nil
+		}
+	}
+	s.server = grpc.NewServer(grpc.ForceServerCodec(RawGRPCCodec()))
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "sensors.SensorDataService",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "SendSensorData", Handler: handler(false)},
+			{MethodName: "SendSensorDataBatch", Handler: handler(true)},
+		},
+	}, struct{}{})
+	go s.server.Serve(listener)
+}
+
+func (s *rawSensorServer) stop() {
+	s.server.Stop()
+}
+
+func (s *rawSensorServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.readings...)
+}
+
+// waitForState waits for the publisher's connection to reach a state
+// accepted by want
+func waitForState(t *testing.T, publisher *GenericGRPCPublisher[float64], want func(connectivity.State) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state := publisher.State()
+		if want(state) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Connection stuck in state %s", state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGenericGRPCPublisher_ReconnectsAfterServerRestart(t *testing.T) {
+	server := newRawSensorServer(t)
+	publisher, err := NewGenericGRPCPublisher[float64](server.address,
+		WithGRPCClient(NewRawGRPCClient("sensors.SensorDataService")),
+		WithWaitForReady(),
+		WithGRPCReconnectBackoff(10*time.Millisecond, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create gRPC publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := publisher.Publish(ctx, testReadings("a")[0]); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	waitForState(t, publisher, func(s connectivity.State) bool { return s == connectivity.Ready })
+
+	server.stop()
+	waitForState(t, publisher, func(s connectivity.State) bool { return s != connectivity.Ready })
+
+	// A batch published while the server is down waits for it to return
+	published := make(chan error, 1)
+	go func() {
+		published <- publisher.PublishBatch(ctx, testReadings("b", "c"))
+	}()
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-published:
+		t.Fatalf("Expected the batch to wait for the server, got %v", err)
+	default:
+	}
+
+	server.start()
+	if err := <-published; err != nil {
+		t.Fatalf("Expected the batch delivered after the restart, got %v", err)
+	}
+	waitForState(t, publisher, func(s connectivity.State) bool { return s == connectivity.Ready })
+
+	if got := server.received(); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Expected readings a, b and c, got %v", got)
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if state := publisher.State(); state != connectivity.Shutdown {
+		t.Errorf("Expected SHUTDOWN after Close, got %s", state)
+	}
+}
+
+func TestGenericGRPCPublisher_FailsFastWithoutWaitForReady(t *testing.T) {
+	server := newRawSensorServer(t)
+	publisher, err := NewGenericGRPCPublisher[float64](server.address,
+		WithGRPCClient(NewRawGRPCClient("sensors.SensorDataService")))
+	if err != nil {
+		t.Fatalf("Failed to create gRPC publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	server.stop()
+	waitForState(t, publisher, func(s connectivity.State) bool { return s == connectivity.TransientFailure })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := publisher.Publish(ctx, testReadings("a")[0]); err == nil {
+		t.Error("Expected an error while the server is down")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the publish to fail at once, took %v", elapsed)
+	}
+}
@@ -3,70 +3,383 @@ package publisher
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
 )
 
+// HTTPOption configures a GenericHTTPPublisher
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	client         *http.Client
+	transport      *HTTPTransportConfig
+	connectTimeout time.Duration
+	requestTimeout time.Duration
+	batchEnvelope  bool
+	backoff        engine.BackoffStrategy
+	observer       engine.PublisherObserver
+	json           JSONOptions
+	cbor           bool
+	template       *template.Template
+	metadata       map[string]string
+}
+
+// HTTPTransportConfig tunes connection pooling for the publisher's HTTP client.
+// Zero values fall back to the net/http defaults.
+type HTTPTransportConfig struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept per host (net/http default: 2)
+	MaxConnsPerHost     int           // Total connections per host, 0 for unlimited
+	IdleConnTimeout     time.Duration // How long an idle connection is kept
+	DisableKeepAlives   bool          // Open a new connection for every request
+}
+
+// HighThroughputTransport returns transport settings for publishing from
+// many workers to a single endpoint. The net/http default of 2 idle
+// connections per host forces most concurrent workers to open a new
+// connection per request; keeping one idle connection per worker avoids this.
+func HighThroughputTransport() HTTPTransportConfig {
+	return HTTPTransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// WithHTTPClient uses the given client for all requests. It takes precedence
+// over WithTransport.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(o *httpOptions) {
+		o.client = client
+	}
+}
+
+// WithTransport configures connection pooling for the default client
+func WithTransport(config HTTPTransportConfig) HTTPOption {
+	return func(o *httpOptions) {
+		o.transport = &config
+	}
+}
+
+// WithConnectTimeout bounds how long the default client waits to establish
+// a connection, so an unreachable host fails fast even when
+// WithRequestTimeout allows slow uploads of large batches. It has no effect
+// with WithHTTPClient.
+func WithConnectTimeout(d time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		o.connectTimeout = d
+	}
+}
+
+// WithRequestTimeout bounds each request, from connecting to reading the
+// response, to d in place of the default client's overall 5s timeout.
+// Every retry gets its own d. With WithHTTPClient it applies on top of the
+// client's own timeout.
+func WithRequestTimeout(d time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		o.requestTimeout = d
+	}
+}
+
+// WithBatchEnvelope wraps batch payloads in an engine.BatchEnvelope instead of
+// sending a bare JSON array
+func WithBatchEnvelope() HTTPOption {
+	return func(o *httpOptions) {
+		o.batchEnvelope = true
+	}
+}
+
+// WithRetry retries failed requests up to maxRetries times using exponential
+// backoff with full jitter: before retry n the publisher sleeps a random
+// duration between 0 and min(maxDelay, baseDelay*2^n). Retries stop early
+// when the next delay would exceed the context deadline. Network errors,
+// 429 and 5xx responses are retried; other 4xx responses are not.
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) HTTPOption {
+	return WithBackoff(engine.FullJitterBackoff{Base: baseDelay, Max: maxDelay, MaxRetries: maxRetries})
+}
+
+// WithBackoff retries failed requests as long as strategy allows, with the
+// same rules as WithRetry for which failures are retried
+func WithBackoff(strategy engine.BackoffStrategy) HTTPOption {
+	return func(o *httpOptions) {
+		o.backoff = strategy
+	}
+}
+
+// WithObserver reports the payload size, duration and outcome of every
+// Publish and PublishBatch call to observer. Duration includes retries.
+func WithObserver(observer engine.PublisherObserver) HTTPOption {
+	return func(o *httpOptions) {
+		o.observer = observer
+	}
+}
+
+// WithJSONOptions sets how readings are serialized
+func WithJSONOptions(options JSONOptions) HTTPOption {
+	return func(o *httpOptions) {
+		o.json = options
+	}
+}
+
+// WithCBOR sends payloads as CBOR with Content-Type application/cbor
+// instead of JSON; see CBORSerializer. It does not apply to payload
+// templates, which always render JSON.
+func WithCBOR() HTTPOption {
+	return func(o *httpOptions) {
+		o.cbor = true
+	}
+}
+
+// httpStatusError reports a non-2xx HTTP response
+type httpStatusError struct {
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status: %d", e.statusCode)
+}
+
+// retryable reports whether a failed request is worth retrying
+func retryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= 500
+	}
+	return true
+}
+
 // GenericHTTPPublisher is a generic HTTP publisher
 type GenericHTTPPublisher[T any] struct {
-	endpoint string
-	client   *http.Client
+	endpoint   string
+	client     *http.Client
+	options    httpOptions
+	serializer Serializer[T] // From NewGenericHTTPPublisherWithSerializer, if set
+	closeOnce  sync.Once
 }
 
 // NewGenericHTTPPublisher creates a new generic HTTP publisher
-func NewGenericHTTPPublisher[T any](endpoint string) *GenericHTTPPublisher[T] {
+func NewGenericHTTPPublisher[T any](endpoint string, opts ...HTTPOption) *GenericHTTPPublisher[T] {
+	var options httpOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	client := options.client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 5 * time.Second,
+		}
+		if options.requestTimeout > 0 {
+			// Bounded per request in send instead
+			client.Timeout = 0
+		}
+		if options.transport != nil || options.connectTimeout > 0 {
+			var config HTTPTransportConfig
+			if options.transport != nil {
+				config = *options.transport
+			}
+			client.Transport = newTransport(config, options.connectTimeout)
+		}
+	}
+
 	return &GenericHTTPPublisher[T]{
 		endpoint: endpoint,
-		client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
+		client:   client,
+		options:  options,
 	}
 }
 
+// NewGenericHTTPPublisherWithSerializer creates a generic HTTP publisher
+// that sends readings and batches encoded by serializer, with its content
+// type, instead of JSON; use it with ProtoSerializer for protobuf.
+// Envelopes, including batch envelopes from WithBatchEnvelope, cannot be
+// encoded this way and fail to publish, and payload templates still render
+// JSON.
+func NewGenericHTTPPublisherWithSerializer[T any](endpoint string, serializer Serializer[T], opts ...HTTPOption) *GenericHTTPPublisher[T] {
+	h := NewGenericHTTPPublisher[T](endpoint, opts...)
+	h.serializer = serializer
+	return h
+}
+
+// newTransport builds an http.Transport from the default transport
+// settings, dialing with connectTimeout when it is positive
+func newTransport(config HTTPTransportConfig, connectTimeout time.Duration) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if connectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = config.DisableKeepAlives
+	return transport
+}
+
 // Publish publishes a single sensor data point
 func (h *GenericHTTPPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
-	payload, err := json.Marshal(data)
+	if h.options.template != nil {
+		return h.publishTemplated(ctx, []engine.SensorData[T]{data}, 1)
+	}
+
+	payload, err := h.marshal(data)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewBuffer(payload))
+	return h.post(ctx, payload)
+}
+
+// PublishBatch publishes a batch of sensor data points
+func (h *GenericHTTPPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	if h.options.template != nil {
+		return h.publishTemplated(ctx, data, len(data))
+	}
+
+	var body any = data
+	if h.options.batchEnvelope {
+		body = engine.NewBatchEnvelope(ctx, data)
+	}
+
+	payload, err := h.marshal(body)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	return h.post(ctx, payload)
+}
 
-	resp, err := h.client.Do(req)
+// PublishEnvelopes publishes a batch of readings wrapped in envelopes, for
+// engines in envelope mode. With WithBatchEnvelope the envelopes become the
+// batch envelope's items.
+func (h *GenericHTTPPublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	if h.options.template != nil {
+		return h.publishTemplated(ctx, envelopes, len(envelopes))
+	}
+
+	var body any = envelopes
+	if h.options.batchEnvelope {
+		body = newEnvelopeBatch(ctx, envelopes)
+	}
+
+	payload, err := h.marshal(body)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
+	return h.post(ctx, payload)
+}
+
+// envelopeBatch is a batch envelope around envelopes instead of readings
+type envelopeBatch[T any] struct {
+	engine.BatchEnvelope[T]
+	Items []engine.Envelope[T] `json:"items"` // Replaces the batch envelope's items
+}
+
+// newEnvelopeBatch wraps envelopes in a batch envelope, taking the window
+// from ctx or else from the envelopes' readings
+func newEnvelopeBatch[T any](ctx context.Context, envelopes []engine.Envelope[T]) envelopeBatch[T] {
+	if _, ok := engine.BatchWindowFromContext(ctx); !ok {
+		readings := make([]engine.SensorData[T], len(envelopes))
+		for i, env := range envelopes {
+			readings[i] = env.SensorData
+		}
+		ctx = engine.WithBatchWindow(ctx, engine.BatchWindowOf(readings))
 	}
+	batch := engine.NewBatchEnvelope[T](ctx, nil)
+	batch.Count = len(envelopes)
+	return envelopeBatch[T]{batch, envelopes}
+}
 
-	return nil
+// marshal serializes a reading, batch or envelope in the configured format
+func (h *GenericHTTPPublisher[T]) marshal(v any) ([]byte, error) {
+	if h.serializer != nil {
+		switch v := v.(type) {
+		case engine.SensorData[T]:
+			return h.serializer.Marshal(v)
+		case []engine.SensorData[T]:
+			return h.serializer.MarshalBatch(v)
+		default:
+			return nil, fmt.Errorf("serializer %T cannot encode %T", h.serializer, v)
+		}
+	}
+	if h.options.cbor {
+		return cborEncMode.Marshal(v)
+	}
+	return h.options.json.Marshal(v)
 }
 
-// PublishBatch publishes a batch of sensor data points
-func (h *GenericHTTPPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
-	payload, err := json.Marshal(data)
+// contentType returns the media type of the payloads sent
+func (h *GenericHTTPPublisher[T]) contentType() string {
+	if h.serializer != nil && h.options.template == nil {
+		return h.serializer.ContentType()
+	}
+	if h.options.cbor && h.options.template == nil {
+		return CBORSerializer[T]{}.ContentType()
+	}
+	return JSONSerializer[T]{}.ContentType()
+}
+
+// publishTemplated posts items wrapped by the payload template
+func (h *GenericHTTPPublisher[T]) publishTemplated(ctx context.Context, items any, count int) error {
+	payload, err := h.renderPayload(ctx, items, count)
 	if err != nil {
 		return err
 	}
+	return h.post(ctx, payload)
+}
+
+// post sends a payload to the configured endpoint and reports the
+// outcome to the observer, if any
+func (h *GenericHTTPPublisher[T]) post(ctx context.Context, payload []byte) error {
+	if h.options.observer == nil {
+		return h.postWithRetry(ctx, payload)
+	}
+
+	start := time.Now()
+	err := h.postWithRetry(ctx, payload)
+	h.options.observer.ObservePublish(len(payload), time.Since(start), err)
+	return err
+}
+
+// postWithRetry sends a payload, retrying according to the configured
+// backoff strategy
+func (h *GenericHTTPPublisher[T]) postWithRetry(ctx context.Context, payload []byte) error {
+	return engine.Retry(ctx, h.options.backoff, retryable, func() error {
+		return h.send(ctx, payload)
+	})
+}
+
+// send performs a single POST of the payload
+func (h *GenericHTTPPublisher[T]) send(ctx context.Context, payload []byte) error {
+	if h.options.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.options.requestTimeout)
+		defer cancel()
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", h.contentType())
 
 	resp, err := h.client.Do(req)
 	if err != nil {
@@ -75,14 +388,21 @@ func (h *GenericHTTPPublisher[T]) PublishBatch(ctx context.Context, data []engin
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
+		return &httpStatusError{statusCode: resp.StatusCode}
 	}
 
 	return nil
 }
 
-// Close closes the HTTP publisher
+// Close closes the HTTP publisher. It is safe to call more than once. A
+// client from WithHTTPClient may be shared, so its connections are left
+// alone.
 func (h *GenericHTTPPublisher[T]) Close() error {
-	// HTTP client doesn't need explicit closing
+	h.closeOnce.Do(func() {
+		// Release pooled connections; the client itself needs no closing
+		if h.options.client == nil {
+			h.client.CloseIdleConnections()
+		}
+	})
 	return nil
 }
@@ -2,8 +2,9 @@ package publisher
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -11,64 +12,327 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// kafkaWriter is the subset of kafka.Writer used by GenericKafkaPublisher
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaOption configures a GenericKafkaPublisher
+type KafkaOption[T any] func(*kafkaOptions[T])
+
+type kafkaOptions[T any] struct {
+	topicFunc      DestinationFunc[T]
+	observer       engine.PublisherObserver
+	json           JSONOptions
+	serializer     Serializer[T]
+	publishTimeout time.Duration
+	headers        *KafkaHeaders
+	backoff        engine.BackoffStrategy
+}
+
+// KafkaHeaders selects the headers set on every message, so consumers can
+// route and filter without deserializing payloads
+type KafkaHeaders struct {
+	Quality       bool              // "quality": the reading's quality
+	SchemaVersion string            // "schema_version", when not empty
+	Metadata      map[string]string // One header per entry, named by its key
+}
+
+// WithTopicFunc routes each reading to the topic returned by fn. Readings for
+// which fn returns "" go to the publisher's default topic. See
+// DestinationFunc for the cost of per-reading topics.
+func WithTopicFunc[T any](fn DestinationFunc[T]) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.topicFunc = fn
+	}
+}
+
+// WithKafkaObserver reports the size, duration and outcome of every write
+// to observer. The size is the total of the message keys and values.
+func WithKafkaObserver[T any](observer engine.PublisherObserver) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.observer = observer
+	}
+}
+
+// WithKafkaJSONOptions sets how message values are serialized
+func WithKafkaJSONOptions[T any](options JSONOptions) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.json = options
+	}
+}
+
+// WithKafkaSerializer encodes message values with serializer instead of
+// JSON; use it with ProtoSerializer for protobuf. Envelopes cannot be
+// encoded this way and fail to publish.
+func WithKafkaSerializer[T any](serializer Serializer[T]) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.serializer = serializer
+	}
+}
+
+// WithKafkaPublishTimeout bounds every write, including the writer's
+// internal retries, to d. A write still in flight when d expires, or when
+// the caller's context is cancelled, returns the context error; the writer
+// may keep retrying the messages in the background until Close. 0 waits
+// for the caller's context only.
+func WithKafkaPublishTimeout[T any](d time.Duration) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.publishTimeout = d
+	}
+}
+
+// WithKafkaHeaders sets the headers selected by headers on every message.
+// For envelopes, an empty SchemaVersion falls back to the envelope's
+// version, and envelope metadata is added to the configured metadata.
+func WithKafkaHeaders[T any](headers KafkaHeaders) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.headers = &headers
+	}
+}
+
+// WithKafkaBackoff retries failed writes as long as strategy allows. Each
+// attempt gets its own publish timeout. A retried write resends all of its
+// messages, so messages the brokers accepted before the failure may be
+// delivered twice.
+func WithKafkaBackoff[T any](strategy engine.BackoffStrategy) KafkaOption[T] {
+	return func(o *kafkaOptions[T]) {
+		o.backoff = strategy
+	}
+}
+
+// QualityTopicFunc returns a topic function that routes CORRUPT and PARTIAL
+// readings to quarantineTopic and everything else to the default topic
+func QualityTopicFunc[T any](quarantineTopic string) DestinationFunc[T] {
+	return func(data engine.SensorData[T]) string {
+		switch data.Quality {
+		case engine.QualityCorrupt, engine.QualityPartial:
+			return quarantineTopic
+		default:
+			return ""
+		}
+	}
+}
+
 // GenericKafkaPublisher is a generic Kafka publisher
 type GenericKafkaPublisher[T any] struct {
-	writer *kafka.Writer
-	batch  []kafka.Message
-	mutex  sync.Mutex
+	writer    kafkaWriter
+	topic     string
+	options   kafkaOptions[T]
+	batch     []kafka.Message
+	mutex     sync.Mutex
+	closeOnce sync.Once
 }
 
 // NewGenericKafkaPublisher creates a new generic Kafka publisher
-func NewGenericKafkaPublisher[T any](brokers []string, topic string) *GenericKafkaPublisher[T] {
-	writer := kafka.NewWriter(kafka.WriterConfig{
+func NewGenericKafkaPublisher[T any](brokers []string, topic string, opts ...KafkaOption[T]) *GenericKafkaPublisher[T] {
+	var options kafkaOptions[T]
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	config := kafka.WriterConfig{
 		Brokers:      brokers,
 		Topic:        topic,
 		Balancer:     &kafka.Hash{},
 		BatchTimeout: 10 * time.Millisecond,
 		BatchSize:    100,
-	})
+	}
+	if options.topicFunc != nil {
+		// The writer rejects messages carrying their own topic when it has one
+		config.Topic = ""
+	}
+
 	return &GenericKafkaPublisher[T]{
-		writer: writer,
-		batch:  make([]kafka.Message, 0, 100),
+		writer:  kafka.NewWriter(config),
+		topic:   topic,
+		options: options,
+		batch:   make([]kafka.Message, 0, 100),
 	}
 }
 
 // Publish publishes a single sensor data point
 func (k *GenericKafkaPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
-	value, err := json.Marshal(data)
+	msg, err := k.message(data)
 	if err != nil {
 		return err
 	}
-	msg := kafka.Message{
-		Key:   []byte(data.ID),
-		Value: value,
-		Time:  time.Now(),
-	}
-	return k.writer.WriteMessages(ctx, msg)
+	return k.write(ctx, msg)
 }
 
-// PublishBatch publishes a batch of sensor data points
+// PublishBatch publishes a batch of sensor data points. With a topic function
+// the batch is split per topic and each group is written with one call.
 func (k *GenericKafkaPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
-	k.mutex.Lock()
-	defer k.mutex.Unlock()
-
 	messages := make([]kafka.Message, len(data))
 	for i, d := range data {
-		value, err := json.Marshal(d)
+		msg, err := k.message(d)
 		if err != nil {
 			return err
 		}
-		messages[i] = kafka.Message{
-			Key:   []byte(d.ID),
-			Value: value,
-			Time:  time.Now(),
+		messages[i] = msg
+	}
+	return k.writeBatch(ctx, messages)
+}
+
+// PublishEnvelopes publishes a batch of readings wrapped in envelopes, for
+// engines in envelope mode. Topics and keys are derived from the wrapped
+// readings as in PublishBatch.
+func (k *GenericKafkaPublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	messages := make([]kafka.Message, len(envelopes))
+	for i, env := range envelopes {
+		msg, err := k.envelopeMessage(env)
+		if err != nil {
+			return err
+		}
+		messages[i] = msg
+	}
+	return k.writeBatch(ctx, messages)
+}
+
+// writeBatch writes a batch of messages, with one call per topic when a
+// topic function is set
+func (k *GenericKafkaPublisher[T]) writeBatch(ctx context.Context, messages []kafka.Message) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.options.topicFunc == nil {
+		return k.write(ctx, messages...)
+	}
+
+	topics, groups := groupByDestination(messages, func(msg kafka.Message) string {
+		return msg.Topic
+	})
+	for _, topic := range topics {
+		if err := k.write(ctx, groups[topic]...); err != nil {
+			return fmt.Errorf("failed to write to topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// write writes messages, retrying according to the backoff strategy, if any
+func (k *GenericKafkaPublisher[T]) write(ctx context.Context, msgs ...kafka.Message) error {
+	return engine.Retry(ctx, k.options.backoff, nil, func() error {
+		return k.writeOnce(ctx, msgs...)
+	})
+}
+
+// writeOnce writes messages within the publish timeout, if any, and reports
+// the outcome to the observer, if any
+func (k *GenericKafkaPublisher[T]) writeOnce(ctx context.Context, msgs ...kafka.Message) error {
+	if k.options.publishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.options.publishTimeout)
+		defer cancel()
+	}
+
+	if k.options.observer == nil {
+		return k.writer.WriteMessages(ctx, msgs...)
+	}
+
+	size := 0
+	for _, msg := range msgs {
+		size += len(msg.Key) + len(msg.Value)
+	}
+	start := time.Now()
+	err := k.writer.WriteMessages(ctx, msgs...)
+	k.options.observer.ObservePublish(size, time.Since(start), err)
+	return err
+}
+
+// message converts a reading into a Kafka message
+func (k *GenericKafkaPublisher[T]) message(data engine.SensorData[T]) (kafka.Message, error) {
+	msg, err := k.messageWithValue(data, data)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	msg.Headers = k.headers(data, "", nil)
+	return msg, nil
+}
+
+// envelopeMessage converts an envelope into a Kafka message
+func (k *GenericKafkaPublisher[T]) envelopeMessage(env engine.Envelope[T]) (kafka.Message, error) {
+	msg, err := k.messageWithValue(env.SensorData, env)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	msg.Headers = k.headers(env.SensorData, env.Version, env.Metadata)
+	return msg, nil
+}
+
+// headers builds the configured headers of a reading, with metadata headers
+// in key order. version and metadata come from the reading's envelope, if
+// any.
+func (k *GenericKafkaPublisher[T]) headers(data engine.SensorData[T], version string, metadata map[string]string) []kafka.Header {
+	config := k.options.headers
+	if config == nil {
+		return nil
+	}
+
+	var headers []kafka.Header
+	if config.Quality {
+		headers = append(headers, kafka.Header{Key: "quality", Value: []byte(data.Quality)})
+	}
+	if config.SchemaVersion != "" {
+		version = config.SchemaVersion
+	}
+	if version != "" {
+		headers = append(headers, kafka.Header{Key: "schema_version", Value: []byte(version)})
+	}
+
+	merged := config.Metadata
+	if len(metadata) > 0 {
+		merged = maps.Clone(metadata)
+		maps.Copy(merged, config.Metadata)
+	}
+	for _, key := range slices.Sorted(maps.Keys(merged)) {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(merged[key])})
+	}
+	return headers
+}
+
+// marshal serializes a reading or envelope in the configured format
+func (k *GenericKafkaPublisher[T]) marshal(v any) ([]byte, error) {
+	if k.options.serializer == nil {
+		return k.options.json.Marshal(v)
+	}
+	data, ok := v.(engine.SensorData[T])
+	if !ok {
+		return nil, fmt.Errorf("serializer %T cannot encode %T", k.options.serializer, v)
+	}
+	return k.options.serializer.Marshal(data)
+}
+
+// messageWithValue builds the Kafka message for a reading with value as the
+// payload
+func (k *GenericKafkaPublisher[T]) messageWithValue(data engine.SensorData[T], v any) (kafka.Message, error) {
+	value, err := k.marshal(v)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(data.ID),
+		Value: value,
+		Time:  time.Now(),
+	}
+	if k.options.topicFunc != nil {
+		msg.Topic = k.options.topicFunc(data)
+		if msg.Topic == "" {
+			msg.Topic = k.topic
 		}
 	}
-	return k.writer.WriteMessages(ctx, messages...)
+	return msg, nil
 }
 
-// Close closes the Kafka publisher
+// Close closes the Kafka publisher. It is safe to call more than once; only
+// the first call closes the writer and reports its error.
 func (k *GenericKafkaPublisher[T]) Close() error {
-	fmt.Println("Closing Kafka publisher")
-	return k.writer.Close()
+	var err error
+	k.closeOnce.Do(func() {
+		fmt.Println("Closing Kafka publisher")
+		err = k.writer.Close()
+	})
+	return err
 }
@@ -0,0 +1,310 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeKafkaWriter records every WriteMessages call
+type fakeKafkaWriter struct {
+	calls [][]kafka.Message
+}
+
+func (f *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.calls = append(f.calls, msgs)
+	return nil
+}
+
+func (f *fakeKafkaWriter) Close() error {
+	return nil
+}
+
+func TestGenericKafkaPublisher_TopicFunc(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64](
+		[]string{"localhost:9092"},
+		"sensors",
+		WithTopicFunc(QualityTopicFunc[float64]("sensors-quarantine")),
+	)
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	batch := []engine.SensorData[float64]{
+		{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK},
+		{ID: "b", Timestamp: time.Now(), Data: 2, Quality: engine.QualityCorrupt},
+		{ID: "c", Timestamp: time.Now(), Data: 3, Quality: engine.QualityNoisy},
+		{ID: "d", Timestamp: time.Now(), Data: 4, Quality: engine.QualityPartial},
+	}
+
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.calls) != 2 {
+		t.Fatalf("Expected one WriteMessages call per topic, got %d", len(writer.calls))
+	}
+
+	want := map[string][]string{
+		"sensors":            {"a", "c"},
+		"sensors-quarantine": {"b", "d"},
+	}
+	for _, call := range writer.calls {
+		topic := call[0].Topic
+		ids := want[topic]
+		if len(call) != len(ids) {
+			t.Errorf("Topic %s: expected %d messages, got %d", topic, len(ids), len(call))
+			continue
+		}
+		for i, msg := range call {
+			if msg.Topic != topic {
+				t.Errorf("Mixed topics in one call: %s and %s", topic, msg.Topic)
+			}
+			if string(msg.Key) != ids[i] {
+				t.Errorf("Topic %s: expected key %s, got %s", topic, ids[i], msg.Key)
+			}
+		}
+	}
+}
+
+func TestGenericKafkaPublisher_DefaultTopicSingleWrite(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors")
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	batch := []engine.SensorData[float64]{
+		{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK},
+		{ID: "b", Timestamp: time.Now(), Data: 2, Quality: engine.QualityCorrupt},
+	}
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.calls) != 1 || len(writer.calls[0]) != 2 {
+		t.Fatalf("Expected a single write of 2 messages, got %v", writer.calls)
+	}
+	if writer.calls[0][0].Topic != "" {
+		t.Errorf("Messages should not carry a topic without a topic func, got %q", writer.calls[0][0].Topic)
+	}
+}
+
+func TestGenericKafkaPublisher_WithKafkaObserver(t *testing.T) {
+	var calls, bytes int
+	observer := engine.PublisherObserverFunc(func(n int, d time.Duration, err error) {
+		calls++
+		bytes += n
+	})
+
+	publisher := NewGenericKafkaPublisher[float64](
+		[]string{"localhost:9092"},
+		"sensors",
+		WithTopicFunc(QualityTopicFunc[float64]("sensors-quarantine")),
+		WithKafkaObserver[float64](observer),
+	)
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	batch := []engine.SensorData[float64]{
+		{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK},
+		{ID: "b", Timestamp: time.Now(), Data: 2, Quality: engine.QualityCorrupt},
+	}
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected one observation per topic write, got %d", calls)
+	}
+	want := 0
+	for _, call := range writer.calls {
+		for _, msg := range call {
+			want += len(msg.Key) + len(msg.Value)
+		}
+	}
+	if bytes != want {
+		t.Errorf("Expected %d observed bytes, got %d", want, bytes)
+	}
+}
+
+func TestGenericKafkaPublisher_PublishEnvelopes(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64](
+		[]string{"localhost:9092"},
+		"sensors",
+		WithTopicFunc(QualityTopicFunc[float64]("sensors-quarantine")),
+	)
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	envelopes := []engine.Envelope[float64]{
+		{SensorData: engine.SensorData[float64]{ID: "a", Data: 1, Quality: engine.QualityOK}, Seq: 1},
+		{SensorData: engine.SensorData[float64]{ID: "b", Data: 2, Quality: engine.QualityCorrupt}, Seq: 2},
+	}
+	if err := publisher.PublishEnvelopes(context.Background(), envelopes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.calls) != 2 {
+		t.Fatalf("Expected one WriteMessages call per topic, got %d", len(writer.calls))
+	}
+	msg := writer.calls[1][0]
+	if msg.Topic != "sensors-quarantine" || string(msg.Key) != "b" {
+		t.Errorf("Expected the wrapped reading to set topic and key, got %s and %s", msg.Topic, msg.Key)
+	}
+	var env engine.Envelope[float64]
+	if err := json.Unmarshal(msg.Value, &env); err != nil {
+		t.Fatalf("Invalid envelope value: %v", err)
+	}
+	if env.Seq != 2 {
+		t.Errorf("Expected seq 2 in the message value, got %d", env.Seq)
+	}
+}
+
+// blackholeBroker accepts TCP connections and never answers, like a broker
+// behind a firewall that drops traffic
+func blackholeBroker(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	var mutex sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mutex.Lock()
+			conns = append(conns, conn)
+			mutex.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	return listener.Addr().String()
+}
+
+func TestGenericKafkaPublisher_ContextCancelAbortsWrite(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{blackholeBroker(t)}, "sensors")
+	batch := []engine.SensorData[float64]{{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := publisher.PublishBatch(ctx, batch)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("PublishBatch returned %v after the context was cancelled", elapsed-100*time.Millisecond)
+	}
+}
+
+func TestGenericKafkaPublisher_PublishTimeout(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{blackholeBroker(t)}, "sensors",
+		WithKafkaPublishTimeout[float64](100*time.Millisecond))
+	data := engine.SensorData[float64]{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK}
+
+	start := time.Now()
+	err := publisher.Publish(context.Background(), data)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Publish took %v despite a 100ms publish timeout", elapsed)
+	}
+}
+
+func TestGenericKafkaPublisher_Headers(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors",
+		WithKafkaHeaders[float64](KafkaHeaders{
+			Quality:       true,
+			SchemaVersion: "2",
+			Metadata:      map[string]string{"site": "lab", "env": "test"},
+		}))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	batch := []engine.SensorData[float64]{
+		{ID: "a", Timestamp: time.Now(), Data: 1, Quality: engine.QualityOK},
+		{ID: "b", Timestamp: time.Now(), Data: 2, Quality: engine.QualityCorrupt},
+	}
+	if err := publisher.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), batch[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	messages := append(writer.calls[0], writer.calls[1]...)
+	wantQuality := []engine.Quality{engine.QualityOK, engine.QualityCorrupt, engine.QualityOK}
+	for i, msg := range messages {
+		got := make([]string, len(msg.Headers))
+		for j, h := range msg.Headers {
+			got[j] = h.Key + "=" + string(h.Value)
+		}
+		want := []string{"quality=" + string(wantQuality[i]), "schema_version=2", "env=test", "site=lab"}
+		if !slices.Equal(got, want) {
+			t.Errorf("Message %d: expected headers %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestGenericKafkaPublisher_EnvelopeHeaders(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors",
+		WithKafkaHeaders[float64](KafkaHeaders{Metadata: map[string]string{"site": "lab"}}))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	envelopes := []engine.Envelope[float64]{{
+		SensorData: engine.SensorData[float64]{ID: "a", Data: 1, Quality: engine.QualityOK},
+		Version:    "3",
+		Metadata:   map[string]string{"site": "field", "region": "eu"},
+	}}
+	if err := publisher.PublishEnvelopes(context.Background(), envelopes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, h := range writer.calls[0][0].Headers {
+		got = append(got, h.Key+"="+string(h.Value))
+	}
+	want := []string{"schema_version=3", "region=eu", "site=lab"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected headers %v, got %v", want, got)
+	}
+}
+
+func TestGenericKafkaPublisher_NoHeadersByDefault(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors")
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	data := engine.SensorData[float64]{ID: "a", Data: 1, Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if headers := writer.calls[0][0].Headers; headers != nil {
+		t.Errorf("Expected no headers, got %v", headers)
+	}
+}
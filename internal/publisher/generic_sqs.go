@@ -0,0 +1,145 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sqsMaxBatchSize is the maximum number of entries SQS accepts per SendMessageBatch call
+const sqsMaxBatchSize = 10
+
+// SQSAPI is the subset of the SQS client used by SQSPublisher
+type SQSAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// SQSPublisher publishes each reading as an Amazon SQS message
+type SQSPublisher[T any] struct {
+	client   SQSAPI
+	queueURL string
+}
+
+// NewSQSPublisher creates an SQS publisher using the standard AWS SDK
+// configuration chain (environment, shared config files, instance roles)
+// for credentials and region
+func NewSQSPublisher[T any](ctx context.Context, queueURL string) (*SQSPublisher[T], error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return NewSQSPublisherWithClient[T](sqs.NewFromConfig(cfg), queueURL), nil
+}
+
+// NewSQSPublisherWithClient creates an SQS publisher with an existing client
+func NewSQSPublisherWithClient[T any](client SQSAPI, queueURL string) *SQSPublisher[T] {
+	return &SQSPublisher[T]{
+		client:   client,
+		queueURL: queueURL,
+	}
+}
+
+// Publish publishes a single sensor data point
+func (s *SQSPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(s.queueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// PublishBatch publishes a batch of sensor data points in chunks of up to
+// ten messages. Entries that fail without a sender fault are retried once;
+// any remaining failures are reported in the returned error.
+func (s *SQSPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	failed := 0
+	var firstFailure string
+
+	for start := 0; start < len(data); start += sqsMaxBatchSize {
+		end := min(start+sqsMaxBatchSize, len(data))
+
+		entries := make([]types.SendMessageBatchRequestEntry, 0, end-start)
+		for i, d := range data[start:end] {
+			body, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(i)),
+				MessageBody: aws.String(string(body)),
+			})
+		}
+
+		remaining, err := s.sendBatch(ctx, entries)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			// Retry entries the service failed on its side
+			retry := make([]types.SendMessageBatchRequestEntry, 0, len(remaining))
+			var senderFaults []types.BatchResultErrorEntry
+			for _, f := range remaining {
+				if f.SenderFault {
+					senderFaults = append(senderFaults, f)
+					continue
+				}
+				retry = append(retry, entries[entryIndex(f.Id)])
+			}
+			if len(retry) > 0 {
+				if remaining, err = s.sendBatch(ctx, retry); err != nil {
+					return err
+				}
+				senderFaults = append(senderFaults, remaining...)
+			}
+			remaining = senderFaults
+		}
+
+		for _, f := range remaining {
+			if failed == 0 {
+				firstFailure = fmt.Sprintf("%s: %s", data[start+entryIndex(f.Id)].ID, aws.ToString(f.Message))
+			}
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("SQS batch publish failed for %d of %d messages (first: %s)", failed, len(data), firstFailure)
+	}
+	return nil
+}
+
+// sendBatch sends one SendMessageBatch request and returns the failed entries
+func (s *SQSPublisher[T]) sendBatch(ctx context.Context, entries []types.SendMessageBatchRequestEntry) ([]types.BatchResultErrorEntry, error) {
+	out, err := s.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(s.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send SQS batch: %w", err)
+	}
+	return out.Failed, nil
+}
+
+// entryIndex converts a batch entry ID back into its index within the chunk
+func entryIndex(id *string) int {
+	i, _ := strconv.Atoi(aws.ToString(id))
+	return i
+}
+
+// Close closes the SQS publisher. Messages are sent synchronously, so there
+// is nothing to flush and it is safe to call more than once.
+func (s *SQSPublisher[T]) Close() error {
+	return nil
+}
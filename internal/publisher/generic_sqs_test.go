@@ -0,0 +1,120 @@
+package publisher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeSQSClient records batch sizes and fails the configured entry IDs
+type fakeSQSClient struct {
+	batchSizes  []int
+	single      int
+	failIDs     map[string]bool // Entry IDs that fail on every attempt
+	senderFault bool
+	flakyIDs    map[string]bool // Entry IDs that fail only on the first attempt
+}
+
+func (f *fakeSQSClient) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.single++
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (f *fakeSQSClient) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.batchSizes = append(f.batchSizes, len(params.Entries))
+	out := &sqs.SendMessageBatchOutput{}
+	for _, entry := range params.Entries {
+		id := aws.ToString(entry.Id)
+		if f.failIDs[id] || f.flakyIDs[id] {
+			delete(f.flakyIDs, id)
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("InternalError"),
+				Message:     aws.String("simulated failure"),
+				SenderFault: f.senderFault,
+			})
+		}
+	}
+	return out, nil
+}
+
+func makeSQSBatch(n int) []engine.SensorData[float64] {
+	batch := make([]engine.SensorData[float64], n)
+	for i := range batch {
+		batch[i] = engine.SensorData[float64]{
+			ID:        "reading-" + string(rune('a'+i%26)),
+			Timestamp: time.Now(),
+			Data:      float64(i),
+			Quality:   engine.QualityOK,
+		}
+	}
+	return batch
+}
+
+func TestSQSPublisher_PublishBatchChunks(t *testing.T) {
+	client := &fakeSQSClient{}
+	publisher := NewSQSPublisherWithClient[float64](client, "https://sqs.example/queue")
+
+	if err := publisher.PublishBatch(context.Background(), makeSQSBatch(25)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []int{10, 10, 5}
+	if len(client.batchSizes) != len(want) {
+		t.Fatalf("Expected batch sizes %v, got %v", want, client.batchSizes)
+	}
+	for i := range want {
+		if client.batchSizes[i] != want[i] {
+			t.Errorf("Expected batch sizes %v, got %v", want, client.batchSizes)
+		}
+	}
+}
+
+func TestSQSPublisher_RetriesServerFaults(t *testing.T) {
+	client := &fakeSQSClient{flakyIDs: map[string]bool{"3": true}}
+	publisher := NewSQSPublisherWithClient[float64](client, "https://sqs.example/queue")
+
+	if err := publisher.PublishBatch(context.Background(), makeSQSBatch(5)); err != nil {
+		t.Fatalf("Expected retry to succeed, got: %v", err)
+	}
+	if len(client.batchSizes) != 2 || client.batchSizes[1] != 1 {
+		t.Errorf("Expected a single-entry retry, got batch sizes %v", client.batchSizes)
+	}
+}
+
+func TestSQSPublisher_ReportsPartialFailure(t *testing.T) {
+	client := &fakeSQSClient{failIDs: map[string]bool{"1": true, "2": true}, senderFault: true}
+	publisher := NewSQSPublisherWithClient[float64](client, "https://sqs.example/queue")
+
+	err := publisher.PublishBatch(context.Background(), makeSQSBatch(4))
+	if err == nil {
+		t.Fatal("Expected partial failure error")
+	}
+	if !strings.Contains(err.Error(), "2 of 4") {
+		t.Errorf("Expected failure count in error, got: %v", err)
+	}
+	if len(client.batchSizes) != 1 {
+		t.Errorf("Sender faults should not be retried, got batch sizes %v", client.batchSizes)
+	}
+}
+
+func TestSQSPublisher_Publish(t *testing.T) {
+	client := &fakeSQSClient{}
+	publisher := NewSQSPublisherWithClient[float64](client, "https://sqs.example/queue")
+
+	if err := publisher.Publish(context.Background(), makeSQSBatch(1)[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if client.single != 1 {
+		t.Errorf("Expected 1 SendMessage call, got %d", client.single)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Unexpected error closing SQS publisher: %v", err)
+	}
+}
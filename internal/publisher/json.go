@@ -0,0 +1,30 @@
+package publisher
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONOptions controls how publishers serialize readings. The zero value
+// matches json.Marshal: compact output with HTML characters escaped.
+type JSONOptions struct {
+	DisableHTMLEscape bool   // Keep <, > and & as is instead of escaping them as \u003c, \u003e and \u0026
+	Indent            string // Pretty-print with this indent per level; "" for compact output
+}
+
+// Marshal serializes v according to the options
+func (o JSONOptions) Marshal(v any) ([]byte, error) {
+	if !o.DisableHTMLEscape && o.Indent == "" {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(!o.DisableHTMLEscape)
+	encoder.SetIndent("", o.Indent)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates the value with a newline that Marshal does not
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,74 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func TestJSONOptions_Marshal(t *testing.T) {
+	data := map[string]string{"expr": "a < b && c > d"}
+
+	tests := []struct {
+		name    string
+		options JSONOptions
+		want    string
+	}{
+		{"default escapes HTML", JSONOptions{}, `{"expr":"a \u003c b \u0026\u0026 c \u003e d"}`},
+		{"disable escaping", JSONOptions{DisableHTMLEscape: true}, `{"expr":"a < b && c > d"}`},
+		{"indent", JSONOptions{DisableHTMLEscape: true, Indent: "  "}, "{\n  \"expr\": \"a < b && c > d\"\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.Marshal(data)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenericHTTPPublisher_WithJSONOptions(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[string](server.URL, WithJSONOptions(JSONOptions{DisableHTMLEscape: true}))
+	data := engine.SensorData[string]{ID: "html-1", Timestamp: time.Now(), Data: "<b>", Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(string(body), `"data":"<b>"`) {
+		t.Errorf("Expected unescaped HTML in payload, got %s", body)
+	}
+}
+
+func TestGenericKafkaPublisher_WithKafkaJSONOptions(t *testing.T) {
+	publisher := NewGenericKafkaPublisher[string]([]string{"localhost:9092"}, "sensors",
+		WithKafkaJSONOptions[string](JSONOptions{DisableHTMLEscape: true}))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	data := engine.SensorData[string]{ID: "html-1", Timestamp: time.Now(), Data: "<b>", Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(writer.calls[0][0].Value, []byte(`"data":"<b>"`)) {
+		t.Errorf("Expected unescaped HTML in message, got %s", writer.calls[0][0].Value)
+	}
+}
@@ -0,0 +1,106 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// LastValuePublisher keeps the most recent reading per key while forwarding
+// every reading to the next publisher, so the current state of each sensor
+// can be looked up in O(1) for dashboards and "current state" APIs without
+// scanning the stream. Readings are recorded whether or not forwarding
+// succeeds, and a reading older than the one already held for its key, as
+// can happen with several publish workers, does not replace it.
+type LastValuePublisher[T any] struct {
+	next engine.Publisher[T]
+	key  func(engine.SensorData[T]) string
+
+	mutex  sync.RWMutex
+	latest map[string]engine.SensorData[T]
+}
+
+// NewLastValuePublisher creates a last value cache in front of next, keyed
+// by key. A nil key keys readings by their ID.
+func NewLastValuePublisher[T any](next engine.Publisher[T], key func(engine.SensorData[T]) string) *LastValuePublisher[T] {
+	if key == nil {
+		key = func(data engine.SensorData[T]) string { return data.ID }
+	}
+	return &LastValuePublisher[T]{
+		next:   next,
+		key:    key,
+		latest: make(map[string]engine.SensorData[T]),
+	}
+}
+
+// record stores data as the latest reading of its key unless a newer one is
+// held. The mutex must be held.
+func (l *LastValuePublisher[T]) record(data engine.SensorData[T]) {
+	key := l.key(data)
+	if current, ok := l.latest[key]; ok && data.Timestamp.Before(current.Timestamp) {
+		return
+	}
+	l.latest[key] = data
+}
+
+// Publish records and forwards a single sensor data point
+func (l *LastValuePublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	l.mutex.Lock()
+	l.record(data)
+	l.mutex.Unlock()
+
+	return l.next.Publish(ctx, data)
+}
+
+// PublishBatch records and forwards a batch of sensor data points
+func (l *LastValuePublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	l.mutex.Lock()
+	for _, d := range data {
+		l.record(d)
+	}
+	l.mutex.Unlock()
+
+	return l.next.PublishBatch(ctx, data)
+}
+
+// PublishEnvelopes records the readings of a batch of envelopes and
+// forwards the envelopes, for engines in envelope mode. The next publisher
+// must implement engine.EnvelopePublisher.
+func (l *LastValuePublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	next, ok := l.next.(engine.EnvelopePublisher[T])
+	if !ok {
+		return fmt.Errorf("last value: next publisher %T does not support envelopes", l.next)
+	}
+
+	l.mutex.Lock()
+	for _, env := range envelopes {
+		l.record(env.SensorData)
+	}
+	l.mutex.Unlock()
+
+	return next.PublishEnvelopes(ctx, envelopes)
+}
+
+// Latest returns the most recent reading recorded for key, and false if
+// there is none
+func (l *LastValuePublisher[T]) Latest(key string) (engine.SensorData[T], bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	data, ok := l.latest[key]
+	return data, ok
+}
+
+// All returns a copy of the most recent reading of every key
+func (l *LastValuePublisher[T]) All() map[string]engine.SensorData[T] {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return maps.Clone(l.latest)
+}
+
+// Close closes the next publisher
+func (l *LastValuePublisher[T]) Close() error {
+	return l.next.Close()
+}
@@ -0,0 +1,61 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func TestLastValuePublisher_KeepsLatestPerKey(t *testing.T) {
+	next := NewMockPublisher[float64]()
+	start := time.Now()
+	reading := func(sensor string, offset time.Duration, value float64) engine.SensorData[float64] {
+		return engine.SensorData[float64]{ID: sensor, Timestamp: start.Add(offset), Data: value}
+	}
+	publisher := NewLastValuePublisher[float64](next, nil)
+	ctx := context.Background()
+
+	publisher.PublishBatch(ctx, []engine.SensorData[float64]{
+		reading("a", 0, 1), reading("b", 0, 10), reading("a", time.Second, 2),
+	})
+	publisher.Publish(ctx, reading("b", 2*time.Second, 20))
+	// A late reading from another worker does not overwrite a newer one
+	publisher.Publish(ctx, reading("a", 500*time.Millisecond, 99))
+
+	if latest, ok := publisher.Latest("a"); !ok || latest.Data != 2 {
+		t.Errorf("Expected 2 as the latest reading of a, got %v (found %v)", latest.Data, ok)
+	}
+	if latest, ok := publisher.Latest("b"); !ok || latest.Data != 20 {
+		t.Errorf("Expected 20 as the latest reading of b, got %v (found %v)", latest.Data, ok)
+	}
+	if _, ok := publisher.Latest("c"); ok {
+		t.Error("Expected no reading for an unknown sensor")
+	}
+	if all := publisher.All(); len(all) != 2 || all["a"].Data != 2 || all["b"].Data != 20 {
+		t.Errorf("Expected the latest readings of a and b, got %v", all)
+	}
+	if got := len(next.PublishedData); got != 5 {
+		t.Errorf("Expected all 5 readings forwarded, got %d", got)
+	}
+}
+
+func TestLastValuePublisher_KeyFunc(t *testing.T) {
+	byQuality := func(data engine.SensorData[float64]) string { return string(data.Quality) }
+	publisher := NewLastValuePublisher[float64](NewMockPublisher[float64](), byQuality)
+
+	readings := testReadings("a", "b", "c")
+	readings[1].Quality = engine.QualityNoisy
+	publisher.PublishBatch(context.Background(), readings)
+
+	all := publisher.All()
+	if all[string(engine.QualityOK)].ID != "c" || all[string(engine.QualityNoisy)].ID != "b" {
+		t.Errorf("Expected the latest reading per quality, got %v", all)
+	}
+	// All returns a copy
+	delete(all, string(engine.QualityOK))
+	if _, ok := publisher.Latest(string(engine.QualityOK)); !ok {
+		t.Error("Modifying the result of All should not affect the cache")
+	}
+}
@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// PayloadData is the data a payload template is executed with
+type PayloadData struct {
+	Readings string            // The readings serialized as a JSON array
+	Items    any               // The readings themselves, for ranging over
+	Count    int               // Number of readings
+	BatchID  string            // Batch ID carried by the context, if any
+	Metadata map[string]string // Metadata passed to WithPayloadTemplate
+	Now      time.Time         // When the payload was built
+}
+
+// NewPayloadTemplate parses a payload template. Besides the standard
+// text/template functions it provides json, which serializes any value, so
+// metadata can be quoted safely:
+//
+//	{"sensor":{{json .Metadata.sensor}},"readings":{{.Readings}},"ts":{{.Now.Unix}}}
+func NewPayloadTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// WithPayloadTemplate builds every request body by executing tmpl with a
+// PayloadData instead of sending the readings as is, to match server
+// contracts that expect a specific wrapper. Single readings are passed as a
+// batch of one. The output must be valid JSON. It takes precedence over
+// WithBatchEnvelope.
+func WithPayloadTemplate(tmpl *template.Template, metadata map[string]string) HTTPOption {
+	return func(o *httpOptions) {
+		o.template = tmpl
+		o.metadata = metadata
+	}
+}
+
+// renderPayload serializes items and executes the payload template around
+// them
+func (h *GenericHTTPPublisher[T]) renderPayload(ctx context.Context, items any, count int) ([]byte, error) {
+	readings, err := h.options.json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	batchID, _ := engine.BatchIDFromContext(ctx)
+
+	var buf bytes.Buffer
+	err = h.options.template.Execute(&buf, PayloadData{
+		Readings: string(readings),
+		Items:    items,
+		Count:    count,
+		BatchID:  batchID,
+		Metadata: h.options.metadata,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute payload template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload template produced invalid JSON: %s", buf.Bytes())
+	}
+	return buf.Bytes(), nil
+}
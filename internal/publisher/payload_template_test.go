@@ -0,0 +1,91 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// bodyRecorder is a test server that records the last request body
+func bodyRecorder(t *testing.T) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read body: %v", err)
+		}
+		body = b
+	}))
+	t.Cleanup(server.Close)
+	return server, &body
+}
+
+func TestGenericHTTPPublisher_PayloadTemplate(t *testing.T) {
+	server, body := bodyRecorder(t)
+	tmpl, err := NewPayloadTemplate(`{"sensor":{{json .Metadata.sensor}},"batch":{{json .BatchID}},"count":{{.Count}},"readings":{{.Readings}},"ts":{{.Now.Unix}}}`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	publisher := NewGenericHTTPPublisher[float64](server.URL,
+		WithPayloadTemplate(tmpl, map[string]string{"sensor": `boiler "7"`}),
+		WithBatchEnvelope())
+
+	ctx := engine.WithBatchID(context.Background(), "trace-789")
+	if err := publisher.PublishBatch(ctx, testReadings("a", "b")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+
+	var received struct {
+		Sensor   string                       `json:"sensor"`
+		Batch    string                       `json:"batch"`
+		Count    int                          `json:"count"`
+		Readings []engine.SensorData[float64] `json:"readings"`
+		TS       int64                        `json:"ts"`
+	}
+	if err := json.Unmarshal(*body, &received); err != nil {
+		t.Fatalf("Body is not the templated JSON: %v\n%s", err, *body)
+	}
+	if received.Sensor != `boiler "7"` || received.Batch != "trace-789" || received.Count != 2 || received.TS == 0 {
+		t.Errorf("Unexpected wrapper fields: %+v", received)
+	}
+	if len(received.Readings) != 2 || received.Readings[1].ID != "b" {
+		t.Errorf("Expected the readings inside the wrapper, got %+v", received.Readings)
+	}
+}
+
+func TestGenericHTTPPublisher_PayloadTemplateSingleAndItems(t *testing.T) {
+	server, body := bodyRecorder(t)
+	tmpl, err := NewPayloadTemplate(`{"ids":[{{range $i, $d := .Items}}{{if $i}},{{end}}{{json $d.ID}}{{end}}]}`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithPayloadTemplate(tmpl, nil))
+
+	if err := publisher.Publish(context.Background(), testReadings("solo")[0]); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if got := string(*body); got != `{"ids":["solo"]}` {
+		t.Errorf("Expected a single reading as a batch of one, got %s", got)
+	}
+}
+
+func TestGenericHTTPPublisher_PayloadTemplateInvalidJSON(t *testing.T) {
+	server, _ := bodyRecorder(t)
+	tmpl, err := NewPayloadTemplate(`readings={{.Readings}}`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithPayloadTemplate(tmpl, nil))
+
+	err = publisher.PublishBatch(context.Background(), testReadings("a"))
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("Expected an invalid JSON error, got %v", err)
+	}
+}
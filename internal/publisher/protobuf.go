@@ -0,0 +1,185 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Field numbers of the SensorReading message in proto/sensor_reading.proto
+const (
+	protoFieldID           protowire.Number = 1
+	protoFieldTimestamp    protowire.Number = 2
+	protoFieldQuality      protowire.Number = 3
+	protoFieldDataJSON     protowire.Number = 4
+	protoFieldDataProto    protowire.Number = 5
+	protoFieldInput        protowire.Number = 6
+	protoFieldQualityScore protowire.Number = 7
+
+	protoFieldReadings protowire.Number = 1 // SensorReadingBatch.readings
+)
+
+// ProtoSerializer encodes readings as the SensorReading protobuf message of
+// proto/sensor_reading.proto, and batches as SensorReadingBatch. Since the
+// envelope cannot know T, Data is carried as JSON in data_json by default:
+// consumers only need the envelope's schema, at the cost of a text payload
+// inside the binary message. Setting MarshalData instead embeds T as its own
+// protobuf message in data_proto, which is compact and strongly typed but
+// means consumers must also have T's schema to read it.
+type ProtoSerializer[T any] struct {
+	// MarshalData converts Data to a protobuf message for data_proto; nil
+	// encodes it as JSON in data_json
+	MarshalData func(T) (proto.Message, error)
+
+	// UnmarshalData decodes data_proto back into Data; Unmarshal fails on
+	// readings with data_proto if it is nil
+	UnmarshalData func(payload []byte) (T, error)
+}
+
+// Marshal encodes a single reading as a SensorReading
+func (s ProtoSerializer[T]) Marshal(data engine.SensorData[T]) ([]byte, error) {
+	return s.appendReading(nil, data)
+}
+
+// MarshalBatch encodes a batch of readings as a SensorReadingBatch
+func (s ProtoSerializer[T]) MarshalBatch(data []engine.SensorData[T]) ([]byte, error) {
+	var payload []byte
+	for _, d := range data {
+		reading, err := s.appendReading(nil, d)
+		if err != nil {
+			return nil, err
+		}
+		payload = protowire.AppendTag(payload, protoFieldReadings, protowire.BytesType)
+		payload = protowire.AppendBytes(payload, reading)
+	}
+	return payload, nil
+}
+
+// appendReading appends the SensorReading encoding of data to b
+func (s ProtoSerializer[T]) appendReading(b []byte, data engine.SensorData[T]) ([]byte, error) {
+	timestamp, err := proto.Marshal(timestamppb.New(data.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+
+	dataField := protoFieldDataJSON
+	var payload []byte
+	if s.MarshalData != nil {
+		dataField = protoFieldDataProto
+		message, err := s.MarshalData(data.Data)
+		if err != nil {
+			return nil, err
+		}
+		payload, err = proto.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+	} else if payload, err = json.Marshal(data.Data); err != nil {
+		return nil, err
+	}
+
+	if data.ID != "" {
+		b = protowire.AppendTag(b, protoFieldID, protowire.BytesType)
+		b = protowire.AppendString(b, data.ID)
+	}
+	b = protowire.AppendTag(b, protoFieldTimestamp, protowire.BytesType)
+	b = protowire.AppendBytes(b, timestamp)
+	if data.Quality != "" {
+		b = protowire.AppendTag(b, protoFieldQuality, protowire.BytesType)
+		b = protowire.AppendString(b, string(data.Quality))
+	}
+	if len(payload) > 0 {
+		b = protowire.AppendTag(b, dataField, protowire.BytesType)
+		b = protowire.AppendBytes(b, payload)
+	}
+	for _, f := range []struct {
+		number protowire.Number
+		value  *float64
+	}{{protoFieldInput, data.Input}, {protoFieldQualityScore, data.QualityScore}} {
+		if f.value != nil {
+			b = protowire.AppendTag(b, f.number, protowire.Fixed64Type)
+			b = protowire.AppendFixed64(b, math.Float64bits(*f.value))
+		}
+	}
+	return b, nil
+}
+
+// Unmarshal decodes a single SensorReading. Unknown fields are skipped, so
+// readings from a newer schema still decode.
+func (s ProtoSerializer[T]) Unmarshal(payload []byte) (engine.SensorData[T], error) {
+	var data engine.SensorData[T]
+	for len(payload) > 0 {
+		number, wireType, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return data, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+
+		var err error
+		switch {
+		case wireType == protowire.BytesType && number <= protoFieldDataProto:
+			var value []byte
+			value, n = protowire.ConsumeBytes(payload)
+			if n < 0 {
+				return data, protowire.ParseError(n)
+			}
+			err = s.unmarshalField(&data, number, value)
+		case wireType == protowire.Fixed64Type && (number == protoFieldInput || number == protoFieldQualityScore):
+			var bits uint64
+			bits, n = protowire.ConsumeFixed64(payload)
+			value := math.Float64frombits(bits)
+			if number == protoFieldInput {
+				data.Input = &value
+			} else {
+				data.QualityScore = &value
+			}
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, payload)
+		}
+		if n < 0 {
+			return data, protowire.ParseError(n)
+		}
+		if err != nil {
+			return data, fmt.Errorf("field %d: %w", number, err)
+		}
+		payload = payload[n:]
+	}
+	return data, nil
+}
+
+// unmarshalField decodes one length-delimited field of a SensorReading
+func (s ProtoSerializer[T]) unmarshalField(data *engine.SensorData[T], number protowire.Number, value []byte) error {
+	switch number {
+	case protoFieldID:
+		data.ID = string(value)
+	case protoFieldTimestamp:
+		var timestamp timestamppb.Timestamp
+		if err := proto.Unmarshal(value, &timestamp); err != nil {
+			return err
+		}
+		data.Timestamp = timestamp.AsTime()
+	case protoFieldQuality:
+		data.Quality = engine.Quality(value)
+	case protoFieldDataJSON:
+		return json.Unmarshal(value, &data.Data)
+	case protoFieldDataProto:
+		if s.UnmarshalData == nil {
+			return errors.New("reading carries data_proto but the serializer has no UnmarshalData")
+		}
+		var err error
+		data.Data, err = s.UnmarshalData(value)
+		return err
+	}
+	return nil
+}
+
+// ContentType returns the media type of protobuf payloads
+func (ProtoSerializer[T]) ContentType() string {
+	return "application/x-protobuf"
+}
@@ -0,0 +1,217 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// sensorReadingDescriptor builds the SensorReading message of
+// proto/sensor_reading.proto, to check payloads against the published
+// schema with a generic protobuf decoder
+func sensorReadingDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     kind.Enum(),
+			JsonName: proto.String(name),
+		}
+	}
+	timestamp := field("timestamp", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	timestamp.TypeName = proto.String(".google.protobuf.Timestamp")
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("sensor_reading.proto"),
+		Package:    proto.String("sensors"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("SensorReading"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				timestamp,
+				field("quality", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("data_json", 4, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+				field("data_proto", 5, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+				field("input", 6, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+				field("quality_score", 7, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("Failed to build the SensorReading descriptor: %v", err)
+	}
+	return file.Messages().ByName("SensorReading")
+}
+
+func TestProtoSerializer_RoundTrip(t *testing.T) {
+	input, score := 42.5, 0.5
+	reading := engine.SensorData[vitals]{
+		ID:           "sensor-7",
+		Timestamp:    time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC),
+		Data:         vitals{HeartRate: 72, Oxygen: 97.5, Alarm: true},
+		Quality:      engine.QualityNoisy,
+		Input:        &input,
+		QualityScore: &score,
+	}
+
+	serializer := ProtoSerializer[vitals]{}
+	payload, err := serializer.Marshal(reading)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	decoded, err := serializer.Unmarshal(payload)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, reading) {
+		t.Errorf("Expected %+v after the round trip, got %+v", reading, decoded)
+	}
+
+	// Any protobuf decoder with the schema reads the envelope
+	message := dynamicpb.NewMessage(sensorReadingDescriptor(t))
+	if err := proto.Unmarshal(payload, message); err != nil {
+		t.Fatalf("Payload does not match the schema: %v", err)
+	}
+	get := func(name string) protoreflect.Value {
+		return message.Get(message.Descriptor().Fields().ByName(protoreflect.Name(name)))
+	}
+	seconds := get("timestamp").Message().Get(timestampSecondsField()).Int()
+	if get("id").String() != "sensor-7" || get("quality").String() != "NOISY" || seconds != reading.Timestamp.Unix() {
+		t.Errorf("Expected the envelope fields, got %v", message)
+	}
+	if data := string(get("data_json").Bytes()); data != `{"heart_rate":72,"oxygen":97.5,"alarm":true}` {
+		t.Errorf("Expected Data as JSON in data_json, got %s", data)
+	}
+	if get("input").Float() != 42.5 || get("quality_score").Float() != 0.5 {
+		t.Errorf("Expected input 42.5 and quality score 0.5, got %v", message)
+	}
+}
+
+// timestampSecondsField returns the seconds field of google.protobuf.Timestamp
+func timestampSecondsField() protoreflect.FieldDescriptor {
+	return (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().Fields().ByName("seconds")
+}
+
+func TestProtoSerializer_MarshalData(t *testing.T) {
+	serializer := ProtoSerializer[float64]{
+		MarshalData: func(v float64) (proto.Message, error) { return wrapperspb.Double(v), nil },
+		UnmarshalData: func(payload []byte) (float64, error) {
+			var v wrapperspb.DoubleValue
+			err := proto.Unmarshal(payload, &v)
+			return v.GetValue(), err
+		},
+	}
+	batch := testReadings("a", "b")
+	batch[1].Data = 21.5
+
+	payload, err := serializer.MarshalBatch(batch)
+	if err != nil {
+		t.Fatalf("MarshalBatch failed: %v", err)
+	}
+	readings := decodeProtoBatch(t, serializer, payload)
+	if len(readings) != 2 || readings[1].ID != "b" || readings[1].Data != 21.5 {
+		t.Errorf("Expected the batch to arrive intact, got %+v", readings)
+	}
+
+	single, _ := serializer.Marshal(batch[1])
+	message := dynamicpb.NewMessage(sensorReadingDescriptor(t))
+	if err := proto.Unmarshal(single, message); err != nil {
+		t.Fatalf("Payload does not match the schema: %v", err)
+	}
+	if message.Has(message.Descriptor().Fields().ByName("data_json")) {
+		t.Error("Expected Data only in data_proto")
+	}
+
+	if _, err := (ProtoSerializer[float64]{}).Unmarshal(single); err == nil {
+		t.Error("Expected an error decoding data_proto without UnmarshalData")
+	}
+}
+
+// decodeProtoBatch decodes a SensorReadingBatch
+func decodeProtoBatch[T any](t *testing.T, serializer ProtoSerializer[T], payload []byte) []engine.SensorData[T] {
+	t.Helper()
+	var readings []engine.SensorData[T]
+	for len(payload) > 0 {
+		number, wireType, n := protowire.ConsumeTag(payload)
+		if n < 0 || number != 1 || wireType != protowire.BytesType {
+			t.Fatalf("Expected a readings field, got field %d of type %d", number, wireType)
+		}
+		reading, m := protowire.ConsumeBytes(payload[n:])
+		if m < 0 {
+			t.Fatalf("Malformed reading: %v", protowire.ParseError(m))
+		}
+		decoded, err := serializer.Unmarshal(reading)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		readings = append(readings, decoded)
+		payload = payload[n+m:]
+	}
+	return readings
+}
+
+func TestGenericHTTPPublisher_Protobuf(t *testing.T) {
+	var contentType string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisherWithSerializer[float64](server.URL, ProtoSerializer[float64]{})
+	if err := publisher.PublishBatch(context.Background(), testReadings("a", "b")); err != nil {
+		t.Fatalf("Unexpected error publishing batch: %v", err)
+	}
+
+	if contentType != "application/x-protobuf" {
+		t.Errorf("Expected Content-Type application/x-protobuf, got %q", contentType)
+	}
+	if received := decodeProtoBatch(t, ProtoSerializer[float64]{}, body); len(received) != 2 || received[1].ID != "b" || received[1].Data != 1 {
+		t.Errorf("Expected the batch to arrive intact, got %+v", received)
+	}
+
+	envelopes := []engine.Envelope[float64]{{SensorData: testReadings("c")[0]}}
+	if err := publisher.PublishEnvelopes(context.Background(), envelopes); err == nil {
+		t.Error("Expected an error publishing envelopes with a serializer")
+	}
+}
+
+func TestGenericKafkaPublisher_Protobuf(t *testing.T) {
+	serializer := ProtoSerializer[float64]{}
+	publisher := NewGenericKafkaPublisher[float64]([]string{"localhost:9092"}, "sensors", WithKafkaSerializer[float64](serializer))
+	writer := &fakeKafkaWriter{}
+	publisher.writer = writer
+
+	if err := publisher.PublishBatch(context.Background(), testReadings("a", "b")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(writer.calls) != 1 || len(writer.calls[0]) != 2 {
+		t.Fatalf("Expected one call with two messages, got %v", writer.calls)
+	}
+	for _, msg := range writer.calls[0] {
+		reading, err := serializer.Unmarshal(msg.Value)
+		if err != nil || reading.ID != string(msg.Key) {
+			t.Errorf("Expected a SensorReading for %s, got %+v (%v)", msg.Key, reading, err)
+		}
+	}
+}
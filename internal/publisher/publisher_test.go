@@ -2,6 +2,12 @@ package publisher
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -52,6 +58,273 @@ func TestGenericHTTPPublisher_PublishBatch(t *testing.T) {
 	}
 }
 
+func TestGenericHTTPPublisher_BatchEnvelope(t *testing.T) {
+	var received engine.BatchEnvelope[float64]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode envelope: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithBatchEnvelope())
+
+	batch := []engine.SensorData[float64]{
+		{ID: "batch-1", Timestamp: time.Now(), Data: 25.5, Quality: engine.QualityOK},
+		{ID: "batch-2", Timestamp: time.Now(), Data: 26.0, Quality: engine.QualityOK},
+	}
+
+	ctx := engine.WithBatchID(context.Background(), "trace-123")
+	if err := publisher.PublishBatch(ctx, batch); err != nil {
+		t.Fatalf("Unexpected error publishing batch: %v", err)
+	}
+
+	if received.BatchID != "trace-123" {
+		t.Errorf("Expected batch ID trace-123, got %q", received.BatchID)
+	}
+	if received.Count != 2 || len(received.Items) != 2 {
+		t.Errorf("Expected 2 items, got count=%d items=%d", received.Count, len(received.Items))
+	}
+	if received.CreatedAt.IsZero() {
+		t.Error("Envelope created_at should be set")
+	}
+	if !received.WindowStart.Equal(batch[0].Timestamp) || !received.WindowEnd.Equal(batch[1].Timestamp) {
+		t.Errorf("Expected window [%v, %v], got [%v, %v]", batch[0].Timestamp, batch[1].Timestamp, received.WindowStart, received.WindowEnd)
+	}
+}
+
+func TestGenericHTTPPublisher_PublishEnvelopes(t *testing.T) {
+	var received struct {
+		BatchID string                     `json:"batch_id"`
+		Count   int                        `json:"count"`
+		Items   []engine.Envelope[float64] `json:"items"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode envelopes: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithBatchEnvelope())
+	envelopes := []engine.Envelope[float64]{
+		{SensorData: engine.SensorData[float64]{ID: "a", Data: 1, Quality: engine.QualityOK}, Seq: 1, Source: "plant-7"},
+		{SensorData: engine.SensorData[float64]{ID: "b", Data: 2, Quality: engine.QualityOK}, Seq: 2, Source: "plant-7"},
+	}
+
+	ctx := engine.WithBatchID(context.Background(), "trace-456")
+	if err := publisher.PublishEnvelopes(ctx, envelopes); err != nil {
+		t.Fatalf("Unexpected error publishing envelopes: %v", err)
+	}
+
+	if received.BatchID != "trace-456" || received.Count != 2 {
+		t.Errorf("Expected batch trace-456 with count 2, got %q and %d", received.BatchID, received.Count)
+	}
+	if len(received.Items) != 2 || received.Items[1].ID != "b" || received.Items[1].Seq != 2 || received.Items[1].Source != "plant-7" {
+		t.Errorf("Expected the envelopes as batch items, got %+v", received.Items)
+	}
+}
+
+func TestGenericHTTPPublisher_RetryEventuallySucceeds(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithRetry(5, time.Millisecond, 10*time.Millisecond))
+
+	data := engine.SensorData[float64]{ID: "retry-1", Timestamp: time.Now(), Data: 1.0, Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Expected publish to succeed after retries, got: %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestGenericHTTPPublisher_RetryStopsAtClientError(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithRetry(5, time.Millisecond, 10*time.Millisecond))
+
+	data := engine.SensorData[float64]{ID: "retry-2", Timestamp: time.Now(), Data: 1.0, Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err == nil {
+		t.Fatal("Expected error for 400 response")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 4xx not to be retried, got %d attempts", got)
+	}
+}
+
+func TestGenericHTTPPublisher_RetryRespectsDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithRetry(10, time.Second, 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	data := engine.SensorData[float64]{ID: "retry-3", Timestamp: time.Now(), Data: 1.0, Quality: engine.QualityOK}
+	if err := publisher.Publish(ctx, data); err == nil {
+		t.Fatal("Expected error from always-failing server")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Retries should be bounded by the context deadline, took %v", elapsed)
+	}
+}
+
+func TestGenericHTTPPublisher_BackoffFullJitter(t *testing.T) {
+	publisher := NewGenericHTTPPublisher[float64]("http://localhost", WithRetry(5, 10*time.Millisecond, 50*time.Millisecond))
+
+	for attempt, ceiling := range []time.Duration{10, 20, 40, 50, 50} {
+		ceiling *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d, ok := publisher.options.backoff.NextDelay(attempt); !ok || d < 0 || d > ceiling {
+				t.Fatalf("Attempt %d: delay %v outside [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestGenericHTTPPublisher_WithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithHTTPClient(&http.Client{Transport: transport}))
+
+	data := engine.SensorData[float64]{ID: "client-1", Timestamp: time.Now(), Data: 1.0, Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transport.requests.Load() != 1 {
+		t.Errorf("Expected the injected client to be used, got %d requests", transport.requests.Load())
+	}
+}
+
+func TestGenericHTTPPublisher_WithTransport(t *testing.T) {
+	config := HighThroughputTransport()
+	config.DisableKeepAlives = true
+	publisher := NewGenericHTTPPublisher[float64]("http://localhost", WithTransport(config))
+
+	transport, ok := publisher.client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", publisher.client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != config.MaxIdleConnsPerHost {
+		t.Errorf("Expected MaxIdleConnsPerHost %d, got %d", config.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxIdleConns != config.MaxIdleConns {
+		t.Errorf("Expected MaxIdleConns %d, got %d", config.MaxIdleConns, transport.MaxIdleConns)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("Expected keep-alives to be disabled")
+	}
+	if publisher.client.Timeout != 5*time.Second {
+		t.Errorf("Expected default timeout to be kept, got %v", publisher.client.Timeout)
+	}
+}
+
+func TestGenericHTTPPublisher_ConnectTimeout(t *testing.T) {
+	// 10.255.255.1 is unroutable, so connecting hangs until the timeout
+	// unless the network rejects it outright
+	publisher := NewGenericHTTPPublisher[float64]("http://10.255.255.1:81",
+		WithConnectTimeout(100*time.Millisecond), WithRequestTimeout(time.Minute))
+
+	start := time.Now()
+	err := publisher.Publish(context.Background(), testReadings("a")[0])
+	if err == nil {
+		t.Fatal("Expected an error publishing to an unreachable host")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the connect timeout to fail fast, took %v", elapsed)
+	}
+	if publisher.client.Timeout != 0 {
+		t.Errorf("Expected the request timeout to replace the client timeout, got %v", publisher.client.Timeout)
+	}
+}
+
+func TestGenericHTTPPublisher_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithRequestTimeout(50*time.Millisecond))
+	start := time.Now()
+	if err := publisher.Publish(context.Background(), testReadings("a")[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected the request to stop after 50ms, took %v", elapsed)
+	}
+}
+
+// countingTransport counts requests passing through it
+type countingTransport struct {
+	next     http.RoundTripper
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return c.next.RoundTrip(req)
+}
+
+func TestGenericHTTPPublisher_WithObserver(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received.Add(int64(len(body)))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	metrics := &engine.PublishMetrics{}
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithObserver(metrics))
+	failing := NewGenericHTTPPublisher[float64](server.URL+"/fail", WithObserver(metrics))
+
+	data := engine.SensorData[float64]{ID: "obs-1", Timestamp: time.Now(), Data: 1.5, Quality: engine.QualityOK}
+	if err := publisher.Publish(context.Background(), data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := publisher.PublishBatch(context.Background(), []engine.SensorData[float64]{data, data}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := failing.Publish(context.Background(), data); err == nil {
+		t.Fatal("Expected error from failing endpoint")
+	}
+
+	snapshot := metrics.Snapshot()
+	if snapshot.Operations != 3 {
+		t.Errorf("Expected 3 observed operations, got %d", snapshot.Operations)
+	}
+	if snapshot.Errors != 1 {
+		t.Errorf("Expected 1 observed error, got %d", snapshot.Errors)
+	}
+	payload, _ := json.Marshal(data)
+	if want := uint64(received.Load()) - uint64(len(payload)); snapshot.Bytes != want {
+		t.Errorf("Expected %d bytes from successful operations, got %d", want, snapshot.Bytes)
+	}
+	if snapshot.AvgLatency <= 0 {
+		t.Error("Expected a positive average latency")
+	}
+}
+
 func TestGenericHTTPPublisher_Close(t *testing.T) {
 	publisher := NewGenericHTTPPublisher[float64]("https://example.com")
 
@@ -59,6 +332,31 @@ func TestGenericHTTPPublisher_Close(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error closing HTTP publisher: %v", err)
 	}
+
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Unexpected error closing HTTP publisher twice: %v", err)
+	}
+}
+
+// idleClosingTransport counts the times its idle connections are closed
+type idleClosingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (i *idleClosingTransport) CloseIdleConnections() {
+	i.closed++
+}
+
+func TestGenericHTTPPublisher_CloseLeavesInjectedClient(t *testing.T) {
+	transport := &idleClosingTransport{RoundTripper: http.DefaultTransport}
+	publisher := NewGenericHTTPPublisher[float64]("https://example.com", WithHTTPClient(&http.Client{Transport: transport}))
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Unexpected error closing HTTP publisher: %v", err)
+	}
+	if transport.closed != 0 {
+		t.Errorf("Expected the injected client's connections to be left open, closed %d times", transport.closed)
+	}
 }
 
 func TestGenericKafkaPublisher_Publish(t *testing.T) {
@@ -121,6 +419,10 @@ func TestGenericKafkaPublisher_Close(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error closing Kafka publisher: %v", err)
 	}
+
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Unexpected error closing Kafka publisher twice: %v", err)
+	}
 }
 
 func TestGenericGRPCPublisher_Publish(t *testing.T) {
@@ -185,6 +487,10 @@ func TestGenericGRPCPublisher_Close(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error closing gRPC publisher: %v", err)
 	}
+
+	if err := publisher.Close(); err != nil {
+		t.Errorf("Unexpected error closing gRPC publisher twice: %v", err)
+	}
 }
 
 // Mock publisher for testing
@@ -280,6 +586,7 @@ func BenchmarkGenericHTTPPublisher_Publish(b *testing.B) {
 		Quality:   engine.QualityOK,
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		// Note: This benchmark requires internet connection
@@ -296,6 +603,7 @@ func BenchmarkMockPublisher_Publish(b *testing.B) {
 		Quality:   engine.QualityOK,
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		publisher.Publish(context.Background(), data)
@@ -314,6 +622,7 @@ func BenchmarkMockPublisher_PublishBatch(b *testing.B) {
 		}
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		publisher.PublishBatch(context.Background(), batch)
@@ -0,0 +1,102 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// DefaultReplayInterval is the gap ReplayNDJSON uses for readings whose
+// timestamp cannot be trusted when ReplayOptions.FallbackInterval is unset
+const DefaultReplayInterval = time.Second
+
+// ReplayOptions sets the pace of ReplayNDJSON
+type ReplayOptions struct {
+	// Speed scales the captured gaps: 2 replays twice as fast, 0.5 at half
+	// speed. Zero or less replays in real time.
+	Speed float64
+
+	// FallbackInterval is waited, regardless of Speed, before a reading
+	// without a timestamp or with one earlier than a reading already
+	// replayed; zero uses DefaultReplayInterval
+	FallbackInterval time.Duration
+}
+
+// ReplayNDJSON publishes the readings of an NDJSON capture, as written by a
+// FilePublisher, to next one at a time, waiting between readings for the gap
+// between their timestamps divided by Speed. Gaps are measured from the
+// latest timestamp replayed so far, so a reading out of order does not
+// stretch the gap after it. It returns the number of readings published and
+// stops at the first malformed line, publish error or cancellation of ctx.
+func ReplayNDJSON[T any](ctx context.Context, r io.Reader, next engine.Publisher[T], options ReplayOptions) (int, error) {
+	return replayNDJSON(ctx, r, next, options, sleepContext)
+}
+
+// replayNDJSON replays with a replaceable sleep, for tests
+func replayNDJSON[T any](ctx context.Context, r io.Reader, next engine.Publisher[T], options ReplayOptions, sleep func(context.Context, time.Duration) error) (int, error) {
+	speed := options.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	fallback := options.FallbackInterval
+	if fallback <= 0 {
+		fallback = DefaultReplayInterval
+	}
+
+	var latest time.Time
+	published, line := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var data engine.SensorData[T]
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			return published, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+
+		if published > 0 {
+			wait := fallback
+			if !data.Timestamp.IsZero() && !latest.IsZero() && !data.Timestamp.Before(latest) {
+				wait = time.Duration(float64(data.Timestamp.Sub(latest)) / speed)
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return published, err
+			}
+		}
+		if data.Timestamp.After(latest) {
+			latest = data.Timestamp
+		}
+
+		if err := next.Publish(ctx, data); err != nil {
+			return published, fmt.Errorf("failed to publish record on line %d: %w", line, err)
+		}
+		published++
+	}
+	if err := scanner.Err(); err != nil {
+		return published, fmt.Errorf("failed to read capture: %w", err)
+	}
+	return published, nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
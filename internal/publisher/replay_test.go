@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayNDJSON_Pacing(t *testing.T) {
+	capture, err := os.Open("testdata/capture.ndjson")
+	if err != nil {
+		t.Fatalf("Failed to open capture: %v", err)
+	}
+	defer capture.Close()
+
+	var waits []time.Duration
+	sleep := func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	sink := NewRingBufferPublisher[float64](16)
+	published, err := replayNDJSON(context.Background(), capture, sink, ReplayOptions{Speed: 2, FallbackInterval: 100 * time.Millisecond}, sleep)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	var ids []string
+	for _, d := range sink.Snapshot() {
+		ids = append(ids, d.ID)
+	}
+	if wantIDs := []string{"sensor-1", "sensor-2", "sensor-3", "sensor-4", "sensor-5", "sensor-6"}; published != 6 || !slices.Equal(ids, wantIDs) {
+		t.Fatalf("Expected %v in capture order, got %d published: %v", wantIDs, published, ids)
+	}
+	if data := sink.Snapshot()[2]; data.Data != 21.1 || data.Quality != "NOISY" {
+		t.Errorf("Expected readings to be replayed unchanged, got %+v", data)
+	}
+
+	// Captured gaps are halved; the missing and out-of-order timestamps get
+	// the fallback interval, and sensor-6 is measured from sensor-3
+	want := []time.Duration{500 * time.Millisecond, time.Second, 100 * time.Millisecond, 100 * time.Millisecond, 500 * time.Millisecond}
+	if !slices.Equal(waits, want) {
+		t.Errorf("Expected waits %v, got %v", want, waits)
+	}
+}
+
+func TestReplayNDJSON_Errors(t *testing.T) {
+	noSleep := func(context.Context, time.Duration) error { return nil }
+	capture := `{"id":"a","timestamp":"2024-03-01T12:00:00Z","data":1}` + "\nnot json\n"
+	sink := NewRingBufferPublisher[float64](4)
+	published, err := replayNDJSON(context.Background(), strings.NewReader(capture), sink, ReplayOptions{}, noSleep)
+	if err == nil || !strings.Contains(err.Error(), "line 2") || published != 1 {
+		t.Errorf("Expected an error on line 2 after one reading, got %d published and %v", published, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	capture = strings.Repeat(`{"id":"a","timestamp":"2024-03-01T12:00:00Z","data":1}`+"\n", 2)
+	if _, err := ReplayNDJSON(ctx, strings.NewReader(capture), sink, ReplayOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected replay to stop when cancelled, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// RingBufferPublisher keeps the most recent readings in a fixed-size
+// circular buffer, overwriting the oldest once it is full. Memory use is
+// bounded however long the engine runs, and Snapshot can be called at any
+// time for live inspection from tests, UIs or HTTP handlers.
+type RingBufferPublisher[T any] struct {
+	mutex    sync.RWMutex
+	readings []engine.SensorData[T]
+	next     int    // Index the next reading is written to
+	full     bool   // Whether the buffer has wrapped around
+	total    uint64 // Readings received, including overwritten ones
+}
+
+// NewRingBufferPublisher creates a ring buffer holding up to capacity
+// readings. A capacity below 1 is treated as 1.
+func NewRingBufferPublisher[T any](capacity int) *RingBufferPublisher[T] {
+	return &RingBufferPublisher[T]{
+		readings: make([]engine.SensorData[T], max(capacity, 1)),
+	}
+}
+
+// Publish stores a single sensor data point
+func (r *RingBufferPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.add(data)
+	return nil
+}
+
+// PublishBatch stores a batch of sensor data points
+func (r *RingBufferPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, d := range data {
+		r.add(d)
+	}
+	return nil
+}
+
+// add stores one reading, overwriting the oldest when the buffer is full
+func (r *RingBufferPublisher[T]) add(data engine.SensorData[T]) {
+	r.readings[r.next] = data
+	r.next++
+	if r.next == len(r.readings) {
+		r.next = 0
+		r.full = true
+	}
+	r.total++
+}
+
+// Snapshot returns a copy of the buffered readings, oldest first
+func (r *RingBufferPublisher[T]) Snapshot() []engine.SensorData[T] {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if !r.full {
+		return append([]engine.SensorData[T](nil), r.readings[:r.next]...)
+	}
+	snapshot := make([]engine.SensorData[T], 0, len(r.readings))
+	snapshot = append(snapshot, r.readings[r.next:]...)
+	return append(snapshot, r.readings[:r.next]...)
+}
+
+// Len returns the number of buffered readings
+func (r *RingBufferPublisher[T]) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.full {
+		return len(r.readings)
+	}
+	return r.next
+}
+
+// Total returns the number of readings received, including those that have
+// since been overwritten
+func (r *RingBufferPublisher[T]) Total() uint64 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.total
+}
+
+// Close does nothing; the buffered readings remain available to Snapshot
+func (r *RingBufferPublisher[T]) Close() error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func snapshotIDs(r *RingBufferPublisher[float64]) []string {
+	var ids []string
+	for _, d := range r.Snapshot() {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func TestRingBufferPublisher_KeepsMostRecent(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](3)
+	ctx := context.Background()
+
+	if ids := snapshotIDs(ring); len(ids) != 0 {
+		t.Errorf("Expected an empty snapshot, got %v", ids)
+	}
+
+	if err := ring.PublishBatch(ctx, testReadings("a", "b")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if ids := fmt.Sprint(snapshotIDs(ring)); ids != "[a b]" {
+		t.Errorf("Expected [a b] before wrapping, got %s", ids)
+	}
+
+	if err := ring.PublishBatch(ctx, testReadings("c", "d")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if err := ring.Publish(ctx, testReadings("e")[0]); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if ids := fmt.Sprint(snapshotIDs(ring)); ids != "[c d e]" {
+		t.Errorf("Expected the 3 most recent readings [c d e], got %s", ids)
+	}
+	if ring.Len() != 3 || ring.Total() != 5 {
+		t.Errorf("Expected Len 3 and Total 5, got %d and %d", ring.Len(), ring.Total())
+	}
+}
+
+func TestRingBufferPublisher_SnapshotIsACopy(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](2)
+	ring.PublishBatch(context.Background(), testReadings("a", "b"))
+
+	snapshot := ring.Snapshot()
+	snapshot[0].ID = "changed"
+	if ids := fmt.Sprint(snapshotIDs(ring)); ids != "[a b]" {
+		t.Errorf("Modifying a snapshot changed the buffer: %s", ids)
+	}
+}
+
+func TestRingBufferPublisher_Concurrent(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](50)
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				ring.Publish(context.Background(), engine.SensorData[float64]{ID: fmt.Sprintf("%d-%d", w, i)})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if n := len(ring.Snapshot()); n > 50 {
+					t.Errorf("Snapshot exceeded capacity: %d", n)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ring.Len() != 50 || ring.Total() != 400 {
+		t.Errorf("Expected Len 50 and Total 400, got %d and %d", ring.Len(), ring.Total())
+	}
+}
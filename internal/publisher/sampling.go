@@ -0,0 +1,134 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// SamplingConfig selects which readings a SamplingPublisher forwards
+type SamplingConfig struct {
+	SampleEveryN int         // Forward every Nth reading, starting with the first
+	SampleRate   float64     // Without SampleEveryN, forward each reading with this probability
+	Source       rand.Source // Random source for SampleRate; nil uses the global source
+}
+
+// SamplingPublisher forwards a subset of the readings it receives to the
+// next publisher and drops the rest. Unlike aggregation, forwarded readings
+// are passed on unchanged. Dropped readings count as published in the
+// engine's Stats, since the engine handed them over successfully; Counts
+// reports how many were actually forwarded.
+type SamplingPublisher[T any] struct {
+	next   engine.Publisher[T]
+	config SamplingConfig
+	rand   *rand.Rand
+
+	mutex     sync.Mutex
+	received  uint64
+	forwarded uint64
+}
+
+// NewSamplingPublisher creates a sampler in front of next. With neither
+// SampleEveryN above 1 nor SampleRate below 1 set, every reading is
+// forwarded.
+func NewSamplingPublisher[T any](next engine.Publisher[T], config SamplingConfig) *SamplingPublisher[T] {
+	s := &SamplingPublisher[T]{next: next, config: config}
+	if config.Source != nil {
+		s.rand = rand.New(config.Source)
+	}
+	return s
+}
+
+// keep decides whether the next reading is forwarded, counting it. The
+// mutex must be held.
+func (s *SamplingPublisher[T]) keep() bool {
+	s.received++
+
+	var keep bool
+	switch {
+	case s.config.SampleEveryN > 1:
+		keep = (s.received-1)%uint64(s.config.SampleEveryN) == 0
+	case s.config.SampleRate > 0 && s.config.SampleRate < 1:
+		if s.rand != nil {
+			keep = s.rand.Float64() < s.config.SampleRate
+		} else {
+			keep = rand.Float64() < s.config.SampleRate
+		}
+	default:
+		keep = true
+	}
+
+	if keep {
+		s.forwarded++
+	}
+	return keep
+}
+
+// Publish forwards a single sensor data point if it is sampled
+func (s *SamplingPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	s.mutex.Lock()
+	keep := s.keep()
+	s.mutex.Unlock()
+
+	if !keep {
+		return nil
+	}
+	return s.next.Publish(ctx, data)
+}
+
+// PublishBatch forwards the sampled readings of a batch as one batch, or
+// nothing if none is sampled
+func (s *SamplingPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	s.mutex.Lock()
+	sampled := make([]engine.SensorData[T], 0, len(data))
+	for _, d := range data {
+		if s.keep() {
+			sampled = append(sampled, d)
+		}
+	}
+	s.mutex.Unlock()
+
+	if len(sampled) == 0 {
+		return nil
+	}
+	return s.next.PublishBatch(ctx, sampled)
+}
+
+// PublishEnvelopes forwards the sampled envelopes of a batch, for engines in
+// envelope mode. The next publisher must implement engine.EnvelopePublisher.
+func (s *SamplingPublisher[T]) PublishEnvelopes(ctx context.Context, envelopes []engine.Envelope[T]) error {
+	next, ok := s.next.(engine.EnvelopePublisher[T])
+	if !ok {
+		return fmt.Errorf("sampling: next publisher %T does not support envelopes", s.next)
+	}
+
+	s.mutex.Lock()
+	sampled := make([]engine.Envelope[T], 0, len(envelopes))
+	for _, env := range envelopes {
+		if s.keep() {
+			sampled = append(sampled, env)
+		}
+	}
+	s.mutex.Unlock()
+
+	if len(sampled) == 0 {
+		return nil
+	}
+	return next.PublishEnvelopes(ctx, sampled)
+}
+
+// Counts returns how many readings were received and how many of them were
+// forwarded
+func (s *SamplingPublisher[T]) Counts() (received, forwarded uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.received, s.forwarded
+}
+
+// Close closes the next publisher
+func (s *SamplingPublisher[T]) Close() error {
+	return s.next.Close()
+}
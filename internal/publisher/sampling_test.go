@@ -0,0 +1,70 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// numberedIDs returns the IDs 0 to n-1
+func numberedIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+	return ids
+}
+
+func TestSamplingPublisher_EveryN(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](100)
+	sampler := NewSamplingPublisher[float64](ring, SamplingConfig{SampleEveryN: 10})
+	ctx := context.Background()
+
+	readings := testReadings(numberedIDs(100)...)
+	for i := 0; i < 90; i += 6 {
+		if err := sampler.PublishBatch(ctx, readings[i:i+6]); err != nil {
+			t.Fatalf("PublishBatch failed: %v", err)
+		}
+	}
+	for _, d := range readings[90:] {
+		if err := sampler.Publish(ctx, d); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	if ids := fmt.Sprint(snapshotIDs(ring)); ids != "[0 10 20 30 40 50 60 70 80 90]" {
+		t.Errorf("Expected every 10th reading across batches and singles, got %s", ids)
+	}
+	if received, forwarded := sampler.Counts(); received != 100 || forwarded != 10 {
+		t.Errorf("Expected 100 received and 10 forwarded, got %d and %d", received, forwarded)
+	}
+}
+
+func TestSamplingPublisher_Rate(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](10000)
+	sampler := NewSamplingPublisher[float64](ring, SamplingConfig{SampleRate: 0.1, Source: rand.NewPCG(1, 2)})
+
+	if err := sampler.PublishBatch(context.Background(), testReadings(numberedIDs(10000)...)); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+
+	if n := ring.Len(); n < 900 || n > 1100 {
+		t.Errorf("Expected roughly 1000 of 10000 readings forwarded at rate 0.1, got %d", n)
+	}
+	if _, forwarded := sampler.Counts(); forwarded != uint64(ring.Len()) {
+		t.Errorf("Counts reports %d forwarded, the next publisher got %d", forwarded, ring.Len())
+	}
+}
+
+func TestSamplingPublisher_Unset(t *testing.T) {
+	ring := NewRingBufferPublisher[float64](10)
+	sampler := NewSamplingPublisher[float64](ring, SamplingConfig{})
+
+	if err := sampler.PublishBatch(context.Background(), testReadings("a", "b", "c")); err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if n := ring.Len(); n != 3 {
+		t.Errorf("Expected every reading forwarded without a sampling setting, got %d", n)
+	}
+}
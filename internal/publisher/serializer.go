@@ -0,0 +1,87 @@
+package publisher
+
+import (
+	"encoding/json"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Serializer encodes readings into the bytes a publisher sends and decodes
+// them back
+type Serializer[T any] interface {
+	Marshal(data engine.SensorData[T]) ([]byte, error)
+	MarshalBatch(data []engine.SensorData[T]) ([]byte, error)
+	Unmarshal(payload []byte) (engine.SensorData[T], error)
+	ContentType() string
+}
+
+// JSONSerializer encodes readings as JSON, the format publishers use by
+// default
+type JSONSerializer[T any] struct {
+	Options JSONOptions
+}
+
+// Marshal encodes a single reading
+func (s JSONSerializer[T]) Marshal(data engine.SensorData[T]) ([]byte, error) {
+	return s.Options.Marshal(data)
+}
+
+// MarshalBatch encodes a batch of readings as a JSON array
+func (s JSONSerializer[T]) MarshalBatch(data []engine.SensorData[T]) ([]byte, error) {
+	return s.Options.Marshal(data)
+}
+
+// Unmarshal decodes a single reading
+func (s JSONSerializer[T]) Unmarshal(payload []byte) (engine.SensorData[T], error) {
+	var data engine.SensorData[T]
+	err := json.Unmarshal(payload, &data)
+	return data, err
+}
+
+// ContentType returns the media type of JSON payloads
+func (s JSONSerializer[T]) ContentType() string {
+	return "application/json"
+}
+
+// CBORSerializer encodes readings as CBOR (RFC 8949), a binary format that
+// keeps integers and floats typed and is much smaller than JSON for
+// numeric-heavy readings, which suits bandwidth-constrained IoT links.
+// Fields use the same names as in JSON; timestamps are tagged RFC 3339
+// strings, so they keep nanosecond precision.
+type CBORSerializer[T any] struct{}
+
+// Marshal encodes a single reading
+func (CBORSerializer[T]) Marshal(data engine.SensorData[T]) ([]byte, error) {
+	return cborEncMode.Marshal(data)
+}
+
+// MarshalBatch encodes a batch of readings as a CBOR array
+func (CBORSerializer[T]) MarshalBatch(data []engine.SensorData[T]) ([]byte, error) {
+	return cborEncMode.Marshal(data)
+}
+
+// Unmarshal decodes a single reading
+func (CBORSerializer[T]) Unmarshal(payload []byte) (engine.SensorData[T], error) {
+	var data engine.SensorData[T]
+	err := cbor.Unmarshal(payload, &data)
+	return data, err
+}
+
+// ContentType returns the media type of CBOR payloads
+func (CBORSerializer[T]) ContentType() string {
+	return "application/cbor"
+}
+
+// cborEncMode encodes time.Time as tagged RFC 3339 strings; the library
+// default of whole Unix seconds would drop sub-second timestamps
+var cborEncMode = func() cbor.EncMode {
+	mode, err := cbor.EncOptions{
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
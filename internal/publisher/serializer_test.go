@@ -0,0 +1,110 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+	"github.com/fxamacker/cbor/v2"
+)
+
+type vitals struct {
+	HeartRate int     `json:"heart_rate"`
+	Oxygen    float64 `json:"oxygen"`
+	Alarm     bool    `json:"alarm"`
+}
+
+func TestCBORSerializer_RoundTrip(t *testing.T) {
+	input := 42.5
+	reading := engine.SensorData[vitals]{
+		ID:        "sensor-7",
+		Timestamp: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC),
+		Data:      vitals{HeartRate: 72, Oxygen: 97.5, Alarm: true},
+		Quality:   engine.QualityNoisy,
+		Input:     &input,
+	}
+
+	serializer := CBORSerializer[vitals]{}
+	payload, err := serializer.Marshal(reading)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	decoded, err := serializer.Unmarshal(payload)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, reading) {
+		t.Errorf("Expected %+v after the round trip, got %+v", reading, decoded)
+	}
+
+	jsonPayload, _ := JSONSerializer[vitals]{}.Marshal(reading)
+	if len(payload) >= len(jsonPayload) {
+		t.Errorf("Expected CBOR to be smaller than JSON, got %d and %d bytes", len(payload), len(jsonPayload))
+	}
+}
+
+func TestGenericHTTPPublisher_CBOR(t *testing.T) {
+	var contentType string
+	var received []engine.SensorData[float64]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		if err := cbor.Unmarshal(body, &received); err != nil {
+			t.Errorf("Failed to decode CBOR batch: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	publisher := NewGenericHTTPPublisher[float64](server.URL, WithCBOR())
+	if err := publisher.PublishBatch(context.Background(), testReadings("a", "b")); err != nil {
+		t.Fatalf("Unexpected error publishing batch: %v", err)
+	}
+
+	if contentType != "application/cbor" {
+		t.Errorf("Expected Content-Type application/cbor, got %q", contentType)
+	}
+	if len(received) != 2 || received[1].ID != "b" || received[1].Data != 1 {
+		t.Errorf("Expected the batch to arrive intact, got %+v", received)
+	}
+}
+
+func TestFilePublisher_CBORSequence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.cbor")
+	publisher, err := NewFilePublisher[float64](path, WithFileFormat(FileFormatCBOR))
+	if err != nil {
+		t.Fatalf("NewFilePublisher failed: %v", err)
+	}
+	publisher.PublishBatch(context.Background(), testReadings("a", "b"))
+	publisher.Publish(context.Background(), testReadings("c")[0])
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var ids []string
+	decoder := cbor.NewDecoder(bytes.NewReader(content))
+	for {
+		var data engine.SensorData[float64]
+		if err := decoder.Decode(&data); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Invalid CBOR item: %v", err)
+		}
+		ids = append(ids, data.ID)
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("Expected readings a, b, c, got %v", ids)
+	}
+}
@@ -0,0 +1,150 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+// StatsSnapshot is a point-in-time summary of the readings a
+// StatsSinkPublisher has received
+type StatsSnapshot struct {
+	Count     uint64                    `json:"count"`     // Readings received
+	Qualities map[engine.Quality]uint64 `json:"qualities"` // Readings received per quality
+
+	// Summary of the extracted numeric values; zero when no value was extracted
+	Values   uint64  `json:"values"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"` // Sample variance
+	StdDev   float64 `json:"std_dev"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+}
+
+// StatsSinkPublisher keeps running statistics over the readings it receives
+// instead of forwarding them. Mean and variance are maintained with Welford's
+// algorithm, so memory use is constant however long the stream runs.
+type StatsSinkPublisher[T any] struct {
+	extract func(T) (float64, bool)
+
+	mutex     sync.Mutex
+	count     uint64
+	qualities map[engine.Quality]uint64
+	values    uint64
+	mean      float64
+	m2        float64 // Sum of squared differences from the mean
+	min       float64
+	max       float64
+	closeOnce sync.Once
+}
+
+// NewStatsSinkPublisher creates a statistics sink. extract returns the
+// numeric field to summarize and whether the reading has one; with a nil
+// extract only counts per quality are kept, which suits non-numeric T.
+func NewStatsSinkPublisher[T any](extract func(T) (float64, bool)) *StatsSinkPublisher[T] {
+	return &StatsSinkPublisher[T]{
+		extract:   extract,
+		qualities: make(map[engine.Quality]uint64),
+	}
+}
+
+// NewNumericStatsSinkPublisher creates a statistics sink that summarizes
+// the reading values themselves
+func NewNumericStatsSinkPublisher[N engine.Number]() *StatsSinkPublisher[N] {
+	return NewStatsSinkPublisher(func(v N) (float64, bool) {
+		return float64(v), true
+	})
+}
+
+// Publish records a single sensor data point
+func (s *StatsSinkPublisher[T]) Publish(ctx context.Context, data engine.SensorData[T]) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.observe(data)
+	return nil
+}
+
+// PublishBatch records a batch of sensor data points
+func (s *StatsSinkPublisher[T]) PublishBatch(ctx context.Context, data []engine.SensorData[T]) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, d := range data {
+		s.observe(d)
+	}
+	return nil
+}
+
+// observe updates the statistics with one reading
+func (s *StatsSinkPublisher[T]) observe(data engine.SensorData[T]) {
+	s.count++
+	s.qualities[data.Quality]++
+
+	if s.extract == nil {
+		return
+	}
+	value, ok := s.extract(data.Data)
+	if !ok {
+		return
+	}
+
+	s.values++
+	if s.values == 1 {
+		s.min, s.max = value, value
+	} else {
+		s.min = math.Min(s.min, value)
+		s.max = math.Max(s.max, value)
+	}
+	delta := value - s.mean
+	s.mean += delta / float64(s.values)
+	s.m2 += delta * (value - s.mean)
+}
+
+// Snapshot returns the current statistics
+func (s *StatsSinkPublisher[T]) Snapshot() StatsSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := StatsSnapshot{
+		Count:     s.count,
+		Qualities: make(map[engine.Quality]uint64, len(s.qualities)),
+		Values:    s.values,
+		Mean:      s.mean,
+		Min:       s.min,
+		Max:       s.max,
+	}
+	for q, n := range s.qualities {
+		snapshot.Qualities[q] = n
+	}
+	if s.values > 1 {
+		snapshot.Variance = s.m2 / float64(s.values-1)
+		snapshot.StdDev = math.Sqrt(snapshot.Variance)
+	}
+	return snapshot
+}
+
+// Close prints a summary of the statistics. It is safe to call more than
+// once; the summary is printed only the first time.
+func (s *StatsSinkPublisher[T]) Close() error {
+	s.closeOnce.Do(func() {
+		snapshot := s.Snapshot()
+		fmt.Printf("Stats sink: %d readings\n", snapshot.Count)
+		if snapshot.Values > 0 {
+			fmt.Printf("  mean=%.4f std_dev=%.4f min=%.4f max=%.4f (%d values)\n",
+				snapshot.Mean, snapshot.StdDev, snapshot.Min, snapshot.Max, snapshot.Values)
+		}
+
+		qualities := make([]engine.Quality, 0, len(snapshot.Qualities))
+		for q := range snapshot.Qualities {
+			qualities = append(qualities, q)
+		}
+		sort.Slice(qualities, func(i, j int) bool { return qualities[i] < qualities[j] })
+		for _, q := range qualities {
+			fmt.Printf("  %s: %d\n", q, snapshot.Qualities[q])
+		}
+	})
+	return nil
+}
@@ -0,0 +1,122 @@
+package publisher
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Utsav-pixel/go-sensor-engine/internal/engine"
+)
+
+func TestStatsSinkPublisher_Numeric(t *testing.T) {
+	sink := NewNumericStatsSinkPublisher[float64]()
+
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	batch := make([]engine.SensorData[float64], len(values))
+	for i, v := range values {
+		batch[i] = engine.SensorData[float64]{ID: "s", Timestamp: time.Now(), Data: v, Quality: engine.QualityOK}
+	}
+	batch[0].Quality = engine.QualityNoisy
+
+	if err := sink.PublishBatch(context.Background(), batch[:4]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, d := range batch[4:] {
+		if err := sink.Publish(context.Background(), d); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	snapshot := sink.Snapshot()
+	if snapshot.Count != 8 || snapshot.Values != 8 {
+		t.Errorf("Expected 8 readings and values, got %d and %d", snapshot.Count, snapshot.Values)
+	}
+	if snapshot.Mean != 5 {
+		t.Errorf("Expected mean 5, got %v", snapshot.Mean)
+	}
+	if want := 32.0 / 7; math.Abs(snapshot.Variance-want) > 1e-9 {
+		t.Errorf("Expected sample variance %v, got %v", want, snapshot.Variance)
+	}
+	if snapshot.Min != 2 || snapshot.Max != 9 {
+		t.Errorf("Expected min 2 and max 9, got %v and %v", snapshot.Min, snapshot.Max)
+	}
+	if snapshot.Qualities[engine.QualityOK] != 7 || snapshot.Qualities[engine.QualityNoisy] != 1 {
+		t.Errorf("Unexpected quality counts: %v", snapshot.Qualities)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Errorf("Unexpected error closing: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Second Close should be a no-op, got %v", err)
+	}
+}
+
+func TestStatsSinkPublisher_NonNumeric(t *testing.T) {
+	sink := NewStatsSinkPublisher[string](nil)
+
+	batch := []engine.SensorData[string]{
+		{ID: "a", Data: "open", Quality: engine.QualityOK},
+		{ID: "b", Data: "closed", Quality: engine.QualityCorrupt},
+		{ID: "c", Data: "open", Quality: engine.QualityOK},
+	}
+	if err := sink.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := sink.Snapshot()
+	if snapshot.Count != 3 || snapshot.Values != 0 {
+		t.Errorf("Expected 3 readings and no values, got %d and %d", snapshot.Count, snapshot.Values)
+	}
+	if snapshot.Qualities[engine.QualityOK] != 2 || snapshot.Qualities[engine.QualityCorrupt] != 1 {
+		t.Errorf("Unexpected quality counts: %v", snapshot.Qualities)
+	}
+}
+
+func TestStatsSinkPublisher_StructField(t *testing.T) {
+	type reading struct {
+		Temperature float64
+		Valid       bool
+	}
+	sink := NewStatsSinkPublisher(func(r reading) (float64, bool) {
+		return r.Temperature, r.Valid
+	})
+
+	batch := []engine.SensorData[reading]{
+		{Data: reading{Temperature: 10, Valid: true}},
+		{Data: reading{Temperature: 1000, Valid: false}},
+		{Data: reading{Temperature: 20, Valid: true}},
+	}
+	if err := sink.PublishBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := sink.Snapshot()
+	if snapshot.Count != 3 || snapshot.Values != 2 {
+		t.Errorf("Expected 3 readings and 2 values, got %d and %d", snapshot.Count, snapshot.Values)
+	}
+	if snapshot.Mean != 15 || snapshot.Max != 20 {
+		t.Errorf("Expected mean 15 and max 20, got %v and %v", snapshot.Mean, snapshot.Max)
+	}
+}
+
+func BenchmarkStatsSinkPublisher_PublishBatch(b *testing.B) {
+	type reading struct {
+		Temperature float64
+		Valid       bool
+	}
+	sink := NewStatsSinkPublisher(func(r reading) (float64, bool) {
+		return r.Temperature, r.Valid
+	})
+	batch := make([]engine.SensorData[reading], 100)
+	for i := range batch {
+		batch[i] = engine.SensorData[reading]{Data: reading{Temperature: float64(i), Valid: true}, Quality: engine.QualityOK}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		sink.PublishBatch(context.Background(), batch)
+	}
+}